@@ -0,0 +1,59 @@
+package armazenamento
+
+import (
+	"context"
+	"fmt"
+	"site/config"
+	"strings"
+)
+
+const (
+	TipoLocal = "local"
+	TipoGCS   = "gcs"
+
+	DiretorioPadrao = "/tmp/arquivos"
+	URLBasePadrao   = "/arquivos"
+)
+
+// Armazenamento guarda arquivos enviados pelos usuarios e devolve a URL publica de acesso
+type Armazenamento interface {
+	Salvar(c context.Context, nome string, conteudo []byte, tipo string) (string, error)
+	Remover(c context.Context, nome string) error
+}
+
+// Novo retorna o armazenamento configurado em armazenamento.tipo, usando o disco local como padrão
+func Novo(c context.Context) (Armazenamento, error) {
+	tipo := config.GetDefault(c, config.ArmazenamentoTipo, TipoLocal).Value
+
+	switch tipo {
+	case TipoLocal:
+		return NovoLocal(c), nil
+	case TipoGCS:
+		bucket := config.GetDefault(c, config.ArmazenamentoBucket, "").Value
+		if bucket == "" {
+			return nil, fmt.Errorf("Bucket do armazenamento não configurado")
+		}
+		return &GCS{Bucket: bucket}, nil
+	default:
+		return nil, fmt.Errorf("Tipo de armazenamento desconhecido: %s", tipo)
+	}
+}
+
+// NovoLocal retorna o armazenamento em disco com o diretorio e a URL base configurados
+func NovoLocal(c context.Context) *Local {
+	return &Local{
+		Diretorio: config.GetDefault(c, config.ArmazenamentoDiretorio, DiretorioPadrao).Value,
+		URLBase:   config.GetDefault(c, config.ArmazenamentoURLBase, URLBasePadrao).Value,
+	}
+}
+
+// validarNome impede nomes vazios ou que tentem sair do diretorio base
+func validarNome(nome string) error {
+	if nome == "" {
+		return fmt.Errorf("Nome do arquivo não informado")
+	}
+	if strings.HasPrefix(nome, "/") || strings.Contains(nome, "..") {
+		return fmt.Errorf("Nome do arquivo inválido: %s", nome)
+	}
+	return nil
+}
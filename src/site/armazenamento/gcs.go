@@ -0,0 +1,68 @@
+package armazenamento
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"site/utils/log"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/storage/v1"
+)
+
+// GCS grava os arquivos em um bucket do Cloud Storage com leitura publica
+type GCS struct {
+	Bucket string
+}
+
+func (g *GCS) Salvar(c context.Context, nome string, conteudo []byte, tipo string) (string, error) {
+	if err := validarNome(nome); err != nil {
+		return "", err
+	}
+
+	servico, err := storage.NewService(c)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Cloud Storage: %v", err)
+		return "", err
+	}
+
+	objeto := &storage.Object{
+		Name:         nome,
+		ContentType:  tipo,
+		CacheControl: "public, max-age=3600",
+	}
+	_, err = servico.Objects.Insert(g.Bucket, objeto).
+		Media(bytes.NewReader(conteudo), googleapi.ContentType(tipo)).
+		PredefinedAcl("publicRead").
+		Context(c).
+		Do()
+	if err != nil {
+		log.Warningf(c, "Falha ao gravar arquivo %s no bucket %s: %v", nome, g.Bucket, err)
+		return "", err
+	}
+
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", g.Bucket, nome), nil
+}
+
+func (g *GCS) Remover(c context.Context, nome string) error {
+	if err := validarNome(nome); err != nil {
+		return err
+	}
+
+	servico, err := storage.NewService(c)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Cloud Storage: %v", err)
+		return err
+	}
+
+	err = servico.Objects.Delete(g.Bucket, nome).Context(c).Do()
+	if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		log.Warningf(c, "Falha ao remover arquivo %s do bucket %s: %v", nome, g.Bucket, err)
+		return err
+	}
+	return nil
+}
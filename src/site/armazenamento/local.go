@@ -0,0 +1,49 @@
+package armazenamento
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"site/utils/log"
+	"strings"
+)
+
+// Local grava os arquivos em um diretorio do servidor, servido pela rota /arquivos
+type Local struct {
+	Diretorio string
+	URLBase   string
+}
+
+func (l *Local) Salvar(c context.Context, nome string, conteudo []byte, tipo string) (string, error) {
+	if err := validarNome(nome); err != nil {
+		return "", err
+	}
+
+	caminho := filepath.Join(l.Diretorio, filepath.FromSlash(nome))
+	if err := os.MkdirAll(filepath.Dir(caminho), 0755); err != nil {
+		log.Warningf(c, "Falha ao criar diretorio do arquivo %s: %v", caminho, err)
+		return "", err
+	}
+
+	if err := ioutil.WriteFile(caminho, conteudo, 0644); err != nil {
+		log.Warningf(c, "Falha ao gravar arquivo %s: %v", caminho, err)
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(l.URLBase, "/"), nome), nil
+}
+
+func (l *Local) Remover(c context.Context, nome string) error {
+	if err := validarNome(nome); err != nil {
+		return err
+	}
+
+	caminho := filepath.Join(l.Diretorio, filepath.FromSlash(nome))
+	if err := os.Remove(caminho); err != nil && !os.IsNotExist(err) {
+		log.Warningf(c, "Falha ao remover arquivo %s: %v", caminho, err)
+		return err
+	}
+	return nil
+}
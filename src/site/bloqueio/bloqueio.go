@@ -0,0 +1,151 @@
+package bloqueio
+
+import (
+	"context"
+	"fmt"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindBloqueio = "Bloqueio"
+)
+
+// Bloqueio impede o contato entre dois usuarios. A chave é "usuario:bloqueado", então bloquear de novo não duplica.
+type Bloqueio struct {
+	UsuarioID   int64
+	BloqueadoID int64
+	DataCriacao utils.JsonSpecialDateTime
+}
+
+func chaveBloqueio(usuarioID, bloqueadoID int64) *datastore.Key {
+	return datastore.NameKey(KindBloqueio, fmt.Sprintf("%d:%d", usuarioID, bloqueadoID), nil)
+}
+
+func PutBloqueio(c context.Context, bloqueio *Bloqueio) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := chaveBloqueio(bloqueio.UsuarioID, bloqueio.BloqueadoID)
+	if _, err = datastoreClient.Put(c, key, bloqueio); err != nil {
+		log.Warningf(c, "Erro ao inserir bloqueio: %v", err)
+		return err
+	}
+	return nil
+}
+
+func Bloquear(c context.Context, usuarioID, bloqueadoID int64) error {
+	if usuarioID == bloqueadoID {
+		return fmt.Errorf("Não é possivel bloquear você mesmo")
+	}
+
+	return PutBloqueio(c, &Bloqueio{
+		UsuarioID:   usuarioID,
+		BloqueadoID: bloqueadoID,
+		DataCriacao: utils.GetSpecialTimeNow(),
+	})
+}
+
+func Desbloquear(c context.Context, usuarioID, bloqueadoID int64) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	if err = datastoreClient.Delete(c, chaveBloqueio(usuarioID, bloqueadoID)); err != nil {
+		log.Warningf(c, "Erro ao remover bloqueio: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Bloqueado diz se algum dos dois usuarios bloqueou o outro
+func Bloqueado(c context.Context, usuarioA, usuarioB int64) bool {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return false
+	}
+	defer datastoreClient.Close()
+
+	keys := []*datastore.Key{chaveBloqueio(usuarioA, usuarioB), chaveBloqueio(usuarioB, usuarioA)}
+	bloqueios := make([]Bloqueio, len(keys))
+
+	bloqueado, err := algumEncontrado(datastoreClient.GetMulti(c, keys, bloqueios))
+	if err != nil {
+		log.Warningf(c, "Erro ao verificar bloqueio entre %d e %d: %v", usuarioA, usuarioB, err)
+	}
+	return bloqueado
+}
+
+// algumEncontrado interpreta o erro do GetMulti: sem erro as duas chaves existem; com MultiError, basta uma
+// posição sem erro. Outros erros são devolvidos para serem registrados.
+func algumEncontrado(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	if errs, ok := err.(datastore.MultiError); ok {
+		for _, e := range errs {
+			if e == nil {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, err
+}
+
+// BuscarBloqueados traz os ids dos usuarios bloqueados pelo usuario
+func BuscarBloqueados(c context.Context, usuarioID int64) ([]int64, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	var bloqueios []Bloqueio
+	q := datastore.NewQuery(KindBloqueio).Filter("UsuarioID =", usuarioID)
+	if _, err = datastoreClient.GetAll(c, q, &bloqueios); err != nil {
+		log.Warningf(c, "Erro ao buscar bloqueios: %v", err)
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(bloqueios))
+	for _, b := range bloqueios {
+		ids = append(ids, b.BloqueadoID)
+	}
+	return ids, nil
+}
+
+// BuscarBloqueadores traz os ids dos usuarios que bloquearam o usuario
+func BuscarBloqueadores(c context.Context, usuarioID int64) ([]int64, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	var bloqueios []Bloqueio
+	q := datastore.NewQuery(KindBloqueio).Filter("BloqueadoID =", usuarioID)
+	if _, err = datastoreClient.GetAll(c, q, &bloqueios); err != nil {
+		log.Warningf(c, "Erro ao buscar bloqueios: %v", err)
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(bloqueios))
+	for _, b := range bloqueios {
+		ids = append(ids, b.UsuarioID)
+	}
+	return ids, nil
+}
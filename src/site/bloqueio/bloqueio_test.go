@@ -0,0 +1,55 @@
+package bloqueio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+)
+
+func TestChaveBloqueio(t *testing.T) {
+	casos := []struct {
+		usuarioID, bloqueadoID int64
+		nome                   string
+	}{
+		{1, 2, "1:2"},
+		{2, 1, "2:1"},
+		{10, 10, "10:10"},
+	}
+	for _, caso := range casos {
+		key := chaveBloqueio(caso.usuarioID, caso.bloqueadoID)
+		if key.Kind != KindBloqueio || key.Name != caso.nome {
+			t.Errorf("chaveBloqueio(%d, %d) = %v, esperado %s", caso.usuarioID, caso.bloqueadoID, key, caso.nome)
+		}
+	}
+}
+
+func TestAlgumEncontrado(t *testing.T) {
+	falha := errors.New("falha no Datastore")
+
+	casos := []struct {
+		nome      string
+		err       error
+		bloqueado bool
+		falha     bool
+	}{
+		{"os dois bloquearam", nil, true, false},
+		{"só o primeiro bloqueou", datastore.MultiError{nil, datastore.ErrNoSuchEntity}, true, false},
+		{"só o segundo bloqueou", datastore.MultiError{datastore.ErrNoSuchEntity, nil}, true, false},
+		{"nenhum bloqueio", datastore.MultiError{datastore.ErrNoSuchEntity, datastore.ErrNoSuchEntity}, false, false},
+		{"erro fora do MultiError", falha, false, true},
+	}
+	for _, caso := range casos {
+		bloqueado, err := algumEncontrado(caso.err)
+		if bloqueado != caso.bloqueado || (err != nil) != caso.falha {
+			t.Errorf("%s: algumEncontrado() = %v, %v, esperado %v", caso.nome, bloqueado, err, caso.bloqueado)
+		}
+	}
+}
+
+func TestBloquearVoceMesmo(t *testing.T) {
+	if err := Bloquear(context.Background(), 7, 7); err == nil {
+		t.Errorf("Bloquear() deveria recusar bloquear o proprio usuario")
+	}
+}
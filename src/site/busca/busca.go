@@ -0,0 +1,313 @@
+package busca
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"site/utils/esclient"
+	"site/utils/log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olivere/elastic/v7"
+)
+
+const (
+	IndiceUsuarios         = "usuarios"
+	IndicePublicacoes      = "publicacoes"
+	IndiceEstabelecimentos = "estabelecimentos"
+
+	LimitePadrao    = 20
+	LimiteMaximo    = 100
+	TamanhoLoteBulk = 500
+
+	mapeamentoUsuarios = `{
+		"mappings": {
+			"properties": {
+				"ID":   {"type": "long"},
+				"Nome": {"type": "text"},
+				"Nick": {"type": "text", "fields": {"keyword": {"type": "keyword"}}}
+			}
+		}
+	}`
+
+	mapeamentoPublicacoes = `{
+		"mappings": {
+			"properties": {
+				"ID":          {"type": "long"},
+				"Titulo":      {"type": "text"},
+				"Conteudo":    {"type": "text"},
+				"AutorID":     {"type": "long"},
+				"AutorNick":   {"type": "keyword"},
+				"DataCriacao": {"type": "date"}
+			}
+		}
+	}`
+)
+
+// DocumentoUsuario é a parte do usuario que fica indexada para busca
+type DocumentoUsuario struct {
+	ID   int64
+	Nome string
+	Nick string
+}
+
+// DocumentoPublicacao é a parte da publicação que fica indexada para busca
+type DocumentoPublicacao struct {
+	ID          int64
+	Titulo      string
+	Conteudo    string
+	AutorID     int64
+	AutorNick   string
+	DataCriacao time.Time
+}
+
+type ResultadoUsuario struct {
+	DocumentoUsuario
+	Pontuacao float64
+	Destaques map[string][]string
+}
+
+type ResultadoPublicacao struct {
+	DocumentoPublicacao
+	Pontuacao float64
+	Destaques map[string][]string
+}
+
+// Busca concentra a indexação e as consultas no Elasticsearch
+type Busca struct {
+	cliente *elastic.Client
+}
+
+// ErrNaoConfigurado é retornado por Nova quando não há Elasticsearch configurado
+var ErrNaoConfigurado = esclient.ErrNotConfigured
+
+// O cliente é criado na primeira chamada e compartilhado pelas seguintes. A falta de configuração fica
+// guardada por intervaloConfiguracao, para configurar o Elasticsearch depois não exigir reiniciar a
+// instancia; os outros erros, como uma falha ao ler a configuração, são tentados de novo.
+var (
+	clienteMu          sync.Mutex
+	clientePadrao      *elastic.Client
+	semConfiguracaoAte time.Time
+)
+
+const intervaloConfiguracao = time.Minute
+
+// Nova cria a busca com o cliente configurado em elasticsearch.*
+func Nova(c context.Context) (*Busca, error) {
+	clienteMu.Lock()
+	defer clienteMu.Unlock()
+
+	if clientePadrao == nil {
+		if time.Now().Before(semConfiguracaoAte) {
+			return nil, ErrNaoConfigurado
+		}
+		cliente, err := esclient.NewClient(c)
+		if err == ErrNaoConfigurado {
+			semConfiguracaoAte = time.Now().Add(intervaloConfiguracao)
+		}
+		if err != nil {
+			return nil, err
+		}
+		clientePadrao = cliente
+	}
+	return NovaComCliente(clientePadrao), nil
+}
+
+func NovaComCliente(cliente *elastic.Client) *Busca {
+	return &Busca{cliente: cliente}
+}
+
+// CriarIndices cria os indices de usuarios, publicações e estabelecimentos caso ainda não existam
+func (b *Busca) CriarIndices(c context.Context) error {
+	indices := map[string]string{
+		IndiceUsuarios:         mapeamentoUsuarios,
+		IndicePublicacoes:      mapeamentoPublicacoes,
+		IndiceEstabelecimentos: mapeamentoEstabelecimentos,
+	}
+
+	for indice, mapeamento := range indices {
+		existe, err := b.cliente.IndexExists(indice).Do(c)
+		if err != nil {
+			return fmt.Errorf("Erro ao verificar indice %s: %v", indice, err)
+		}
+		if existe {
+			continue
+		}
+		if _, err = b.cliente.CreateIndex(indice).BodyString(mapeamento).Do(c); err != nil {
+			return fmt.Errorf("Erro ao criar indice %s: %v", indice, err)
+		}
+	}
+	return nil
+}
+
+// RecriarIndices apaga os indices e cria de novo vazios. Usado pela reindexação completa, para que
+// documentos de usuarios, publicações e estabelecimentos removidos ou ocultados não fiquem para trás.
+func (b *Busca) RecriarIndices(c context.Context) error {
+	for _, indice := range []string{IndiceUsuarios, IndicePublicacoes, IndiceEstabelecimentos} {
+		_, err := b.cliente.DeleteIndex(indice).Do(c)
+		if err != nil && !elastic.IsNotFound(err) {
+			return fmt.Errorf("Erro ao apagar indice %s: %v", indice, err)
+		}
+	}
+	return b.CriarIndices(c)
+}
+
+func (b *Busca) IndexarUsuario(c context.Context, doc DocumentoUsuario) error {
+	return b.indexar(c, IndiceUsuarios, doc.ID, doc)
+}
+
+func (b *Busca) RemoverUsuario(c context.Context, id int64) error {
+	return b.remover(c, IndiceUsuarios, id)
+}
+
+func (b *Busca) IndexarPublicacao(c context.Context, doc DocumentoPublicacao) error {
+	return b.indexar(c, IndicePublicacoes, doc.ID, doc)
+}
+
+func (b *Busca) RemoverPublicacao(c context.Context, id int64) error {
+	return b.remover(c, IndicePublicacoes, id)
+}
+
+func (b *Busca) indexar(c context.Context, indice string, id int64, doc interface{}) error {
+	if id == 0 {
+		return fmt.Errorf("Documento sem ID não pode ser indexado em %s", indice)
+	}
+	_, err := b.cliente.Index().Index(indice).Id(strconv.FormatInt(id, 10)).BodyJson(doc).Do(c)
+	return err
+}
+
+func (b *Busca) remover(c context.Context, indice string, id int64) error {
+	_, err := b.cliente.Delete().Index(indice).Id(strconv.FormatInt(id, 10)).Do(c)
+	if elastic.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// ReindexarUsuarios envia todos os usuarios em lotes pela API bulk
+func (b *Busca) ReindexarUsuarios(c context.Context, docs []DocumentoUsuario) (int, error) {
+	requisicoes := make([]elastic.BulkableRequest, 0, len(docs))
+	for _, doc := range docs {
+		requisicoes = append(requisicoes, elastic.NewBulkIndexRequest().Index(IndiceUsuarios).Id(strconv.FormatInt(doc.ID, 10)).Doc(doc))
+	}
+	return b.bulk(c, requisicoes)
+}
+
+// ReindexarPublicacoes envia todas as publicações em lotes pela API bulk
+func (b *Busca) ReindexarPublicacoes(c context.Context, docs []DocumentoPublicacao) (int, error) {
+	requisicoes := make([]elastic.BulkableRequest, 0, len(docs))
+	for _, doc := range docs {
+		requisicoes = append(requisicoes, elastic.NewBulkIndexRequest().Index(IndicePublicacoes).Id(strconv.FormatInt(doc.ID, 10)).Doc(doc))
+	}
+	return b.bulk(c, requisicoes)
+}
+
+func (b *Busca) bulk(c context.Context, requisicoes []elastic.BulkableRequest) (int, error) {
+	var indexados int
+	for inicio := 0; inicio < len(requisicoes); inicio += TamanhoLoteBulk {
+		fim := inicio + TamanhoLoteBulk
+		if fim > len(requisicoes) {
+			fim = len(requisicoes)
+		}
+
+		resp, err := b.cliente.Bulk().Add(requisicoes[inicio:fim]...).Do(c)
+		if err != nil {
+			return indexados, err
+		}
+		indexados += len(resp.Succeeded())
+		if falhas := resp.Failed(); len(falhas) > 0 {
+			return indexados, fmt.Errorf("%d documentos falharam no bulk, primeiro erro: %v", len(falhas), falhas[0].Error)
+		}
+	}
+	return indexados, nil
+}
+
+// BuscarUsuarios procura por nome ou nick aceitando prefixo ("joa" encontra "joao") e erros de digitação
+func (b *Busca) BuscarUsuarios(c context.Context, termo string, limite int) ([]ResultadoUsuario, error) {
+	termo = strings.TrimSpace(termo)
+	if termo == "" {
+		return nil, fmt.Errorf("Termo de busca não informado")
+	}
+
+	consulta := elastic.NewBoolQuery().
+		Should(
+			elastic.NewMultiMatchQuery(termo, "Nome", "Nick").Type("phrase_prefix"),
+			elastic.NewMultiMatchQuery(termo, "Nome", "Nick").Fuzziness("AUTO"),
+			elastic.NewPrefixQuery("Nick.keyword", termo),
+		).
+		MinimumNumberShouldMatch(1)
+
+	destaque := elastic.NewHighlight().Encoder("html").Fields(elastic.NewHighlighterField("Nome"), elastic.NewHighlighterField("Nick"))
+
+	resp, err := b.cliente.Search(IndiceUsuarios).Query(consulta).Highlight(destaque).Size(normalizarLimite(limite)).Do(c)
+	if err != nil {
+		return nil, err
+	}
+
+	resultados := make([]ResultadoUsuario, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		var resultado ResultadoUsuario
+		if err := json.Unmarshal(hit.Source, &resultado.DocumentoUsuario); err != nil {
+			log.Warningf(c, "Documento de usuario inválido no indice: %v", err)
+			continue
+		}
+		resultado.Pontuacao = pontuacao(hit)
+		resultado.Destaques = hit.Highlight
+		resultados = append(resultados, resultado)
+	}
+	return resultados, nil
+}
+
+// BuscarPublicacoes faz busca textual no titulo e no conteudo das publicações, com trechos destacados
+func (b *Busca) BuscarPublicacoes(c context.Context, termo string, limite int) ([]ResultadoPublicacao, error) {
+	termo = strings.TrimSpace(termo)
+	if termo == "" {
+		return nil, fmt.Errorf("Termo de busca não informado")
+	}
+
+	consulta := elastic.NewMultiMatchQuery(termo, "Titulo^2", "Conteudo").Fuzziness("AUTO")
+
+	destaque := elastic.NewHighlight().
+		Fields(elastic.NewHighlighterField("Titulo"), elastic.NewHighlighterField("Conteudo")).
+		Encoder("html").
+		PreTags("<mark>").
+		PostTags("</mark>")
+
+	resp, err := b.cliente.Search(IndicePublicacoes).Query(consulta).Highlight(destaque).Size(normalizarLimite(limite)).Do(c)
+	if err != nil {
+		return nil, err
+	}
+
+	resultados := make([]ResultadoPublicacao, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		var resultado ResultadoPublicacao
+		if err := json.Unmarshal(hit.Source, &resultado.DocumentoPublicacao); err != nil {
+			log.Warningf(c, "Documento de publicação inválido no indice: %v", err)
+			continue
+		}
+		resultado.Pontuacao = pontuacao(hit)
+		resultado.Destaques = hit.Highlight
+		resultados = append(resultados, resultado)
+	}
+	return resultados, nil
+}
+
+func normalizarLimite(limite int) int {
+	if limite <= 0 {
+		return LimitePadrao
+	}
+	if limite > LimiteMaximo {
+		return LimiteMaximo
+	}
+	return limite
+}
+
+func pontuacao(hit *elastic.SearchHit) float64 {
+	if hit.Score == nil {
+		return 0
+	}
+	return *hit.Score
+}
@@ -0,0 +1,383 @@
+package busca
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// fakeES simula o suficiente da API do Elasticsearch para os testes: criação de indices,
+// indexação, remoção, bulk e uma busca por substring que devolve destaques.
+type fakeES struct {
+	mu      sync.Mutex
+	indices map[string]bool
+	docs    map[string]map[string]map[string]interface{}
+	buscas  []string
+}
+
+func novoFakeES() *fakeES {
+	return &fakeES{
+		indices: map[string]bool{},
+		docs:    map[string]map[string]map[string]interface{}{},
+	}
+}
+
+func (f *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	partes := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	corpo, _ := ioutil.ReadAll(r.Body)
+
+	switch {
+	case len(partes) == 1 && partes[0] == "_bulk":
+		f.bulk(w, string(corpo))
+	case len(partes) == 1 && r.Method == http.MethodHead:
+		if f.indices[partes[0]] {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case len(partes) == 1 && r.Method == http.MethodDelete:
+		if !f.indices[partes[0]] {
+			responder(w, http.StatusNotFound, map[string]interface{}{"error": "index_not_found_exception", "status": 404})
+			return
+		}
+		delete(f.indices, partes[0])
+		delete(f.docs, partes[0])
+		responder(w, http.StatusOK, map[string]interface{}{"acknowledged": true})
+	case len(partes) == 1 && r.Method == http.MethodPut:
+		f.indices[partes[0]] = true
+		responder(w, http.StatusOK, map[string]interface{}{"acknowledged": true, "index": partes[0]})
+	case len(partes) == 3 && partes[1] == "_doc" && r.Method == http.MethodDelete:
+		if _, ok := f.docs[partes[0]][partes[2]]; !ok {
+			responder(w, http.StatusNotFound, map[string]interface{}{"_index": partes[0], "_id": partes[2], "result": "not_found"})
+			return
+		}
+		delete(f.docs[partes[0]], partes[2])
+		responder(w, http.StatusOK, map[string]interface{}{"_index": partes[0], "_id": partes[2], "result": "deleted"})
+	case len(partes) == 3 && partes[1] == "_doc":
+		f.salvar(partes[0], partes[2], corpo)
+		responder(w, http.StatusCreated, map[string]interface{}{"_index": partes[0], "_id": partes[2], "result": "created", "_version": 1})
+	case len(partes) == 2 && partes[1] == "_search":
+		f.buscas = append(f.buscas, string(corpo))
+		f.buscar(w, partes[0], corpo)
+	default:
+		responder(w, http.StatusBadRequest, map[string]interface{}{"error": "rota não suportada: " + r.Method + " " + r.URL.Path})
+	}
+}
+
+func (f *fakeES) salvar(indice, id string, corpo []byte) {
+	var doc map[string]interface{}
+	json.Unmarshal(corpo, &doc)
+	if f.docs[indice] == nil {
+		f.docs[indice] = map[string]map[string]interface{}{}
+	}
+	f.docs[indice][id] = doc
+}
+
+func (f *fakeES) bulk(w http.ResponseWriter, corpo string) {
+	var itens []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(corpo))
+	for scanner.Scan() {
+		var acao map[string]map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &acao); err != nil || acao["index"] == nil {
+			continue
+		}
+		scanner.Scan()
+		f.salvar(acao["index"]["_index"], acao["index"]["_id"], scanner.Bytes())
+		itens = append(itens, map[string]interface{}{
+			"index": map[string]interface{}{"_index": acao["index"]["_index"], "_id": acao["index"]["_id"], "status": 201, "result": "created"},
+		})
+	}
+	responder(w, http.StatusOK, map[string]interface{}{"took": 1, "errors": false, "items": itens})
+}
+
+func (f *fakeES) buscar(w http.ResponseWriter, indice string, corpo []byte) {
+	if strings.Contains(string(corpo), "geo_distance") {
+		f.buscarGeo(w, indice)
+		return
+	}
+
+	var requisicao map[string]interface{}
+	json.Unmarshal(corpo, &requisicao)
+	termo := strings.ToLower(encontrarTermo(requisicao["query"]))
+
+	preTag, posTag := "<em>", "</em>"
+	if destaque, ok := requisicao["highlight"].(map[string]interface{}); ok {
+		if tags, ok := destaque["pre_tags"].([]interface{}); ok && len(tags) > 0 {
+			preTag = tags[0].(string)
+		}
+		if tags, ok := destaque["post_tags"].([]interface{}); ok && len(tags) > 0 {
+			posTag = tags[0].(string)
+		}
+	}
+
+	ids := make([]string, 0, len(f.docs[indice]))
+	for id := range f.docs[indice] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var hits []map[string]interface{}
+	for _, id := range ids {
+		doc := f.docs[indice][id]
+		destaques := map[string][]string{}
+		for campo, valor := range doc {
+			texto, ok := valor.(string)
+			if !ok {
+				continue
+			}
+			if pos := strings.Index(strings.ToLower(texto), termo); termo != "" && pos >= 0 {
+				destaques[campo] = []string{texto[:pos] + preTag + texto[pos:pos+len(termo)] + posTag + texto[pos+len(termo):]}
+			}
+		}
+		if len(destaques) == 0 {
+			continue
+		}
+		hits = append(hits, map[string]interface{}{"_index": indice, "_id": id, "_score": 1.0, "_source": doc, "highlight": destaques})
+	}
+
+	responder(w, http.StatusOK, map[string]interface{}{
+		"took": 1,
+		"hits": map[string]interface{}{"total": map[string]interface{}{"value": len(hits), "relation": "eq"}, "hits": hits},
+	})
+}
+
+// buscarGeo devolve todos os documentos do indice com a distancia na ordenação, sem calcular o raio
+func (f *fakeES) buscarGeo(w http.ResponseWriter, indice string) {
+	ids := make([]string, 0, len(f.docs[indice]))
+	for id := range f.docs[indice] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	hits := make([]map[string]interface{}, 0, len(ids))
+	for i, id := range ids {
+		hits = append(hits, map[string]interface{}{"_index": indice, "_id": id, "_source": f.docs[indice][id], "sort": []interface{}{float64(i) + 0.5}})
+	}
+	responder(w, http.StatusOK, map[string]interface{}{
+		"took": 1,
+		"hits": map[string]interface{}{"total": map[string]interface{}{"value": len(hits), "relation": "eq"}, "hits": hits},
+	})
+}
+
+// encontrarTermo procura o primeiro campo "query" textual dentro da consulta
+func encontrarTermo(no interface{}) string {
+	switch v := no.(type) {
+	case map[string]interface{}:
+		if q, ok := v["query"].(string); ok {
+			return q
+		}
+		for _, filho := range v {
+			if termo := encontrarTermo(filho); termo != "" {
+				return termo
+			}
+		}
+	case []interface{}:
+		for _, filho := range v {
+			if termo := encontrarTermo(filho); termo != "" {
+				return termo
+			}
+		}
+	}
+	return ""
+}
+
+func responder(w http.ResponseWriter, status int, corpo interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(corpo)
+}
+
+func novaBuscaTeste(t *testing.T) (*Busca, *fakeES) {
+	fake := novoFakeES()
+	servidor := httptest.NewServer(fake)
+	t.Cleanup(servidor.Close)
+
+	cliente, err := elastic.NewClient(elastic.SetURL(servidor.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	if err != nil {
+		t.Fatalf("Falha ao criar cliente do Elasticsearch: %v", err)
+	}
+	return NovaComCliente(cliente), fake
+}
+
+func TestCriarIndices(t *testing.T) {
+	b, fake := novaBuscaTeste(t)
+	c := context.Background()
+
+	if err := b.CriarIndices(c); err != nil {
+		t.Fatalf("Erro ao criar indices: %v", err)
+	}
+	if !fake.indices[IndiceUsuarios] || !fake.indices[IndicePublicacoes] || !fake.indices[IndiceEstabelecimentos] {
+		t.Errorf("Indices não foram criados: %v", fake.indices)
+	}
+
+	// Chamar de novo não deve recriar nem falhar
+	if err := b.CriarIndices(c); err != nil {
+		t.Errorf("Erro ao chamar CriarIndices com indices existentes: %v", err)
+	}
+}
+
+func TestRecriarIndices(t *testing.T) {
+	b, fake := novaBuscaTeste(t)
+	c := context.Background()
+
+	//Sem indices ainda, a remoção é ignorada
+	if err := b.RecriarIndices(c); err != nil {
+		t.Fatalf("Erro ao recriar indices inexistentes: %v", err)
+	}
+
+	if _, err := b.ReindexarUsuarios(c, []DocumentoUsuario{{ID: 1, Nome: "Removido", Nick: "removido"}}); err != nil {
+		t.Fatalf("Erro ao reindexar: %v", err)
+	}
+	if err := b.RecriarIndices(c); err != nil {
+		t.Fatalf("Erro ao recriar indices: %v", err)
+	}
+	if len(fake.docs[IndiceUsuarios]) != 0 {
+		t.Errorf("Documentos antigos continuam no indice: %v", fake.docs[IndiceUsuarios])
+	}
+	if !fake.indices[IndiceUsuarios] || !fake.indices[IndicePublicacoes] || !fake.indices[IndiceEstabelecimentos] {
+		t.Errorf("Indices não foram recriados: %v", fake.indices)
+	}
+}
+
+func TestBuscarUsuariosPorPrefixo(t *testing.T) {
+	b, fake := novaBuscaTeste(t)
+	c := context.Background()
+
+	usuarios := []DocumentoUsuario{
+		{ID: 1, Nome: "Joana Silva", Nick: "joaninha"},
+		{ID: 2, Nome: "Maria Souza", Nick: "mari"},
+	}
+	for _, u := range usuarios {
+		if err := b.IndexarUsuario(c, u); err != nil {
+			t.Fatalf("Erro ao indexar usuario %d: %v", u.ID, err)
+		}
+	}
+
+	resultados, err := b.BuscarUsuarios(c, "joa", 0)
+	if err != nil {
+		t.Fatalf("Erro ao buscar usuarios: %v", err)
+	}
+	if len(resultados) != 1 || resultados[0].ID != 1 {
+		t.Fatalf("Resultado inesperado: %#v", resultados)
+	}
+	if len(resultados[0].Destaques["Nome"]) == 0 {
+		t.Errorf("Busca de usuario sem destaque no nome: %#v", resultados[0].Destaques)
+	}
+
+	consulta := fake.buscas[len(fake.buscas)-1]
+	for _, esperado := range []string{`"phrase_prefix"`, `"fuzziness":"AUTO"`, `"Nick.keyword"`, `"size":20`, `"encoder":"html"`} {
+		if !strings.Contains(consulta, esperado) {
+			t.Errorf("Consulta de usuarios deveria conter %s: %s", esperado, consulta)
+		}
+	}
+
+	if _, err := b.BuscarUsuarios(c, "   ", 0); err == nil {
+		t.Errorf("Busca com termo vazio deveria falhar")
+	}
+}
+
+func TestBuscarPublicacoesComDestaque(t *testing.T) {
+	b, fake := novaBuscaTeste(t)
+	c := context.Background()
+
+	b.IndexarPublicacao(c, DocumentoPublicacao{ID: 10, Titulo: "Jogo de ontem", Conteudo: "Que partida de futebol!", AutorID: 1, AutorNick: "joaninha"})
+	b.IndexarPublicacao(c, DocumentoPublicacao{ID: 11, Titulo: "Receita", Conteudo: "Bolo de cenoura", AutorID: 2, AutorNick: "mari"})
+
+	resultados, err := b.BuscarPublicacoes(c, "futebol", 500)
+	if err != nil {
+		t.Fatalf("Erro ao buscar publicações: %v", err)
+	}
+	if len(resultados) != 1 || resultados[0].ID != 10 {
+		t.Fatalf("Resultado inesperado: %#v", resultados)
+	}
+	if destaque := resultados[0].Destaques["Conteudo"]; len(destaque) == 0 || !strings.Contains(destaque[0], "<mark>futebol</mark>") {
+		t.Errorf("Destaque inválido: %#v", resultados[0].Destaques)
+	}
+
+	consulta := fake.buscas[len(fake.buscas)-1]
+	if !strings.Contains(consulta, fmt.Sprintf(`"size":%d`, LimiteMaximo)) {
+		t.Errorf("Limite deveria ser truncado em %d: %s", LimiteMaximo, consulta)
+	}
+	if !strings.Contains(consulta, `"Titulo^2"`) {
+		t.Errorf("Titulo deveria ter peso maior na busca: %s", consulta)
+	}
+	if !strings.Contains(consulta, `"encoder":"html"`) {
+		t.Errorf("Destaque deveria escapar o HTML do texto: %s", consulta)
+	}
+}
+
+func TestReindexarERemover(t *testing.T) {
+	b, fake := novaBuscaTeste(t)
+	c := context.Background()
+
+	var docs []DocumentoUsuario
+	for i := int64(1); i <= 3; i++ {
+		docs = append(docs, DocumentoUsuario{ID: i, Nome: fmt.Sprintf("Usuario %d", i), Nick: fmt.Sprintf("usu%d", i)})
+	}
+
+	total, err := b.ReindexarUsuarios(c, docs)
+	if err != nil {
+		t.Fatalf("Erro ao reindexar: %v", err)
+	}
+	if total != 3 || len(fake.docs[IndiceUsuarios]) != 3 {
+		t.Errorf("Esperado 3 usuarios indexados, indexados %d, no indice %d", total, len(fake.docs[IndiceUsuarios]))
+	}
+
+	if err := b.RemoverUsuario(c, 2); err != nil {
+		t.Errorf("Erro ao remover usuario: %v", err)
+	}
+	if _, ok := fake.docs[IndiceUsuarios]["2"]; ok {
+		t.Errorf("Usuario 2 continua no indice")
+	}
+
+	if err := b.RemoverUsuario(c, 99); err != nil {
+		t.Errorf("Remover usuario inexistente não deveria falhar: %v", err)
+	}
+
+	if err := b.IndexarUsuario(c, DocumentoUsuario{Nome: "Sem ID"}); err == nil {
+		t.Errorf("Indexar documento sem ID deveria falhar")
+	}
+}
+
+func TestBuscarEstabelecimentosProximos(t *testing.T) {
+	b, fake := novaBuscaTeste(t)
+	c := context.Background()
+
+	b.IndexarEstabelecimento(c, DocumentoEstabelecimento{ID: 1, Nome: "Bar da Sé", Setor: 1, Localizacao: elastic.GeoPointFromLatLon(-23.5503, -46.634)})
+	b.IndexarEstabelecimento(c, DocumentoEstabelecimento{ID: 2, Nome: "Restaurante", Setor: 2, Localizacao: elastic.GeoPointFromLatLon(-23.5587, -46.635)})
+	if doc := fake.docs[IndiceEstabelecimentos]["1"]; doc["Localizacao"] == nil {
+		t.Fatalf("Localizacao não foi indexada: %#v", doc)
+	}
+
+	resultados, err := b.BuscarEstabelecimentosProximos(c, -23.5505, -46.6333, 2.5, 1, 0)
+	if err != nil {
+		t.Fatalf("Erro ao buscar estabelecimentos proximos: %v", err)
+	}
+	if len(resultados) != 2 || resultados[0].ID != 1 || resultados[0].DistanciaKm != 0.5 {
+		t.Fatalf("Resultado inesperado: %#v", resultados)
+	}
+
+	consulta := fake.buscas[len(fake.buscas)-1]
+	for _, esperado := range []string{`"geo_distance"`, `"distance":"2.5km"`, `"_geo_distance"`, `"Setor"`} {
+		if !strings.Contains(consulta, esperado) {
+			t.Errorf("Consulta de estabelecimentos deveria conter %s: %s", esperado, consulta)
+		}
+	}
+
+	if _, err := b.BuscarEstabelecimentosProximos(c, -23.5505, -46.6333, 0, 0, 0); err == nil {
+		t.Errorf("Busca sem raio deveria falhar")
+	}
+}
@@ -0,0 +1,90 @@
+package busca
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"site/utils/log"
+	"strconv"
+
+	"github.com/olivere/elastic/v7"
+)
+
+const mapeamentoEstabelecimentos = `{
+	"mappings": {
+		"properties": {
+			"ID":          {"type": "long"},
+			"Nome":        {"type": "text"},
+			"Setor":       {"type": "long"},
+			"Localizacao": {"type": "geo_point"}
+		}
+	}
+}`
+
+// DocumentoEstabelecimento é a parte do estabelecimento que fica indexada para a busca por proximidade
+type DocumentoEstabelecimento struct {
+	ID          int64
+	Nome        string
+	Setor       int64
+	Localizacao *elastic.GeoPoint
+}
+
+type ResultadoEstabelecimento struct {
+	DocumentoEstabelecimento
+	DistanciaKm float64
+}
+
+func (b *Busca) IndexarEstabelecimento(c context.Context, doc DocumentoEstabelecimento) error {
+	return b.indexar(c, IndiceEstabelecimentos, doc.ID, doc)
+}
+
+func (b *Busca) RemoverEstabelecimento(c context.Context, id int64) error {
+	return b.remover(c, IndiceEstabelecimentos, id)
+}
+
+// ReindexarEstabelecimentos envia todos os estabelecimentos em lotes pela API bulk
+func (b *Busca) ReindexarEstabelecimentos(c context.Context, docs []DocumentoEstabelecimento) (int, error) {
+	requisicoes := make([]elastic.BulkableRequest, 0, len(docs))
+	for _, doc := range docs {
+		requisicoes = append(requisicoes, elastic.NewBulkIndexRequest().Index(IndiceEstabelecimentos).Id(strconv.FormatInt(doc.ID, 10)).Doc(doc))
+	}
+	return b.bulk(c, requisicoes)
+}
+
+// BuscarEstabelecimentosProximos traz os estabelecimentos dentro do raio, do mais perto para o mais
+// longe. Setor zero não filtra.
+func (b *Busca) BuscarEstabelecimentosProximos(c context.Context, latitude, longitude, raioKm float64, setor int64, limite int) ([]ResultadoEstabelecimento, error) {
+	if raioKm <= 0 {
+		return nil, fmt.Errorf("Raio inválido: %v", raioKm)
+	}
+
+	consulta := elastic.NewBoolQuery().Filter(
+		elastic.NewGeoDistanceQuery("Localizacao").Point(latitude, longitude).Distance(fmt.Sprintf("%gkm", raioKm)),
+	)
+	if setor != 0 {
+		consulta = consulta.Filter(elastic.NewTermQuery("Setor", setor))
+	}
+
+	ordem := elastic.NewGeoDistanceSort("Localizacao").Point(latitude, longitude).Unit("km").Asc()
+
+	resp, err := b.cliente.Search(IndiceEstabelecimentos).Query(consulta).SortBy(ordem).Size(normalizarLimite(limite)).Do(c)
+	if err != nil {
+		return nil, err
+	}
+
+	resultados := make([]ResultadoEstabelecimento, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		var resultado ResultadoEstabelecimento
+		if err := json.Unmarshal(hit.Source, &resultado.DocumentoEstabelecimento); err != nil {
+			log.Warningf(c, "Documento de estabelecimento inválido no indice: %v", err)
+			continue
+		}
+		if len(hit.Sort) > 0 {
+			if distancia, ok := hit.Sort[0].(float64); ok {
+				resultado.DistanciaKm = distancia
+			}
+		}
+		resultados = append(resultados, resultado)
+	}
+	return resultados, nil
+}
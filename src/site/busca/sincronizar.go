@@ -0,0 +1,97 @@
+package busca
+
+import (
+	"context"
+	"site/utils/log"
+)
+
+// As funções abaixo são chamadas nas gravações do Datastore. Uma falha no Elasticsearch
+// apenas é registrada, já que o indice pode ser refeito pelo comando cmd/reindexar. Sem o
+// Elasticsearch configurado elas retornam sem fazer nada.
+
+// buscaSincronizacao retorna a busca, ou false quando o Elasticsearch não está configurado ou disponivel
+func buscaSincronizacao(c context.Context, falha string, args ...interface{}) (*Busca, bool) {
+	b, err := Nova(c)
+	if err == ErrNaoConfigurado {
+		return nil, false
+	}
+	if err != nil {
+		log.Warningf(c, "Elasticsearch indisponivel, "+falha+": %v", append(args, err)...)
+		return nil, false
+	}
+	return b, true
+}
+
+func SincronizarUsuario(c context.Context, doc DocumentoUsuario) {
+	b, ok := buscaSincronizacao(c, "usuario %d não indexado", doc.ID)
+	if !ok {
+		return
+	}
+	if err := b.IndexarUsuario(c, doc); err != nil {
+		log.Warningf(c, "Falha ao indexar usuario %d: %v", doc.ID, err)
+	}
+}
+
+func DesindexarUsuario(c context.Context, id int64) {
+	b, ok := buscaSincronizacao(c, "usuario %d não removido do indice", id)
+	if !ok {
+		return
+	}
+	if err := b.RemoverUsuario(c, id); err != nil {
+		log.Warningf(c, "Falha ao remover usuario %d do indice: %v", id, err)
+	}
+}
+
+func SincronizarPublicacao(c context.Context, doc DocumentoPublicacao) {
+	b, ok := buscaSincronizacao(c, "publicação %d não indexada", doc.ID)
+	if !ok {
+		return
+	}
+	if err := b.IndexarPublicacao(c, doc); err != nil {
+		log.Warningf(c, "Falha ao indexar publicação %d: %v", doc.ID, err)
+	}
+}
+
+func DesindexarPublicacao(c context.Context, id int64) {
+	b, ok := buscaSincronizacao(c, "publicação %d não removida do indice", id)
+	if !ok {
+		return
+	}
+	if err := b.RemoverPublicacao(c, id); err != nil {
+		log.Warningf(c, "Falha ao remover publicação %d do indice: %v", id, err)
+	}
+}
+
+func SincronizarEstabelecimento(c context.Context, doc DocumentoEstabelecimento) {
+	b, ok := buscaSincronizacao(c, "estabelecimento %d não indexado", doc.ID)
+	if !ok {
+		return
+	}
+	if err := b.IndexarEstabelecimento(c, doc); err != nil {
+		log.Warningf(c, "Falha ao indexar estabelecimento %d: %v", doc.ID, err)
+	}
+}
+
+func DesindexarEstabelecimento(c context.Context, id int64) {
+	b, ok := buscaSincronizacao(c, "estabelecimento %d não removido do indice", id)
+	if !ok {
+		return
+	}
+	if err := b.RemoverEstabelecimento(c, id); err != nil {
+		log.Warningf(c, "Falha ao remover estabelecimento %d do indice: %v", id, err)
+	}
+}
+
+// SincronizarEstabelecimentos indexa um lote de estabelecimentos numa chamada bulk
+func SincronizarEstabelecimentos(c context.Context, docs []DocumentoEstabelecimento) {
+	if len(docs) == 0 {
+		return
+	}
+	b, ok := buscaSincronizacao(c, "%d estabelecimentos não indexados", len(docs))
+	if !ok {
+		return
+	}
+	if total, err := b.ReindexarEstabelecimentos(c, docs); err != nil {
+		log.Warningf(c, "Falha ao indexar lote de estabelecimentos (%d indexados): %v", total, err)
+	}
+}
@@ -0,0 +1,408 @@
+package cardapio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"site/estabelecimento"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindCategoria = "CategoriasCardapio"
+	KindItem      = "ItensCardapio"
+
+	TamanhoMaximoNome      = 100
+	TamanhoMaximoDescricao = 500
+
+	// PrecoMaximo em reais, para barrar valores digitados em centavos por engano
+	PrecoMaximo = 100000
+
+	TamanhoMaximoCSV = 1 << 20
+
+	tamanhoLoteDatastore = 500
+)
+
+var (
+	ErrCategoriaNaoEncontrada = errors.New("Categoria não encontrada")
+	ErrItemNaoEncontrado      = errors.New("Item não encontrado")
+	ErrCategoriaComItens      = errors.New("Exclua ou mova os itens da categoria antes de excluí-la")
+)
+
+func init() {
+	estabelecimento.AoExcluir(excluirCardapio)
+}
+
+// excluirCardapio apaga os itens e as categorias de um estabelecimento excluido
+func excluirCardapio(c context.Context, estabelecimentoID int64) error {
+	if err := estabelecimento.ExcluirPorEstabelecimento(c, KindItem, estabelecimentoID); err != nil {
+		return err
+	}
+	return estabelecimento.ExcluirPorEstabelecimento(c, KindCategoria, estabelecimentoID)
+}
+
+// Categoria agrupa os itens do cardapio, como "Bebidas" ou "Porções"
+type Categoria struct {
+	ID                int64 `datastore:"-"`
+	EstabelecimentoID int64
+	Nome              string
+	Descricao         string `datastore:",noindex"`
+	Ordem             int64
+	DataCadastro      time.Time
+	DataAtualizacao   time.Time
+}
+
+// Item é um produto do cardapio. O preço é gravado em centavos em Preco; a API recebe e devolve o
+// valor em reais em PrecoReais.
+type Item struct {
+	ID                int64 `datastore:"-"`
+	EstabelecimentoID int64
+	CategoriaID       int64
+	Nome              string
+	Descricao         string `datastore:",noindex"`
+	Preco             int64
+	PrecoReais        float64 `datastore:"-"`
+	Disponivel        bool
+	Ordem             int64
+	DataCadastro      time.Time
+	DataAtualizacao   time.Time
+}
+
+// CategoriaCardapio é a categoria com os seus itens, como o cardapio é exibido
+type CategoriaCardapio struct {
+	Categoria
+	Itens []Item
+}
+
+// Cardapio é o menu completo do estabelecimento
+type Cardapio struct {
+	EstabelecimentoID int64
+	Categorias        []CategoriaCardapio
+}
+
+func (categoria *Categoria) validar() error {
+	categoria.Nome = strings.TrimSpace(categoria.Nome)
+	categoria.Descricao = strings.TrimSpace(categoria.Descricao)
+
+	if categoria.Nome == "" {
+		return fmt.Errorf("Nome da categoria é obrigatorio")
+	}
+	if len([]rune(categoria.Nome)) > TamanhoMaximoNome {
+		return fmt.Errorf("O nome da categoria pode ter até %d caracteres", TamanhoMaximoNome)
+	}
+	if len([]rune(categoria.Descricao)) > TamanhoMaximoDescricao {
+		return fmt.Errorf("A descrição da categoria pode ter até %d caracteres", TamanhoMaximoDescricao)
+	}
+	return nil
+}
+
+// validar confere os campos e converte PrecoReais para centavos
+func (item *Item) validar() error {
+	item.Nome = strings.TrimSpace(item.Nome)
+	item.Descricao = strings.TrimSpace(item.Descricao)
+
+	if item.Nome == "" {
+		return fmt.Errorf("Nome do item é obrigatorio")
+	}
+	if len([]rune(item.Nome)) > TamanhoMaximoNome {
+		return fmt.Errorf("O nome do item pode ter até %d caracteres", TamanhoMaximoNome)
+	}
+	if len([]rune(item.Descricao)) > TamanhoMaximoDescricao {
+		return fmt.Errorf("A descrição do item pode ter até %d caracteres", TamanhoMaximoDescricao)
+	}
+	if item.PrecoReais < 0 || item.PrecoReais > PrecoMaximo {
+		return fmt.Errorf("Preço inválido: %.2f", item.PrecoReais)
+	}
+
+	item.Preco = utils.FloatToCurrency(item.PrecoReais)
+	return nil
+}
+
+// carregado preenche os campos que não são gravados depois de ler o item do Datastore
+func (item *Item) carregado(id int64) {
+	item.ID = id
+	item.PrecoReais = utils.CurrencyToFloat(item.Preco)
+}
+
+// verificarPermissao garante que o estabelecimento existe e que o papel do usuario na equipe tem a
+// permissão. Proprietario e gerentes alteram o cardapio; atendentes só mudam a disponibilidade dos itens.
+func verificarPermissao(c context.Context, usuarioID, estabelecimentoID int64, permissao string) error {
+	_, err := estabelecimento.VerificarPermissao(c, usuarioID, estabelecimentoID, permissao)
+	return err
+}
+
+// SalvarCategoria cria a categoria, ou altera quando ela já tem ID
+func SalvarCategoria(c context.Context, usuarioID, estabelecimentoID int64, categoria *Categoria) error {
+	if err := verificarPermissao(c, usuarioID, estabelecimentoID, estabelecimento.PermissaoEditarCardapio); err != nil {
+		return err
+	}
+	if err := categoria.validar(); err != nil {
+		return err
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	agora := time.Now()
+	key := datastore.IDKey(KindCategoria, categoria.ID, nil)
+	if categoria.ID != 0 {
+		var atual Categoria
+		if err = datastoreClient.Get(c, key, &atual); err != nil || atual.EstabelecimentoID != estabelecimentoID {
+			return ErrCategoriaNaoEncontrada
+		}
+		categoria.DataCadastro = atual.DataCadastro
+	} else {
+		categoria.DataCadastro = agora
+	}
+	categoria.EstabelecimentoID = estabelecimentoID
+	categoria.DataAtualizacao = agora
+
+	key, err = datastoreClient.Put(c, key, categoria)
+	if err != nil {
+		log.Warningf(c, "Erro ao gravar categoria do cardapio: %v", err)
+		return err
+	}
+	categoria.ID = key.ID
+	return nil
+}
+
+// ExcluirCategoria exclui uma categoria vazia
+func ExcluirCategoria(c context.Context, usuarioID, estabelecimentoID, categoriaID int64) error {
+	if err := verificarPermissao(c, usuarioID, estabelecimentoID, estabelecimento.PermissaoEditarCardapio); err != nil {
+		return err
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindCategoria, categoriaID, nil)
+	var categoria Categoria
+	if err = datastoreClient.Get(c, key, &categoria); err != nil || categoria.EstabelecimentoID != estabelecimentoID {
+		return ErrCategoriaNaoEncontrada
+	}
+
+	q := datastore.NewQuery(KindItem).Filter("CategoriaID =", categoriaID).KeysOnly().Limit(1)
+	keys, err := datastoreClient.GetAll(c, q, nil)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar itens da categoria %d: %v", categoriaID, err)
+		return err
+	}
+	if len(keys) > 0 {
+		return ErrCategoriaComItens
+	}
+
+	if err = datastoreClient.Delete(c, key); err != nil {
+		log.Warningf(c, "Erro ao excluir categoria %d: %v", categoriaID, err)
+		return err
+	}
+	return nil
+}
+
+// SalvarItem cria o item, ou altera quando ele já tem ID. A categoria precisa ser do mesmo estabelecimento.
+func SalvarItem(c context.Context, usuarioID, estabelecimentoID int64, item *Item) error {
+	if err := verificarPermissao(c, usuarioID, estabelecimentoID, estabelecimento.PermissaoEditarCardapio); err != nil {
+		return err
+	}
+	if err := item.validar(); err != nil {
+		return err
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	var categoria Categoria
+	err = datastoreClient.Get(c, datastore.IDKey(KindCategoria, item.CategoriaID, nil), &categoria)
+	if err != nil || categoria.EstabelecimentoID != estabelecimentoID {
+		return ErrCategoriaNaoEncontrada
+	}
+
+	agora := time.Now()
+	key := datastore.IDKey(KindItem, item.ID, nil)
+	if item.ID != 0 {
+		var atual Item
+		if err = datastoreClient.Get(c, key, &atual); err != nil || atual.EstabelecimentoID != estabelecimentoID {
+			return ErrItemNaoEncontrado
+		}
+		item.DataCadastro = atual.DataCadastro
+	} else {
+		item.DataCadastro = agora
+	}
+	item.EstabelecimentoID = estabelecimentoID
+	item.DataAtualizacao = agora
+
+	key, err = datastoreClient.Put(c, key, item)
+	if err != nil {
+		log.Warningf(c, "Erro ao gravar item do cardapio: %v", err)
+		return err
+	}
+	item.carregado(key.ID)
+	return nil
+}
+
+// ExcluirItem tira o item do cardapio
+func ExcluirItem(c context.Context, usuarioID, estabelecimentoID, itemID int64) error {
+	if err := verificarPermissao(c, usuarioID, estabelecimentoID, estabelecimento.PermissaoEditarCardapio); err != nil {
+		return err
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindItem, itemID, nil)
+	var item Item
+	if err = datastoreClient.Get(c, key, &item); err != nil || item.EstabelecimentoID != estabelecimentoID {
+		return ErrItemNaoEncontrado
+	}
+
+	if err = datastoreClient.Delete(c, key); err != nil {
+		log.Warningf(c, "Erro ao excluir item %d: %v", itemID, err)
+		return err
+	}
+	return nil
+}
+
+// AlterarDisponibilidade liga ou desliga o item sem mexer nos outros campos, para quando acaba um produto
+func AlterarDisponibilidade(c context.Context, usuarioID, estabelecimentoID, itemID int64, disponivel bool) (*Item, error) {
+	if err := verificarPermissao(c, usuarioID, estabelecimentoID, estabelecimento.PermissaoAlterarDisponivel); err != nil {
+		return nil, err
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindItem, itemID, nil)
+	var item Item
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		if err := tx.Get(key, &item); err != nil || item.EstabelecimentoID != estabelecimentoID {
+			return ErrItemNaoEncontrado
+		}
+		item.Disponivel = disponivel
+		item.DataAtualizacao = time.Now()
+		_, err := tx.Put(key, &item)
+		return err
+	})
+	if err != nil {
+		if err != ErrItemNaoEncontrado {
+			log.Warningf(c, "Erro ao alterar disponibilidade do item %d: %v", itemID, err)
+		}
+		return nil, err
+	}
+
+	item.carregado(itemID)
+	return &item, nil
+}
+
+// BuscarCardapio traz as categorias com os itens, na ordem de exibição. Com somenteDisponiveis os itens
+// desligados e as categorias que ficam vazias não aparecem, como no cardapio publico.
+func BuscarCardapio(c context.Context, estabelecimentoID int64, somenteDisponiveis bool) (*Cardapio, error) {
+	categorias, itens, err := buscarCategoriasItens(c, estabelecimentoID)
+	if err != nil {
+		return nil, err
+	}
+	return montarCardapio(estabelecimentoID, categorias, itens, somenteDisponiveis), nil
+}
+
+// BuscarCardapioCompleto traz também os itens indisponiveis e as categorias vazias, para o proprietario
+// gerenciar o cardapio
+func BuscarCardapioCompleto(c context.Context, usuarioID, estabelecimentoID int64) (*Cardapio, error) {
+	if err := verificarPermissao(c, usuarioID, estabelecimentoID, estabelecimento.PermissaoAlterarDisponivel); err != nil {
+		return nil, err
+	}
+	return BuscarCardapio(c, estabelecimentoID, false)
+}
+
+func buscarCategoriasItens(c context.Context, estabelecimentoID int64) ([]Categoria, []Item, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, nil, err
+	}
+	defer datastoreClient.Close()
+
+	var categorias []Categoria
+	q := datastore.NewQuery(KindCategoria).Filter("EstabelecimentoID =", estabelecimentoID)
+	keys, err := datastoreClient.GetAll(c, q, &categorias)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar categorias do estabelecimento %d: %v", estabelecimentoID, err)
+		return nil, nil, err
+	}
+	for i := range keys {
+		categorias[i].ID = keys[i].ID
+	}
+
+	var itens []Item
+	q = datastore.NewQuery(KindItem).Filter("EstabelecimentoID =", estabelecimentoID)
+	keys, err = datastoreClient.GetAll(c, q, &itens)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar itens do estabelecimento %d: %v", estabelecimentoID, err)
+		return nil, nil, err
+	}
+	for i := range keys {
+		itens[i].carregado(keys[i].ID)
+	}
+	return categorias, itens, nil
+}
+
+// montarCardapio agrupa os itens nas categorias, ordenando por Ordem e depois por nome
+func montarCardapio(estabelecimentoID int64, categorias []Categoria, itens []Item, somenteDisponiveis bool) *Cardapio {
+	sort.SliceStable(categorias, func(i, j int) bool {
+		if categorias[i].Ordem != categorias[j].Ordem {
+			return categorias[i].Ordem < categorias[j].Ordem
+		}
+		return strings.ToLower(categorias[i].Nome) < strings.ToLower(categorias[j].Nome)
+	})
+	sort.SliceStable(itens, func(i, j int) bool {
+		if itens[i].Ordem != itens[j].Ordem {
+			return itens[i].Ordem < itens[j].Ordem
+		}
+		return strings.ToLower(itens[i].Nome) < strings.ToLower(itens[j].Nome)
+	})
+
+	porCategoria := make(map[int64][]Item, len(categorias))
+	for _, item := range itens {
+		if somenteDisponiveis && !item.Disponivel {
+			continue
+		}
+		porCategoria[item.CategoriaID] = append(porCategoria[item.CategoriaID], item)
+	}
+
+	cardapio := &Cardapio{EstabelecimentoID: estabelecimentoID, Categorias: make([]CategoriaCardapio, 0, len(categorias))}
+	for _, categoria := range categorias {
+		itensCategoria := porCategoria[categoria.ID]
+		if somenteDisponiveis && len(itensCategoria) == 0 {
+			continue
+		}
+		if itensCategoria == nil {
+			itensCategoria = make([]Item, 0)
+		}
+		cardapio.Categorias = append(cardapio.Categorias, CategoriaCardapio{Categoria: categoria, Itens: itensCategoria})
+	}
+	return cardapio
+}
@@ -0,0 +1,155 @@
+package cardapio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestValidarItemConvertePreco(t *testing.T) {
+	casos := []struct {
+		reais    float64
+		centavos int64
+		valido   bool
+	}{
+		{12.5, 1250, true},
+		{0.1 + 0.2, 30, true},
+		{19.999, 2000, true},
+		{0, 0, true},
+		{-5, 0, false},
+		{PrecoMaximo + 1, 0, false},
+	}
+
+	for _, caso := range casos {
+		item := Item{Nome: " Chopp ", PrecoReais: caso.reais}
+		err := item.validar()
+		if (err == nil) != caso.valido {
+			t.Errorf("validar(%v) = %v, esperado valido = %v", caso.reais, err, caso.valido)
+			continue
+		}
+		if caso.valido && item.Preco != caso.centavos {
+			t.Errorf("validar(%v): Preco = %d, esperado %d", caso.reais, item.Preco, caso.centavos)
+		}
+	}
+}
+
+func TestLerCSV(t *testing.T) {
+	arquivo := "\xef\xbb\xbfCategoria;Item;Preco;Disponivel\n" +
+		"Bebidas;Chopp;R$ 9,90;sim\n" +
+		"\n" +
+		"Porções;Fritas;1.234,50;nao\n" +
+		"bebidas;Água;4;\n"
+
+	linhas, err := lerCSV(strings.NewReader(arquivo))
+	if err != nil {
+		t.Fatalf("lerCSV() = %v", err)
+	}
+	if len(linhas) != 3 {
+		t.Fatalf("lerCSV() leu %d linhas, esperado 3", len(linhas))
+	}
+
+	esperado := []struct {
+		categoria  string
+		nome       string
+		centavos   int64
+		disponivel bool
+	}{
+		{"Bebidas", "Chopp", 990, true},
+		{"Porções", "Fritas", 123450, false},
+		{"bebidas", "Água", 400, true},
+	}
+	for i, e := range esperado {
+		linha := linhas[i]
+		if linha.Categoria != e.categoria || linha.Item.Nome != e.nome || linha.Item.Preco != e.centavos || linha.Item.Disponivel != e.disponivel {
+			t.Errorf("linha %d = %+v, esperado %+v", i, linha, e)
+		}
+	}
+}
+
+func TestLerCSVErros(t *testing.T) {
+	casos := []struct {
+		nome     string
+		arquivo  string
+		mensagem string
+	}{
+		{"vazio", "", "vazio"},
+		{"sem coluna de preço", "categoria,item\nBebidas,Chopp\n", "preco"},
+		{"preço inválido", "categoria,item,preco\nBebidas,Chopp,9.90\nBebidas,Suco,dez\n", "Linha 3"},
+		{"preço negativo", "categoria,item,preco\nBebidas,Chopp,-1\n", "Linha 2"},
+		{"preço NaN", "categoria,item,preco\nBebidas,Chopp,NaN\n", "Linha 2"},
+		{"preço infinito", "categoria,item,preco\nBebidas,Chopp,Inf\n", "Linha 2"},
+		{"milhar sem centavos", "categoria,item,preco\nBebidas,Chopp,1.234\n", "virgula"},
+		{"sem categoria", "categoria,item,preco\n,Chopp,9.90\n", "Linha 2"},
+		{"disponivel inválido", "categoria,item,preco,disponivel\nBebidas,Chopp,9.90,talvez\n", "Linha 2"},
+	}
+
+	for _, caso := range casos {
+		_, err := lerCSV(strings.NewReader(caso.arquivo))
+		if err == nil || !strings.Contains(err.Error(), caso.mensagem) {
+			t.Errorf("%s: lerCSV() = %v, esperado erro com %q", caso.nome, err, caso.mensagem)
+		}
+	}
+}
+
+func TestMontarCardapio(t *testing.T) {
+	categorias := []Categoria{
+		{ID: 1, Nome: "Porções", Ordem: 1},
+		{ID: 2, Nome: "Bebidas", Ordem: 0},
+		{ID: 3, Nome: "Sobremesas", Ordem: 2},
+	}
+	itens := []Item{
+		{ID: 10, CategoriaID: 2, Nome: "Suco", Disponivel: true},
+		{ID: 11, CategoriaID: 2, Nome: "Chopp", Disponivel: true},
+		{ID: 12, CategoriaID: 1, Nome: "Fritas", Disponivel: false},
+		{ID: 13, CategoriaID: 2, Nome: "Água", Disponivel: false, Ordem: 1},
+	}
+
+	completo := montarCardapio(5, append([]Categoria(nil), categorias...), append([]Item(nil), itens...), false)
+	if len(completo.Categorias) != 3 || completo.Categorias[0].Nome != "Bebidas" || completo.Categorias[2].Nome != "Sobremesas" {
+		t.Fatalf("categorias fora de ordem: %+v", completo.Categorias)
+	}
+	bebidas := completo.Categorias[0].Itens
+	if len(bebidas) != 3 || bebidas[0].Nome != "Chopp" || bebidas[1].Nome != "Suco" || bebidas[2].Nome != "Água" {
+		t.Errorf("itens fora de ordem: %+v", bebidas)
+	}
+	if completo.Categorias[2].Itens == nil {
+		t.Errorf("categoria vazia deveria ter lista vazia, não nil")
+	}
+
+	publico := montarCardapio(5, categorias, itens, true)
+	if len(publico.Categorias) != 1 || len(publico.Categorias[0].Itens) != 2 {
+		t.Errorf("cardapio publico deveria ter só Bebidas com 2 itens: %+v", publico.Categorias)
+	}
+}
+
+func TestEscreverCSVReimporta(t *testing.T) {
+	cardapio := montarCardapio(5,
+		[]Categoria{{ID: 1, Nome: "Bebidas"}},
+		[]Item{
+			{ID: 10, CategoriaID: 1, Nome: "Chopp, 500ml", Descricao: "Pilsen \"da casa\"", Preco: 1290, Disponivel: true},
+			{ID: 11, CategoriaID: 1, Nome: "Suco", Preco: 800},
+		}, false)
+
+	var buf bytes.Buffer
+	if err := escreverCSV(&buf, cardapio); err != nil {
+		t.Fatalf("escreverCSV() = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "categoria,item,descricao,preco,disponivel\n") {
+		t.Errorf("cabeçalho inesperado: %q", buf.String())
+	}
+
+	linhas, err := lerCSV(&buf)
+	if err != nil {
+		t.Fatalf("lerCSV() do exportado = %v", err)
+	}
+	if len(linhas) != 2 {
+		t.Fatalf("lerCSV() leu %d linhas, esperado 2", len(linhas))
+	}
+	chopp := linhas[0].Item
+	if chopp.Nome != "Chopp, 500ml" || chopp.Descricao != "Pilsen \"da casa\"" || chopp.Preco != 1290 || !chopp.Disponivel {
+		t.Errorf("item exportado e reimportado mudou: %+v", chopp)
+	}
+	if linhas[1].Item.Disponivel {
+		t.Errorf("item indisponivel voltou disponivel")
+	}
+}
@@ -0,0 +1,331 @@
+package cardapio
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"site/estabelecimento"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+// Colunas do CSV do cardapio, na ordem em que são exportadas. Na importação a ordem é livre e
+// descricao e disponivel são opcionais.
+var colunasCSV = []string{"categoria", "item", "descricao", "preco", "disponivel"}
+
+// linhaCSV é um item lido do arquivo com o nome da categoria em que ele entra
+type linhaCSV struct {
+	Categoria string
+	Item      Item
+}
+
+// ResultadoImportacao resume o que a importação gravou
+type ResultadoImportacao struct {
+	CategoriasCriadas int
+	ItensCriados      int
+	ItensAtualizados  int
+}
+
+// ExportarCSV escreve o cardapio completo do estabelecimento, incluindo os itens indisponiveis
+func ExportarCSV(c context.Context, usuarioID, estabelecimentoID int64, w io.Writer) error {
+	cardapio, err := BuscarCardapioCompleto(c, usuarioID, estabelecimentoID)
+	if err != nil {
+		return err
+	}
+	return escreverCSV(w, cardapio)
+}
+
+func escreverCSV(w io.Writer, cardapio *Cardapio) error {
+	escritor := csv.NewWriter(w)
+	if err := escritor.Write(colunasCSV); err != nil {
+		return err
+	}
+
+	for _, categoria := range cardapio.Categorias {
+		for _, item := range categoria.Itens {
+			disponivel := "nao"
+			if item.Disponivel {
+				disponivel = "sim"
+			}
+			linha := []string{
+				categoria.Nome,
+				item.Nome,
+				item.Descricao,
+				strconv.FormatFloat(utils.CurrencyToFloat(item.Preco), 'f', 2, 64),
+				disponivel,
+			}
+			if err := escritor.Write(linha); err != nil {
+				return err
+			}
+		}
+	}
+
+	escritor.Flush()
+	return escritor.Error()
+}
+
+// lerCSV interpreta o arquivo inteiro antes de gravar qualquer coisa, para a importação não ficar pela
+// metade. Aceita virgula ou ponto e virgula como separador e precos como "12.50", "12,50" ou "R$ 1.234,50",
+// ver lerPreco.
+func lerCSV(r io.Reader) ([]linhaCSV, error) {
+	conteudo, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	//Planilhas salvas no Excel começam com BOM
+	conteudo = bytes.TrimPrefix(conteudo, []byte("\xef\xbb\xbf"))
+
+	leitor := csv.NewReader(bytes.NewReader(conteudo))
+	primeiraLinha := conteudo
+	if i := bytes.IndexByte(conteudo, '\n'); i >= 0 {
+		primeiraLinha = conteudo[:i]
+	}
+	if bytes.Count(primeiraLinha, []byte(";")) > bytes.Count(primeiraLinha, []byte(",")) {
+		leitor.Comma = ';'
+	}
+	leitor.FieldsPerRecord = -1
+	leitor.TrimLeadingSpace = true
+
+	registros, err := leitor.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV inválido: %v", err)
+	}
+	if len(registros) == 0 {
+		return nil, fmt.Errorf("CSV vazio")
+	}
+
+	posicao := make(map[string]int)
+	for i, coluna := range registros[0] {
+		posicao[strings.ToLower(strings.TrimSpace(coluna))] = i
+	}
+	for _, obrigatoria := range []string{"categoria", "item", "preco"} {
+		if _, ok := posicao[obrigatoria]; !ok {
+			return nil, fmt.Errorf("Coluna obrigatoria ausente no CSV: %s", obrigatoria)
+		}
+	}
+	campo := func(registro []string, coluna string) string {
+		i, ok := posicao[coluna]
+		if !ok || i >= len(registro) {
+			return ""
+		}
+		return strings.TrimSpace(registro[i])
+	}
+
+	linhas := make([]linhaCSV, 0, len(registros)-1)
+	for i, registro := range registros[1:] {
+		numero := i + 2
+		if strings.TrimSpace(strings.Join(registro, "")) == "" {
+			continue
+		}
+
+		preco, err := lerPreco(campo(registro, "preco"))
+		if err != nil {
+			return nil, fmt.Errorf("Linha %d: %v", numero, err)
+		}
+		disponivel, err := lerDisponivel(campo(registro, "disponivel"))
+		if err != nil {
+			return nil, fmt.Errorf("Linha %d: %v", numero, err)
+		}
+
+		linha := linhaCSV{
+			Categoria: campo(registro, "categoria"),
+			Item: Item{
+				Nome:       campo(registro, "item"),
+				Descricao:  campo(registro, "descricao"),
+				PrecoReais: preco,
+				Disponivel: disponivel,
+			},
+		}
+
+		categoria := Categoria{Nome: linha.Categoria}
+		if err = categoria.validar(); err != nil {
+			return nil, fmt.Errorf("Linha %d: %v", numero, err)
+		}
+		linha.Categoria = categoria.Nome
+
+		if err = linha.Item.validar(); err != nil {
+			return nil, fmt.Errorf("Linha %d: %v", numero, err)
+		}
+
+		linhas = append(linhas, linha)
+	}
+	return linhas, nil
+}
+
+// lerPreco lê o preço em reais. Com virgula vale o formato brasileiro, em que o ponto separa os milhares.
+// Sem virgula o ponto separa os centavos, como no CSV exportado, e por isso não pode ter mais de duas
+// casas: "1.234" é recusado em vez de virar R$ 1,23.
+func lerPreco(valor string) (float64, error) {
+	original := strings.TrimSpace(valor)
+	valor = strings.TrimSpace(strings.TrimPrefix(original, "R$"))
+	if valor == "" {
+		return 0, fmt.Errorf("Preço é obrigatorio")
+	}
+	if strings.Contains(valor, ",") {
+		valor = strings.Replace(strings.Replace(valor, ".", "", -1), ",", ".", 1)
+	} else if ponto := strings.LastIndex(valor, "."); ponto >= 0 && len(valor)-ponto-1 > 2 {
+		return 0, fmt.Errorf("Preço inválido: %q, use virgula para separar os centavos", original)
+	}
+	preco, err := strconv.ParseFloat(valor, 64)
+	if err != nil || math.IsNaN(preco) || math.IsInf(preco, 0) {
+		return 0, fmt.Errorf("Preço inválido: %q", original)
+	}
+	return preco, nil
+}
+
+// lerDisponivel aceita sim/nao, true/false e 1/0. Vazio conta como disponivel.
+func lerDisponivel(valor string) (bool, error) {
+	switch strings.ToLower(valor) {
+	case "", "sim", "s", "true", "1":
+		return true, nil
+	case "nao", "não", "n", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("Valor inválido para disponivel: %q", valor)
+}
+
+// ImportarCSV cria as categorias que ainda não existem e cria ou atualiza os itens pelo nome dentro da
+// categoria, sem diferenciar maiusculas. Itens que não estão no arquivo ficam como estão.
+func ImportarCSV(c context.Context, usuarioID, estabelecimentoID int64, r io.Reader) (*ResultadoImportacao, error) {
+	if err := verificarPermissao(c, usuarioID, estabelecimentoID, estabelecimento.PermissaoEditarCardapio); err != nil {
+		return nil, err
+	}
+
+	linhas, err := lerCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	categorias, itens, err := buscarCategoriasItens(c, estabelecimentoID)
+	if err != nil {
+		return nil, err
+	}
+
+	agora := time.Now()
+	resultado := &ResultadoImportacao{}
+
+	categoriaPorNome := make(map[string]int64, len(categorias))
+	var ordemCategoria int64
+	for _, categoria := range categorias {
+		categoriaPorNome[strings.ToLower(categoria.Nome)] = categoria.ID
+		if categoria.Ordem >= ordemCategoria {
+			ordemCategoria = categoria.Ordem + 1
+		}
+	}
+
+	var novasCategorias []Categoria
+	for _, linha := range linhas {
+		nome := strings.ToLower(linha.Categoria)
+		if _, ok := categoriaPorNome[nome]; ok {
+			continue
+		}
+		categoriaPorNome[nome] = 0
+		novasCategorias = append(novasCategorias, Categoria{
+			EstabelecimentoID: estabelecimentoID,
+			Nome:              linha.Categoria,
+			Ordem:             ordemCategoria,
+			DataCadastro:      agora,
+			DataAtualizacao:   agora,
+		})
+		ordemCategoria++
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	for inicio := 0; inicio < len(novasCategorias); inicio += tamanhoLoteDatastore {
+		fim := inicio + tamanhoLoteDatastore
+		if fim > len(novasCategorias) {
+			fim = len(novasCategorias)
+		}
+		lote := novasCategorias[inicio:fim]
+
+		keys := make([]*datastore.Key, len(lote))
+		for i := range lote {
+			keys[i] = datastore.IncompleteKey(KindCategoria, nil)
+		}
+		keys, err = datastoreClient.PutMulti(c, keys, lote)
+		if err != nil {
+			log.Warningf(c, "Erro ao gravar categorias importadas: %v", err)
+			return nil, err
+		}
+		for i := range keys {
+			categoriaPorNome[strings.ToLower(lote[i].Nome)] = keys[i].ID
+		}
+	}
+	resultado.CategoriasCriadas = len(novasCategorias)
+
+	chaveItem := func(categoriaID int64, nome string) string {
+		return fmt.Sprintf("%d:%s", categoriaID, strings.ToLower(nome))
+	}
+	existentes := make(map[string]int, len(itens))
+	ordemItem := make(map[int64]int64)
+	for i, item := range itens {
+		existentes[chaveItem(item.CategoriaID, item.Nome)] = i
+		if item.Ordem >= ordemItem[item.CategoriaID] {
+			ordemItem[item.CategoriaID] = item.Ordem + 1
+		}
+	}
+
+	//Uma linha repetida no arquivo sobrescreve a anterior
+	var gravar []Item
+	posicaoGravar := make(map[string]int)
+	for _, linha := range linhas {
+		item := linha.Item
+		item.CategoriaID = categoriaPorNome[strings.ToLower(linha.Categoria)]
+		item.EstabelecimentoID = estabelecimentoID
+		item.DataAtualizacao = agora
+
+		chave := chaveItem(item.CategoriaID, item.Nome)
+		if i, ok := posicaoGravar[chave]; ok {
+			item.ID, item.Ordem, item.DataCadastro = gravar[i].ID, gravar[i].Ordem, gravar[i].DataCadastro
+			gravar[i] = item
+			continue
+		}
+
+		if i, ok := existentes[chave]; ok {
+			item.ID, item.Ordem, item.DataCadastro = itens[i].ID, itens[i].Ordem, itens[i].DataCadastro
+			resultado.ItensAtualizados++
+		} else {
+			item.Ordem = ordemItem[item.CategoriaID]
+			ordemItem[item.CategoriaID]++
+			item.DataCadastro = agora
+			resultado.ItensCriados++
+		}
+		posicaoGravar[chave] = len(gravar)
+		gravar = append(gravar, item)
+	}
+
+	for inicio := 0; inicio < len(gravar); inicio += tamanhoLoteDatastore {
+		fim := inicio + tamanhoLoteDatastore
+		if fim > len(gravar) {
+			fim = len(gravar)
+		}
+		lote := gravar[inicio:fim]
+
+		keys := make([]*datastore.Key, len(lote))
+		for i := range lote {
+			keys[i] = datastore.IDKey(KindItem, lote[i].ID, nil)
+		}
+		if _, err = datastoreClient.PutMulti(c, keys, lote); err != nil {
+			log.Warningf(c, "Erro ao gravar itens importados: %v", err)
+			return nil, err
+		}
+	}
+
+	return resultado, nil
+}
@@ -0,0 +1,20 @@
+// Migrarhorarios converte o HorarioFunc e o DiasFunc antigos dos estabelecimentos para o horario estruturado.
+//
+// Uso: GOOGLE_CLOUD_PROJECT=<projeto> go run ./cmd/migrarhorarios
+package main
+
+import (
+	"context"
+	"log"
+	"site/estabelecimento"
+)
+
+func main() {
+	c := context.Background()
+
+	total, err := estabelecimento.MigrarHorarios(c)
+	if err != nil {
+		log.Fatalf("Falha ao migrar horarios: %v", err)
+	}
+	log.Printf("%d estabelecimentos migrados", total)
+}
@@ -0,0 +1,81 @@
+// Reindexar recria os indices de usuarios, publicações e estabelecimentos do Elasticsearch a partir do Datastore.
+// Os indices são apagados antes, então a busca fica vazia até o fim da reindexação.
+//
+// Uso: GOOGLE_CLOUD_PROJECT=<projeto> go run ./cmd/reindexar
+package main
+
+import (
+	"context"
+	"log"
+	"site/busca"
+	"site/estabelecimento"
+	"site/publicacao"
+	"site/usuario"
+)
+
+func main() {
+	c := context.Background()
+
+	b, err := busca.Nova(c)
+	if err != nil {
+		log.Fatalf("Falha ao conectar-se com o Elasticsearch: %v", err)
+	}
+
+	if err = b.RecriarIndices(c); err != nil {
+		log.Fatalf("Falha ao recriar indices: %v", err)
+	}
+
+	usuarios, err := usuario.FiltrarUsuario(c, usuario.Usuario{})
+	if err != nil {
+		log.Fatalf("Falha ao buscar usuarios: %v", err)
+	}
+
+	docsUsuarios := make([]busca.DocumentoUsuario, 0, len(usuarios))
+	for i := range usuarios {
+		docsUsuarios = append(docsUsuarios, usuarios[i].DocumentoBusca())
+	}
+
+	total, err := b.ReindexarUsuarios(c, docsUsuarios)
+	if err != nil {
+		log.Fatalf("Falha ao reindexar usuarios (%d indexados): %v", total, err)
+	}
+	log.Printf("%d usuarios indexados", total)
+
+	publicacoes, err := publicacao.FiltrarPublicacoes(c, publicacao.Publicacao{})
+	if err != nil {
+		log.Fatalf("Falha ao buscar publicações: %v", err)
+	}
+
+	docsPublicacoes := make([]busca.DocumentoPublicacao, 0, len(publicacoes))
+	for i := range publicacoes {
+		if !publicacoes[i].Indexavel() {
+			continue
+		}
+		docsPublicacoes = append(docsPublicacoes, publicacoes[i].DocumentoBusca())
+	}
+
+	total, err = b.ReindexarPublicacoes(c, docsPublicacoes)
+	if err != nil {
+		log.Fatalf("Falha ao reindexar publicações (%d indexadas): %v", total, err)
+	}
+	log.Printf("%d publicações indexadas", total)
+
+	estabelecimentos, err := estabelecimento.FiltrarEstabelecimento(c, estabelecimento.Estabelecimento{})
+	if err != nil {
+		log.Fatalf("Falha ao buscar estabelecimentos: %v", err)
+	}
+
+	docsEstabelecimentos := make([]busca.DocumentoEstabelecimento, 0, len(estabelecimentos))
+	for i := range estabelecimentos {
+		if !estabelecimentos[i].Endereco.Geocodificado() {
+			continue
+		}
+		docsEstabelecimentos = append(docsEstabelecimentos, estabelecimentos[i].DocumentoBusca())
+	}
+
+	total, err = b.ReindexarEstabelecimentos(c, docsEstabelecimentos)
+	if err != nil {
+		log.Fatalf("Falha ao reindexar estabelecimentos (%d indexados): %v", total, err)
+	}
+	log.Printf("%d estabelecimentos indexados", total)
+}
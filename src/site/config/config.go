@@ -21,6 +21,26 @@ const (
 	ElasticSearchEndpoint = "elasticsearch.endpoint"
 	ElasticSearchUsername = "elasticsearch.username"
 	ElasticSearchPassword = "elasticsearch.password"
+
+	ArmazenamentoTipo      = "armazenamento.tipo"
+	ArmazenamentoDiretorio = "armazenamento.diretorio"
+	ArmazenamentoBucket    = "armazenamento.bucket"
+	ArmazenamentoURLBase   = "armazenamento.urlbase"
+
+	ModeracaoModeradores = "moderacao.moderadores"
+
+	GeocodificadorTipo = "geocodificador.tipo"
+	GeocodificadorURL  = "geocodificador.url"
+
+	RegistroEmpresaTipo = "registroempresa.tipo"
+	RegistroEmpresaURL  = "registroempresa.url"
+
+	TributosVersaoICMS = "tributos.versaoicms"
+
+	FiltroPalavras     = "filtro.palavras"
+	FiltroLinks        = "filtro.links"
+	FiltroRajadaLimite = "filtro.rajada.limite"
+	FiltroRajadaJanela = "filtro.rajada.janela"
 )
 
 var SecretKey []byte
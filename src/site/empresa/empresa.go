@@ -0,0 +1,168 @@
+package empresa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"site/config"
+	"strings"
+	"time"
+)
+
+const (
+	RegistroBrasilAPI = "brasilapi"
+	RegistroDesligado = "desligado"
+
+	URLBrasilAPI = "https://brasilapi.com.br/api/cnpj/v1/"
+
+	// TimeoutRegistro é o prazo da consulta ao registro. O cadastro segue sem os dados se ele não responder.
+	TimeoutRegistro = 5 * time.Second
+
+	SituacaoAtiva = "ATIVA"
+)
+
+var (
+	ErrCNPJNaoEncontrado = errors.New("CNPJ não encontrado no cadastro da Receita Federal")
+	ErrRegistroDesligado = errors.New("Consulta ao cadastro de empresas desligada")
+)
+
+// DadosEmpresa é o que o registro de empresas informa sobre um CNPJ
+type DadosEmpresa struct {
+	CNPJ              string
+	RazaoSocial       string
+	NomeFantasia      string
+	SituacaoCadastral string
+	Logradouro        string
+	Numero            string
+	Complemento       string
+	Bairro            string
+	Municipio         string
+	UF                string
+	CEP               string
+}
+
+// Ativa diz se a empresa está com a situação cadastral ativa na Receita
+func (dados *DadosEmpresa) Ativa() bool {
+	return strings.EqualFold(dados.SituacaoCadastral, SituacaoAtiva)
+}
+
+// Registro consulta os dados de uma empresa pelo CNPJ, que chega só com os 14 digitos. Quando o CNPJ
+// não existe o erro é ErrCNPJNaoEncontrado.
+type Registro interface {
+	Consultar(c context.Context, cnpj string) (*DadosEmpresa, error)
+}
+
+// NovoRegistro retorna o registro configurado em registroempresa.tipo, usando a BrasilAPI como padrão
+func NovoRegistro(c context.Context) (Registro, error) {
+	tipo := config.GetDefault(c, config.RegistroEmpresaTipo, RegistroBrasilAPI).Value
+
+	switch tipo {
+	case RegistroBrasilAPI:
+		return &BrasilAPI{
+			URLBase: config.GetDefault(c, config.RegistroEmpresaURL, URLBrasilAPI).Value,
+			Cliente: &http.Client{Timeout: TimeoutRegistro},
+		}, nil
+	case RegistroDesligado:
+		return Desligado{}, nil
+	default:
+		return nil, fmt.Errorf("Tipo de registro de empresas desconhecido: %s", tipo)
+	}
+}
+
+// BrasilAPI consulta o CNPJ em brasilapi.com.br, que repassa os dados abertos da Receita
+type BrasilAPI struct {
+	URLBase string
+	Cliente *http.Client
+}
+
+type retBrasilAPI struct {
+	CNPJ                       string `json:"cnpj"`
+	RazaoSocial                string `json:"razao_social"`
+	NomeFantasia               string `json:"nome_fantasia"`
+	DescricaoSituacaoCadastral string `json:"descricao_situacao_cadastral"`
+	DescricaoTipoLogradouro    string `json:"descricao_tipo_de_logradouro"`
+	Logradouro                 string `json:"logradouro"`
+	Numero                     string `json:"numero"`
+	Complemento                string `json:"complemento"`
+	Bairro                     string `json:"bairro"`
+	Municipio                  string `json:"municipio"`
+	UF                         string `json:"uf"`
+	CEP                        string `json:"cep"`
+}
+
+func (b *BrasilAPI) Consultar(c context.Context, cnpj string) (*DadosEmpresa, error) {
+	url := strings.TrimRight(b.URLBase, "/") + "/" + cnpj
+	req, err := http.NewRequestWithContext(c, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	cliente := b.Cliente
+	if cliente == nil {
+		cliente = http.DefaultClient
+	}
+	resp, err := cliente.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrCNPJNaoEncontrado
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Status %d ao consultar o CNPJ", resp.StatusCode)
+	}
+
+	var retorno retBrasilAPI
+	if err = json.NewDecoder(resp.Body).Decode(&retorno); err != nil {
+		return nil, err
+	}
+
+	logradouro := retorno.Logradouro
+	if retorno.DescricaoTipoLogradouro != "" && !strings.HasPrefix(strings.ToUpper(logradouro), strings.ToUpper(retorno.DescricaoTipoLogradouro)+" ") {
+		logradouro = retorno.DescricaoTipoLogradouro + " " + logradouro
+	}
+	return &DadosEmpresa{
+		CNPJ:              retorno.CNPJ,
+		RazaoSocial:       retorno.RazaoSocial,
+		NomeFantasia:      retorno.NomeFantasia,
+		SituacaoCadastral: strings.ToUpper(retorno.DescricaoSituacaoCadastral),
+		Logradouro:        logradouro,
+		Numero:            retorno.Numero,
+		Complemento:       retorno.Complemento,
+		Bairro:            retorno.Bairro,
+		Municipio:         retorno.Municipio,
+		UF:                retorno.UF,
+		CEP:               retorno.CEP,
+	}, nil
+}
+
+// Desligado não consulta nada, para ambientes sem acesso ao registro
+type Desligado struct{}
+
+func (Desligado) Consultar(c context.Context, cnpj string) (*DadosEmpresa, error) {
+	return nil, ErrRegistroDesligado
+}
+
+// Fixo responde com as empresas cadastradas em Empresas, para testes. CNPJs fora do mapa não são
+// encontrados; com Erro preenchido toda consulta falha com ele, simulando o registro fora do ar.
+type Fixo struct {
+	Empresas map[string]DadosEmpresa
+	Erro     error
+}
+
+func (f *Fixo) Consultar(c context.Context, cnpj string) (*DadosEmpresa, error) {
+	if f.Erro != nil {
+		return nil, f.Erro
+	}
+	dados, ok := f.Empresas[cnpj]
+	if !ok {
+		return nil, ErrCNPJNaoEncontrado
+	}
+	dados.CNPJ = cnpj
+	return &dados, nil
+}
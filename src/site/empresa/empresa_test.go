@@ -0,0 +1,77 @@
+package empresa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBrasilAPI(t *testing.T) {
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cnpj/v1/11222333000181":
+			w.Write([]byte(`{
+				"cnpj": "11222333000181",
+				"razao_social": "BAR DO ZE LTDA",
+				"nome_fantasia": "BAR DO ZE",
+				"situacao_cadastral": 2,
+				"descricao_situacao_cadastral": "Ativa",
+				"descricao_tipo_de_logradouro": "PRACA",
+				"logradouro": "DA SE",
+				"numero": "10",
+				"complemento": "",
+				"bairro": "SE",
+				"municipio": "SAO PAULO",
+				"uf": "SP",
+				"cep": "01001000"
+			}`))
+		case "/cnpj/v1/99999999000191":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer servidor.Close()
+
+	registro := &BrasilAPI{URLBase: servidor.URL + "/cnpj/v1/"}
+
+	dados, err := registro.Consultar(context.Background(), "11222333000181")
+	if err != nil {
+		t.Fatalf("Consultar() = %v", err)
+	}
+	if dados.RazaoSocial != "BAR DO ZE LTDA" || dados.Logradouro != "PRACA DA SE" || dados.CEP != "01001000" || dados.UF != "SP" {
+		t.Errorf("dados inesperados: %+v", dados)
+	}
+	if !dados.Ativa() {
+		t.Errorf("situação %q deveria contar como ativa", dados.SituacaoCadastral)
+	}
+
+	if _, err = registro.Consultar(context.Background(), "99999999000191"); err != ErrCNPJNaoEncontrado {
+		t.Errorf("CNPJ inexistente: Consultar() = %v, esperado ErrCNPJNaoEncontrado", err)
+	}
+	if _, err = registro.Consultar(context.Background(), "00000000000000"); err == nil || err == ErrCNPJNaoEncontrado {
+		t.Errorf("falha do provedor: Consultar() = %v, esperado erro de status", err)
+	}
+}
+
+func TestFixo(t *testing.T) {
+	registro := &Fixo{Empresas: map[string]DadosEmpresa{
+		"11222333000181": {RazaoSocial: "BAR DO ZE LTDA", SituacaoCadastral: "BAIXADA"},
+	}}
+
+	dados, err := registro.Consultar(context.Background(), "11222333000181")
+	if err != nil || dados.CNPJ != "11222333000181" || dados.Ativa() {
+		t.Errorf("Consultar() = %+v, %v", dados, err)
+	}
+	if _, err = registro.Consultar(context.Background(), "99999999000191"); err != ErrCNPJNaoEncontrado {
+		t.Errorf("CNPJ fora do mapa: Consultar() = %v", err)
+	}
+
+	foraDoAr := errors.New("fora do ar")
+	registro.Erro = foraDoAr
+	if _, err = registro.Consultar(context.Background(), "11222333000181"); err != foraDoAr {
+		t.Errorf("com Erro: Consultar() = %v", err)
+	}
+}
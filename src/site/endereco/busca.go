@@ -0,0 +1,122 @@
+package endereco
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"site/utils"
+	"site/utils/log"
+	"strings"
+	"time"
+)
+
+const (
+	// Tamanho minimo do municipio e do logradouro aceito na busca por endereço
+	tamanhoMinimoBusca = 3
+)
+
+// ProvedorEndereco é um provedor de CEP que também busca os CEPs de um logradouro. Nem todo
+// provedor oferece essa busca; o buscador só usa os que implementam esta interface.
+type ProvedorEndereco interface {
+	ProvedorCEP
+	BuscarPorEndereco(c context.Context, uf, municipio, logradouro string) ([]Endereco, error)
+}
+
+// BuscarPorEndereco usa a busca /ws/UF/municipio/logradouro/json/ do ViaCEP
+func (v ViaCEP) BuscarPorEndereco(c context.Context, uf, municipio, logradouro string) ([]Endereco, error) {
+	caminho := fmt.Sprintf("%s/%s/%s/json/", url.PathEscape(uf), url.PathEscape(municipio), url.PathEscape(logradouro))
+
+	var retorno []retViaCEP
+	if err := consultarCEP(c, v.Cliente, v.URLBase+caminho, &retorno); err != nil {
+		return nil, err
+	}
+
+	enderecos := make([]Endereco, 0, len(retorno))
+	for _, r := range retorno {
+		enderecos = append(enderecos, Endereco{
+			CEP:         utils.OnlyNumbers(r.Cep),
+			Logradouro:  r.Logradouro,
+			Bairro:      r.Bairro,
+			Municipio:   r.Localidade,
+			UF:          r.UF,
+			Complemento: r.Complemento,
+		})
+	}
+	return enderecos, nil
+}
+
+// validarBusca confere os campos exigidos na busca por endereço
+func validarBusca(filtro *Endereco) error {
+	filtro.UF = strings.ToUpper(strings.TrimSpace(filtro.UF))
+	filtro.Municipio = strings.TrimSpace(filtro.Municipio)
+	filtro.Logradouro = strings.TrimSpace(filtro.Logradouro)
+	filtro.Bairro = strings.TrimSpace(filtro.Bairro)
+
+	if len(filtro.UF) != 2 {
+		return fmt.Errorf("UF inválida: %v", filtro.UF)
+	}
+	if len([]rune(filtro.Municipio)) < tamanhoMinimoBusca {
+		return fmt.Errorf("Informe ao menos %d letras do municipio", tamanhoMinimoBusca)
+	}
+	if len([]rune(filtro.Logradouro)) < tamanhoMinimoBusca {
+		return fmt.Errorf("Informe ao menos %d letras do logradouro", tamanhoMinimoBusca)
+	}
+	return nil
+}
+
+// mesmoNome compara nomes sem diferenciar acentos e maiusculas
+func mesmoNome(a, b string) bool {
+	return strings.EqualFold(utils.LimparString(strings.TrimSpace(a)), utils.LimparString(strings.TrimSpace(b)))
+}
+
+// BuscarEnderecos procura os CEPs do logradouro nos provedores que oferecem a busca por endereço,
+// com o mesmo failover e disjuntores da busca por CEP. Os CEPs encontrados vão para o cache.
+func (buscador *BuscadorCEP) BuscarEnderecos(c context.Context, filtro Endereco) ([]Endereco, error) {
+	if err := validarBusca(&filtro); err != nil {
+		return nil, err
+	}
+
+	for _, p := range buscador.provedores {
+		provedor, ok := p.ProvedorCEP.(ProvedorEndereco)
+		if !ok {
+			continue
+		}
+		if !p.disjuntor.Permitir(time.Now()) {
+			log.Debugf(c, "Provedor de CEP %s com disjuntor aberto, pulando", p.Nome())
+			continue
+		}
+
+		enderecos, err := buscador.consultarEndereco(c, provedor, filtro)
+		p.disjuntor.Registrar(time.Now(), err != nil && err != ErrCEPNaoEncontrado)
+		if err == ErrCEPNaoEncontrado {
+			return []Endereco{}, nil
+		}
+		if err != nil {
+			log.Warningf(c, "Falha ao buscar endereço em %s: %v", p.Nome(), err)
+			continue
+		}
+
+		encontrados := make([]Endereco, 0, len(enderecos))
+		for _, e := range enderecos {
+			if filtro.Bairro != "" && !mesmoNome(e.Bairro, filtro.Bairro) {
+				continue
+			}
+			if buscador.Cache != nil && e.CEP != "" {
+				buscador.Cache.Put(c, e.CEP, &e)
+			}
+			encontrados = append(encontrados, e)
+		}
+		return encontrados, nil
+	}
+
+	return nil, ErrCEPIndisponivel
+}
+
+func (buscador *BuscadorCEP) consultarEndereco(c context.Context, provedor ProvedorEndereco, filtro Endereco) ([]Endereco, error) {
+	if buscador.Timeout > 0 {
+		var cancelar context.CancelFunc
+		c, cancelar = context.WithTimeout(c, buscador.Timeout)
+		defer cancelar()
+	}
+	return provedor.BuscarPorEndereco(c, filtro.UF, filtro.Municipio, filtro.Logradouro)
+}
@@ -0,0 +1,157 @@
+package endereco
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const corpoViaCEPLogradouro = `[
+	{"cep":"01310-100","logradouro":"Avenida Paulista","complemento":"de 1 a 610 - lado par","bairro":"Bela Vista","localidade":"São Paulo","uf":"SP"},
+	{"cep":"01311-000","logradouro":"Avenida Paulista","complemento":"de 612 a 1510 - lado par","bairro":"Cerqueira César","localidade":"São Paulo","uf":"SP"}
+]`
+
+func TestBuscarEnderecos(t *testing.T) {
+	var caminho string
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caminho = r.URL.Path
+		w.Write([]byte(corpoViaCEPLogradouro))
+	}))
+	t.Cleanup(servidor.Close)
+
+	cache := &CacheMemoria{}
+	buscador := NovoBuscadorCEP(cache, time.Second, ViaCEP{URLBase: servidor.URL + "/ws/"})
+
+	enderecos, err := buscador.BuscarEnderecos(context.Background(), Endereco{UF: "sp", Municipio: "São Paulo", Logradouro: "Paulista"})
+	if err != nil {
+		t.Fatalf("BuscarEnderecos() = %v", err)
+	}
+	if caminho != "/ws/SP/São Paulo/Paulista/json/" {
+		t.Errorf("caminho inesperado: %q", caminho)
+	}
+	if len(enderecos) != 2 || enderecos[0].CEP != "01310100" {
+		t.Fatalf("endereços inesperados: %#v", enderecos)
+	}
+	if _, ok := cache.Get(context.Background(), "01311000"); !ok {
+		t.Errorf("CEPs encontrados deveriam ir para o cache")
+	}
+
+	enderecos, err = buscador.BuscarEnderecos(context.Background(), Endereco{UF: "SP", Municipio: "São Paulo", Logradouro: "Paulista", Bairro: "cerqueira cesar"})
+	if err != nil {
+		t.Fatalf("BuscarEnderecos() = %v", err)
+	}
+	if len(enderecos) != 1 || enderecos[0].Bairro != "Cerqueira César" {
+		t.Errorf("filtro por bairro deveria ignorar acentos e maiusculas: %#v", enderecos)
+	}
+}
+
+func TestBuscarEnderecosErros(t *testing.T) {
+	vazio := novoStub(t, http.StatusOK, `[]`, 0)
+	buscador := NovoBuscadorCEP(nil, time.Second, ViaCEP{URLBase: vazio.URL + "/"})
+
+	invalidos := []Endereco{
+		{UF: "SPP", Municipio: "São Paulo", Logradouro: "Paulista"},
+		{UF: "SP", Municipio: "SP", Logradouro: "Paulista"},
+		{UF: "SP", Municipio: "São Paulo", Logradouro: "  Pa "},
+	}
+	for _, filtro := range invalidos {
+		if _, err := buscador.BuscarEnderecos(context.Background(), filtro); err == nil {
+			t.Errorf("BuscarEnderecos(%#v) deveria falhar", filtro)
+		}
+	}
+	if vazio.Chamadas() != 0 {
+		t.Errorf("buscas inválidas não deveriam chegar ao provedor")
+	}
+
+	enderecos, err := buscador.BuscarEnderecos(context.Background(), Endereco{UF: "SP", Municipio: "São Paulo", Logradouro: "Inexistente"})
+	if err != nil || len(enderecos) != 0 {
+		t.Errorf("BuscarEnderecos() = %v, %v, esperado lista vazia", enderecos, err)
+	}
+
+	//Provedores sem busca por endereço são ignorados
+	postmon := novoStub(t, http.StatusOK, corpoPostmon, 0)
+	buscador = NovoBuscadorCEP(nil, time.Second, Postmon{URLBase: postmon.URL + "/"})
+	if _, err := buscador.BuscarEnderecos(context.Background(), Endereco{UF: "SP", Municipio: "São Paulo", Logradouro: "Paulista"}); err != ErrCEPIndisponivel {
+		t.Errorf("BuscarEnderecos() = %v, esperado ErrCEPIndisponivel", err)
+	}
+	if postmon.Chamadas() != 0 {
+		t.Errorf("Postmon não oferece busca por endereço e não deveria ser chamado")
+	}
+}
+
+func TestNominatim(t *testing.T) {
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != userAgentGeocodificador {
+			t.Errorf("User-Agent inesperado: %q", r.Header.Get("User-Agent"))
+		}
+		q := r.URL.Query()
+		if q.Get("street") != "1578 Avenida Paulista" || q.Get("city") != "São Paulo" || q.Get("state") != "SP" || q.Get("postalcode") != "01310200" {
+			t.Errorf("parametros inesperados: %v", q)
+		}
+		w.Write([]byte(`[{"lat":"-23.5613","lon":"-46.6565"}]`))
+	}))
+	t.Cleanup(servidor.Close)
+
+	n := &Nominatim{URLBase: servidor.URL + "/"}
+	coordenadas, err := n.Geocodificar(context.Background(), Endereco{
+		CEP: "01310200", Numero: "1578", Logradouro: "Avenida Paulista", Municipio: "São Paulo", UF: "SP",
+	})
+	if err != nil {
+		t.Fatalf("Geocodificar() = %v", err)
+	}
+	if coordenadas.Latitude != -23.5613 || coordenadas.Longitude != -46.6565 {
+		t.Errorf("coordenadas inesperadas: %#v", coordenadas)
+	}
+}
+
+func TestNominatimNaoEncontrado(t *testing.T) {
+	vazio := novoStub(t, http.StatusOK, `[]`, 0)
+	n := &Nominatim{URLBase: vazio.URL}
+	if _, err := n.Geocodificar(context.Background(), Endereco{UF: "SP"}); err != ErrEnderecoNaoGeocodificado {
+		t.Errorf("Geocodificar() = %v, esperado ErrEnderecoNaoGeocodificado", err)
+	}
+}
+
+func TestNominatimIntervalo(t *testing.T) {
+	var horarios []time.Time
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		horarios = append(horarios, time.Now())
+		w.Write([]byte(`[{"lat":"-23.5613","lon":"-46.6565"}]`))
+	}))
+	t.Cleanup(servidor.Close)
+
+	n := &Nominatim{URLBase: servidor.URL, Intervalo: 50 * time.Millisecond}
+	for i := 0; i < 3; i++ {
+		if _, err := n.Geocodificar(context.Background(), Endereco{UF: "SP"}); err != nil {
+			t.Fatalf("Geocodificar() = %v", err)
+		}
+	}
+	for i := 1; i < len(horarios); i++ {
+		if intervalo := horarios[i].Sub(horarios[i-1]); intervalo < 45*time.Millisecond {
+			t.Errorf("requisições %d e %d com %v entre elas, esperado ao menos %v", i, i+1, intervalo, n.Intervalo)
+		}
+	}
+
+	cancelado, cancelar := context.WithCancel(context.Background())
+	cancelar()
+	if _, err := n.Geocodificar(cancelado, Endereco{UF: "SP"}); err == nil {
+		t.Errorf("Geocodificar() com contexto cancelado deveria falhar enquanto espera a vez")
+	}
+}
+
+func TestOffline(t *testing.T) {
+	fixo := Coordenadas{Latitude: -23.5505, Longitude: -46.6333}
+	o := &Offline{Fixos: map[string]Coordenadas{"01001000": fixo}}
+
+	if coordenadas, err := o.Geocodificar(context.Background(), Endereco{CEP: "01001000", UF: "SP"}); err != nil || coordenadas != fixo {
+		t.Errorf("CEP fixo: Geocodificar() = %v, %v", coordenadas, err)
+	}
+	if coordenadas, err := o.Geocodificar(context.Background(), Endereco{CEP: "20000000", UF: "rj"}); err != nil || coordenadas != capitais["RJ"] {
+		t.Errorf("capital: Geocodificar() = %v, %v", coordenadas, err)
+	}
+	if _, err := o.Geocodificar(context.Background(), Endereco{UF: "XX"}); err != ErrEnderecoNaoGeocodificado {
+		t.Errorf("UF desconhecida: Geocodificar() = %v", err)
+	}
+}
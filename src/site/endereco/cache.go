@@ -0,0 +1,95 @@
+package endereco
+
+import (
+	"context"
+	"site/utils/consts"
+	"site/utils/log"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindCacheCEP = "CacheCEP"
+
+	// ValidadeCacheCEP é o tempo que um CEP resolvido fica guardado antes de ser consultado de novo
+	ValidadeCacheCEP = 90 * 24 * time.Hour
+)
+
+// CacheCEP guarda os CEPs já resolvidos para não consultar os provedores de novo
+type CacheCEP interface {
+	Get(c context.Context, cep string) (*Endereco, bool)
+	Put(c context.Context, cep string, endereco *Endereco)
+}
+
+type cepGuardado struct {
+	Endereco     Endereco `datastore:",noindex"`
+	DataConsulta time.Time
+}
+
+// CacheDatastore guarda os CEPs no Datastore, com o CEP como chave
+type CacheDatastore struct{}
+
+func (CacheDatastore) Get(c context.Context, cep string) (*Endereco, bool) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, false
+	}
+	defer datastoreClient.Close()
+
+	var guardado cepGuardado
+	if err = datastoreClient.Get(c, datastore.NameKey(KindCacheCEP, cep, nil), &guardado); err != nil {
+		if err != datastore.ErrNoSuchEntity {
+			log.Warningf(c, "Falha ao buscar CEP %s no cache: %v", cep, err)
+		}
+		return nil, false
+	}
+
+	if time.Since(guardado.DataConsulta) > ValidadeCacheCEP {
+		return nil, false
+	}
+	return &guardado.Endereco, true
+}
+
+func (CacheDatastore) Put(c context.Context, cep string, endereco *Endereco) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return
+	}
+	defer datastoreClient.Close()
+
+	guardado := cepGuardado{Endereco: *endereco, DataConsulta: time.Now()}
+	if _, err = datastoreClient.Put(c, datastore.NameKey(KindCacheCEP, cep, nil), &guardado); err != nil {
+		log.Warningf(c, "Falha ao guardar CEP %s no cache: %v", cep, err)
+	}
+}
+
+// CacheMemoria guarda os CEPs em memoria, sem validade. Usado nos testes.
+type CacheMemoria struct {
+	mu   sync.Mutex
+	ceps map[string]Endereco
+}
+
+func (cache *CacheMemoria) Get(c context.Context, cep string) (*Endereco, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	endereco, ok := cache.ceps[cep]
+	if !ok {
+		return nil, false
+	}
+	return &endereco, true
+}
+
+func (cache *CacheMemoria) Put(c context.Context, cep string, endereco *Endereco) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.ceps == nil {
+		cache.ceps = make(map[string]Endereco)
+	}
+	cache.ceps[cep] = *endereco
+}
@@ -0,0 +1,57 @@
+package endereco
+
+import (
+	"sync"
+	"time"
+)
+
+// Disjuntor para de chamar um provedor depois de LimiteFalhas falhas seguidas. Passado o tempo de
+// Espera ele deixa uma chamada passar de teste: se der certo fecha de novo, se falhar volta a abrir.
+type Disjuntor struct {
+	LimiteFalhas int
+	Espera       time.Duration
+
+	mu        sync.Mutex
+	falhas    int
+	abertoAte time.Time
+	testando  bool
+}
+
+// Permitir diz se o provedor pode ser chamado agora
+func (d *Disjuntor) Permitir(agora time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.falhas < d.LimiteFalhas {
+		return true
+	}
+	if agora.Before(d.abertoAte) || d.testando {
+		return false
+	}
+	d.testando = true
+	return true
+}
+
+// Registrar conta o resultado da chamada. Só falhas do provedor contam; CEP inexistente é sucesso.
+func (d *Disjuntor) Registrar(agora time.Time, falhou bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.testando = false
+	if !falhou {
+		d.falhas = 0
+		return
+	}
+
+	d.falhas++
+	if d.falhas >= d.LimiteFalhas {
+		d.abertoAte = agora.Add(d.Espera)
+	}
+}
+
+// Aberto diz se o disjuntor está recusando chamadas
+func (d *Disjuntor) Aberto(agora time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.falhas >= d.LimiteFalhas && agora.Before(d.abertoAte)
+}
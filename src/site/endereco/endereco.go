@@ -2,21 +2,27 @@ package endereco
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"site/utils"
 	"site/utils/log"
+	"time"
 )
 
-type retPostmon struct {
-	Complemento string `json:"complemento"`
-	Bairro      string `json:"bairro"`
-	Cidade      string `json:"cidade"`
-	Logradouro  string `json:"logradouro"`
-	Cep         string `json:"cep"`
-	Estado      string `json:"estado"`
-}
+const (
+	// TimeoutProvedorCEP é o prazo de cada consulta a um provedor antes de tentar o proximo
+	TimeoutProvedorCEP = 3 * time.Second
+
+	falhasAbrirDisjuntor = 3
+	esperaDisjuntor      = time.Minute
+)
+
+var (
+	ErrCEPInvalido      = errors.New("CEP inválido")
+	ErrCEPNaoEncontrado = errors.New("CEP não encontrado")
+	ErrCEPIndisponivel  = errors.New("Nenhum serviço de CEP disponível no momento")
+)
 
 type Endereco struct {
 	CEP         string
@@ -29,48 +35,146 @@ type Endereco struct {
 	Complemento string
 }
 
-func BuscarEndereco(c context.Context, endereco *Endereco) error {
-	log.Debugf(c, "Buscando endereco por cep e numero %#v", endereco)
+// provedorProtegido é um provedor com o disjuntor que controla as chamadas a ele
+type provedorProtegido struct {
+	ProvedorCEP
+	disjuntor *Disjuntor
+}
+
+// BuscadorCEP consulta os provedores na ordem, passando para o proximo quando um falha, demora
+// mais que o Timeout ou está com o disjuntor aberto. Os CEPs resolvidos ficam no Cache.
+type BuscadorCEP struct {
+	Timeout    time.Duration
+	Cache      CacheCEP
+	provedores []provedorProtegido
+}
+
+// NovoBuscadorCEP monta o buscador com um disjuntor para cada provedor
+func NovoBuscadorCEP(cache CacheCEP, timeout time.Duration, provedores ...ProvedorCEP) *BuscadorCEP {
+	buscador := &BuscadorCEP{Timeout: timeout, Cache: cache}
+	for _, p := range provedores {
+		buscador.provedores = append(buscador.provedores, provedorProtegido{
+			ProvedorCEP: p,
+			disjuntor:   &Disjuntor{LimiteFalhas: falhasAbrirDisjuntor, Espera: esperaDisjuntor},
+		})
+	}
+	return buscador
+}
+
+// BuscadorPadrao é o buscador usado por BuscaEnderecoPorCEP. Os disjuntores valem para a instancia
+// inteira, então um provedor fora do ar deixa de ser chamado por todas as requisições.
+var BuscadorPadrao = NovoBuscadorCEP(CacheDatastore{}, TimeoutProvedorCEP,
+	ViaCEP{URLBase: URLViaCEP, Cliente: &http.Client{Timeout: TimeoutProvedorCEP}},
+	BrasilAPI{URLBase: URLBrasilAPI, Cliente: &http.Client{Timeout: TimeoutProvedorCEP}},
+	Postmon{URLBase: URLPostmon, Cliente: &http.Client{Timeout: TimeoutProvedorCEP}},
+)
+
+// NormalizarCEP deixa só os digitos e confere se sobraram 8
+func NormalizarCEP(cep string) (string, error) {
+	cep = utils.OnlyNumbers(cep)
+	if len(cep) != 8 {
+		return "", ErrCEPInvalido
+	}
+	return cep, nil
+}
+
+// Buscar resolve o CEP pelo cache ou pelos provedores. Só responde ErrCEPNaoEncontrado quando nenhum
+// provedor encontrou o CEP e pelo menos um respondeu que ele não existe.
+func (buscador *BuscadorCEP) Buscar(c context.Context, cep string) (*Endereco, error) {
+	cep, err := NormalizarCEP(cep)
+	if err != nil {
+		return nil, err
+	}
+
+	if buscador.Cache != nil {
+		if endereco, ok := buscador.Cache.Get(c, cep); ok {
+			log.Debugf(c, "CEP %s encontrado no cache", cep)
+			return endereco, nil
+		}
+	}
+
+	naoEncontrado := false
+	for _, p := range buscador.provedores {
+		if !p.disjuntor.Permitir(time.Now()) {
+			log.Debugf(c, "Provedor de CEP %s com disjuntor aberto, pulando", p.Nome())
+			continue
+		}
+
+		endereco, err := buscador.consultar(c, p, cep)
+		p.disjuntor.Registrar(time.Now(), err != nil && err != ErrCEPNaoEncontrado)
+
+		if err == ErrCEPNaoEncontrado {
+			naoEncontrado = true
+			continue
+		}
+		if err != nil {
+			log.Warningf(c, "Falha ao consultar CEP %s em %s: %v", cep, p.Nome(), err)
+			continue
+		}
+
+		endereco.CEP = cep
+		if buscador.Cache != nil {
+			buscador.Cache.Put(c, cep, endereco)
+		}
+		return endereco, nil
+	}
+
+	if naoEncontrado {
+		return nil, ErrCEPNaoEncontrado
+	}
+	return nil, ErrCEPIndisponivel
+}
+
+func (buscador *BuscadorCEP) consultar(c context.Context, p provedorProtegido, cep string) (*Endereco, error) {
+	if buscador.Timeout > 0 {
+		var cancelar context.CancelFunc
+		c, cancelar = context.WithTimeout(c, buscador.Timeout)
+		defer cancelar()
+	}
+
+	endereco, err := p.Buscar(c, cep)
+	if err == nil && endereco.Municipio == "" && endereco.UF == "" {
+		return nil, fmt.Errorf("Resposta sem municipio e UF")
+	}
+	return endereco, err
+}
+
+// BuscarEndereco procura os CEPs de um logradouro pela UF, municipio e logradouro, filtrando pelo
+// bairro quando informado
+func BuscarEndereco(c context.Context, endereco *Endereco) ([]Endereco, error) {
+	log.Debugf(c, "Buscando endereco por logradouro %#v", endereco)
 
 	if endereco.CEP == "" && endereco.Logradouro == "" && endereco.Bairro == "" && endereco.Municipio == "" {
-		return fmt.Errorf("Dados insuficientes para busca do endereco %v", endereco)
+		return nil, fmt.Errorf("Dados insuficientes para busca do endereco %v", endereco)
 	}
 
-	return nil
+	return BuscadorPadrao.BuscarEnderecos(c, *endereco)
 }
 
+// BuscaEnderecoPorCEP completa o endereço com os dados do CEP. Numero e complemento informados são
+// mantidos, assim como logradouro e bairro quando o CEP é de uma cidade inteira e não os traz.
 func BuscaEnderecoPorCEP(c context.Context, endereco *Endereco) error {
 	log.Debugf(c, "Buscando endereco por cep e numero %#v", endereco)
-	urlCep := "http://api.postmon.com.br/v1/cep/%s"
-	urlCep = fmt.Sprintf(urlCep, endereco.CEP)
-	resp, err := http.Get(urlCep)
-	log.Debugf(c, "Realizando requisicao para url %v", urlCep)
+
+	encontrado, err := BuscadorPadrao.Buscar(c, endereco.CEP)
 	if err != nil {
-		log.Warningf(c, "Erro ao Realizar requisicao  %v", err)
+		log.Warningf(c, "Erro ao buscar CEP %s: %v", endereco.CEP, err)
 		return err
 	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Warningf(c, "Erro ao Realizar ReadAll  %v", err)
-		return err
+
+	endereco.CEP = encontrado.CEP
+	endereco.Municipio = encontrado.Municipio
+	endereco.UF = encontrado.UF
+	if encontrado.Logradouro != "" {
+		endereco.Logradouro = encontrado.Logradouro
 	}
-	log.Infof(c, "retorno da busca do cep realizada com sucesso. %#v", string(body))
-	if len(body) == 0 {
-		log.Warningf(c, "CEP não encontrado.")
-		return fmt.Errorf("CEP não encontrado.")
+	if encontrado.Bairro != "" {
+		endereco.Bairro = encontrado.Bairro
 	}
-	var retorno retPostmon
-	err = json.Unmarshal(body, &retorno)
-	if err != nil {
-		log.Warningf(c, "Erro ao Realizar Unmarshal  %v", err)
-		return err
+	if endereco.Pais == "" {
+		endereco.Pais = "Brasil"
 	}
 
-	endereco.CEP = retorno.Cep
-	endereco.Bairro = retorno.Bairro
-	endereco.Logradouro = retorno.Logradouro
-	endereco.UF = retorno.Estado
-
-	log.Infof(c, "retornando endereco atualizado: %#v", retorno)
+	log.Infof(c, "retornando endereco atualizado: %#v", endereco)
 	return nil
 }
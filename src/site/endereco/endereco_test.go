@@ -0,0 +1,198 @@
+package endereco
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubProvedor sobe um servidor local que responde com o status e o corpo informados e conta as chamadas
+type stubProvedor struct {
+	*httptest.Server
+	chamadas int32
+}
+
+func novoStub(t *testing.T, status int, corpo string, atraso time.Duration) *stubProvedor {
+	stub := &stubProvedor{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&stub.chamadas, 1)
+		if atraso > 0 {
+			select {
+			case <-time.After(atraso):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(corpo))
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (stub *stubProvedor) Chamadas() int {
+	return int(atomic.LoadInt32(&stub.chamadas))
+}
+
+const (
+	corpoPostmon   = `{"complemento":"lado ímpar","bairro":"Sé","cidade":"São Paulo","logradouro":"Praça da Sé","estado_info":{},"cep":"01001000","estado":"SP"}`
+	corpoViaCEP    = `{"cep":"01001-000","logradouro":"Praça da Sé","complemento":"lado ímpar","bairro":"Sé","localidade":"São Paulo","uf":"SP"}`
+	corpoBrasilAPI = `{"cep":"01001000","state":"SP","city":"São Paulo","neighborhood":"Sé","street":"Praça da Sé","service":"viacep"}`
+)
+
+func conferirSe(t *testing.T, endereco *Endereco) {
+	t.Helper()
+	if endereco.CEP != "01001000" || endereco.Municipio != "São Paulo" || endereco.UF != "SP" ||
+		endereco.Bairro != "Sé" || endereco.Logradouro != "Praça da Sé" {
+		t.Errorf("endereço inesperado: %#v", endereco)
+	}
+}
+
+func TestProvedores(t *testing.T) {
+	postmon := novoStub(t, http.StatusOK, corpoPostmon, 0)
+	viacep := novoStub(t, http.StatusOK, corpoViaCEP, 0)
+	brasilapi := novoStub(t, http.StatusOK, corpoBrasilAPI, 0)
+
+	provedores := []ProvedorCEP{
+		Postmon{URLBase: postmon.URL + "/v1/cep/"},
+		ViaCEP{URLBase: viacep.URL + "/ws/"},
+		BrasilAPI{URLBase: brasilapi.URL + "/api/cep/v1/"},
+	}
+	for _, p := range provedores {
+		endereco, err := p.Buscar(context.Background(), "01001000")
+		if err != nil {
+			t.Fatalf("%s: Buscar() = %v", p.Nome(), err)
+		}
+		endereco.CEP = strings.Replace(endereco.CEP, "-", "", 1)
+		conferirSe(t, endereco)
+	}
+}
+
+func TestProvedoresNaoEncontrado(t *testing.T) {
+	postmon := novoStub(t, http.StatusNotFound, "", 0)
+	viacep := novoStub(t, http.StatusOK, `{"erro": true}`, 0)
+	brasilapi := novoStub(t, http.StatusNotFound, `{"name":"CepPromiseError","message":"Todos os serviços de CEP retornaram erro."}`, 0)
+
+	provedores := []ProvedorCEP{
+		Postmon{URLBase: postmon.URL + "/"},
+		ViaCEP{URLBase: viacep.URL + "/"},
+		BrasilAPI{URLBase: brasilapi.URL + "/"},
+	}
+	for _, p := range provedores {
+		if _, err := p.Buscar(context.Background(), "99999999"); err != ErrCEPNaoEncontrado {
+			t.Errorf("%s: Buscar() = %v, esperado ErrCEPNaoEncontrado", p.Nome(), err)
+		}
+	}
+}
+
+func TestBuscadorFailover(t *testing.T) {
+	fora := novoStub(t, http.StatusInternalServerError, "erro", 0)
+	lento := novoStub(t, http.StatusOK, corpoViaCEP, time.Second)
+	ok := novoStub(t, http.StatusOK, corpoBrasilAPI, 0)
+
+	buscador := NovoBuscadorCEP(&CacheMemoria{}, 100*time.Millisecond,
+		Postmon{URLBase: fora.URL + "/"},
+		ViaCEP{URLBase: lento.URL + "/"},
+		BrasilAPI{URLBase: ok.URL + "/"},
+	)
+
+	endereco, err := buscador.Buscar(context.Background(), "01001-000")
+	if err != nil {
+		t.Fatalf("Buscar() = %v", err)
+	}
+	conferirSe(t, endereco)
+
+	if fora.Chamadas() != 1 || lento.Chamadas() != 1 || ok.Chamadas() != 1 {
+		t.Errorf("cada provedor deveria ser chamado uma vez: %d %d %d", fora.Chamadas(), lento.Chamadas(), ok.Chamadas())
+	}
+}
+
+func TestBuscadorCache(t *testing.T) {
+	viacep := novoStub(t, http.StatusOK, corpoViaCEP, 0)
+	cache := &CacheMemoria{}
+	buscador := NovoBuscadorCEP(cache, time.Second, ViaCEP{URLBase: viacep.URL + "/"})
+
+	for i := 0; i < 3; i++ {
+		if _, err := buscador.Buscar(context.Background(), "01001000"); err != nil {
+			t.Fatalf("Buscar() = %v", err)
+		}
+	}
+	if viacep.Chamadas() != 1 {
+		t.Errorf("com cache o provedor deveria ser chamado uma vez, foi %d", viacep.Chamadas())
+	}
+
+	if _, ok := cache.Get(context.Background(), "01001000"); !ok {
+		t.Errorf("CEP deveria estar no cache")
+	}
+}
+
+func TestBuscadorDisjuntor(t *testing.T) {
+	fora := novoStub(t, http.StatusServiceUnavailable, "", 0)
+	ok := novoStub(t, http.StatusOK, corpoPostmon, 0)
+
+	buscador := NovoBuscadorCEP(nil, time.Second,
+		BrasilAPI{URLBase: fora.URL + "/"},
+		Postmon{URLBase: ok.URL + "/"},
+	)
+
+	for i := 0; i < falhasAbrirDisjuntor+2; i++ {
+		if _, err := buscador.Buscar(context.Background(), "01001000"); err != nil {
+			t.Fatalf("Buscar() = %v", err)
+		}
+	}
+
+	if fora.Chamadas() != falhasAbrirDisjuntor {
+		t.Errorf("depois de %d falhas o provedor não deveria mais ser chamado, foi chamado %d vezes", falhasAbrirDisjuntor, fora.Chamadas())
+	}
+	if ok.Chamadas() != falhasAbrirDisjuntor+2 {
+		t.Errorf("o segundo provedor deveria atender todas as buscas, atendeu %d", ok.Chamadas())
+	}
+}
+
+func TestBuscadorErros(t *testing.T) {
+	naoExiste := novoStub(t, http.StatusNotFound, "", 0)
+	fora := novoStub(t, http.StatusBadGateway, "", 0)
+
+	buscador := NovoBuscadorCEP(nil, time.Second, Postmon{URLBase: fora.URL + "/"}, Postmon{URLBase: naoExiste.URL + "/"})
+	if _, err := buscador.Buscar(context.Background(), "99999999"); err != ErrCEPNaoEncontrado {
+		t.Errorf("Buscar() = %v, esperado ErrCEPNaoEncontrado", err)
+	}
+
+	buscador = NovoBuscadorCEP(nil, time.Second, Postmon{URLBase: fora.URL + "/"})
+	if _, err := buscador.Buscar(context.Background(), "01001000"); err != ErrCEPIndisponivel {
+		t.Errorf("Buscar() = %v, esperado ErrCEPIndisponivel", err)
+	}
+
+	if _, err := buscador.Buscar(context.Background(), "0100-100"); err != ErrCEPInvalido {
+		t.Errorf("Buscar() = %v, esperado ErrCEPInvalido", err)
+	}
+}
+
+func TestDisjuntorMeioAberto(t *testing.T) {
+	agora := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := &Disjuntor{LimiteFalhas: 2, Espera: time.Minute}
+
+	d.Registrar(agora, true)
+	d.Registrar(agora, true)
+	if d.Permitir(agora.Add(30 * time.Second)) {
+		t.Fatalf("disjuntor deveria estar aberto")
+	}
+
+	depois := agora.Add(2 * time.Minute)
+	if !d.Permitir(depois) {
+		t.Fatalf("passada a espera uma chamada de teste deveria passar")
+	}
+	if d.Permitir(depois) {
+		t.Fatalf("só uma chamada de teste por vez")
+	}
+
+	d.Registrar(depois, false)
+	if !d.Permitir(depois) || d.Aberto(depois) {
+		t.Errorf("depois do teste com sucesso o disjuntor deveria fechar")
+	}
+}
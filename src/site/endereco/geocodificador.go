@@ -0,0 +1,208 @@
+package endereco
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"site/config"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	GeocodificadorNominatim = "nominatim"
+	GeocodificadorOffline   = "offline"
+
+	URLNominatim = "https://nominatim.openstreetmap.org"
+
+	// A politica de uso do Nominatim exige um User-Agent que identifique a aplicação
+	userAgentGeocodificador = "site-estabelecimentos/1.0"
+
+	// e no maximo uma requisição por segundo
+	IntervaloNominatim = time.Second
+)
+
+var ErrEnderecoNaoGeocodificado = errors.New("Não foi possivel localizar o endereço no mapa")
+
+// Coordenadas de um ponto em graus decimais
+type Coordenadas struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Geocodificador converte um endereço em latitude e longitude
+type Geocodificador interface {
+	Geocodificar(c context.Context, endereco Endereco) (Coordenadas, error)
+}
+
+// NovoGeocodificador retorna o geocodificador configurado em geocodificador.tipo, usando o
+// Nominatim como padrão
+func NovoGeocodificador(c context.Context) (Geocodificador, error) {
+	tipo := config.GetDefault(c, config.GeocodificadorTipo, GeocodificadorNominatim).Value
+
+	switch tipo {
+	case GeocodificadorNominatim:
+		return &Nominatim{
+			URLBase:   config.GetDefault(c, config.GeocodificadorURL, URLNominatim).Value,
+			Cliente:   &http.Client{Timeout: TimeoutProvedorCEP},
+			Intervalo: IntervaloNominatim,
+		}, nil
+	case GeocodificadorOffline:
+		return &Offline{}, nil
+	default:
+		return nil, fmt.Errorf("Tipo de geocodificador desconhecido: %s", tipo)
+	}
+}
+
+// Nominatim geocodifica pela busca estruturada do OpenStreetMap. Intervalo é o espaço minimo entre
+// duas requisições da instancia, somando todas as requisições concorrentes; zero não espera.
+type Nominatim struct {
+	URLBase   string
+	Cliente   *http.Client
+	Intervalo time.Duration
+}
+
+var (
+	vezNominatimMu   sync.Mutex
+	proximaNominatim time.Time
+)
+
+// aguardarVez reserva o proximo horario livre e espera até ele, ou até o contexto acabar
+func (n *Nominatim) aguardarVez(c context.Context) error {
+	if n.Intervalo <= 0 {
+		return nil
+	}
+
+	vezNominatimMu.Lock()
+	agora := time.Now()
+	vez := proximaNominatim
+	if vez.Before(agora) {
+		vez = agora
+	}
+	proximaNominatim = vez.Add(n.Intervalo)
+	vezNominatimMu.Unlock()
+
+	espera := time.NewTimer(vez.Sub(agora))
+	defer espera.Stop()
+	select {
+	case <-espera.C:
+		return nil
+	case <-c.Done():
+		return c.Err()
+	}
+}
+
+type retNominatim struct {
+	Lat string `json:"lat"`
+	Lon string `json:"lon"`
+}
+
+func (n *Nominatim) Geocodificar(c context.Context, endereco Endereco) (Coordenadas, error) {
+	parametros := url.Values{}
+	parametros.Set("format", "json")
+	parametros.Set("limit", "1")
+	parametros.Set("countrycodes", "br")
+	if endereco.Logradouro != "" {
+		parametros.Set("street", strings.TrimSpace(endereco.Numero+" "+endereco.Logradouro))
+	}
+	if endereco.Municipio != "" {
+		parametros.Set("city", endereco.Municipio)
+	}
+	if endereco.UF != "" {
+		parametros.Set("state", endereco.UF)
+	}
+	if endereco.CEP != "" {
+		parametros.Set("postalcode", endereco.CEP)
+	}
+
+	req, err := http.NewRequestWithContext(c, http.MethodGet, strings.TrimRight(n.URLBase, "/")+"/search?"+parametros.Encode(), nil)
+	if err != nil {
+		return Coordenadas{}, err
+	}
+	req.Header.Set("User-Agent", userAgentGeocodificador)
+
+	if err = n.aguardarVez(c); err != nil {
+		return Coordenadas{}, err
+	}
+
+	cliente := n.Cliente
+	if cliente == nil {
+		cliente = http.DefaultClient
+	}
+	resp, err := cliente.Do(req)
+	if err != nil {
+		return Coordenadas{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Coordenadas{}, fmt.Errorf("Status %d ao geocodificar endereço", resp.StatusCode)
+	}
+
+	var retorno []retNominatim
+	if err = json.NewDecoder(resp.Body).Decode(&retorno); err != nil {
+		return Coordenadas{}, err
+	}
+	if len(retorno) == 0 {
+		return Coordenadas{}, ErrEnderecoNaoGeocodificado
+	}
+
+	lat, errLat := strconv.ParseFloat(retorno[0].Lat, 64)
+	lon, errLon := strconv.ParseFloat(retorno[0].Lon, 64)
+	if errLat != nil || errLon != nil {
+		return Coordenadas{}, fmt.Errorf("Coordenadas inválidas na resposta: %v %v", retorno[0].Lat, retorno[0].Lon)
+	}
+	return Coordenadas{Latitude: lat, Longitude: lon}, nil
+}
+
+// capitais traz as coordenadas da capital de cada UF, usadas pelo geocodificador offline
+var capitais = map[string]Coordenadas{
+	"AC": {-9.97499, -67.8243},
+	"AL": {-9.66599, -35.735},
+	"AP": {0.034934, -51.0694},
+	"AM": {-3.11866, -60.0212},
+	"BA": {-12.9718, -38.5011},
+	"CE": {-3.71664, -38.5423},
+	"DF": {-15.7795, -47.9297},
+	"ES": {-20.3155, -40.3128},
+	"GO": {-16.6864, -49.2643},
+	"MA": {-2.53874, -44.2825},
+	"MT": {-15.601, -56.0974},
+	"MS": {-20.4486, -54.6295},
+	"MG": {-19.9102, -43.9266},
+	"PA": {-1.45502, -48.5024},
+	"PB": {-7.11509, -34.8641},
+	"PR": {-25.4195, -49.2646},
+	"PE": {-8.04666, -34.8771},
+	"PI": {-5.09194, -42.8034},
+	"RJ": {-22.9129, -43.2003},
+	"RN": {-5.79357, -35.1986},
+	"RS": {-30.0318, -51.2065},
+	"RO": {-8.76077, -63.8999},
+	"RR": {2.81954, -60.6714},
+	"SC": {-27.5945, -48.5477},
+	"SP": {-23.5329, -46.6395},
+	"SE": {-10.9091, -37.0677},
+	"TO": {-10.24, -48.3558},
+}
+
+// Offline geocodifica sem acessar a rede, para testes e ambientes sem internet. CEPs cadastrados em
+// Fixos têm coordenadas exatas; os demais caem na capital da UF do endereço.
+type Offline struct {
+	Fixos map[string]Coordenadas
+}
+
+func (o *Offline) Geocodificar(c context.Context, endereco Endereco) (Coordenadas, error) {
+	if coordenadas, ok := o.Fixos[endereco.CEP]; ok {
+		return coordenadas, nil
+	}
+	if coordenadas, ok := capitais[strings.ToUpper(endereco.UF)]; ok {
+		return coordenadas, nil
+	}
+	return Coordenadas{}, ErrEnderecoNaoGeocodificado
+}
@@ -0,0 +1,156 @@
+package endereco
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	URLPostmon   = "https://api.postmon.com.br/v1/cep/"
+	URLViaCEP    = "https://viacep.com.br/ws/"
+	URLBrasilAPI = "https://brasilapi.com.br/api/cep/v1/"
+)
+
+// ProvedorCEP consulta o endereço de um CEP em um serviço externo. O CEP chega só com os 8 digitos.
+// Quando o serviço responde que o CEP não existe o erro é ErrCEPNaoEncontrado.
+type ProvedorCEP interface {
+	Nome() string
+	Buscar(c context.Context, cep string) (*Endereco, error)
+}
+
+type retPostmon struct {
+	Complemento string `json:"complemento"`
+	Bairro      string `json:"bairro"`
+	Cidade      string `json:"cidade"`
+	Logradouro  string `json:"logradouro"`
+	Cep         string `json:"cep"`
+	Estado      string `json:"estado"`
+}
+
+type retViaCEP struct {
+	Cep         string `json:"cep"`
+	Logradouro  string `json:"logradouro"`
+	Complemento string `json:"complemento"`
+	Bairro      string `json:"bairro"`
+	Localidade  string `json:"localidade"`
+	UF          string `json:"uf"`
+	Erro        bool   `json:"erro"`
+}
+
+type retBrasilAPI struct {
+	Cep          string `json:"cep"`
+	State        string `json:"state"`
+	City         string `json:"city"`
+	Neighborhood string `json:"neighborhood"`
+	Street       string `json:"street"`
+}
+
+// Postmon consulta api.postmon.com.br
+type Postmon struct {
+	URLBase string
+	Cliente *http.Client
+}
+
+func (p Postmon) Nome() string { return "postmon" }
+
+func (p Postmon) Buscar(c context.Context, cep string) (*Endereco, error) {
+	var retorno retPostmon
+	if err := consultarCEP(c, p.Cliente, p.URLBase+cep, &retorno); err != nil {
+		return nil, err
+	}
+	return &Endereco{
+		CEP:        retorno.Cep,
+		Logradouro: retorno.Logradouro,
+		Bairro:     retorno.Bairro,
+		Municipio:  retorno.Cidade,
+		UF:         retorno.Estado,
+	}, nil
+}
+
+// ViaCEP consulta viacep.com.br, que responde 200 com "erro": true para CEP inexistente
+type ViaCEP struct {
+	URLBase string
+	Cliente *http.Client
+}
+
+func (v ViaCEP) Nome() string { return "viacep" }
+
+func (v ViaCEP) Buscar(c context.Context, cep string) (*Endereco, error) {
+	var retorno retViaCEP
+	if err := consultarCEP(c, v.Cliente, v.URLBase+cep+"/json/", &retorno); err != nil {
+		return nil, err
+	}
+	if retorno.Erro {
+		return nil, ErrCEPNaoEncontrado
+	}
+	return &Endereco{
+		CEP:         retorno.Cep,
+		Logradouro:  retorno.Logradouro,
+		Bairro:      retorno.Bairro,
+		Municipio:   retorno.Localidade,
+		UF:          retorno.UF,
+		Complemento: retorno.Complemento,
+	}, nil
+}
+
+// BrasilAPI consulta brasilapi.com.br
+type BrasilAPI struct {
+	URLBase string
+	Cliente *http.Client
+}
+
+func (b BrasilAPI) Nome() string { return "brasilapi" }
+
+func (b BrasilAPI) Buscar(c context.Context, cep string) (*Endereco, error) {
+	var retorno retBrasilAPI
+	if err := consultarCEP(c, b.Cliente, b.URLBase+cep, &retorno); err != nil {
+		return nil, err
+	}
+	return &Endereco{
+		CEP:        retorno.Cep,
+		Logradouro: retorno.Street,
+		Bairro:     retorno.Neighborhood,
+		Municipio:  retorno.City,
+		UF:         retorno.State,
+	}, nil
+}
+
+// consultarCEP faz o GET respeitando o prazo do contexto e decodifica o JSON da resposta. 404 vira
+// ErrCEPNaoEncontrado; qualquer outro status fora de 2xx é falha do provedor.
+func consultarCEP(c context.Context, cliente *http.Client, url string, retorno interface{}) error {
+	if cliente == nil {
+		cliente = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(c, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := cliente.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrCEPNaoEncontrado
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Status %d ao consultar %s", resp.StatusCode, url)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return ErrCEPNaoEncontrado
+	}
+	return json.Unmarshal(body, retorno)
+}
@@ -0,0 +1,210 @@
+package estabelecimento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"site/usuario"
+	"site/utils/consts"
+	"site/utils/log"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindAvaliacoes = "AvaliacoesEstabelecimento"
+
+	NotaMinima = 1
+	NotaMaxima = 5
+
+	TamanhoMaximoAvaliacao = 2000
+
+	LimitePadraoAvaliacoes = 20
+	LimiteMaximoAvaliacoes = 100
+)
+
+var ErrAvaliacaoNaoEncontrada = errors.New("Avaliação não encontrada")
+
+// Avaliacao é a nota de 1 a 5 que um usuario dá ao estabelecimento, com um texto opcional. Cada
+// usuario tem uma avaliação por estabelecimento e a chave é "estabelecimento:usuario".
+type Avaliacao struct {
+	EstabelecimentoID int64
+	UsuarioID         int64
+	UsuarioNick       string
+	Nota              int64
+	Texto             string `datastore:",noindex"`
+	DataCriacao       time.Time
+	DataAtualizacao   time.Time
+}
+
+func chaveAvaliacao(estabelecimentoID, usuarioID int64) *datastore.Key {
+	return datastore.NameKey(KindAvaliacoes, fmt.Sprintf("%d:%d", estabelecimentoID, usuarioID), nil)
+}
+
+func (avaliacao *Avaliacao) validar() error {
+	avaliacao.Texto = strings.TrimSpace(avaliacao.Texto)
+
+	if avaliacao.Nota < NotaMinima || avaliacao.Nota > NotaMaxima {
+		return fmt.Errorf("A nota deve ser de %d a %d", NotaMinima, NotaMaxima)
+	}
+	if len([]rune(avaliacao.Texto)) > TamanhoMaximoAvaliacao {
+		return fmt.Errorf("A avaliação pode ter até %d caracteres", TamanhoMaximoAvaliacao)
+	}
+	return nil
+}
+
+// registrarNota troca a nota anterior do usuario pela nova na média. Nota zero quer dizer que o
+// usuario ainda não tinha avaliado, ou que a avaliação foi removida.
+func (estabelecimento *Estabelecimento) registrarNota(anterior, nova int64) {
+	if anterior != 0 {
+		estabelecimento.SomaNotas -= anterior
+		estabelecimento.TotalAvaliacoes--
+	}
+	if nova != 0 {
+		estabelecimento.SomaNotas += nova
+		estabelecimento.TotalAvaliacoes++
+	}
+
+	if estabelecimento.TotalAvaliacoes <= 0 {
+		estabelecimento.SomaNotas, estabelecimento.TotalAvaliacoes, estabelecimento.NotaMedia = 0, 0, 0
+		return
+	}
+	//Uma casa decimal, como é exibida
+	media := float64(estabelecimento.SomaNotas) / float64(estabelecimento.TotalAvaliacoes)
+	estabelecimento.NotaMedia = math.Round(media*10) / 10
+}
+
+// Avaliar cria ou substitui a avaliação do usuario e atualiza a média do estabelecimento na mesma
+// transação. Proprietario e gerentes não avaliam o proprio estabelecimento.
+func Avaliar(c context.Context, usuarioID, estabelecimentoID int64, avaliacao Avaliacao) (*Avaliacao, error) {
+	if err := avaliacao.validar(); err != nil {
+		return nil, err
+	}
+
+	usuarioBanco := usuario.GetUsuario(c, usuarioID)
+	if usuarioBanco == nil {
+		return nil, fmt.Errorf("Usuario não encontrado")
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	keyEstabelecimento := datastore.IDKey(KindEstabelecimento, estabelecimentoID, nil)
+	keyAvaliacao := chaveAvaliacao(estabelecimentoID, usuarioID)
+
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		var estabelecimento Estabelecimento
+		if err := tx.Get(keyEstabelecimento, &estabelecimento); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return ErrNaoEncontrado
+			}
+			return err
+		}
+		if estabelecimento.Papel(usuarioID) != "" {
+			return fmt.Errorf("Não é possivel avaliar o proprio estabelecimento")
+		}
+
+		var anterior Avaliacao
+		if err := tx.Get(keyAvaliacao, &anterior); err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+
+		agora := time.Now()
+		avaliacao.EstabelecimentoID = estabelecimentoID
+		avaliacao.UsuarioID = usuarioID
+		avaliacao.UsuarioNick = usuarioBanco.Nick
+		avaliacao.DataCriacao = anterior.DataCriacao
+		if avaliacao.DataCriacao.IsZero() {
+			avaliacao.DataCriacao = agora
+		}
+		avaliacao.DataAtualizacao = agora
+
+		estabelecimento.registrarNota(anterior.Nota, avaliacao.Nota)
+
+		if _, err := tx.Put(keyAvaliacao, &avaliacao); err != nil {
+			return err
+		}
+		_, err := tx.Put(keyEstabelecimento, &estabelecimento)
+		return err
+	})
+	if err != nil {
+		log.Warningf(c, "Erro ao avaliar estabelecimento %d: %v", estabelecimentoID, err)
+		return nil, err
+	}
+	return &avaliacao, nil
+}
+
+// RemoverAvaliacao exclui a avaliação do usuario e tira a nota dela da média
+func RemoverAvaliacao(c context.Context, usuarioID, estabelecimentoID int64) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	keyEstabelecimento := datastore.IDKey(KindEstabelecimento, estabelecimentoID, nil)
+	keyAvaliacao := chaveAvaliacao(estabelecimentoID, usuarioID)
+
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		var avaliacao Avaliacao
+		if err := tx.Get(keyAvaliacao, &avaliacao); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return ErrAvaliacaoNaoEncontrada
+			}
+			return err
+		}
+
+		var estabelecimento Estabelecimento
+		if err := tx.Get(keyEstabelecimento, &estabelecimento); err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		} else if err == nil {
+			estabelecimento.registrarNota(avaliacao.Nota, 0)
+			if _, err := tx.Put(keyEstabelecimento, &estabelecimento); err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(keyAvaliacao)
+	})
+	if err != nil && err != ErrAvaliacaoNaoEncontrada {
+		log.Warningf(c, "Erro ao remover avaliação do estabelecimento %d: %v", estabelecimentoID, err)
+	}
+	return err
+}
+
+// BuscarAvaliacoes traz as avaliações do estabelecimento, das mais recentes para as mais antigas, até
+// LimiteMaximoAvaliacoes
+func BuscarAvaliacoes(c context.Context, estabelecimentoID int64, limite int) ([]Avaliacao, error) {
+	if limite <= 0 {
+		limite = LimitePadraoAvaliacoes
+	}
+	if limite > LimiteMaximoAvaliacoes {
+		limite = LimiteMaximoAvaliacoes
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	avaliacoes := make([]Avaliacao, 0)
+	q := datastore.NewQuery(KindAvaliacoes).
+		Filter("EstabelecimentoID =", estabelecimentoID).
+		Order("-DataAtualizacao").
+		Limit(limite)
+	if _, err = datastoreClient.GetAll(c, q, &avaliacoes); err != nil {
+		log.Warningf(c, "Erro ao buscar avaliações do estabelecimento %d: %v", estabelecimentoID, err)
+		return nil, err
+	}
+	return avaliacoes, nil
+}
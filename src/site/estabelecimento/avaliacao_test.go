@@ -0,0 +1,55 @@
+package estabelecimento
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistrarNota(t *testing.T) {
+	var estab Estabelecimento
+
+	estab.registrarNota(0, 5)
+	estab.registrarNota(0, 4)
+	estab.registrarNota(0, 4)
+	if estab.TotalAvaliacoes != 3 || estab.NotaMedia != 4.3 {
+		t.Fatalf("três avaliações: total %d, media %v", estab.TotalAvaliacoes, estab.NotaMedia)
+	}
+
+	//Usuario que muda a nota não conta duas vezes
+	estab.registrarNota(4, 1)
+	if estab.TotalAvaliacoes != 3 || estab.NotaMedia != 3.3 {
+		t.Errorf("nota alterada: total %d, media %v", estab.TotalAvaliacoes, estab.NotaMedia)
+	}
+
+	estab.registrarNota(5, 0)
+	estab.registrarNota(4, 0)
+	estab.registrarNota(1, 0)
+	if estab.TotalAvaliacoes != 0 || estab.SomaNotas != 0 || estab.NotaMedia != 0 {
+		t.Errorf("sem avaliações a media deveria zerar: %#v", estab)
+	}
+
+	//Contadores inconsistentes não deixam a media negativa
+	estab.registrarNota(3, 0)
+	if estab.TotalAvaliacoes != 0 || estab.NotaMedia != 0 {
+		t.Errorf("remoção a mais deveria manter zerado: %#v", estab)
+	}
+}
+
+func TestValidarAvaliacao(t *testing.T) {
+	casos := []struct {
+		avaliacao Avaliacao
+		valida    bool
+	}{
+		{Avaliacao{Nota: 1}, true},
+		{Avaliacao{Nota: 5, Texto: "Otimo chopp"}, true},
+		{Avaliacao{Nota: 0}, false},
+		{Avaliacao{Nota: 6}, false},
+		{Avaliacao{Nota: 3, Texto: strings.Repeat("a", TamanhoMaximoAvaliacao+1)}, false},
+	}
+
+	for _, caso := range casos {
+		if err := caso.avaliacao.validar(); (err == nil) != caso.valida {
+			t.Errorf("validar(%d, %d caracteres) = %v", caso.avaliacao.Nota, len(caso.avaliacao.Texto), err)
+		}
+	}
+}
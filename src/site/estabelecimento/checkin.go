@@ -0,0 +1,135 @@
+package estabelecimento
+
+import (
+	"context"
+	"errors"
+	"site/usuario"
+	"site/utils/consts"
+	"site/utils/log"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindCheckins = "CheckinsEstabelecimento"
+
+	// IntervaloMinimoCheckin é o tempo até o usuario poder fazer check-in de novo no mesmo estabelecimento
+	IntervaloMinimoCheckin = time.Hour
+
+	LimitePadraoCheckins = 20
+	LimiteMaximoCheckins = 100
+)
+
+var ErrCheckinRecente = errors.New("Você já fez check-in neste estabelecimento há pouco tempo")
+
+// Checkin registra que o usuario esteve no estabelecimento
+type Checkin struct {
+	ID                int64 `datastore:"-"`
+	EstabelecimentoID int64
+	UsuarioID         int64
+	UsuarioNick       string
+	Data              time.Time
+}
+
+// FazerCheckin registra a visita do usuario e soma no contador do estabelecimento
+func FazerCheckin(c context.Context, usuarioID, estabelecimentoID int64) (*Checkin, error) {
+	usuarioBanco := usuario.GetUsuario(c, usuarioID)
+	if usuarioBanco == nil {
+		return nil, errors.New("Usuario não encontrado")
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	agora := time.Now()
+	checkin := Checkin{
+		EstabelecimentoID: estabelecimentoID,
+		UsuarioID:         usuarioID,
+		UsuarioNick:       usuarioBanco.Nick,
+		Data:              agora,
+	}
+
+	keyEstabelecimento := datastore.IDKey(KindEstabelecimento, estabelecimentoID, nil)
+	var keyCheckin *datastore.PendingKey
+	commit, err := datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		var estabelecimento Estabelecimento
+		if err := tx.Get(keyEstabelecimento, &estabelecimento); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return ErrNaoEncontrado
+			}
+			return err
+		}
+
+		//A conferencia fica na transação: duas requisições ao mesmo tempo alteram o mesmo estabelecimento,
+		//então uma delas é repetida e já encontra o check-in gravado pela outra
+		var anteriores []Checkin
+		q := datastore.NewQuery(KindCheckins).
+			Filter("EstabelecimentoID =", estabelecimentoID).
+			Filter("UsuarioID =", usuarioID).
+			Transaction(tx)
+		if _, err := datastoreClient.GetAll(c, q, &anteriores); err != nil {
+			return err
+		}
+		for _, anterior := range anteriores {
+			if agora.Sub(anterior.Data) < IntervaloMinimoCheckin {
+				return ErrCheckinRecente
+			}
+		}
+
+		estabelecimento.Checkins++
+		if _, err := tx.Put(keyEstabelecimento, &estabelecimento); err != nil {
+			return err
+		}
+
+		var err error
+		keyCheckin, err = tx.Put(datastore.IncompleteKey(KindCheckins, nil), &checkin)
+		return err
+	})
+	if err == ErrCheckinRecente {
+		return nil, err
+	}
+	if err != nil {
+		log.Warningf(c, "Erro ao fazer check-in no estabelecimento %d: %v", estabelecimentoID, err)
+		return nil, err
+	}
+
+	checkin.ID = commit.Key(keyCheckin).ID
+	return &checkin, nil
+}
+
+// BuscarCheckins traz os check-ins mais recentes no estabelecimento, até LimiteMaximoCheckins
+func BuscarCheckins(c context.Context, estabelecimentoID int64, limite int) ([]Checkin, error) {
+	if limite <= 0 {
+		limite = LimitePadraoCheckins
+	}
+	if limite > LimiteMaximoCheckins {
+		limite = LimiteMaximoCheckins
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	checkins := make([]Checkin, 0)
+	q := datastore.NewQuery(KindCheckins).
+		Filter("EstabelecimentoID =", estabelecimentoID).
+		Order("-Data").
+		Limit(limite)
+	keys, err := datastoreClient.GetAll(c, q, &checkins)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar check-ins do estabelecimento %d: %v", estabelecimentoID, err)
+		return nil, err
+	}
+	for i := range keys {
+		checkins[i].ID = keys[i].ID
+	}
+	return checkins, nil
+}
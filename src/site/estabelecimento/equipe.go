@@ -0,0 +1,509 @@
+package estabelecimento
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"site/notificacao"
+	"site/usuario"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/badoux/checkmail"
+)
+
+const (
+	KindConvite = "ConviteEquipe"
+
+	PapelProprietario = "proprietario"
+	PapelGerente      = "gerente"
+	PapelAtendente    = "atendente"
+
+	PermissaoEditarCadastro         = "editar_cadastro"
+	PermissaoEditarCardapio         = "editar_cardapio"
+	PermissaoAlterarDisponivel      = "alterar_disponibilidade"
+	PermissaoVerEquipe              = "ver_equipe"
+	PermissaoGerenciarEquipe        = "gerenciar_equipe"
+	PermissaoExcluirEstabelecimento = "excluir_estabelecimento"
+
+	ConvitePendente = "pendente"
+	ConviteAceito   = "aceito"
+	ConviteRecusado = "recusado"
+	ConviteRevogado = "revogado"
+
+	// ValidadeConvite é o prazo para o convidado responder. Depois disso o proprietario precisa convidar de novo.
+	ValidadeConvite = 7 * 24 * time.Hour
+
+	tamanhoTokenConvite = 16
+)
+
+var (
+	ErrConviteNaoEncontrado = errors.New("Convite não encontrado")
+	ErrConviteExpirado      = errors.New("Convite expirado")
+	ErrConviteRespondido    = errors.New("Convite já foi respondido ou revogado")
+	ErrConviteOutroEmail    = errors.New("Convite enviado para outro email")
+)
+
+// PermissoesPapel diz o que cada papel da equipe pode fazer. Só o proprietario gerencia a equipe e
+// exclui o estabelecimento.
+var PermissoesPapel = map[string][]string{
+	PapelProprietario: {
+		PermissaoEditarCadastro,
+		PermissaoEditarCardapio,
+		PermissaoAlterarDisponivel,
+		PermissaoVerEquipe,
+		PermissaoGerenciarEquipe,
+		PermissaoExcluirEstabelecimento,
+	},
+	PapelGerente: {
+		PermissaoEditarCadastro,
+		PermissaoEditarCardapio,
+		PermissaoAlterarDisponivel,
+		PermissaoVerEquipe,
+	},
+	PapelAtendente: {
+		PermissaoAlterarDisponivel,
+		PermissaoVerEquipe,
+	},
+}
+
+// Papel retorna o papel do usuario no estabelecimento, ou vazio se ele não faz parte da equipe
+func (estabelecimento *Estabelecimento) Papel(usuarioID int64) string {
+	switch {
+	case estabelecimento.EhProprietario(usuarioID):
+		return PapelProprietario
+	case usuarioID != 0 && contemID(estabelecimento.Gerentes, usuarioID):
+		return PapelGerente
+	case usuarioID != 0 && contemID(estabelecimento.Atendentes, usuarioID):
+		return PapelAtendente
+	}
+	return ""
+}
+
+// Pode diz se o papel do usuario no estabelecimento tem a permissão
+func (estabelecimento *Estabelecimento) Pode(usuarioID int64, permissao string) bool {
+	for _, p := range PermissoesPapel[estabelecimento.Papel(usuarioID)] {
+		if p == permissao {
+			return true
+		}
+	}
+	return false
+}
+
+// VerificarPermissao busca o estabelecimento e confere se o usuario tem a permissão nele
+func VerificarPermissao(c context.Context, usuarioID, estabelecimentoID int64, permissao string) (*Estabelecimento, error) {
+	estabelecimento := GetEstabelecimento(c, estabelecimentoID)
+	if estabelecimento == nil {
+		return nil, ErrNaoEncontrado
+	}
+	if !estabelecimento.Pode(usuarioID, permissao) {
+		return nil, ErrSemPermissao
+	}
+	return estabelecimento, nil
+}
+
+// entrarNaEquipe coloca o usuario no papel, tirando-o do papel que ele tinha antes
+func (estabelecimento *Estabelecimento) entrarNaEquipe(usuarioID int64, papel string) error {
+	if estabelecimento.EhProprietario(usuarioID) {
+		return fmt.Errorf("O proprietario já faz parte da equipe")
+	}
+
+	estabelecimento.sairDaEquipe(usuarioID)
+	switch papel {
+	case PapelGerente:
+		estabelecimento.Gerentes = append(estabelecimento.Gerentes, usuarioID)
+	case PapelAtendente:
+		estabelecimento.Atendentes = append(estabelecimento.Atendentes, usuarioID)
+	default:
+		return fmt.Errorf("Papel inválido: %v", papel)
+	}
+	return nil
+}
+
+func (estabelecimento *Estabelecimento) sairDaEquipe(usuarioID int64) {
+	estabelecimento.Gerentes = removerID(estabelecimento.Gerentes, usuarioID)
+	estabelecimento.Atendentes = removerID(estabelecimento.Atendentes, usuarioID)
+}
+
+func contemID(ids []int64, id int64) bool {
+	for _, atual := range ids {
+		if atual == id {
+			return true
+		}
+	}
+	return false
+}
+
+func removerID(ids []int64, id int64) []int64 {
+	restantes := make([]int64, 0, len(ids))
+	for _, atual := range ids {
+		if atual != id {
+			restantes = append(restantes, atual)
+		}
+	}
+	return restantes
+}
+
+// MembroEquipe é um usuario da equipe com o papel dele
+type MembroEquipe struct {
+	UsuarioID int64
+	Nick      string
+	Nome      string
+	Papel     string
+}
+
+// Equipe é o que a pagina de equipe mostra. Os convites pendentes só vão para quem gerencia a equipe.
+type Equipe struct {
+	Membros  []MembroEquipe
+	Convites []Convite
+}
+
+// BuscarEquipe lista o proprietario, os gerentes e os atendentes do estabelecimento
+func BuscarEquipe(c context.Context, usuarioID, estabelecimentoID int64) (*Equipe, error) {
+	estabelecimento, err := VerificarPermissao(c, usuarioID, estabelecimentoID, PermissaoVerEquipe)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := append([]int64{estabelecimento.ProprietarioID}, estabelecimento.Gerentes...)
+	ids = append(ids, estabelecimento.Atendentes...)
+	keys := make([]*datastore.Key, len(ids))
+	for i, id := range ids {
+		keys[i] = datastore.IDKey(usuario.KindUsuario, id, nil)
+	}
+	usuarios, err := usuario.GetMultUsuario(c, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	equipe := &Equipe{Membros: make([]MembroEquipe, 0, len(usuarios))}
+	for _, u := range usuarios {
+		equipe.Membros = append(equipe.Membros, MembroEquipe{
+			UsuarioID: u.ID,
+			Nick:      u.Nick,
+			Nome:      u.Nome,
+			Papel:     estabelecimento.Papel(u.ID),
+		})
+	}
+
+	if estabelecimento.Pode(usuarioID, PermissaoGerenciarEquipe) {
+		equipe.Convites, err = buscarConvites(c, datastore.NewQuery(KindConvite).
+			Filter("EstabelecimentoID =", estabelecimentoID).
+			Filter("Situacao =", ConvitePendente))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return equipe, nil
+}
+
+// RemoverDaEquipe tira o membro da equipe. O proprietario remove qualquer um e cada membro pode sair
+// sozinho.
+func RemoverDaEquipe(c context.Context, usuarioID, estabelecimentoID, membroID int64) error {
+	_, err := alterarEstabelecimento(c, estabelecimentoID, func(estabelecimento *Estabelecimento) error {
+		if membroID != usuarioID && !estabelecimento.Pode(usuarioID, PermissaoGerenciarEquipe) {
+			return ErrSemPermissao
+		}
+
+		switch estabelecimento.Papel(membroID) {
+		case "":
+			return fmt.Errorf("O usuario não faz parte da equipe")
+		case PapelProprietario:
+			return fmt.Errorf("O proprietario não pode ser removido da equipe")
+		}
+
+		estabelecimento.sairDaEquipe(membroID)
+		return nil
+	})
+	return err
+}
+
+// Convite chama alguém para a equipe pelo email. O token identifica o convite no link enviado ao
+// convidado, que precisa estar logado com o mesmo email para aceitar.
+type Convite struct {
+	Token               string `datastore:"-"`
+	EstabelecimentoID   int64
+	EstabelecimentoNome string
+	Email               string
+	Papel               string
+	ConvidadoPorID      int64
+	Situacao            string
+	UsuarioID           int64 // Quem respondeu o convite
+	DataCriacao         time.Time
+	DataExpiracao       time.Time
+	DataResposta        time.Time
+}
+
+// Expirado diz se o prazo do convite já passou
+func (convite *Convite) Expirado(agora time.Time) bool {
+	return agora.After(convite.DataExpiracao)
+}
+
+// podeResponder confere se o convite ainda está aberto e se foi feito para o email do usuario
+func (convite *Convite) podeResponder(email string, agora time.Time) error {
+	if convite.Situacao != ConvitePendente {
+		return ErrConviteRespondido
+	}
+	if convite.Expirado(agora) {
+		return ErrConviteExpirado
+	}
+	if !strings.EqualFold(strings.TrimSpace(email), convite.Email) {
+		return ErrConviteOutroEmail
+	}
+	return nil
+}
+
+func novoTokenConvite() (string, error) {
+	b := make([]byte, tamanhoTokenConvite)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func buscarConvites(c context.Context, q *datastore.Query) ([]Convite, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	var convites []Convite
+	keys, err := datastoreClient.GetAll(c, q, &convites)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar convites: %v", err)
+		return nil, err
+	}
+
+	agora := time.Now()
+	abertos := make([]Convite, 0, len(convites))
+	for i := range convites {
+		convites[i].Token = keys[i].Name
+		if convites[i].Situacao == ConvitePendente && convites[i].Expirado(agora) {
+			continue
+		}
+		abertos = append(abertos, convites[i])
+	}
+	sort.Slice(abertos, func(i, j int) bool {
+		return abertos[i].DataCriacao.After(abertos[j].DataCriacao)
+	})
+	return abertos, nil
+}
+
+// Convidar cria o convite e, se o email já tem cadastro, avisa o convidado por notificação. Um convite
+// pendente anterior para o mesmo email é revogado.
+func Convidar(c context.Context, usuarioID, estabelecimentoID int64, email, papel string) (*Convite, error) {
+	estabelecimento, err := VerificarPermissao(c, usuarioID, estabelecimentoID, PermissaoGerenciarEquipe)
+	if err != nil {
+		return nil, err
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if checkmail.ValidateFormat(email) != nil {
+		return nil, fmt.Errorf("Email inválido")
+	}
+	if papel != PapelGerente && papel != PapelAtendente {
+		return nil, fmt.Errorf("Papel inválido: %v", papel)
+	}
+
+	convidados, err := usuario.FiltrarUsuario(c, usuario.Usuario{Email: email})
+	if err != nil {
+		return nil, err
+	}
+	for _, convidado := range convidados {
+		if estabelecimento.Papel(convidado.ID) != "" {
+			return nil, fmt.Errorf("O usuario já faz parte da equipe")
+		}
+	}
+
+	token, err := novoTokenConvite()
+	if err != nil {
+		log.Warningf(c, "Erro ao gerar token do convite: %v", err)
+		return nil, err
+	}
+
+	agora := time.Now()
+	convite := &Convite{
+		Token:               token,
+		EstabelecimentoID:   estabelecimentoID,
+		EstabelecimentoNome: estabelecimento.Nome,
+		Email:               email,
+		Papel:               papel,
+		ConvidadoPorID:      usuarioID,
+		Situacao:            ConvitePendente,
+		DataCriacao:         agora,
+		DataExpiracao:       agora.Add(ValidadeConvite),
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	var anteriores []Convite
+	q := datastore.NewQuery(KindConvite).
+		Filter("EstabelecimentoID =", estabelecimentoID).
+		Filter("Email =", email).
+		Filter("Situacao =", ConvitePendente)
+	keysAnteriores, err := datastoreClient.GetAll(c, q, &anteriores)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar convites anteriores: %v", err)
+		return nil, err
+	}
+
+	keys := append(keysAnteriores, datastore.NameKey(KindConvite, token, nil))
+	convites := make([]Convite, 0, len(keys))
+	for _, anterior := range anteriores {
+		anterior.Situacao = ConviteRevogado
+		anterior.DataResposta = agora
+		convites = append(convites, anterior)
+	}
+	convites = append(convites, *convite)
+
+	if _, err = datastoreClient.PutMulti(c, keys, convites); err != nil {
+		log.Warningf(c, "Erro ao gravar convite: %v", err)
+		return nil, err
+	}
+
+	if len(convidados) > 0 {
+		origem := usuario.GetUsuario(c, usuarioID)
+		var origemNick string
+		if origem != nil {
+			origemNick = origem.Nick
+		}
+		for _, convidado := range convidados {
+			err = notificacao.Notificar(c, notificacao.Notificacao{
+				UsuarioID:  convidado.ID,
+				Tipo:       notificacao.TipoConvite,
+				OrigemID:   usuarioID,
+				OrigemNick: origemNick,
+			})
+			if err != nil {
+				log.Warningf(c, "Falha ao notificar convite para o usuario %d: %v", convidado.ID, err)
+			}
+		}
+	}
+
+	return convite, nil
+}
+
+// RevogarConvite cancela um convite pendente do estabelecimento
+func RevogarConvite(c context.Context, usuarioID, estabelecimentoID int64, token string) error {
+	if _, err := VerificarPermissao(c, usuarioID, estabelecimentoID, PermissaoGerenciarEquipe); err != nil {
+		return err
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.NameKey(KindConvite, token, nil)
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		var convite Convite
+		if err := tx.Get(key, &convite); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return ErrConviteNaoEncontrado
+			}
+			return err
+		}
+		if convite.EstabelecimentoID != estabelecimentoID {
+			return ErrConviteNaoEncontrado
+		}
+		if convite.Situacao != ConvitePendente {
+			return ErrConviteRespondido
+		}
+
+		convite.Situacao = ConviteRevogado
+		convite.DataResposta = time.Now()
+		_, err := tx.Put(key, &convite)
+		return err
+	})
+	return err
+}
+
+// ConvitesUsuario lista os convites pendentes e dentro do prazo feitos para o email do usuario
+func ConvitesUsuario(c context.Context, usuarioID int64) ([]Convite, error) {
+	usuarioBanco := usuario.GetUsuario(c, usuarioID)
+	if usuarioBanco == nil {
+		return nil, errors.New("Usuario não encontrado")
+	}
+
+	return buscarConvites(c, datastore.NewQuery(KindConvite).
+		Filter("Email =", strings.ToLower(strings.TrimSpace(usuarioBanco.Email))).
+		Filter("Situacao =", ConvitePendente))
+}
+
+// ResponderConvite aceita ou recusa o convite. Ao aceitar o usuario entra na equipe com o papel do
+// convite, no lugar do papel que tivesse antes.
+func ResponderConvite(c context.Context, usuarioID int64, token string, aceitar bool) (*Convite, error) {
+	usuarioBanco := usuario.GetUsuario(c, usuarioID)
+	if usuarioBanco == nil {
+		return nil, errors.New("Usuario não encontrado")
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	keyConvite := datastore.NameKey(KindConvite, token, nil)
+	var convite Convite
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		if err := tx.Get(keyConvite, &convite); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return ErrConviteNaoEncontrado
+			}
+			return err
+		}
+
+		agora := time.Now()
+		if err := convite.podeResponder(usuarioBanco.Email, agora); err != nil {
+			return err
+		}
+
+		convite.UsuarioID = usuarioID
+		convite.DataResposta = agora
+		convite.Situacao = ConviteRecusado
+		if aceitar {
+			keyEstabelecimento := datastore.IDKey(KindEstabelecimento, convite.EstabelecimentoID, nil)
+			var estabelecimento Estabelecimento
+			if err := tx.Get(keyEstabelecimento, &estabelecimento); err != nil {
+				if err == datastore.ErrNoSuchEntity {
+					return ErrNaoEncontrado
+				}
+				return err
+			}
+			if err := estabelecimento.entrarNaEquipe(usuarioID, convite.Papel); err != nil {
+				return err
+			}
+			estabelecimento.DataAtualizacao = agora
+			if _, err := tx.Put(keyEstabelecimento, &estabelecimento); err != nil {
+				return err
+			}
+			convite.Situacao = ConviteAceito
+		}
+
+		_, err := tx.Put(keyConvite, &convite)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	convite.Token = token
+	return &convite, nil
+}
@@ -0,0 +1,98 @@
+package estabelecimento
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPapelPermissoes(t *testing.T) {
+	estab := Estabelecimento{ProprietarioID: 1, Gerentes: []int64{2}, Atendentes: []int64{3}}
+
+	casos := []struct {
+		usuarioID int64
+		papel     string
+		permissao string
+		pode      bool
+	}{
+		{1, PapelProprietario, PermissaoGerenciarEquipe, true},
+		{1, PapelProprietario, PermissaoExcluirEstabelecimento, true},
+		{2, PapelGerente, PermissaoEditarCardapio, true},
+		{2, PapelGerente, PermissaoEditarCadastro, true},
+		{2, PapelGerente, PermissaoGerenciarEquipe, false},
+		{3, PapelAtendente, PermissaoAlterarDisponivel, true},
+		{3, PapelAtendente, PermissaoEditarCardapio, false},
+		{3, PapelAtendente, PermissaoEditarCadastro, false},
+		{4, "", PermissaoVerEquipe, false},
+		{0, "", PermissaoVerEquipe, false},
+	}
+
+	for _, caso := range casos {
+		if papel := estab.Papel(caso.usuarioID); papel != caso.papel {
+			t.Errorf("Papel(%d) = %q, esperado %q", caso.usuarioID, papel, caso.papel)
+		}
+		if pode := estab.Pode(caso.usuarioID, caso.permissao); pode != caso.pode {
+			t.Errorf("Pode(%d, %s) = %v, esperado %v", caso.usuarioID, caso.permissao, pode, caso.pode)
+		}
+	}
+}
+
+func TestEntrarNaEquipe(t *testing.T) {
+	estab := Estabelecimento{ProprietarioID: 1, Gerentes: []int64{2}}
+
+	if err := estab.entrarNaEquipe(2, PapelAtendente); err != nil {
+		t.Fatalf("entrarNaEquipe() = %v", err)
+	}
+	if len(estab.Gerentes) != 0 || estab.Papel(2) != PapelAtendente {
+		t.Errorf("gerente deveria passar a atendente: %+v", estab)
+	}
+
+	if err := estab.entrarNaEquipe(1, PapelGerente); err == nil {
+		t.Errorf("proprietario não deveria entrar na equipe")
+	}
+	if err := estab.entrarNaEquipe(5, PapelProprietario); err == nil {
+		t.Errorf("papel de proprietario não deveria ser aceito")
+	}
+
+	estab.sairDaEquipe(2)
+	if estab.Papel(2) != "" {
+		t.Errorf("usuario deveria ter saido da equipe: %+v", estab)
+	}
+}
+
+func TestConvitePodeResponder(t *testing.T) {
+	agora := time.Now()
+	pendente := Convite{Email: "ana@bar.com.br", Situacao: ConvitePendente, DataExpiracao: agora.Add(time.Hour)}
+
+	casos := []struct {
+		nome    string
+		alterar func(*Convite)
+		email   string
+		erro    error
+	}{
+		{"pendente", func(cv *Convite) {}, "ana@bar.com.br", nil},
+		{"email com maiusculas", func(cv *Convite) {}, " Ana@Bar.com.br ", nil},
+		{"outro email", func(cv *Convite) {}, "bia@bar.com.br", ErrConviteOutroEmail},
+		{"expirado", func(cv *Convite) { cv.DataExpiracao = agora.Add(-time.Minute) }, "ana@bar.com.br", ErrConviteExpirado},
+		{"aceito", func(cv *Convite) { cv.Situacao = ConviteAceito }, "ana@bar.com.br", ErrConviteRespondido},
+		{"revogado", func(cv *Convite) { cv.Situacao = ConviteRevogado }, "ana@bar.com.br", ErrConviteRespondido},
+	}
+
+	for _, caso := range casos {
+		convite := pendente
+		caso.alterar(&convite)
+		if err := convite.podeResponder(caso.email, agora); err != caso.erro {
+			t.Errorf("%s: podeResponder() = %v, esperado %v", caso.nome, err, caso.erro)
+		}
+	}
+}
+
+func TestNovoTokenConvite(t *testing.T) {
+	a, err := novoTokenConvite()
+	if err != nil {
+		t.Fatalf("novoTokenConvite() = %v", err)
+	}
+	b, _ := novoTokenConvite()
+	if len(a) != 2*tamanhoTokenConvite || a == b {
+		t.Errorf("tokens deveriam ter %d caracteres e ser diferentes: %q %q", 2*tamanhoTokenConvite, a, b)
+	}
+}
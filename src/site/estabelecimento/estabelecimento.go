@@ -2,7 +2,10 @@ package estabelecimento
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"site/busca"
+	"site/empresa"
 	"site/endereco"
 	"site/utils"
 	"site/utils/consts"
@@ -11,10 +14,31 @@ import (
 
 	"cloud.google.com/go/datastore"
 	"github.com/badoux/checkmail"
+	"github.com/olivere/elastic/v7"
 )
 
 const (
 	KindEstabelecimento = "Estabelecimento"
+
+	SetorBar         = 1
+	SetorRestaurante = 2
+	SetorLanchonete  = 3
+	SetorOutros      = 4
+
+	EtapaCriacao = "criacao"
+	EtapaEdicao  = "edicao"
+
+	//Limite de entidades por chamada de PutMulti e DeleteMulti do Datastore
+	tamanhoLoteDatastore = 500
+
+	//Estabelecimentos por chamada de UpsertEstabelecimentos. Cada um pode precisar de uma consulta ao
+	//geocodificador, que no Nominatim publico é limitado a uma por segundo.
+	TamanhoMaximoLote = 50
+)
+
+var (
+	ErrNaoEncontrado = errors.New("Estabelecimento não encontrado")
+	ErrSemPermissao  = errors.New("Usuario sem permissão para alterar o estabelecimento")
 )
 
 type Estabelecimento struct {
@@ -25,10 +49,72 @@ type Estabelecimento struct {
 	Nome         string
 	Email        string
 	Telefone     string
-	Setor        int64 // SELECT 1 - BAR 2 - RESTAURANTE 3 - LANCHONETE 4 - OUTROS
-	HorarioFunc  string
-	DiasFunc     int64 // 1 = Domingo 2 = Segunda 3 = Terça 4 = Quarta 5 = Quinta 6 = Sexta 7 = Sábado
+	Setor        int64  // SELECT 1 - BAR 2 - RESTAURANTE 3 - LANCHONETE 4 - OUTROS
+	HorarioFunc  string // Legado, substituido por Horario
+	DiasFunc     int64  // Legado, substituido por Horario. 1 = Domingo 2 = Segunda 3 = Terça 4 = Quarta 5 = Quinta 6 = Sexta 7 = Sábado
 	DataCadastro time.Time
+
+	ProprietarioID  int64   // Usuario que cadastrou o estabelecimento
+	Gerentes        []int64 // Usuarios que podem editar o estabelecimento junto com o proprietario
+	Atendentes      []int64 // Usuarios que só mudam a disponibilidade do cardapio, ver PermissoesPapel
+	DataAtualizacao time.Time
+
+	Geohash string // Geohash das coordenadas do endereço, usado na busca por proximidade
+
+	// Preenchidos pelo registro de empresas a partir do CNPJ; ficam vazios se a consulta falhar
+	RazaoSocial          string
+	SituacaoCadastral    string
+	DataConsultaCadastro time.Time
+
+	Horario     HorarioFuncionamento
+	AbertoAgora bool `datastore:"-"` // Calculado pelo Horario sempre que o estabelecimento é carregado
+
+	// Mantidos pelas avaliações e check-ins, a edição do cadastro não altera
+	SomaNotas       int64 `datastore:",noindex"`
+	TotalAvaliacoes int64
+	NotaMedia       float64
+	Checkins        int64
+}
+
+// PerfilEstabelecimento é o que qualquer usuario vê na pagina do estabelecimento, sem os dados
+// cadastrais e a equipe
+type PerfilEstabelecimento struct {
+	ID              int64
+	Nome            string
+	Setor           int64
+	Telefone        string
+	Endereco        EnderecoEstabelecimento
+	Horario         HorarioFuncionamento
+	AbertoAgora     bool
+	NotaMedia       float64
+	TotalAvaliacoes int64
+	Checkins        int64
+}
+
+// Perfil retorna os dados publicos do estabelecimento
+func (estabelecimento *Estabelecimento) Perfil() PerfilEstabelecimento {
+	return PerfilEstabelecimento{
+		ID:              estabelecimento.ID,
+		Nome:            estabelecimento.Nome,
+		Setor:           estabelecimento.Setor,
+		Telefone:        estabelecimento.Telefone,
+		Endereco:        estabelecimento.Endereco,
+		Horario:         estabelecimento.Horario,
+		AbertoAgora:     estabelecimento.AbertoAgora,
+		NotaMedia:       estabelecimento.NotaMedia,
+		TotalAvaliacoes: estabelecimento.TotalAvaliacoes,
+		Checkins:        estabelecimento.Checkins,
+	}
+}
+
+// EhProprietario diz se o usuario cadastrou o estabelecimento
+func (estabelecimento *Estabelecimento) EhProprietario(usuarioID int64) bool {
+	return usuarioID != 0 && estabelecimento.ProprietarioID == usuarioID
+}
+
+// PodeEditar diz se o usuario é o proprietario ou um dos gerentes do estabelecimento
+func (estabelecimento *Estabelecimento) PodeEditar(usuarioID int64) bool {
+	return estabelecimento.Pode(usuarioID, PermissaoEditarCadastro)
 }
 
 type EnderecoEstabelecimento struct {
@@ -40,6 +126,22 @@ type EnderecoEstabelecimento struct {
 	UF          string
 	Pais        string
 	Complemento string
+	Latitude    float64 // Preenchidas pelo geocodificador; zeradas quando o endereço não foi localizado
+	Longitude   float64
+}
+
+// Geocodificado diz se o endereço já tem coordenadas
+func (endereco *EnderecoEstabelecimento) Geocodificado() bool {
+	return endereco.Latitude != 0 || endereco.Longitude != 0
+}
+
+// mesmoLocal diz se os dois endereços apontam para o mesmo lugar, sem olhar o complemento
+func (endereco *EnderecoEstabelecimento) mesmoLocal(outro EnderecoEstabelecimento) bool {
+	return utils.OnlyNumbers(endereco.CEP) == utils.OnlyNumbers(outro.CEP) &&
+		endereco.Numero == outro.Numero &&
+		endereco.Logradouro == outro.Logradouro &&
+		endereco.Municipio == outro.Municipio &&
+		endereco.UF == outro.UF
 }
 
 func GetEstabelecimento(c context.Context, id int64) *Estabelecimento {
@@ -58,6 +160,7 @@ func GetEstabelecimento(c context.Context, id int64) *Estabelecimento {
 		return nil
 	}
 	estabelecimento.ID = id
+	estabelecimento.AbertoAgora = estabelecimento.AbertoEm(time.Now())
 	return &estabelecimento
 }
 func GetMultiEstabelecimento(c context.Context, keys []*datastore.Key) ([]Estabelecimento, error) {
@@ -80,8 +183,10 @@ func GetMultiEstabelecimento(c context.Context, keys []*datastore.Key) ([]Estabe
 		log.Warningf(c, "Erro ao buscar Multi Estabelecimentos: %v", err)
 		return []Estabelecimento{}, err
 	}
+	agora := time.Now()
 	for i := range keys {
 		estabelecimentos[i].ID = keys[i].ID
+		estabelecimentos[i].AbertoAgora = estabelecimentos[i].AbertoEm(agora)
 	}
 	return estabelecimentos, nil
 }
@@ -103,10 +208,114 @@ func PutEstabelecimento(c context.Context, estabelecimento *Estabelecimento) err
 	}
 
 	estabelecimento.ID = key.ID
+
+	sincronizarBusca(c, estabelecimento)
 	return nil
 
 }
 
+// alterarEstabelecimento lê o estabelecimento, aplica a alteração e grava na mesma transação, para
+// não sobrescrever as avaliações, os check-ins e as mudanças na equipe gravados nesse meio tempo
+func alterarEstabelecimento(c context.Context, estabelecimentoID int64, alterar func(estabelecimento *Estabelecimento) error) (*Estabelecimento, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindEstabelecimento, estabelecimentoID, nil)
+	var estabelecimento Estabelecimento
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		estabelecimento = Estabelecimento{}
+		if err := tx.Get(key, &estabelecimento); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return ErrNaoEncontrado
+			}
+			return err
+		}
+		estabelecimento.ID = estabelecimentoID
+
+		if err := alterar(&estabelecimento); err != nil {
+			return err
+		}
+		estabelecimento.DataAtualizacao = time.Now()
+
+		_, err := tx.Put(key, &estabelecimento)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	estabelecimento.AbertoAgora = estabelecimento.AbertoEm(time.Now())
+	sincronizarBusca(c, &estabelecimento)
+	return &estabelecimento, nil
+}
+
+// gravarAlteracoes grava estabelecimentos já existentes em transações por lote. Cada um é lido de novo
+// dentro da transação e mesclar decide o que prevalece sobre o que está no banco.
+func gravarAlteracoes(c context.Context, estabelecimentos []Estabelecimento, mesclar func(alterado, atual *Estabelecimento) error) error {
+	if len(estabelecimentos) == 0 {
+		return nil
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	for inicio := 0; inicio < len(estabelecimentos); inicio += tamanhoLoteDatastore {
+		fim := inicio + tamanhoLoteDatastore
+		if fim > len(estabelecimentos) {
+			fim = len(estabelecimentos)
+		}
+		lote := estabelecimentos[inicio:fim]
+
+		keys := make([]*datastore.Key, 0, len(lote))
+		for i := range lote {
+			keys = append(keys, datastore.IDKey(KindEstabelecimento, lote[i].ID, nil))
+		}
+
+		_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+			atuais := make([]Estabelecimento, len(lote))
+			if err := tx.GetMulti(keys, atuais); err != nil {
+				if multi, ok := err.(datastore.MultiError); ok {
+					for i := range multi {
+						if multi[i] == datastore.ErrNoSuchEntity {
+							return fmt.Errorf("Estabelecimento %d: %w", lote[i].ID, ErrNaoEncontrado)
+						}
+					}
+				}
+				return err
+			}
+
+			agora := time.Now()
+			for i := range lote {
+				atuais[i].ID = lote[i].ID
+				if err := mesclar(&lote[i], &atuais[i]); err != nil {
+					return err
+				}
+				lote[i].DataAtualizacao = agora
+			}
+
+			_, err := tx.PutMulti(keys, lote)
+			return err
+		})
+		if err != nil {
+			log.Warningf(c, "Erro ao gravar alterações de Estabelecimentos: %v", err)
+			return err
+		}
+	}
+
+	reindexarLote(c, estabelecimentos)
+	return nil
+}
+
+// PutMultiEstabelecimentos grava os estabelecimentos em lotes. Os novos, com ID zero, recebem o ID
+// gerado pelo Datastore; os demais são sobrescritos.
 func PutMultiEstabelecimentos(c context.Context, estabelecimentos []Estabelecimento) error {
 	if len(estabelecimentos) == 0 {
 		return nil
@@ -120,125 +329,578 @@ func PutMultiEstabelecimentos(c context.Context, estabelecimentos []Estabelecime
 
 	defer datastoreClient.Close()
 
-	keys := make([]*datastore.Key, 0, len(estabelecimentos))
+	for inicio := 0; inicio < len(estabelecimentos); inicio += tamanhoLoteDatastore {
+		fim := inicio + tamanhoLoteDatastore
+		if fim > len(estabelecimentos) {
+			fim = len(estabelecimentos)
+		}
+		lote := estabelecimentos[inicio:fim]
 
-	for i := range estabelecimentos {
-		keys = append(keys, datastore.IDKey(KindEstabelecimento, estabelecimentos[i].ID, nil))
-		if err != nil {
+		keys := make([]*datastore.Key, 0, len(lote))
+		for i := range lote {
+			keys = append(keys, datastore.IDKey(KindEstabelecimento, lote[i].ID, nil))
+		}
 
+		keys, err = datastoreClient.PutMulti(c, keys, lote)
+		if err != nil {
 			log.Warningf(c, "Erro ao inserir Multi Estabelecimentos: %v", err)
+			return err
+		}
+
+		for i := range keys {
+			lote[i].ID = keys[i].ID
 		}
 	}
+
+	reindexarLote(c, estabelecimentos)
 	return nil
 }
 
-func InserirEstabelecimento(c context.Context, estabelecimento *Estabelecimento) error {
+// sincronizarBusca indexa o estabelecimento para a busca por proximidade, ou o tira do indice quando
+// ele ficou sem coordenadas
+func sincronizarBusca(c context.Context, estabelecimento *Estabelecimento) {
+	if estabelecimento.Endereco.Geocodificado() {
+		busca.SincronizarEstabelecimento(c, estabelecimento.DocumentoBusca())
+	} else {
+		busca.DesindexarEstabelecimento(c, estabelecimento.ID)
+	}
+}
+
+// reindexarLote envia os estabelecimentos geocodificados do lote para o indice numa chamada bulk
+func reindexarLote(c context.Context, estabelecimentos []Estabelecimento) {
+	docs := make([]busca.DocumentoEstabelecimento, 0, len(estabelecimentos))
+	for i := range estabelecimentos {
+		if estabelecimentos[i].Endereco.Geocodificado() {
+			docs = append(docs, estabelecimentos[i].DocumentoBusca())
+		}
+	}
+	busca.SincronizarEstabelecimentos(c, docs)
+}
+
+// DocumentoBusca retorna os dados do estabelecimento que são indexados no Elasticsearch
+func (estabelecimento *Estabelecimento) DocumentoBusca() busca.DocumentoEstabelecimento {
+	return busca.DocumentoEstabelecimento{
+		ID:          estabelecimento.ID,
+		Nome:        estabelecimento.Nome,
+		Setor:       estabelecimento.Setor,
+		Localizacao: elastic.GeoPointFromLatLon(estabelecimento.Endereco.Latitude, estabelecimento.Endereco.Longitude),
+	}
+}
+
+// InserirEstabelecimento cadastra um novo estabelecimento tendo o usuario como proprietario
+func InserirEstabelecimento(c context.Context, usuarioID int64, estabelecimento *Estabelecimento) error {
 	log.Debugf(c, "Inserindo Estabelecimento: %#v", estabelecimento)
 
-	validEmail := checkmail.ValidateFormat(estabelecimento.Email)
+	if err := prepararCriacao(c, usuarioID, estabelecimento); err != nil {
+		return err
+	}
 
-	if validEmail != nil {
+	if err := verificarCNPJ(c, estabelecimento); err != nil {
+		return err
+	}
 
-		return fmt.Errorf("Email inválido")
+	return PutEstabelecimento(c, estabelecimento)
+}
+
+// prepararCriacao define o proprietario, completa o endereço pelo CEP e valida o novo estabelecimento
+func prepararCriacao(c context.Context, usuarioID int64, estabelecimento *Estabelecimento) error {
+	if estabelecimento.ID != 0 {
+		return fmt.Errorf("Estabelecimento novo não deve ter ID")
 	}
 
-	var cnpjOk bool
+	estabelecimento.ProprietarioID = usuarioID
+	estabelecimento.Gerentes, estabelecimento.Atendentes = nil, nil
+	estabelecimento.SomaNotas, estabelecimento.TotalAvaliacoes, estabelecimento.NotaMedia = 0, 0, 0
+	estabelecimento.Checkins = 0
+	estabelecimento.DataCadastro = time.Now()
+	estabelecimento.DataAtualizacao = estabelecimento.DataCadastro
+	estabelecimento.RazaoSocial, estabelecimento.SituacaoCadastral = "", ""
+	estabelecimento.DataConsultaCadastro = time.Time{}
 
-	estabelecimento.CNPJ, cnpjOk = utils.NormalizeCPFCNPJ(estabelecimento.CNPJ)
+	if err := consultarCadastro(c, estabelecimento); err != nil {
+		return err
+	}
 
-	if !cnpjOk {
-		return fmt.Errorf("CNPJ Inválido")
+	if err := completarEndereco(c, estabelecimento); err != nil {
+		return err
+	}
+
+	geocodificar(c, estabelecimento)
+	return estabelecimento.Validar(EtapaCriacao)
+}
 
+// AtualizarEstabelecimento altera os dados cadastrais. Proprietario, gerentes e data de cadastro não
+// mudam por aqui; o endereço só é buscado de novo quando o CEP muda.
+func AtualizarEstabelecimento(c context.Context, usuarioID int64, estabelecimento *Estabelecimento) error {
+	if err := prepararEdicao(c, usuarioID, estabelecimento); err != nil {
+		return err
 	}
 
-	if estabelecimento.IE == "" {
-		return fmt.Errorf("IE Inválido")
+	if err := verificarCNPJ(c, estabelecimento); err != nil {
+		return err
+	}
 
+	//A equipe e os contadores são lidos de novo na gravação, já que podem ter mudado durante as consultas acima
+	salvo, err := alterarEstabelecimento(c, estabelecimento.ID, func(atual *Estabelecimento) error {
+		if !atual.PodeEditar(usuarioID) {
+			return ErrSemPermissao
+		}
+		estabelecimento.copiarDadosInternos(atual)
+		*atual = *estabelecimento
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	*estabelecimento = *salvo
+	return nil
+}
 
-	if estabelecimento.Nome == "" {
-		return fmt.Errorf("Nome deve ser informado")
+// copiarDadosInternos mantem os campos que a edição do cadastro não altera: a equipe, a data de
+// cadastro e os contadores de avaliações e check-ins
+func (estabelecimento *Estabelecimento) copiarDadosInternos(atual *Estabelecimento) {
+	estabelecimento.ProprietarioID = atual.ProprietarioID
+	estabelecimento.Gerentes = atual.Gerentes
+	estabelecimento.Atendentes = atual.Atendentes
+	estabelecimento.DataCadastro = atual.DataCadastro
+	estabelecimento.SomaNotas = atual.SomaNotas
+	estabelecimento.TotalAvaliacoes = atual.TotalAvaliacoes
+	estabelecimento.NotaMedia = atual.NotaMedia
+	estabelecimento.Checkins = atual.Checkins
+}
+
+// prepararEdicao confere a permissão do usuario e mantem os campos que a edição não pode alterar
+func prepararEdicao(c context.Context, usuarioID int64, estabelecimento *Estabelecimento) error {
+	atual := GetEstabelecimento(c, estabelecimento.ID)
+	if atual == nil {
+		return ErrNaoEncontrado
 	}
 
-	estabelecimento.Telefone = utils.OnlyNumbers(estabelecimento.Telefone)
-	if len(estabelecimento.Telefone) < 10 || len(estabelecimento.Telefone) > 11 {
-		return fmt.Errorf("Telefone informado é inválido")
+	if !atual.PodeEditar(usuarioID) {
+		return ErrSemPermissao
+	}
+
+	estabelecimento.copiarDadosInternos(atual)
+	estabelecimento.DataAtualizacao = time.Now()
+
+	//O registro só é consultado de novo quando o CNPJ muda
+	if utils.OnlyNumbers(estabelecimento.CNPJ) == atual.CNPJ {
+		estabelecimento.RazaoSocial = atual.RazaoSocial
+		estabelecimento.SituacaoCadastral = atual.SituacaoCadastral
+		estabelecimento.DataConsultaCadastro = atual.DataConsultaCadastro
+	} else {
+		estabelecimento.RazaoSocial, estabelecimento.SituacaoCadastral = "", ""
+		estabelecimento.DataConsultaCadastro = time.Time{}
+		if err := consultarCadastro(c, estabelecimento); err != nil {
+			return err
+		}
+	}
+
+	if utils.OnlyNumbers(estabelecimento.Endereco.CEP) != utils.OnlyNumbers(atual.Endereco.CEP) {
+		if err := completarEndereco(c, estabelecimento); err != nil {
+			return err
+		}
+	}
+
+	//As coordenadas só são recalculadas quando o endereço muda ou ainda não foram encontradas
+	if estabelecimento.Endereco.mesmoLocal(atual.Endereco) && atual.Endereco.Geocodificado() {
+		estabelecimento.Endereco.Latitude = atual.Endereco.Latitude
+		estabelecimento.Endereco.Longitude = atual.Endereco.Longitude
+		estabelecimento.Geohash = CodificarGeohash(atual.Endereco.Latitude, atual.Endereco.Longitude, precisaoGeohash)
+	} else {
+		geocodificar(c, estabelecimento)
+	}
+
+	return estabelecimento.Validar(EtapaEdicao)
+}
 
+// UpsertEstabelecimentos grava até TamanhoMaximoLote estabelecimentos de uma vez. Os sem ID são criados
+// com o usuario como proprietario e os demais passam pelas mesmas regras da edição. Tudo é gravado numa
+// unica transação: se algum for inválido, tiver CNPJ em uso ou a gravação falhar, nada é gravado.
+func UpsertEstabelecimentos(c context.Context, usuarioID int64, estabelecimentos []Estabelecimento) error {
+	if len(estabelecimentos) > TamanhoMaximoLote {
+		return fmt.Errorf("O lote aceita no maximo %d estabelecimentos", TamanhoMaximoLote)
 	}
 
-	if estabelecimento.ID == 0 {
+	cnpjs := make(map[string]int)
+	for i := range estabelecimentos {
+		var err error
+		if estabelecimentos[i].ID == 0 {
+			err = prepararCriacao(c, usuarioID, &estabelecimentos[i])
+		} else {
+			err = prepararEdicao(c, usuarioID, &estabelecimentos[i])
+		}
+		if err != nil {
+			return fmt.Errorf("Estabelecimento %d do lote: %w", i+1, err)
+		}
 
-		enderEstab := endereco.Endereco{
-			CEP:         estabelecimento.Endereco.CEP,
-			Numero:      estabelecimento.Endereco.Numero,
-			Logradouro:  estabelecimento.Endereco.Logradouro,
-			Municipio:   estabelecimento.Endereco.Municipio,
-			Bairro:      estabelecimento.Endereco.Bairro,
-			UF:          estabelecimento.Endereco.UF,
-			Pais:        estabelecimento.Endereco.Pais,
-			Complemento: estabelecimento.Endereco.Complemento,
+		if anterior, repetido := cnpjs[estabelecimentos[i].CNPJ]; repetido {
+			return fmt.Errorf("Estabelecimentos %d e %d do lote têm o mesmo CNPJ", anterior+1, i+1)
 		}
+		cnpjs[estabelecimentos[i].CNPJ] = i
+	}
+
+	return gravarLote(c, usuarioID, estabelecimentos)
+}
+
+// gravarLote grava os novos e os editados do lote na mesma transação. Os IDs dos novos são reservados
+// antes, para que todas as chaves estejam completas dentro dela. A equipe e os contadores dos editados
+// e o CNPJ de todos são conferidos de novo na transação.
+func gravarLote(c context.Context, usuarioID int64, estabelecimentos []Estabelecimento) error {
+	if len(estabelecimentos) == 0 {
+		return nil
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
 
-		err := endereco.BuscaEnderecoPorCEP(c, &enderEstab)
+	keys := make([]*datastore.Key, len(estabelecimentos))
+	var novos []int
+	for i := range estabelecimentos {
+		if estabelecimentos[i].ID == 0 {
+			keys[i] = datastore.IncompleteKey(KindEstabelecimento, nil)
+			novos = append(novos, i)
+		} else {
+			keys[i] = datastore.IDKey(KindEstabelecimento, estabelecimentos[i].ID, nil)
+		}
+	}
 
+	if len(novos) > 0 {
+		incompletas := make([]*datastore.Key, 0, len(novos))
+		for _, i := range novos {
+			incompletas = append(incompletas, keys[i])
+		}
+		reservadas, err := datastoreClient.AllocateIDs(c, incompletas)
 		if err != nil {
+			log.Warningf(c, "Erro ao reservar IDs de Estabelecimentos: %v", err)
 			return err
 		}
+		for j, i := range novos {
+			keys[i] = reservadas[j]
+		}
+	}
+
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		for i := range estabelecimentos {
+			if estabelecimentos[i].ID == 0 {
+				continue
+			}
+			var atual Estabelecimento
+			if err := tx.Get(keys[i], &atual); err != nil {
+				if err == datastore.ErrNoSuchEntity {
+					err = ErrNaoEncontrado
+				}
+				return fmt.Errorf("Estabelecimento %d do lote: %w", i+1, err)
+			}
+			if !atual.PodeEditar(usuarioID) {
+				return fmt.Errorf("Estabelecimento %d do lote: %w", i+1, ErrSemPermissao)
+			}
+			estabelecimentos[i].copiarDadosInternos(&atual)
+		}
+
+		for i := range estabelecimentos {
+			if err := cnpjDisponivel(c, datastoreClient, tx, estabelecimentos[i].CNPJ, keys[i]); err != nil {
+				return fmt.Errorf("Estabelecimento %d do lote: %w", i+1, err)
+			}
+		}
+
+		_, err := tx.PutMulti(keys, estabelecimentos)
+		return err
+	})
+	if err != nil {
+		log.Warningf(c, "Erro ao gravar lote de Estabelecimentos: %v", err)
+		return err
+	}
+
+	for i := range estabelecimentos {
+		estabelecimentos[i].ID = keys[i].ID
+	}
+	reindexarLote(c, estabelecimentos)
+	return nil
+}
 
-		estabelecimento.Endereco.CEP = enderEstab.CEP
-		estabelecimento.Endereco.Numero = enderEstab.Numero
-		estabelecimento.Endereco.Logradouro = enderEstab.Logradouro
-		estabelecimento.Endereco.Municipio = enderEstab.Municipio
-		estabelecimento.Endereco.Bairro = enderEstab.Bairro
-		estabelecimento.Endereco.UF = enderEstab.UF
-		estabelecimento.Endereco.Pais = enderEstab.Pais
-		estabelecimento.Endereco.Complemento = enderEstab.Complemento
+// DeletarEstabelecimento exclui o estabelecimento. Só o proprietario pode excluir.
+func DeletarEstabelecimento(c context.Context, usuarioID, estabelecimentoID int64) error {
+	if _, err := VerificarPermissao(c, usuarioID, estabelecimentoID, PermissaoExcluirEstabelecimento); err != nil {
+		return err
+	}
 
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return err
 	}
+	defer datastoreClient.Close()
 
-	if estabelecimento.Endereco.CEP == "" {
+	if err = datastoreClient.Delete(c, datastore.IDKey(KindEstabelecimento, estabelecimentoID, nil)); err != nil {
+		log.Warningf(c, "Erro ao excluir Estabelecimento %d: %v", estabelecimentoID, err)
+		return err
+	}
 
-		return fmt.Errorf("CEP Inválido")
+	busca.DesindexarEstabelecimento(c, estabelecimentoID)
+	limparDados(c, estabelecimentoID)
+	return nil
+}
+
+var limpezasExclusao []func(c context.Context, estabelecimentoID int64) error
+
+// AoExcluir registra uma limpeza a ser feita depois da exclusão de um estabelecimento. Os pacotes que
+// guardam dados ligados a ele, como cardapio e publicacao, se registram no init sem que este pacote
+// precise conhecê-los.
+func AoExcluir(limpar func(c context.Context, estabelecimentoID int64) error) {
+	limpezasExclusao = append(limpezasExclusao, limpar)
+}
+
+// limparDados apaga as avaliações, os check-ins e os convites do estabelecimento excluido e chama as
+// limpezas registradas. O estabelecimento já foi excluido, então as falhas são só registradas.
+func limparDados(c context.Context, estabelecimentoID int64) {
+	for _, kind := range []string{KindAvaliacoes, KindCheckins, KindConvite} {
+		if err := ExcluirPorEstabelecimento(c, kind, estabelecimentoID); err != nil {
+			log.Warningf(c, "Falha ao excluir %s do Estabelecimento %d: %v", kind, estabelecimentoID, err)
+		}
+	}
+	for _, limpar := range limpezasExclusao {
+		if err := limpar(c, estabelecimentoID); err != nil {
+			log.Warningf(c, "Falha ao limpar dados do Estabelecimento %d: %v", estabelecimentoID, err)
+		}
+	}
+}
 
+// ExcluirPorEstabelecimento apaga todas as entidades do kind ligadas ao estabelecimento pelo campo EstabelecimentoID
+func ExcluirPorEstabelecimento(c context.Context, kind string, estabelecimentoID int64) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return err
 	}
+	defer datastoreClient.Close()
 
-	if estabelecimento.Endereco.Bairro == "" {
+	q := datastore.NewQuery(kind).Filter("EstabelecimentoID =", estabelecimentoID).KeysOnly()
+	keys, err := datastoreClient.GetAll(c, q, nil)
+	if err != nil {
+		return err
+	}
 
-		return fmt.Errorf("Bairro inválido")
+	for inicio := 0; inicio < len(keys); inicio += tamanhoLoteDatastore {
+		fim := inicio + tamanhoLoteDatastore
+		if fim > len(keys) {
+			fim = len(keys)
+		}
+		if err = datastoreClient.DeleteMulti(c, keys[inicio:fim]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// MigrarHorarios preenche o Horario estruturado dos estabelecimentos que só têm HorarioFunc e DiasFunc.
+// Retorna quantos foram migrados; os que têm horario que não dá para interpretar ficam como estão.
+func MigrarHorarios(c context.Context) (int, error) {
+	estabelecimentos, err := FiltrarEstabelecimento(c, Estabelecimento{})
+	if err != nil {
+		return 0, err
 	}
 
-	if estabelecimento.Endereco.Logradouro == "" {
+	var migrados []Estabelecimento
+	for _, estabelecimento := range estabelecimentos {
+		if !estabelecimento.Horario.Vazio() || estabelecimento.HorarioFunc == "" {
+			continue
+		}
 
-		return fmt.Errorf("Logradouro inválido")
+		horario, ok := HorarioLegado(estabelecimento.HorarioFunc, estabelecimento.DiasFunc)
+		if !ok {
+			log.Warningf(c, "Horario do estabelecimento %d não reconhecido: %q", estabelecimento.ID, estabelecimento.HorarioFunc)
+			continue
+		}
+		estabelecimento.Horario = horario
+		migrados = append(migrados, estabelecimento)
+	}
+
+	//Só o horario migrado é gravado sobre o que estiver no banco na hora da gravação
+	err = gravarAlteracoes(c, migrados, func(migrado, atual *Estabelecimento) error {
+		horario := migrado.Horario
+		*migrado = *atual
+		if migrado.Horario.Vazio() {
+			migrado.Horario = horario
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
+	return len(migrados), nil
+}
 
-	if estabelecimento.Endereco.UF == "" {
+// BuscarEstabelecimentosUsuario traz os estabelecimentos de cuja equipe o usuario faz parte
+func BuscarEstabelecimentosUsuario(c context.Context, usuarioID int64) ([]Estabelecimento, error) {
+	proprios, err := FiltrarEstabelecimento(c, Estabelecimento{ProprietarioID: usuarioID})
+	if err != nil {
+		return nil, err
+	}
 
-		return fmt.Errorf("UF inválido")
+	gerenciados, err := FiltrarEstabelecimento(c, Estabelecimento{Gerentes: []int64{usuarioID}})
+	if err != nil {
+		return nil, err
+	}
 
+	atendidos, err := FiltrarEstabelecimento(c, Estabelecimento{Atendentes: []int64{usuarioID}})
+	if err != nil {
+		return nil, err
 	}
 
-	if estabelecimento.Endereco.Pais == "" {
+	return append(append(proprios, gerenciados...), atendidos...), nil
+}
 
-		return fmt.Errorf("País inválido")
+// verificarCNPJ impede dois estabelecimentos com o mesmo CNPJ
+// cnpjDisponivel confere dentro da transação que nenhum outro estabelecimento usa o CNPJ
+func cnpjDisponivel(c context.Context, datastoreClient *datastore.Client, tx *datastore.Transaction, cnpj string, key *datastore.Key) error {
+	q := datastore.NewQuery(KindEstabelecimento).Filter("CNPJ =", cnpj).KeysOnly().Transaction(tx)
+	keys, err := datastoreClient.GetAll(c, q, nil)
+	if err != nil {
+		return err
+	}
+	for _, existente := range keys {
+		if existente.ID != key.ID {
+			return fmt.Errorf("Já existe um estabelecimento com este CNPJ")
+		}
+	}
+	return nil
+}
 
+func verificarCNPJ(c context.Context, estabelecimento *Estabelecimento) error {
+	existentes, err := FiltrarEstabelecimento(c, Estabelecimento{CNPJ: estabelecimento.CNPJ})
+	if err != nil {
+		return err
+	}
+	for _, existente := range existentes {
+		if existente.ID != estabelecimento.ID {
+			return fmt.Errorf("Já existe um estabelecimento com este CNPJ")
+		}
 	}
+	return nil
+}
 
-	if estabelecimento.Endereco.Numero == "" {
+// geocodificar preenche as coordenadas do endereço. Falhar aqui não impede o cadastro; o
+// estabelecimento só fica sem coordenadas até a proxima edição do endereço.
+func geocodificar(c context.Context, estabelecimento *Estabelecimento) {
+	estabelecimento.Endereco.Latitude = 0
+	estabelecimento.Endereco.Longitude = 0
+	estabelecimento.Geohash = ""
 
-		return fmt.Errorf("Número inválido")
+	geocodificador, err := endereco.NovoGeocodificador(c)
+	if err != nil {
+		log.Warningf(c, "Falha ao criar geocodificador: %v", err)
+		return
+	}
 
+	coordenadas, err := geocodificador.Geocodificar(c, endereco.Endereco{
+		CEP:        estabelecimento.Endereco.CEP,
+		Numero:     estabelecimento.Endereco.Numero,
+		Logradouro: estabelecimento.Endereco.Logradouro,
+		Bairro:     estabelecimento.Endereco.Bairro,
+		Municipio:  estabelecimento.Endereco.Municipio,
+		UF:         estabelecimento.Endereco.UF,
+		Pais:       estabelecimento.Endereco.Pais,
+	})
+	if err != nil {
+		log.Warningf(c, "Falha ao geocodificar endereço do estabelecimento %q: %v", estabelecimento.Nome, err)
+		return
 	}
 
-	if estabelecimento.Endereco.Municipio == "" {
+	estabelecimento.Endereco.Latitude = coordenadas.Latitude
+	estabelecimento.Endereco.Longitude = coordenadas.Longitude
+	estabelecimento.Geohash = CodificarGeohash(coordenadas.Latitude, coordenadas.Longitude, precisaoGeohash)
+}
 
-		return fmt.Errorf("Municipio inválido")
+// consultarCadastro enriquece o estabelecimento com o registro de empresas configurado
+func consultarCadastro(c context.Context, estabelecimento *Estabelecimento) error {
+	registro, err := empresa.NovoRegistro(c)
+	if err != nil {
+		log.Warningf(c, "Falha ao criar registro de empresas: %v", err)
+		return nil
+	}
+	return enriquecerCadastro(c, registro, estabelecimento)
+}
 
+// enriquecerCadastro busca o CNPJ no registro de empresas, preenche razão social e situação cadastral
+// e completa só os campos do endereço que vieram vazios. CNPJ inexistente ou empresa que não está ativa
+// impedem o cadastro; o registro fora do ar não, o estabelecimento só fica sem esses dados.
+func enriquecerCadastro(c context.Context, registro empresa.Registro, estabelecimento *Estabelecimento) error {
+	cnpj, ok := utils.NormalizeCNPJ(estabelecimento.CNPJ)
+	if !ok {
+		//Validar recusa o CNPJ em seguida
+		return nil
 	}
 
-	return PutEstabelecimento(c, estabelecimento)
+	dados, err := registro.Consultar(c, cnpj)
+	if err == empresa.ErrCNPJNaoEncontrado {
+		return err
+	}
+	if err != nil {
+		log.Warningf(c, "Falha ao consultar o CNPJ %s no registro de empresas: %v", cnpj, err)
+		return nil
+	}
+
+	if !dados.Ativa() {
+		return fmt.Errorf("CNPJ com situação cadastral %s", dados.SituacaoCadastral)
+	}
 
+	estabelecimento.RazaoSocial = dados.RazaoSocial
+	estabelecimento.SituacaoCadastral = dados.SituacaoCadastral
+	estabelecimento.DataConsultaCadastro = time.Now()
+
+	enderecoEstab := &estabelecimento.Endereco
+	for _, campo := range []struct {
+		destino *string
+		valor   string
+	}{
+		{&enderecoEstab.CEP, utils.OnlyNumbers(dados.CEP)},
+		{&enderecoEstab.Logradouro, dados.Logradouro},
+		{&enderecoEstab.Numero, dados.Numero},
+		{&enderecoEstab.Complemento, dados.Complemento},
+		{&enderecoEstab.Bairro, dados.Bairro},
+		{&enderecoEstab.Municipio, dados.Municipio},
+		{&enderecoEstab.UF, dados.UF},
+	} {
+		if *campo.destino == "" {
+			*campo.destino = campo.valor
+		}
+	}
+	return nil
+}
+
+// completarEndereco busca o endereço pelo CEP e completa os dados do estabelecimento
+func completarEndereco(c context.Context, estabelecimento *Estabelecimento) error {
+	enderEstab := endereco.Endereco{
+		CEP:         estabelecimento.Endereco.CEP,
+		Numero:      estabelecimento.Endereco.Numero,
+		Logradouro:  estabelecimento.Endereco.Logradouro,
+		Municipio:   estabelecimento.Endereco.Municipio,
+		Bairro:      estabelecimento.Endereco.Bairro,
+		UF:          estabelecimento.Endereco.UF,
+		Pais:        estabelecimento.Endereco.Pais,
+		Complemento: estabelecimento.Endereco.Complemento,
+	}
+
+	err := endereco.BuscaEnderecoPorCEP(c, &enderEstab)
+
+	if err != nil {
+		return err
+	}
+
+	estabelecimento.Endereco.CEP = enderEstab.CEP
+	estabelecimento.Endereco.Numero = enderEstab.Numero
+	estabelecimento.Endereco.Logradouro = enderEstab.Logradouro
+	estabelecimento.Endereco.Municipio = enderEstab.Municipio
+	estabelecimento.Endereco.Bairro = enderEstab.Bairro
+	estabelecimento.Endereco.UF = enderEstab.UF
+	estabelecimento.Endereco.Pais = enderEstab.Pais
+	estabelecimento.Endereco.Complemento = enderEstab.Complemento
+	return nil
 }
 
 func FiltrarEstabelecimento(c context.Context, estabelecimento Estabelecimento) ([]Estabelecimento, error) {
@@ -268,6 +930,21 @@ func FiltrarEstabelecimento(c context.Context, estabelecimento Estabelecimento)
 		j = j.Filter("IE =", estabelecimento.IE)
 	}
 
+	if estabelecimento.ProprietarioID != 0 {
+
+		j = j.Filter("ProprietarioID =", estabelecimento.ProprietarioID)
+	}
+
+	if len(estabelecimento.Gerentes) > 0 {
+
+		j = j.Filter("Gerentes =", estabelecimento.Gerentes[0])
+	}
+
+	if len(estabelecimento.Atendentes) > 0 {
+
+		j = j.Filter("Atendentes =", estabelecimento.Atendentes[0])
+	}
+
 	if estabelecimento.ID != 0 {
 
 		key := datastore.IDKey(KindEstabelecimento, estabelecimento.ID, nil)
@@ -286,8 +963,27 @@ func FiltrarEstabelecimento(c context.Context, estabelecimento Estabelecimento)
 
 }
 
+// Validar confere e normaliza os dados do estabelecimento para a etapa de criação ou de edição
 func (estabelecimento *Estabelecimento) Validar(etapa string) error {
 
+	switch etapa {
+	case EtapaCriacao:
+		if estabelecimento.ID != 0 {
+			return fmt.Errorf("Estabelecimento novo não deve ter ID")
+		}
+	case EtapaEdicao:
+		if estabelecimento.ID == 0 {
+			return fmt.Errorf("O ID do estabelecimento é obrigatório na edição")
+		}
+	default:
+		return fmt.Errorf("Etapa de validação inválida: %v", etapa)
+	}
+
+	if estabelecimento.ProprietarioID == 0 {
+
+		return fmt.Errorf("O estabelecimento precisa ter um proprietario")
+	}
+
 	if estabelecimento.Nome == "" {
 
 		return fmt.Errorf("O campo nome é obrigatório: %v", estabelecimento.Nome)
@@ -299,10 +995,88 @@ func (estabelecimento *Estabelecimento) Validar(etapa string) error {
 		return fmt.Errorf("O campo IE é obrigatório: %v", estabelecimento.IE)
 	}
 
+	var ieOk bool
+
+	estabelecimento.IE, ieOk = utils.NormalizeIE(estabelecimento.IE, estabelecimento.Endereco.UF)
+
+	if !ieOk {
+		return fmt.Errorf("Inscrição Estadual inválida para a UF %v", estabelecimento.Endereco.UF)
+	}
+
 	if estabelecimento.CNPJ == "" {
 
 		return fmt.Errorf("O campo CNPJ é obrigatório: %v", estabelecimento.CNPJ)
 	}
 
+	var cnpjOk bool
+
+	estabelecimento.CNPJ, cnpjOk = utils.NormalizeCNPJ(estabelecimento.CNPJ)
+
+	if !cnpjOk {
+		return fmt.Errorf("CNPJ Inválido")
+
+	}
+
+	if checkmail.ValidateFormat(estabelecimento.Email) != nil {
+
+		return fmt.Errorf("Email inválido")
+	}
+
+	estabelecimento.Telefone = utils.OnlyNumbers(estabelecimento.Telefone)
+	if len(estabelecimento.Telefone) < 10 || len(estabelecimento.Telefone) > 11 {
+		return fmt.Errorf("Telefone informado é inválido")
+
+	}
+
+	if err := estabelecimento.Horario.validar(); err != nil {
+		return err
+	}
+
+	return estabelecimento.Endereco.validar()
+}
+
+func (endereco *EnderecoEstabelecimento) validar() error {
+
+	if endereco.CEP == "" {
+
+		return fmt.Errorf("CEP Inválido")
+
+	}
+
+	if endereco.Bairro == "" {
+
+		return fmt.Errorf("Bairro inválido")
+
+	}
+
+	if endereco.Logradouro == "" {
+
+		return fmt.Errorf("Logradouro inválido")
+	}
+
+	if endereco.UF == "" {
+
+		return fmt.Errorf("UF inválido")
+
+	}
+
+	if endereco.Pais == "" {
+
+		return fmt.Errorf("País inválido")
+
+	}
+
+	if endereco.Numero == "" {
+
+		return fmt.Errorf("Número inválido")
+
+	}
+
+	if endereco.Municipio == "" {
+
+		return fmt.Errorf("Municipio inválido")
+
+	}
+
 	return nil
 }
@@ -0,0 +1,133 @@
+package estabelecimento
+
+import (
+	"context"
+	"errors"
+	"site/empresa"
+	"testing"
+)
+
+func estabelecimentoValido() Estabelecimento {
+	return Estabelecimento{
+		ProprietarioID: 1,
+		CNPJ:           "11.222.333/0001-81",
+		IE:             "110.042.490.114",
+		Nome:           "Bar do Zé",
+		Email:          "contato@bardoze.com.br",
+		Telefone:       "(11) 98765-4321",
+		Endereco: EnderecoEstabelecimento{
+			CEP:        "01001000",
+			Numero:     "10",
+			Logradouro: "Praça da Sé",
+			Bairro:     "Sé",
+			Municipio:  "São Paulo",
+			UF:         "SP",
+			Pais:       "Brasil",
+		},
+	}
+}
+
+func TestValidar(t *testing.T) {
+	casos := []struct {
+		nome    string
+		etapa   string
+		alterar func(*Estabelecimento)
+		valido  bool
+	}{
+		{"criacao", EtapaCriacao, func(e *Estabelecimento) {}, true},
+		{"criacao com ID", EtapaCriacao, func(e *Estabelecimento) { e.ID = 5 }, false},
+		{"edicao", EtapaEdicao, func(e *Estabelecimento) { e.ID = 5 }, true},
+		{"edicao sem ID", EtapaEdicao, func(e *Estabelecimento) {}, false},
+		{"etapa desconhecida", "importacao", func(e *Estabelecimento) {}, false},
+		{"sem proprietario", EtapaCriacao, func(e *Estabelecimento) { e.ProprietarioID = 0 }, false},
+		{"CNPJ inválido", EtapaCriacao, func(e *Estabelecimento) { e.CNPJ = "11.222.333/0001-80" }, false},
+		{"CNPJ completado com zeros a partir de CPF", EtapaCriacao, func(e *Estabelecimento) { e.CNPJ = "00052998224725" }, false},
+		{"IE com digito errado", EtapaCriacao, func(e *Estabelecimento) { e.IE = "110.042.490.115" }, false},
+		{"IE de outra UF", EtapaCriacao, func(e *Estabelecimento) { e.Endereco.UF = "RJ" }, false},
+		{"IE isento", EtapaCriacao, func(e *Estabelecimento) { e.IE = "isento" }, true},
+		{"email inválido", EtapaCriacao, func(e *Estabelecimento) { e.Email = "contato" }, false},
+		{"telefone curto", EtapaCriacao, func(e *Estabelecimento) { e.Telefone = "98765" }, false},
+		{"sem municipio", EtapaCriacao, func(e *Estabelecimento) { e.Endereco.Municipio = "" }, false},
+	}
+
+	for _, caso := range casos {
+		estab := estabelecimentoValido()
+		caso.alterar(&estab)
+		if err := estab.Validar(caso.etapa); (err == nil) != caso.valido {
+			t.Errorf("%s: Validar() = %v, esperado valido = %v", caso.nome, err, caso.valido)
+		}
+	}
+}
+
+func TestValidarNormaliza(t *testing.T) {
+	estab := estabelecimentoValido()
+	if err := estab.Validar(EtapaCriacao); err != nil {
+		t.Fatalf("Validar() = %v", err)
+	}
+	if estab.CNPJ != "11222333000181" || estab.IE != "110042490114" || estab.Telefone != "11987654321" {
+		t.Errorf("CNPJ, IE e telefone deveriam ficar só com numeros: %q %q %q", estab.CNPJ, estab.IE, estab.Telefone)
+	}
+}
+
+func TestPodeEditar(t *testing.T) {
+	estab := Estabelecimento{ProprietarioID: 1, Gerentes: []int64{2, 3}}
+
+	for usuarioID, esperado := range map[int64]bool{1: true, 2: true, 3: true, 4: false, 0: false} {
+		if obtido := estab.PodeEditar(usuarioID); obtido != esperado {
+			t.Errorf("PodeEditar(%d) = %v, esperado %v", usuarioID, obtido, esperado)
+		}
+	}
+	if estab.EhProprietario(2) {
+		t.Errorf("gerente não é proprietario")
+	}
+	if (&Estabelecimento{}).PodeEditar(0) {
+		t.Errorf("estabelecimento sem proprietario não pode ser editado")
+	}
+}
+
+func TestEnriquecerCadastro(t *testing.T) {
+	registro := &empresa.Fixo{Empresas: map[string]empresa.DadosEmpresa{
+		"11222333000181": {
+			RazaoSocial:       "BAR DO ZE LTDA",
+			SituacaoCadastral: "ATIVA",
+			Logradouro:        "PRACA DA SE",
+			Numero:            "10",
+			Bairro:            "SE",
+			Municipio:         "SAO PAULO",
+			UF:                "SP",
+			CEP:               "01001-000",
+		},
+		"11444777000161": {RazaoSocial: "BAR FECHADO LTDA", SituacaoCadastral: "BAIXADA"},
+	}}
+	c := context.Background()
+
+	estab := Estabelecimento{CNPJ: "11.222.333/0001-81", Endereco: EnderecoEstabelecimento{Numero: "12"}}
+	if err := enriquecerCadastro(c, registro, &estab); err != nil {
+		t.Fatalf("enriquecerCadastro() = %v", err)
+	}
+	if estab.RazaoSocial != "BAR DO ZE LTDA" || estab.SituacaoCadastral != "ATIVA" || estab.DataConsultaCadastro.IsZero() {
+		t.Errorf("dados cadastrais não preenchidos: %+v", estab)
+	}
+	if estab.Endereco.CEP != "01001000" || estab.Endereco.Logradouro != "PRACA DA SE" || estab.Endereco.UF != "SP" {
+		t.Errorf("endereço vazio deveria vir do registro: %+v", estab.Endereco)
+	}
+	if estab.Endereco.Numero != "12" {
+		t.Errorf("endereço informado não deveria ser sobrescrito: %q", estab.Endereco.Numero)
+	}
+
+	estab = Estabelecimento{CNPJ: "11444777000161"}
+	if err := enriquecerCadastro(c, registro, &estab); err == nil {
+		t.Errorf("CNPJ baixado deveria ser recusado")
+	}
+
+	estab = Estabelecimento{CNPJ: "99.999.999/0001-91"}
+	if err := enriquecerCadastro(c, registro, &estab); err != empresa.ErrCNPJNaoEncontrado {
+		t.Errorf("CNPJ inexistente: enriquecerCadastro() = %v", err)
+	}
+
+	registro.Erro = errors.New("fora do ar")
+	estab = Estabelecimento{CNPJ: "11222333000181"}
+	if err := enriquecerCadastro(c, registro, &estab); err != nil || estab.RazaoSocial != "" {
+		t.Errorf("registro fora do ar não deveria impedir o cadastro: %v %+v", err, estab)
+	}
+}
@@ -0,0 +1,112 @@
+package estabelecimento
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	// Precisão do geohash gravado no estabelecimento, células de uns 5 metros
+	precisaoGeohash = 9
+
+	alfabetoGeohash = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+	raioTerraKm = 6371.0
+	kmPorGrau   = 111.32
+)
+
+// CodificarGeohash gera o geohash do ponto com a quantidade de caracteres informada
+func CodificarGeohash(latitude, longitude float64, precisao int) string {
+	latMin, latMax := -90.0, 90.0
+	lngMin, lngMax := -180.0, 180.0
+
+	var hash strings.Builder
+	bit, caractere := 0, 0
+	par := true
+	for hash.Len() < precisao {
+		if par {
+			meio := (lngMin + lngMax) / 2
+			if longitude >= meio {
+				caractere = caractere<<1 | 1
+				lngMin = meio
+			} else {
+				caractere <<= 1
+				lngMax = meio
+			}
+		} else {
+			meio := (latMin + latMax) / 2
+			if latitude >= meio {
+				caractere = caractere<<1 | 1
+				latMin = meio
+			} else {
+				caractere <<= 1
+				latMax = meio
+			}
+		}
+		par = !par
+
+		if bit++; bit == 5 {
+			hash.WriteByte(alfabetoGeohash[caractere])
+			bit, caractere = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// tamanhoCelula devolve a altura e a largura, em graus, de uma célula de geohash com a precisão informada
+func tamanhoCelula(precisao int) (altura, largura float64) {
+	bits := 5 * precisao
+	return 180 / math.Pow(2, float64(bits/2)), 360 / math.Pow(2, float64((bits+1)/2))
+}
+
+// precisaoParaRaio escolhe a maior precisão cuja célula ainda é maior que o raio, para que a célula do
+// ponto e as oito vizinhas cubram o circulo inteiro
+func precisaoParaRaio(latitude, raioKm float64) int {
+	for precisao := precisaoGeohash; precisao > 1; precisao-- {
+		altura, largura := tamanhoCelula(precisao)
+		if altura*kmPorGrau >= raioKm && largura*kmPorGrau*math.Cos(latitude*math.Pi/180) >= raioKm {
+			return precisao
+		}
+	}
+	return 1
+}
+
+// celulasVizinhas devolve os prefixos de geohash da célula do ponto e das que estão ao redor dela
+func celulasVizinhas(latitude, longitude float64, precisao int) []string {
+	altura, largura := tamanhoCelula(precisao)
+
+	vistos := make(map[string]bool)
+	var celulas []string
+	for _, dLat := range []float64{0, -altura, altura} {
+		lat := latitude + dLat
+		if lat < -90 || lat > 90 {
+			continue
+		}
+		for _, dLng := range []float64{0, -largura, largura} {
+			lng := longitude + dLng
+			if lng < -180 {
+				lng += 360
+			} else if lng >= 180 {
+				lng -= 360
+			}
+
+			hash := CodificarGeohash(lat, lng, precisao)
+			if !vistos[hash] {
+				vistos[hash] = true
+				celulas = append(celulas, hash)
+			}
+		}
+	}
+	return celulas
+}
+
+// DistanciaKm calcula a distancia entre dois pontos pela formula de haversine
+func DistanciaKm(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * raioTerraKm * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,245 @@
+package estabelecimento
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// FusoPadrao é o fuso usado quando o horario não informa outro, o mesmo das datas em utils
+	FusoPadrao = "America/Sao_Paulo"
+
+	layoutHora = "15:04"
+	layoutData = "2006-01-02"
+
+	minutosDia    = 24 * 60
+	minutosSemana = 7 * minutosDia
+)
+
+// IntervaloHorario é um periodo de funcionamento num dia da semana. Fechamento menor ou igual à
+// abertura vira a noite e termina no dia seguinte; "24:00" fecha à meia noite.
+type IntervaloHorario struct {
+	Dia        int64  // 1 = Domingo 2 = Segunda 3 = Terça 4 = Quarta 5 = Quinta 6 = Sexta 7 = Sábado
+	Abertura   string // HH:MM
+	Fechamento string // HH:MM
+}
+
+// ExcecaoHorario substitui o horario da semana numa data, como um feriado. Sem abertura e fechamento o
+// estabelecimento fica fechado no dia; varias exceções na mesma data somam os intervalos.
+type ExcecaoHorario struct {
+	Data       string // AAAA-MM-DD
+	Descricao  string
+	Abertura   string
+	Fechamento string
+}
+
+// Fechado diz se a exceção fecha o estabelecimento o dia todo
+func (excecao *ExcecaoHorario) Fechado() bool {
+	return excecao.Abertura == "" && excecao.Fechamento == ""
+}
+
+// HorarioFuncionamento é a grade semanal do estabelecimento com as exceções por data
+type HorarioFuncionamento struct {
+	Fuso     string
+	Semana   []IntervaloHorario
+	Excecoes []ExcecaoHorario
+}
+
+// Vazio diz se nenhum horario foi cadastrado
+func (horario *HorarioFuncionamento) Vazio() bool {
+	return len(horario.Semana) == 0 && len(horario.Excecoes) == 0
+}
+
+func (horario *HorarioFuncionamento) local() *time.Location {
+	fuso := horario.Fuso
+	if fuso == "" {
+		fuso = FusoPadrao
+	}
+	loc, err := time.LoadLocation(fuso)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// minutosHora converte HH:MM em minutos desde a meia noite, aceitando 24:00
+func minutosHora(hora string) (int, error) {
+	if hora == "24:00" {
+		return minutosDia, nil
+	}
+	t, err := time.Parse(layoutHora, hora)
+	if err != nil {
+		return 0, fmt.Errorf("Hora inválida: %q, use HH:MM", hora)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// duracao devolve em minutos o inicio e o fim do intervalo, com o fim passando de 24h quando vira a noite
+func duracao(abertura, fechamento string) (inicio, fim int, err error) {
+	if inicio, err = minutosHora(abertura); err != nil {
+		return 0, 0, err
+	}
+	if fim, err = minutosHora(fechamento); err != nil {
+		return 0, 0, err
+	}
+	if inicio == minutosDia {
+		return 0, 0, fmt.Errorf("Abertura inválida: %q", abertura)
+	}
+	if fim <= inicio {
+		fim += minutosDia
+	}
+	return inicio, fim, nil
+}
+
+// intervalosData devolve os periodos que começam na data, vindos das exceções da data ou da grade semanal
+func (horario *HorarioFuncionamento) intervalosData(data time.Time) [][2]int {
+	var intervalos [][2]int
+
+	chave := data.Format(layoutData)
+	temExcecao := false
+	for _, excecao := range horario.Excecoes {
+		if excecao.Data != chave {
+			continue
+		}
+		temExcecao = true
+		if excecao.Fechado() {
+			continue
+		}
+		if inicio, fim, err := duracao(excecao.Abertura, excecao.Fechamento); err == nil {
+			intervalos = append(intervalos, [2]int{inicio, fim})
+		}
+	}
+	if temExcecao {
+		return intervalos
+	}
+
+	dia := int64(data.Weekday()) + 1
+	for _, intervalo := range horario.Semana {
+		if intervalo.Dia != dia {
+			continue
+		}
+		if inicio, fim, err := duracao(intervalo.Abertura, intervalo.Fechamento); err == nil {
+			intervalos = append(intervalos, [2]int{inicio, fim})
+		}
+	}
+	return intervalos
+}
+
+// AbertoEm diz se o horario cobre o momento informado. Os intervalos do dia anterior que viram a
+// noite também contam.
+func (horario *HorarioFuncionamento) AbertoEm(momento time.Time) bool {
+	loc := horario.local()
+	momento = momento.In(loc)
+	hoje := time.Date(momento.Year(), momento.Month(), momento.Day(), 0, 0, 0, 0, loc)
+
+	for _, data := range []time.Time{hoje, hoje.AddDate(0, 0, -1)} {
+		for _, intervalo := range horario.intervalosData(data) {
+			inicio := data.Add(time.Duration(intervalo[0]) * time.Minute)
+			fim := data.Add(time.Duration(intervalo[1]) * time.Minute)
+			if !momento.Before(inicio) && momento.Before(fim) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validar confere dias, horas, datas e o fuso, e recusa intervalos da semana que se sobrepõem
+func (horario *HorarioFuncionamento) validar() error {
+	if horario.Fuso != "" {
+		if _, err := time.LoadLocation(horario.Fuso); err != nil {
+			return fmt.Errorf("Fuso horario inválido: %v", horario.Fuso)
+		}
+	}
+
+	//Posição de cada intervalo na semana, em minutos desde domingo à meia noite
+	var periodos [][2]int
+	for _, intervalo := range horario.Semana {
+		if intervalo.Dia < 1 || intervalo.Dia > 7 {
+			return fmt.Errorf("Dia da semana inválido: %d", intervalo.Dia)
+		}
+		inicio, fim, err := duracao(intervalo.Abertura, intervalo.Fechamento)
+		if err != nil {
+			return err
+		}
+		deslocamento := int(intervalo.Dia-1) * minutosDia
+		periodos = append(periodos, [2]int{deslocamento + inicio, deslocamento + fim})
+	}
+
+	sort.Slice(periodos, func(i, j int) bool { return periodos[i][0] < periodos[j][0] })
+	for i := range periodos {
+		proximo := periodos[(i+1)%len(periodos)]
+		if i == len(periodos)-1 {
+			//O ultimo intervalo de sabado pode virar a noite e alcançar o domingo
+			proximo[0] += minutosSemana
+		}
+		if len(periodos) > 1 && periodos[i][1] > proximo[0] {
+			return fmt.Errorf("Intervalos de horario sobrepostos")
+		}
+	}
+
+	for _, excecao := range horario.Excecoes {
+		if _, err := time.Parse(layoutData, excecao.Data); err != nil {
+			return fmt.Errorf("Data de exceção inválida: %q, use AAAA-MM-DD", excecao.Data)
+		}
+		if excecao.Fechado() {
+			continue
+		}
+		if _, _, err := duracao(excecao.Abertura, excecao.Fechamento); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AbertoEm diz se o estabelecimento está aberto no momento informado. Enquanto o horario estruturado
+// não foi cadastrado vale o HorarioFunc antigo.
+func (estabelecimento *Estabelecimento) AbertoEm(momento time.Time) bool {
+	if !estabelecimento.Horario.Vazio() {
+		return estabelecimento.Horario.AbertoEm(momento)
+	}
+	legado, ok := HorarioLegado(estabelecimento.HorarioFunc, estabelecimento.DiasFunc)
+	return ok && legado.AbertoEm(momento)
+}
+
+// Aceita "18:00-02:00", "18h às 2h", "18:00 as 23:30" e variações
+var regexpHorarioFunc = regexp.MustCompile(`^\s*(\d{1,2})(?:[:h](\d{2}))?h?\s*(?:-|a|as|às|até)\s*(\d{1,2})(?:[:h](\d{2}))?h?\s*$`)
+
+// HorarioLegado converte os campos antigos HorarioFunc e DiasFunc. Sem DiasFunc o intervalo vale para
+// todos os dias da semana.
+func HorarioLegado(horarioFunc string, diasFunc int64) (HorarioFuncionamento, bool) {
+	partes := regexpHorarioFunc.FindStringSubmatch(horarioFunc)
+	if partes == nil {
+		return HorarioFuncionamento{}, false
+	}
+
+	hora := func(h, m string) (string, bool) {
+		hh, _ := strconv.Atoi(h)
+		mm, _ := strconv.Atoi(m)
+		if hh > 24 || mm > 59 || (hh == 24 && mm > 0) {
+			return "", false
+		}
+		return fmt.Sprintf("%02d:%02d", hh, mm), true
+	}
+	abertura, okAbertura := hora(partes[1], partes[2])
+	fechamento, okFechamento := hora(partes[3], partes[4])
+	if !okAbertura || !okFechamento || abertura == "24:00" {
+		return HorarioFuncionamento{}, false
+	}
+
+	dias := []int64{diasFunc}
+	if diasFunc == 0 {
+		dias = []int64{1, 2, 3, 4, 5, 6, 7}
+	} else if diasFunc < 1 || diasFunc > 7 {
+		return HorarioFuncionamento{}, false
+	}
+
+	horario := HorarioFuncionamento{Fuso: FusoPadrao}
+	for _, dia := range dias {
+		horario.Semana = append(horario.Semana, IntervaloHorario{Dia: dia, Abertura: abertura, Fechamento: fechamento})
+	}
+	return horario, true
+}
@@ -0,0 +1,111 @@
+package estabelecimento
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHorarioAbertoEm(t *testing.T) {
+	brasilia, err := time.LoadLocation(FusoPadrao)
+	if err != nil {
+		t.Skipf("Fuso %s indisponivel: %v", FusoPadrao, err)
+	}
+	em := func(dia, hora, minuto int) time.Time {
+		//Junho de 2021: dia 6 é domingo
+		return time.Date(2021, 6, dia, hora, minuto, 0, 0, brasilia)
+	}
+
+	horario := HorarioFuncionamento{
+		Semana: []IntervaloHorario{
+			{Dia: 2, Abertura: "11:30", Fechamento: "15:00"},
+			{Dia: 2, Abertura: "18:00", Fechamento: "23:00"},
+			{Dia: 6, Abertura: "18:00", Fechamento: "03:00"},
+			{Dia: 7, Abertura: "12:00", Fechamento: "24:00"},
+		},
+		Excecoes: []ExcecaoHorario{
+			{Data: "2021-06-14", Descricao: "Feriado"},
+			{Data: "2021-06-21", Abertura: "10:00", Fechamento: "12:00"},
+		},
+	}
+
+	casos := []struct {
+		nome    string
+		momento time.Time
+		aberto  bool
+	}{
+		{"segunda no almoço", em(7, 12, 0), true},
+		{"segunda entre os turnos", em(7, 16, 0), false},
+		{"segunda à noite", em(7, 22, 59), true},
+		{"segunda no fechamento", em(7, 23, 0), false},
+		{"sexta à noite", em(11, 23, 0), true},
+		{"madrugada de sabado", em(12, 2, 30), true},
+		{"sabado depois de virar a noite", em(12, 3, 0), false},
+		{"sabado à noite até meia noite", em(12, 23, 59), true},
+		{"domingo fechado", em(13, 0, 30), false},
+		{"feriado fechado", em(14, 12, 0), false},
+		{"exceção com horario reduzido", em(21, 11, 0), true},
+		{"exceção substitui a semana", em(21, 19, 0), false},
+		{"momento em UTC é convertido", time.Date(2021, 6, 8, 1, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, caso := range casos {
+		if aberto := horario.AbertoEm(caso.momento); aberto != caso.aberto {
+			t.Errorf("%s: AbertoEm(%v) = %v", caso.nome, caso.momento, aberto)
+		}
+	}
+
+	outroFuso := HorarioFuncionamento{Fuso: "America/Manaus", Semana: []IntervaloHorario{{Dia: 2, Abertura: "08:00", Fechamento: "09:00"}}}
+	if !outroFuso.AbertoEm(em(7, 9, 30)) {
+		t.Errorf("9h30 em Brasilia são 8h30 em Manaus, deveria estar aberto")
+	}
+}
+
+func TestHorarioValidar(t *testing.T) {
+	casos := []struct {
+		nome    string
+		horario HorarioFuncionamento
+		valido  bool
+	}{
+		{"vazio", HorarioFuncionamento{}, true},
+		{"dois turnos", HorarioFuncionamento{Semana: []IntervaloHorario{{2, "11:00", "15:00"}, {2, "18:00", "23:00"}}}, true},
+		{"sabado virando a noite", HorarioFuncionamento{Semana: []IntervaloHorario{{7, "20:00", "04:00"}, {1, "05:00", "10:00"}}}, true},
+		{"sobrepostos", HorarioFuncionamento{Semana: []IntervaloHorario{{2, "11:00", "15:00"}, {2, "14:00", "23:00"}}}, false},
+		{"sabado invade domingo", HorarioFuncionamento{Semana: []IntervaloHorario{{7, "20:00", "04:00"}, {1, "03:00", "10:00"}}}, false},
+		{"dia inválido", HorarioFuncionamento{Semana: []IntervaloHorario{{8, "11:00", "15:00"}}}, false},
+		{"hora inválida", HorarioFuncionamento{Semana: []IntervaloHorario{{2, "11h", "15:00"}}}, false},
+		{"abre à meia noite do dia seguinte", HorarioFuncionamento{Semana: []IntervaloHorario{{2, "24:00", "02:00"}}}, false},
+		{"fuso inválido", HorarioFuncionamento{Fuso: "Brasil/Lua"}, false},
+		{"data inválida", HorarioFuncionamento{Excecoes: []ExcecaoHorario{{Data: "25/12/2021"}}}, false},
+		{"exceção com hora inválida", HorarioFuncionamento{Excecoes: []ExcecaoHorario{{Data: "2021-12-25", Abertura: "10:00", Fechamento: "25:00"}}}, false},
+	}
+
+	for _, caso := range casos {
+		if err := caso.horario.validar(); (err == nil) != caso.valido {
+			t.Errorf("%s: validar() = %v, esperado valido = %v", caso.nome, err, caso.valido)
+		}
+	}
+}
+
+func TestHorarioLegado(t *testing.T) {
+	horario, ok := HorarioLegado("18h às 2h", 6)
+	if !ok || len(horario.Semana) != 1 || horario.Semana[0] != (IntervaloHorario{Dia: 6, Abertura: "18:00", Fechamento: "02:00"}) {
+		t.Errorf("HorarioLegado() = %#v, %v", horario, ok)
+	}
+
+	horario, ok = HorarioLegado("08:00-18:00", 0)
+	if !ok || len(horario.Semana) != 7 {
+		t.Errorf("sem DiasFunc deveria valer todos os dias: %#v", horario)
+	}
+	if err := horario.validar(); err != nil {
+		t.Errorf("horario migrado deveria ser valido: %v", err)
+	}
+
+	for _, invalido := range []string{"", "a combinar", "25:00-26:00"} {
+		if _, ok := HorarioLegado(invalido, 0); ok {
+			t.Errorf("HorarioLegado(%q) deveria falhar", invalido)
+		}
+	}
+	if _, ok := HorarioLegado("08:00-18:00", 9); ok {
+		t.Errorf("DiasFunc inválido deveria falhar")
+	}
+}
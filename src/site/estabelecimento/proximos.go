@@ -0,0 +1,306 @@
+package estabelecimento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"site/busca"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	RaioPadraoKm = 5.0
+	RaioMaximoKm = 50.0
+
+	LimitePadraoProximos = 20
+	LimiteMaximoProximos = 100
+
+	//GetMulti do Datastore aceita no maximo 1000 chaves; a consulta pede uma a mais para saber se passou
+	limiteCelulaGeohash = 999
+)
+
+// ErrMuitosProximos indica que uma célula de geohash passou de limiteCelulaGeohash. A busca não
+// devolve uma lista truncada antes da ordenação por distancia, que deixaria de fora os mais perto.
+var ErrMuitosProximos = errors.New("Há estabelecimentos demais na região, reduza o raio da busca")
+
+// setores traz o nome de cada setor aceito no filtro da busca
+var setores = map[string]int64{
+	"bar":         SetorBar,
+	"restaurante": SetorRestaurante,
+	"lanchonete":  SetorLanchonete,
+	"outros":      SetorOutros,
+}
+
+// SetorPorNome converte o nome ou o numero do setor. Nome vazio não filtra e retorna zero.
+func SetorPorNome(nome string) (int64, error) {
+	nome = strings.ToLower(strings.TrimSpace(nome))
+	if nome == "" {
+		return 0, nil
+	}
+	if setor, ok := setores[nome]; ok {
+		return setor, nil
+	}
+	if setor, err := strconv.ParseInt(nome, 10, 64); err == nil && setor >= SetorBar && setor <= SetorOutros {
+		return setor, nil
+	}
+	return 0, fmt.Errorf("Setor inválido: %s", nome)
+}
+
+// FiltroProximos descreve a busca por estabelecimentos ao redor de um ponto
+type FiltroProximos struct {
+	Latitude       float64
+	Longitude      float64
+	RaioKm         float64
+	Setor          int64
+	SomenteAbertos bool
+	Momento        time.Time // Momento usado no filtro de abertos, agora quando vazio
+	Limite         int
+}
+
+func (filtro *FiltroProximos) validar() error {
+	//NaN passa por qualquer comparação, então é recusado antes
+	if math.IsNaN(filtro.Latitude) || math.IsNaN(filtro.Longitude) ||
+		filtro.Latitude < -90 || filtro.Latitude > 90 || filtro.Longitude < -180 || filtro.Longitude > 180 {
+		return fmt.Errorf("Coordenadas inválidas: %v, %v", filtro.Latitude, filtro.Longitude)
+	}
+	if filtro.RaioKm == 0 {
+		filtro.RaioKm = RaioPadraoKm
+	}
+	if math.IsNaN(filtro.RaioKm) || filtro.RaioKm < 0 || filtro.RaioKm > RaioMaximoKm {
+		return fmt.Errorf("O raio deve ser de até %g km", RaioMaximoKm)
+	}
+	if filtro.Limite <= 0 {
+		filtro.Limite = LimitePadraoProximos
+	}
+	if filtro.Limite > LimiteMaximoProximos {
+		filtro.Limite = LimiteMaximoProximos
+	}
+	if filtro.Momento.IsZero() {
+		filtro.Momento = time.Now()
+	}
+	return nil
+}
+
+// EstabelecimentoProximo traz os dados publicos do estabelecimento com a distancia até o ponto da busca
+type EstabelecimentoProximo struct {
+	PerfilEstabelecimento
+	DistanciaKm float64
+}
+
+// BuscadorProximos encontra os estabelecimentos dentro do raio, do mais perto para o mais longe
+type BuscadorProximos interface {
+	BuscarProximos(c context.Context, filtro FiltroProximos) ([]EstabelecimentoProximo, error)
+}
+
+// NovoBuscadorProximos usa o Elasticsearch quando ele está configurado e, sem ele, o indice de geohash do Datastore
+func NovoBuscadorProximos(c context.Context) BuscadorProximos {
+	b, err := busca.Nova(c)
+	if err != nil {
+		log.Debugf(c, "Elasticsearch indisponivel, buscando estabelecimentos proximos pelo Datastore: %v", err)
+		return &BuscaGeohash{Fonte: FonteDatastore{}}
+	}
+	return &BuscaElasticsearch{Busca: b}
+}
+
+// BuscarProximos busca com o buscador padrão. Se o Elasticsearch falhar, refaz a busca pelo Datastore.
+func BuscarProximos(c context.Context, filtro FiltroProximos) ([]EstabelecimentoProximo, error) {
+	buscador := NovoBuscadorProximos(c)
+	proximos, err := buscador.BuscarProximos(c, filtro)
+	if _, elasticsearch := buscador.(*BuscaElasticsearch); err != nil && elasticsearch {
+		log.Debugf(c, "Buscando estabelecimentos proximos pelo Datastore após falha no Elasticsearch")
+		return (&BuscaGeohash{Fonte: FonteDatastore{}}).BuscarProximos(c, filtro)
+	}
+	return proximos, err
+}
+
+// FonteGeohash devolve os estabelecimentos cujo geohash começa com o prefixo
+type FonteGeohash interface {
+	PorGeohash(c context.Context, prefixo string) ([]Estabelecimento, error)
+}
+
+// BuscaGeohash busca nas células de geohash que cobrem o circulo e depois confere a distancia exata
+type BuscaGeohash struct {
+	Fonte FonteGeohash
+}
+
+func (b *BuscaGeohash) BuscarProximos(c context.Context, filtro FiltroProximos) ([]EstabelecimentoProximo, error) {
+	if err := filtro.validar(); err != nil {
+		return nil, err
+	}
+
+	precisao := precisaoParaRaio(filtro.Latitude, filtro.RaioKm)
+	vistos := make(map[int64]bool)
+	var candidatos []Estabelecimento
+	for _, prefixo := range celulasVizinhas(filtro.Latitude, filtro.Longitude, precisao) {
+		estabelecimentos, err := b.Fonte.PorGeohash(c, prefixo)
+		if err != nil {
+			return nil, err
+		}
+		for _, estabelecimento := range estabelecimentos {
+			if !vistos[estabelecimento.ID] {
+				vistos[estabelecimento.ID] = true
+				candidatos = append(candidatos, estabelecimento)
+			}
+		}
+	}
+
+	return filtrarProximos(candidatos, filtro), nil
+}
+
+// FonteDatastore consulta o campo Geohash por intervalo, do prefixo até o ultimo hash que começa com ele
+type FonteDatastore struct{}
+
+func (FonteDatastore) PorGeohash(c context.Context, prefixo string) ([]Estabelecimento, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	q := datastore.NewQuery(KindEstabelecimento).
+		Filter("Geohash >=", prefixo).
+		Filter("Geohash <", prefixo+"~").
+		Limit(limiteCelulaGeohash + 1).
+		KeysOnly()
+	keys, err := datastoreClient.GetAll(c, q, nil)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar estabelecimentos pelo geohash %s: %v", prefixo, err)
+		return nil, err
+	}
+	if len(keys) > limiteCelulaGeohash {
+		log.Warningf(c, "Geohash %s tem mais de %d estabelecimentos", prefixo, limiteCelulaGeohash)
+		return nil, ErrMuitosProximos
+	}
+	return GetMultiEstabelecimento(c, keys)
+}
+
+// FonteMemoria guarda os estabelecimentos em memoria. Usada nos testes.
+type FonteMemoria struct {
+	Estabelecimentos []Estabelecimento
+}
+
+func (fonte *FonteMemoria) PorGeohash(c context.Context, prefixo string) ([]Estabelecimento, error) {
+	var encontrados []Estabelecimento
+	for _, estabelecimento := range fonte.Estabelecimentos {
+		if estabelecimento.Geohash != "" && strings.HasPrefix(estabelecimento.Geohash, prefixo) {
+			encontrados = append(encontrados, estabelecimento)
+		}
+	}
+	return encontrados, nil
+}
+
+// BuscaElasticsearch usa a consulta geo_distance do indice de estabelecimentos e completa os dados no Datastore
+type BuscaElasticsearch struct {
+	Busca *busca.Busca
+}
+
+func (b *BuscaElasticsearch) BuscarProximos(c context.Context, filtro FiltroProximos) ([]EstabelecimentoProximo, error) {
+	if err := filtro.validar(); err != nil {
+		return nil, err
+	}
+
+	//Com o filtro de abertos parte dos resultados é descartada depois, então pede o maximo
+	limite := filtro.Limite
+	if filtro.SomenteAbertos {
+		limite = LimiteMaximoProximos
+	}
+
+	resultados, err := b.Busca.BuscarEstabelecimentosProximos(c, filtro.Latitude, filtro.Longitude, filtro.RaioKm, filtro.Setor, limite)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar estabelecimentos proximos no Elasticsearch: %v", err)
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(resultados))
+	for _, resultado := range resultados {
+		ids = append(ids, resultado.ID)
+	}
+	candidatos, err := buscarPorIDs(c, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return filtrarProximos(candidatos, filtro), nil
+}
+
+// buscarPorIDs traz os estabelecimentos ignorando os que já foram excluidos mas ainda estão no indice
+func buscarPorIDs(c context.Context, ids []int64) ([]Estabelecimento, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Erro ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	keys := make([]*datastore.Key, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, datastore.IDKey(KindEstabelecimento, id, nil))
+	}
+
+	estabelecimentos := make([]Estabelecimento, len(keys))
+	err = datastoreClient.GetMulti(c, keys, estabelecimentos)
+	erros, _ := err.(datastore.MultiError)
+	if err != nil && erros == nil {
+		log.Warningf(c, "Erro ao buscar Multi Estabelecimentos: %v", err)
+		return nil, err
+	}
+
+	agora := time.Now()
+	encontrados := make([]Estabelecimento, 0, len(keys))
+	for i := range keys {
+		if erros != nil && erros[i] != nil {
+			if erros[i] != datastore.ErrNoSuchEntity {
+				log.Warningf(c, "Erro ao buscar estabelecimento %d: %v", keys[i].ID, erros[i])
+			}
+			continue
+		}
+		estabelecimentos[i].ID = keys[i].ID
+		estabelecimentos[i].AbertoAgora = estabelecimentos[i].AbertoEm(agora)
+		encontrados = append(encontrados, estabelecimentos[i])
+	}
+	return encontrados, nil
+}
+
+// filtrarProximos calcula a distancia de cada candidato, descarta os que estão fora do raio, do setor
+// ou fechados e ordena do mais perto para o mais longe
+func filtrarProximos(candidatos []Estabelecimento, filtro FiltroProximos) []EstabelecimentoProximo {
+	proximos := make([]EstabelecimentoProximo, 0, len(candidatos))
+	for _, estabelecimento := range candidatos {
+		if !estabelecimento.Endereco.Geocodificado() {
+			continue
+		}
+		if filtro.Setor != 0 && estabelecimento.Setor != filtro.Setor {
+			continue
+		}
+
+		distancia := DistanciaKm(filtro.Latitude, filtro.Longitude, estabelecimento.Endereco.Latitude, estabelecimento.Endereco.Longitude)
+		if distancia > filtro.RaioKm {
+			continue
+		}
+		if filtro.SomenteAbertos && !estabelecimento.AbertoEm(filtro.Momento) {
+			continue
+		}
+		proximos = append(proximos, EstabelecimentoProximo{PerfilEstabelecimento: estabelecimento.Perfil(), DistanciaKm: distancia})
+	}
+
+	sort.SliceStable(proximos, func(i, j int) bool {
+		return proximos[i].DistanciaKm < proximos[j].DistanciaKm
+	})
+	if len(proximos) > filtro.Limite {
+		proximos = proximos[:filtro.Limite]
+	}
+	return proximos
+}
@@ -0,0 +1,171 @@
+package estabelecimento
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodificarGeohash(t *testing.T) {
+	if hash := CodificarGeohash(42.6, -5.6, 5); hash != "ezs42" {
+		t.Errorf("CodificarGeohash() = %q, esperado ezs42", hash)
+	}
+	if hash := CodificarGeohash(-23.5505, -46.6333, 6); hash != "6gyf4b" {
+		t.Errorf("CodificarGeohash() = %q, esperado 6gyf4b", hash)
+	}
+}
+
+func TestDistanciaKm(t *testing.T) {
+	//Praça da Sé em São Paulo até a Candelária no Rio de Janeiro
+	distancia := DistanciaKm(-23.5505, -46.6333, -22.9009, -43.1776)
+	if math.Abs(distancia-360) > 5 {
+		t.Errorf("DistanciaKm() = %v, esperado cerca de 360", distancia)
+	}
+}
+
+func TestCelulasVizinhasCobremORaio(t *testing.T) {
+	lat, lng, raio := -23.5505, -46.6333, 3.0
+	precisao := precisaoParaRaio(lat, raio)
+	celulas := celulasVizinhas(lat, lng, precisao)
+	if len(celulas) != 9 {
+		t.Fatalf("esperadas 9 células, vieram %d: %v", len(celulas), celulas)
+	}
+
+	//Pontos na borda do circulo em varias direções precisam cair em alguma das células
+	for angulo := 0.0; angulo < 360; angulo += 15 {
+		rad := angulo * math.Pi / 180
+		pLat := lat + raio/kmPorGrau*math.Cos(rad)
+		pLng := lng + raio/(kmPorGrau*math.Cos(lat*math.Pi/180))*math.Sin(rad)
+		hash := CodificarGeohash(pLat, pLng, precisao)
+
+		coberto := false
+		for _, celula := range celulas {
+			coberto = coberto || strings.HasPrefix(hash, celula)
+		}
+		if !coberto {
+			t.Errorf("ponto a %v graus (%s) fora das células %v", angulo, hash, celulas)
+		}
+	}
+}
+
+func estabelecimentoEm(id int64, nome string, setor int64, lat, lng float64) Estabelecimento {
+	return Estabelecimento{
+		ID:             id,
+		CNPJ:           "11222333000181",
+		ProprietarioID: 99,
+		Nome:           nome,
+		Setor:          setor,
+		HorarioFunc:    "18:00-02:00",
+		Endereco:       EnderecoEstabelecimento{Latitude: lat, Longitude: lng},
+		Geohash:        CodificarGeohash(lat, lng, precisaoGeohash),
+	}
+}
+
+func TestBuscaGeohash(t *testing.T) {
+	fonte := &FonteMemoria{Estabelecimentos: []Estabelecimento{
+		estabelecimentoEm(1, "Bar da Sé", SetorBar, -23.5503, -46.6340),
+		estabelecimentoEm(2, "Restaurante Liberdade", SetorRestaurante, -23.5587, -46.6350),
+		estabelecimentoEm(3, "Bar da Paulista", SetorBar, -23.5614, -46.6559),
+		estabelecimentoEm(4, "Bar em Santos", SetorBar, -23.9608, -46.3336),
+		{ID: 5, Nome: "Sem coordenadas", Setor: SetorBar},
+	}}
+	buscador := &BuscaGeohash{Fonte: fonte}
+	c := context.Background()
+
+	proximos, err := buscador.BuscarProximos(c, FiltroProximos{Latitude: -23.5505, Longitude: -46.6333, RaioKm: 5})
+	if err != nil {
+		t.Fatalf("BuscarProximos() = %v", err)
+	}
+	if len(proximos) != 3 || proximos[0].ID != 1 || proximos[1].ID != 2 || proximos[2].ID != 3 {
+		t.Fatalf("esperados 1, 2 e 3 por distancia: %#v", proximos)
+	}
+	if proximos[0].DistanciaKm > proximos[1].DistanciaKm {
+		t.Errorf("resultados fora de ordem")
+	}
+	if dados, _ := json.Marshal(proximos[0]); strings.Contains(string(dados), "CNPJ") || strings.Contains(string(dados), "ProprietarioID") {
+		t.Errorf("busca de proximos expõe dados internos: %s", dados)
+	}
+
+	proximos, _ = buscador.BuscarProximos(c, FiltroProximos{Latitude: -23.5505, Longitude: -46.6333, RaioKm: 5, Setor: SetorRestaurante})
+	if len(proximos) != 1 || proximos[0].ID != 2 {
+		t.Errorf("filtro por setor: %#v", proximos)
+	}
+
+	proximos, _ = buscador.BuscarProximos(c, FiltroProximos{Latitude: -23.5505, Longitude: -46.6333, RaioKm: 0.5})
+	if len(proximos) != 1 || proximos[0].ID != 1 {
+		t.Errorf("raio de 500 m: %#v", proximos)
+	}
+
+	//Quarta às 15h em Brasilia todos estão fechados; às 23h todos abertos
+	tarde := time.Date(2021, 6, 2, 18, 0, 0, 0, time.UTC)
+	proximos, _ = buscador.BuscarProximos(c, FiltroProximos{Latitude: -23.5505, Longitude: -46.6333, SomenteAbertos: true, Momento: tarde})
+	if len(proximos) != 0 {
+		t.Errorf("ninguem deveria estar aberto às 15h: %#v", proximos)
+	}
+	noite := time.Date(2021, 6, 3, 2, 0, 0, 0, time.UTC)
+	proximos, _ = buscador.BuscarProximos(c, FiltroProximos{Latitude: -23.5505, Longitude: -46.6333, SomenteAbertos: true, Momento: noite})
+	if len(proximos) != 3 {
+		t.Errorf("todos deveriam estar abertos às 23h: %#v", proximos)
+	}
+
+	if _, err := buscador.BuscarProximos(c, FiltroProximos{Latitude: -23.5, Longitude: -46.6, RaioKm: RaioMaximoKm + 1}); err == nil {
+		t.Errorf("raio acima do maximo deveria falhar")
+	}
+	if _, err := buscador.BuscarProximos(c, FiltroProximos{Latitude: 91}); err == nil {
+		t.Errorf("latitude inválida deveria falhar")
+	}
+	for _, filtro := range []FiltroProximos{
+		{Latitude: math.NaN(), Longitude: -46.6},
+		{Latitude: -23.5, Longitude: math.NaN()},
+		{Latitude: -23.5, Longitude: math.Inf(1)},
+		{Latitude: -23.5, Longitude: -46.6, RaioKm: math.NaN()},
+		{Latitude: -23.5, Longitude: -46.6, RaioKm: math.Inf(1)},
+	} {
+		if _, err := buscador.BuscarProximos(c, filtro); err == nil {
+			t.Errorf("filtro %+v deveria falhar", filtro)
+		}
+	}
+}
+
+func TestSetorPorNome(t *testing.T) {
+	casos := map[string]int64{"": 0, "bar": SetorBar, " Restaurante ": SetorRestaurante, "3": SetorLanchonete}
+	for nome, esperado := range casos {
+		if setor, err := SetorPorNome(nome); err != nil || setor != esperado {
+			t.Errorf("SetorPorNome(%q) = %v, %v, esperado %v", nome, setor, err, esperado)
+		}
+	}
+	for _, nome := range []string{"padaria", "9"} {
+		if _, err := SetorPorNome(nome); err == nil {
+			t.Errorf("SetorPorNome(%q) deveria falhar", nome)
+		}
+	}
+}
+
+func TestAbertoEm(t *testing.T) {
+	brasilia := time.FixedZone("BRT", -3*60*60)
+	casos := []struct {
+		horario string
+		dias    int64
+		momento time.Time
+		aberto  bool
+	}{
+		{"18:00-23:00", 0, time.Date(2021, 6, 2, 19, 0, 0, 0, brasilia), true},
+		{"18:00-23:00", 0, time.Date(2021, 6, 2, 23, 0, 0, 0, brasilia), false},
+		{"18h às 2h", 0, time.Date(2021, 6, 3, 1, 30, 0, 0, brasilia), true},
+		{"18h às 2h", 0, time.Date(2021, 6, 3, 2, 30, 0, 0, brasilia), false},
+		//Sexta de madrugada ainda conta como quinta
+		{"20:00-03:00", 5, time.Date(2021, 6, 4, 1, 0, 0, 0, brasilia), true},
+		{"20:00-03:00", 5, time.Date(2021, 6, 4, 21, 0, 0, 0, brasilia), false},
+		{"a combinar", 0, time.Date(2021, 6, 2, 19, 0, 0, 0, brasilia), false},
+	}
+
+	for _, caso := range casos {
+		estab := Estabelecimento{HorarioFunc: caso.horario, DiasFunc: caso.dias}
+		if aberto := estab.AbertoEm(caso.momento); aberto != caso.aberto {
+			t.Errorf("%q dia %d em %v: AbertoEm() = %v", caso.horario, caso.dias, caso.momento, aberto)
+		}
+	}
+}
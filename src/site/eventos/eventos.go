@@ -0,0 +1,73 @@
+package eventos
+
+import (
+	"context"
+	"fmt"
+	"site/utils/log"
+	"sync"
+)
+
+const (
+	TipoPublicacao       = "publicacao"
+	TipoCurtidas         = "curtidas"
+	TipoNotificacao      = "notificacao"
+	TipoMensagem         = "mensagem"
+	TipoMensagemEditada  = "mensagem_editada"
+	TipoMensagemExcluida = "mensagem_excluida"
+	TipoSeguidos         = "seguidos"
+)
+
+// Evento é uma mensagem entregue em tempo real aos usuarios conectados
+type Evento struct {
+	Tipo  string
+	Dados interface{}
+}
+
+// Barramento distribui eventos entre quem publica e quem está assinando os canais.
+// A implementação em memória atende uma instancia; com varias instancias basta trocar por outra via Definir.
+type Barramento interface {
+	Publicar(c context.Context, canal string, evento Evento) error
+	Assinar(c context.Context, canais ...string) (Assinatura, error)
+}
+
+// Assinatura entrega os eventos dos canais assinados até ser cancelada
+type Assinatura interface {
+	Eventos() <-chan Evento
+	Cancelar()
+}
+
+var (
+	mu     sync.RWMutex
+	padrao Barramento = NovoMemoria()
+)
+
+// Padrao retorna o barramento usado pela aplicação
+func Padrao() Barramento {
+	mu.RLock()
+	defer mu.RUnlock()
+	return padrao
+}
+
+// Definir troca o barramento usado pela aplicação
+func Definir(b Barramento) {
+	mu.Lock()
+	defer mu.Unlock()
+	padrao = b
+}
+
+// CanalUsuario recebe os eventos destinados a um usuario, como as notificações
+func CanalUsuario(usuarioID int64) string {
+	return fmt.Sprintf("usuario:%d", usuarioID)
+}
+
+// CanalAutor recebe as novas publicações de um usuario e as curtidas nelas, assinado por quem o segue
+func CanalAutor(autorID int64) string {
+	return fmt.Sprintf("autor:%d", autorID)
+}
+
+// Publicar envia o evento pelo barramento padrão. Falhas são apenas registradas, o evento em tempo real é um extra.
+func Publicar(c context.Context, canal string, tipo string, dados interface{}) {
+	if err := Padrao().Publicar(c, canal, Evento{Tipo: tipo, Dados: dados}); err != nil {
+		log.Warningf(c, "Falha ao publicar evento %s no canal %s: %v", tipo, canal, err)
+	}
+}
@@ -0,0 +1,90 @@
+package eventos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// TamanhoBufferAssinatura é quantos eventos ficam pendentes para um assinante lento antes de começarem a ser descartados
+const TamanhoBufferAssinatura = 64
+
+// Memoria é o barramento dentro do proprio processo
+type Memoria struct {
+	mu          sync.RWMutex
+	assinaturas map[string]map[*assinaturaMemoria]bool
+}
+
+type assinaturaMemoria struct {
+	barramento *Memoria
+	canais     []string
+	eventos    chan Evento
+	cancelar   sync.Once
+}
+
+func NovoMemoria() *Memoria {
+	return &Memoria{assinaturas: make(map[string]map[*assinaturaMemoria]bool)}
+}
+
+// Publicar entrega o evento para todos os assinantes do canal sem bloquear quem publica.
+// Se o buffer de um assinante estiver cheio o evento é descartado só para ele.
+func (m *Memoria) Publicar(c context.Context, canal string, evento Evento) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var descartados int
+	for a := range m.assinaturas[canal] {
+		select {
+		case a.eventos <- evento:
+		default:
+			descartados++
+		}
+	}
+
+	if descartados > 0 {
+		return fmt.Errorf("%d assinantes do canal %s com buffer cheio, evento descartado para eles", descartados, canal)
+	}
+	return nil
+}
+
+func (m *Memoria) Assinar(c context.Context, canais ...string) (Assinatura, error) {
+	if len(canais) == 0 {
+		return nil, fmt.Errorf("Nenhum canal informado para assinatura")
+	}
+
+	a := &assinaturaMemoria{
+		barramento: m,
+		canais:     canais,
+		eventos:    make(chan Evento, TamanhoBufferAssinatura),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, canal := range canais {
+		if m.assinaturas[canal] == nil {
+			m.assinaturas[canal] = make(map[*assinaturaMemoria]bool)
+		}
+		m.assinaturas[canal][a] = true
+	}
+	return a, nil
+}
+
+func (a *assinaturaMemoria) Eventos() <-chan Evento {
+	return a.eventos
+}
+
+// Cancelar remove a assinatura de todos os canais e fecha o canal de eventos. Pode ser chamado mais de uma vez.
+func (a *assinaturaMemoria) Cancelar() {
+	a.cancelar.Do(func() {
+		m := a.barramento
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, canal := range a.canais {
+			delete(m.assinaturas[canal], a)
+			if len(m.assinaturas[canal]) == 0 {
+				delete(m.assinaturas, canal)
+			}
+		}
+		close(a.eventos)
+	})
+}
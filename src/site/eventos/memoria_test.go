@@ -0,0 +1,102 @@
+package eventos
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func receber(t *testing.T, a Assinatura) Evento {
+	t.Helper()
+	select {
+	case e := <-a.Eventos():
+		return e
+	case <-time.After(time.Second):
+		t.Fatalf("Evento não recebido")
+	}
+	return Evento{}
+}
+
+func TestMemoriaEntregaSoParaOsCanaisAssinados(t *testing.T) {
+	c := context.Background()
+	m := NovoMemoria()
+
+	a, err := m.Assinar(c, CanalUsuario(1), CanalAutor(2))
+	if err != nil {
+		t.Fatalf("Erro ao assinar: %v", err)
+	}
+	defer a.Cancelar()
+
+	m.Publicar(c, CanalUsuario(3), Evento{Tipo: TipoNotificacao})
+	m.Publicar(c, CanalAutor(2), Evento{Tipo: TipoPublicacao, Dados: 10})
+	m.Publicar(c, CanalUsuario(1), Evento{Tipo: TipoNotificacao, Dados: 20})
+
+	if e := receber(t, a); e.Tipo != TipoPublicacao || e.Dados != 10 {
+		t.Errorf("Primeiro evento inesperado: %#v", e)
+	}
+	if e := receber(t, a); e.Tipo != TipoNotificacao || e.Dados != 20 {
+		t.Errorf("Segundo evento inesperado: %#v", e)
+	}
+	select {
+	case e := <-a.Eventos():
+		t.Errorf("Evento de canal não assinado entregue: %#v", e)
+	default:
+	}
+}
+
+func TestMemoriaCancelar(t *testing.T) {
+	c := context.Background()
+	m := NovoMemoria()
+
+	a, _ := m.Assinar(c, CanalAutor(3))
+	a.Cancelar()
+	a.Cancelar()
+
+	if _, aberto := <-a.Eventos(); aberto {
+		t.Errorf("Canal de eventos deveria estar fechado")
+	}
+	if err := m.Publicar(c, CanalAutor(3), Evento{Tipo: TipoCurtidas}); err != nil {
+		t.Errorf("Publicar sem assinantes não deveria falhar: %v", err)
+	}
+	if len(m.assinaturas) != 0 {
+		t.Errorf("Assinaturas não foram removidas: %v", m.assinaturas)
+	}
+}
+
+func TestMemoriaNaoBloqueiaComAssinanteLento(t *testing.T) {
+	c := context.Background()
+	m := NovoMemoria()
+
+	lento, _ := m.Assinar(c, CanalAutor(3))
+	defer lento.Cancelar()
+
+	for i := 0; i < TamanhoBufferAssinatura; i++ {
+		if err := m.Publicar(c, CanalAutor(3), Evento{Tipo: TipoCurtidas}); err != nil {
+			t.Fatalf("Erro antes de encher o buffer: %v", err)
+		}
+	}
+	if err := m.Publicar(c, CanalAutor(3), Evento{Tipo: TipoCurtidas}); err == nil {
+		t.Errorf("Deveria avisar que o evento foi descartado")
+	}
+}
+
+func TestMemoriaConcorrente(t *testing.T) {
+	c := context.Background()
+	m := NovoMemoria()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a, _ := m.Assinar(c, CanalAutor(3))
+			a.Cancelar()
+		}()
+		go func() {
+			defer wg.Done()
+			m.Publicar(c, CanalAutor(3), Evento{Tipo: TipoCurtidas})
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,328 @@
+package mensagem
+
+import (
+	"context"
+	"fmt"
+	"site/bloqueio"
+	"site/seguidores"
+	"site/usuario"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindConversa = "Conversa"
+
+	MaxParticipantes = 8
+)
+
+// Leitura guarda, para cada participante, até quando ele leu a conversa e quantas mensagens ainda não leu
+type Leitura struct {
+	UsuarioID int64
+	Data      utils.JsonSpecialDateTime
+	NaoLidas  int64
+}
+
+// Conversa entre dois usuarios ou um grupo pequeno. Conversas diretas têm Chave "menorID:maiorID" para não duplicar.
+// Nicks acompanha Participantes na mesma ordem.
+type Conversa struct {
+	ID                 int64 `datastore:"-"`
+	Participantes      []int64
+	Nicks              []string
+	Chave              string
+	Titulo             string
+	CriadorID          int64
+	Leituras           []Leitura
+	UltimaMensagem     string `datastore:",noindex"`
+	DataUltimaMensagem utils.JsonSpecialDateTime
+	DataCriacao        utils.JsonSpecialDateTime
+}
+
+func GetConversa(c context.Context, id int64) *Conversa {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindConversa, id, nil)
+
+	var conversa Conversa
+	if err = datastoreClient.Get(c, key, &conversa); err != nil {
+		log.Warningf(c, "Falha ao buscar conversa: %v", err)
+		return nil
+	}
+	conversa.ID = id
+	return &conversa
+}
+
+func GetMultConversa(c context.Context, keys []*datastore.Key) ([]Conversa, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return []Conversa{}, err
+	}
+	defer datastoreClient.Close()
+
+	conversas := make([]Conversa, len(keys))
+	if err := datastoreClient.GetMulti(c, keys, conversas); err != nil {
+		if errs, ok := err.(datastore.MultiError); ok {
+			for _, e := range errs {
+				if e == datastore.ErrNoSuchEntity {
+					return []Conversa{}, nil
+				}
+			}
+		}
+		log.Warningf(c, "Erro ao buscar Multi Conversas: %v", err)
+		return []Conversa{}, err
+	}
+	for i := range keys {
+		conversas[i].ID = keys[i].ID
+	}
+	return conversas, nil
+}
+
+func PutConversa(c context.Context, conversa *Conversa) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindConversa, conversa.ID, nil)
+	key, err = datastoreClient.Put(c, key, conversa)
+	if err != nil {
+		log.Warningf(c, "Erro ao inserir conversa: %v", err)
+		return err
+	}
+	conversa.ID = key.ID
+	return nil
+}
+
+// FiltrarConversas traz as conversas de um usuario. Com chave preenchida busca a conversa direta correspondente.
+func FiltrarConversas(c context.Context, usuarioID int64, chave string) ([]Conversa, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	q := datastore.NewQuery(KindConversa)
+
+	if usuarioID != 0 {
+		q = q.Filter("Participantes =", usuarioID)
+	}
+
+	if chave != "" {
+		q = q.Filter("Chave =", chave)
+	}
+
+	q = q.KeysOnly()
+	keys, err := datastoreClient.GetAll(c, q, nil)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar conversas: %v", err)
+		return nil, err
+	}
+	return GetMultConversa(c, keys)
+}
+
+func chaveDireta(usuarioA, usuarioB int64) string {
+	if usuarioA > usuarioB {
+		usuarioA, usuarioB = usuarioB, usuarioA
+	}
+	return fmt.Sprintf("%d:%d", usuarioA, usuarioB)
+}
+
+// Participa diz se o usuario faz parte da conversa
+func (conversa *Conversa) Participa(usuarioID int64) bool {
+	return utils.InIntArray(usuarioID, conversa.Participantes)
+}
+
+// Direta diz se a conversa é entre apenas dois usuarios
+func (conversa *Conversa) Direta() bool {
+	return conversa.Chave != ""
+}
+
+// Leitura retorna o estado de leitura do usuario na conversa
+func (conversa *Conversa) Leitura(usuarioID int64) Leitura {
+	for _, l := range conversa.Leituras {
+		if l.UsuarioID == usuarioID {
+			return l
+		}
+	}
+	return Leitura{UsuarioID: usuarioID}
+}
+
+// CriarConversa abre uma conversa do criador com os participantes informados. Todos precisam seguir e ser
+// seguidos pelo criador e não pode haver bloqueio entre eles. Uma conversa direta que já existe é reaproveitada.
+func CriarConversa(c context.Context, criadorID int64, participantes []int64, titulo string) (*Conversa, error) {
+	conversa := Conversa{
+		Participantes: []int64{criadorID},
+		Titulo:        titulo,
+		CriadorID:     criadorID,
+		DataCriacao:   utils.GetSpecialTimeNow(),
+	}
+
+	for _, id := range participantes {
+		if id != 0 && !utils.InIntArray(id, conversa.Participantes) {
+			conversa.Participantes = append(conversa.Participantes, id)
+		}
+	}
+
+	if len(conversa.Participantes) < 2 {
+		return nil, fmt.Errorf("Informe ao menos um participante além de você")
+	}
+	if len(conversa.Participantes) > MaxParticipantes {
+		return nil, fmt.Errorf("Uma conversa pode ter no máximo %d participantes", MaxParticipantes)
+	}
+
+	for i, id := range conversa.Participantes {
+		usu := usuario.GetUsuario(c, id)
+		if usu == nil {
+			return nil, fmt.Errorf("Usuario %d não encontrado", id)
+		}
+		conversa.Nicks = append(conversa.Nicks, usu.Nick)
+
+		if i == 0 {
+			continue
+		}
+		if err := podeConversar(c, criadorID, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(conversa.Participantes) == 2 {
+		conversa.Chave = chaveDireta(conversa.Participantes[0], conversa.Participantes[1])
+		conversa.Titulo = ""
+
+		existentes, err := FiltrarConversas(c, 0, conversa.Chave)
+		if err != nil {
+			return nil, err
+		}
+		if len(existentes) > 0 {
+			return &existentes[0], nil
+		}
+	}
+
+	for _, id := range conversa.Participantes {
+		conversa.Leituras = append(conversa.Leituras, Leitura{UsuarioID: id, Data: conversa.DataCriacao})
+	}
+
+	if err := PutConversa(c, &conversa); err != nil {
+		return nil, err
+	}
+	return &conversa, nil
+}
+
+// podeConversar aplica as regras de contato entre dois usuarios: seguir mutuamente e nenhum bloqueio
+func podeConversar(c context.Context, usuarioA, usuarioB int64) error {
+	return verificarContato(usuarioB,
+		func() bool { return bloqueio.Bloqueado(c, usuarioA, usuarioB) },
+		func() bool { return seguidores.SeguemUmAoOutro(c, usuarioA, usuarioB) })
+}
+
+// verificarContato recebe as consultas como funções; a de seguidores só é feita se não houver bloqueio
+func verificarContato(usuarioB int64, bloqueado, seguemUmAoOutro func() bool) error {
+	if bloqueado() {
+		return fmt.Errorf("Não é possivel conversar com o usuario %d", usuarioB)
+	}
+	if !seguemUmAoOutro() {
+		return fmt.Errorf("Só é possivel conversar com usuarios que você segue e que seguem você")
+	}
+	return nil
+}
+
+// BuscarConversas traz as conversas do usuario, as com mensagem mais recente primeiro
+func BuscarConversas(c context.Context, usuarioID int64) ([]Conversa, error) {
+	conversas, err := FiltrarConversas(c, usuarioID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(conversas, func(i, j int) bool {
+		return ultimaAtividade(conversas[i]).After(ultimaAtividade(conversas[j]).Time)
+	})
+	return conversas, nil
+}
+
+func ultimaAtividade(conversa Conversa) utils.JsonSpecialDateTime {
+	if conversa.DataUltimaMensagem.IsZero() {
+		return conversa.DataCriacao
+	}
+	return conversa.DataUltimaMensagem
+}
+
+// ContarNaoLidas soma as mensagens não lidas do usuario em todas as conversas
+func ContarNaoLidas(c context.Context, usuarioID int64) (int64, error) {
+	conversas, err := FiltrarConversas(c, usuarioID, "")
+	if err != nil {
+		return 0, err
+	}
+
+	return somarNaoLidas(conversas, usuarioID), nil
+}
+
+func somarNaoLidas(conversas []Conversa, usuarioID int64) int64 {
+	var total int64
+	for _, conversa := range conversas {
+		total += conversa.Leitura(usuarioID).NaoLidas
+	}
+	return total
+}
+
+// MarcarLida registra que o usuario leu a conversa até agora, zerando as não lidas dele
+func MarcarLida(c context.Context, conversaID, usuarioID int64) error {
+	return atualizarConversa(c, conversaID, func(conversa *Conversa) error {
+		if !conversa.Participa(usuarioID) {
+			return fmt.Errorf("Usuario não participa da conversa")
+		}
+		marcarLeitura(conversa, usuarioID, utils.GetSpecialTimeNow())
+		return nil
+	})
+}
+
+func marcarLeitura(conversa *Conversa, usuarioID int64, data utils.JsonSpecialDateTime) {
+	for i := range conversa.Leituras {
+		if conversa.Leituras[i].UsuarioID == usuarioID {
+			conversa.Leituras[i].Data = data
+			conversa.Leituras[i].NaoLidas = 0
+		}
+	}
+}
+
+// atualizarConversa lê e grava a conversa em uma transação, evitando perder contadores com envios simultaneos
+func atualizarConversa(c context.Context, conversaID int64, alterar func(conversa *Conversa) error) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindConversa, conversaID, nil)
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		var conversa Conversa
+		if err := tx.Get(key, &conversa); err != nil {
+			return err
+		}
+		conversa.ID = conversaID
+
+		if err := alterar(&conversa); err != nil {
+			return err
+		}
+
+		_, err := tx.Put(key, &conversa)
+		return err
+	})
+	if err != nil {
+		log.Warningf(c, "Falha ao atualizar conversa %d: %v", conversaID, err)
+	}
+	return err
+}
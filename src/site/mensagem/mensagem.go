@@ -0,0 +1,371 @@
+package mensagem
+
+import (
+	"context"
+	"fmt"
+	"site/bloqueio"
+	"site/eventos"
+	"site/usuario"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindMensagem = "Mensagem"
+
+	TamanhoMaximoMensagem = 2000
+	tamanhoResumo         = 80
+	resumoExcluida        = "Mensagem apagada"
+
+	LimitePadrao = 50
+	LimiteMaximo = 200
+)
+
+// Mensagem enviada em uma conversa. Mensagens excluidas continuam gravadas, mas sem conteudo.
+type Mensagem struct {
+	ID          int64 `datastore:"-"`
+	ConversaID  int64
+	AutorID     int64
+	AutorNick   string
+	Conteudo    string `datastore:",noindex"`
+	Editada     bool
+	Excluida    bool
+	LidaPor     []int64 `datastore:"-"`
+	DataCriacao utils.JsonSpecialDateTime
+	DataEdicao  utils.JsonSpecialDateTime
+}
+
+func GetMensagem(c context.Context, id int64) *Mensagem {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindMensagem, id, nil)
+
+	var mensagem Mensagem
+	if err = datastoreClient.Get(c, key, &mensagem); err != nil {
+		log.Warningf(c, "Falha ao buscar mensagem: %v", err)
+		return nil
+	}
+	mensagem.ID = id
+	return &mensagem
+}
+
+func GetMultMensagem(c context.Context, keys []*datastore.Key) ([]Mensagem, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return []Mensagem{}, err
+	}
+	defer datastoreClient.Close()
+
+	mensagens := make([]Mensagem, len(keys))
+	if err := datastoreClient.GetMulti(c, keys, mensagens); err != nil {
+		if errs, ok := err.(datastore.MultiError); ok {
+			for _, e := range errs {
+				if e == datastore.ErrNoSuchEntity {
+					return []Mensagem{}, nil
+				}
+			}
+		}
+		log.Warningf(c, "Erro ao buscar Multi Mensagens: %v", err)
+		return []Mensagem{}, err
+	}
+	for i := range keys {
+		mensagens[i].ID = keys[i].ID
+	}
+	return mensagens, nil
+}
+
+func PutMensagem(c context.Context, mensagem *Mensagem) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindMensagem, mensagem.ID, nil)
+	key, err = datastoreClient.Put(c, key, mensagem)
+	if err != nil {
+		log.Warningf(c, "Erro ao inserir mensagem: %v", err)
+		return err
+	}
+	mensagem.ID = key.ID
+	return nil
+}
+
+// FiltrarMensagens traz até limite mensagens da conversa, das mais recentes para as mais antigas.
+// Com antes preenchido, traz só as enviadas antes dessa data.
+func FiltrarMensagens(c context.Context, conversaID int64, antes time.Time, limite int) ([]Mensagem, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	q := datastore.NewQuery(KindMensagem).Filter("ConversaID =", conversaID)
+	if !antes.IsZero() {
+		q = q.Filter("DataCriacao.Time <", antes)
+	}
+	q = q.Order("-DataCriacao.Time").Limit(limite).KeysOnly()
+	keys, err := datastoreClient.GetAll(c, q, nil)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar mensagens: %v", err)
+		return nil, err
+	}
+	return GetMultMensagem(c, keys)
+}
+
+// BuscarConversa traz a conversa garantindo que o usuario faz parte dela
+func BuscarConversa(c context.Context, conversaID, usuarioID int64) (*Conversa, error) {
+	conversa := GetConversa(c, conversaID)
+	if conversa == nil {
+		return nil, fmt.Errorf("Conversa não encontrada")
+	}
+	if !conversa.Participa(usuarioID) {
+		return nil, fmt.Errorf("Usuario não participa da conversa")
+	}
+	return conversa, nil
+}
+
+func validarConteudo(conteudo string) (string, error) {
+	conteudo = strings.TrimSpace(conteudo)
+	if conteudo == "" {
+		return "", fmt.Errorf("A mensagem não pode estar em branco")
+	}
+	if len([]rune(conteudo)) > TamanhoMaximoMensagem {
+		return "", fmt.Errorf("A mensagem pode ter no máximo %d caracteres", TamanhoMaximoMensagem)
+	}
+	return conteudo, nil
+}
+
+// EnviarMensagem grava a mensagem e atualiza os contadores de não lidas dos demais participantes.
+// Em conversas diretas os dois precisam continuar se seguindo; bloqueios impedem o envio em qualquer conversa.
+func EnviarMensagem(c context.Context, conversaID, autorID int64, conteudo string) (*Mensagem, error) {
+	conversa, err := BuscarConversa(c, conversaID, autorID)
+	if err != nil {
+		return nil, err
+	}
+
+	conteudo, err = validarConteudo(conteudo)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range conversa.Participantes {
+		if id == autorID {
+			continue
+		}
+		if conversa.Direta() {
+			if err := podeConversar(c, autorID, id); err != nil {
+				return nil, err
+			}
+		} else if bloqueio.Bloqueado(c, autorID, id) {
+			return nil, fmt.Errorf("Não é possivel enviar mensagens nesta conversa")
+		}
+	}
+
+	mensagem := Mensagem{
+		ConversaID:  conversaID,
+		AutorID:     autorID,
+		Conteudo:    conteudo,
+		DataCriacao: utils.GetSpecialTimeNow(),
+	}
+	if autor := usuario.GetUsuario(c, autorID); autor != nil {
+		mensagem.AutorNick = autor.Nick
+	}
+
+	if err := PutMensagem(c, &mensagem); err != nil {
+		return nil, err
+	}
+
+	err = atualizarConversa(c, conversaID, func(conversa *Conversa) error {
+		registrarEnvio(conversa, mensagem)
+		return nil
+	})
+	if err != nil {
+		log.Warningf(c, "Mensagem %d gravada, mas a conversa não foi atualizada: %v", mensagem.ID, err)
+	}
+
+	publicarMensagem(c, conversa, eventos.TipoMensagem, &mensagem)
+	return &mensagem, nil
+}
+
+// registrarEnvio marca a conversa como lida pelo autor, soma uma não lida para os demais e troca a ultima mensagem
+func registrarEnvio(conversa *Conversa, mensagem Mensagem) {
+	for i := range conversa.Leituras {
+		if conversa.Leituras[i].UsuarioID == mensagem.AutorID {
+			conversa.Leituras[i].Data = mensagem.DataCriacao
+			conversa.Leituras[i].NaoLidas = 0
+		} else {
+			conversa.Leituras[i].NaoLidas++
+		}
+	}
+	conversa.UltimaMensagem = resumo(mensagem.Conteudo)
+	conversa.DataUltimaMensagem = mensagem.DataCriacao
+}
+
+// publicarMensagem avisa em tempo real os demais participantes da conversa. tipo diz se a mensagem é nova,
+// editada ou excluida.
+func publicarMensagem(c context.Context, conversa *Conversa, tipo string, mensagem *Mensagem) {
+	for _, id := range conversa.Participantes {
+		if id != mensagem.AutorID {
+			eventos.Publicar(c, eventos.CanalUsuario(id), tipo, mensagem)
+		}
+	}
+}
+
+func resumo(conteudo string) string {
+	runas := []rune(strings.Join(strings.Fields(conteudo), " "))
+	if len(runas) <= tamanhoResumo {
+		return string(runas)
+	}
+	return string(runas[:tamanhoResumo]) + "..."
+}
+
+// ListarMensagens traz as ultimas mensagens da conversa em ordem cronologica, com quem já leu cada uma.
+// Para paginar, antesID é a mensagem mais antiga que o cliente já tem; vem a pagina anterior a ela.
+func ListarMensagens(c context.Context, conversaID, usuarioID, antesID int64, limite int) ([]Mensagem, error) {
+	if limite <= 0 {
+		limite = LimitePadrao
+	}
+	if limite > LimiteMaximo {
+		limite = LimiteMaximo
+	}
+
+	conversa, err := BuscarConversa(c, conversaID, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+
+	var antes time.Time
+	if antesID != 0 {
+		anterior := GetMensagem(c, antesID)
+		if anterior == nil || anterior.ConversaID != conversaID {
+			return nil, fmt.Errorf("Mensagem não encontrada")
+		}
+		antes = anterior.DataCriacao.Time
+	}
+
+	mensagens, err := FiltrarMensagens(c, conversaID, antes, limite)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(mensagens, func(i, j int) bool {
+		return mensagens[i].DataCriacao.Before(mensagens[j].DataCriacao.Time)
+	})
+
+	for i := range mensagens {
+		mensagens[i].LidaPor = lidaPor(conversa, mensagens[i])
+	}
+	return mensagens, nil
+}
+
+// lidaPor retorna os participantes, fora o autor, que leram a conversa depois da mensagem
+func lidaPor(conversa *Conversa, mensagem Mensagem) []int64 {
+	var ids []int64
+	for _, l := range conversa.Leituras {
+		if l.UsuarioID == mensagem.AutorID {
+			continue
+		}
+		if !l.Data.Before(mensagem.DataCriacao.Time) {
+			ids = append(ids, l.UsuarioID)
+		}
+	}
+	return ids
+}
+
+// buscarMensagemAutor traz a mensagem garantindo que foi escrita pelo usuario
+func buscarMensagemAutor(c context.Context, mensagemID, usuarioID int64) (*Mensagem, error) {
+	mensagem := GetMensagem(c, mensagemID)
+	if mensagem == nil {
+		return nil, fmt.Errorf("Mensagem não encontrada")
+	}
+	if mensagem.AutorID != usuarioID {
+		return nil, fmt.Errorf("Somente o autor pode alterar a mensagem")
+	}
+	if mensagem.Excluida {
+		return nil, fmt.Errorf("A mensagem foi excluida")
+	}
+	return mensagem, nil
+}
+
+// EditarMensagem troca o conteudo de uma mensagem do proprio usuario
+func EditarMensagem(c context.Context, mensagemID, usuarioID int64, conteudo string) (*Mensagem, error) {
+	mensagem, err := buscarMensagemAutor(c, mensagemID, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+
+	conteudo, err = validarConteudo(conteudo)
+	if err != nil {
+		return nil, err
+	}
+
+	mensagem.Conteudo = conteudo
+	mensagem.Editada = true
+	mensagem.DataEdicao = utils.GetSpecialTimeNow()
+
+	if err := PutMensagem(c, mensagem); err != nil {
+		return nil, err
+	}
+
+	atualizarUltimaMensagem(c, mensagem, resumo(conteudo), eventos.TipoMensagemEditada)
+	return mensagem, nil
+}
+
+// ExcluirMensagem apaga o conteudo da mensagem, mantendo o registro para a conversa não perder a sequencia
+func ExcluirMensagem(c context.Context, mensagemID, usuarioID int64) error {
+	mensagem, err := buscarMensagemAutor(c, mensagemID, usuarioID)
+	if err != nil {
+		return err
+	}
+
+	mensagem.Conteudo = ""
+	mensagem.Excluida = true
+	mensagem.DataEdicao = utils.GetSpecialTimeNow()
+
+	if err := PutMensagem(c, mensagem); err != nil {
+		return err
+	}
+
+	atualizarUltimaMensagem(c, mensagem, resumoExcluida, eventos.TipoMensagemExcluida)
+	return nil
+}
+
+// atualizarUltimaMensagem troca o resumo da conversa quando a mensagem alterada é a ultima dela e avisa os
+// demais participantes. A conferencia é feita dentro da transação para não sobrescrever uma mensagem enviada
+// nesse meio tempo.
+func atualizarUltimaMensagem(c context.Context, mensagem *Mensagem, texto string, tipo string) {
+	var atualizada *Conversa
+	err := atualizarConversa(c, mensagem.ConversaID, func(conversa *Conversa) error {
+		if ehUltimaMensagem(conversa, mensagem) {
+			conversa.UltimaMensagem = texto
+		}
+		atualizada = conversa
+		return nil
+	})
+	if err != nil {
+		log.Warningf(c, "Mensagem %d alterada, mas o resumo da conversa não foi atualizado: %v", mensagem.ID, err)
+		atualizada = GetConversa(c, mensagem.ConversaID)
+	}
+	if atualizada != nil {
+		publicarMensagem(c, atualizada, tipo, mensagem)
+	}
+}
+
+func ehUltimaMensagem(conversa *Conversa, mensagem *Mensagem) bool {
+	return !mensagem.DataCriacao.IsZero() && conversa.DataUltimaMensagem.Equal(mensagem.DataCriacao.Time)
+}
@@ -0,0 +1,145 @@
+package mensagem
+
+import (
+	"reflect"
+	"site/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func data(minuto int) utils.JsonSpecialDateTime {
+	return utils.JsonSpecialDateTime{Time: time.Date(2021, 6, 1, 12, minuto, 0, 0, time.UTC)}
+}
+
+func TestResumo(t *testing.T) {
+	longo := strings.Repeat("á", tamanhoResumo+5)
+
+	casos := []struct {
+		conteudo, esperado string
+	}{
+		{"oi", "oi"},
+		{"  várias   linhas\n\ne\tespaços  ", "várias linhas e espaços"},
+		{longo, strings.Repeat("á", tamanhoResumo) + "..."},
+		{strings.Repeat("b", tamanhoResumo), strings.Repeat("b", tamanhoResumo)},
+		{"", ""},
+	}
+	for _, caso := range casos {
+		if obtido := resumo(caso.conteudo); obtido != caso.esperado {
+			t.Errorf("resumo(%q) = %q, esperado %q", caso.conteudo, obtido, caso.esperado)
+		}
+	}
+}
+
+func TestLidaPor(t *testing.T) {
+	conversa := &Conversa{Leituras: []Leitura{
+		{UsuarioID: 1, Data: data(10)},
+		{UsuarioID: 2, Data: data(5)},
+		{UsuarioID: 3, Data: data(20)},
+	}}
+
+	casos := []struct {
+		nome     string
+		mensagem Mensagem
+		esperado []int64
+	}{
+		{"antiga, lida por todos", Mensagem{AutorID: 1, DataCriacao: data(1)}, []int64{2, 3}},
+		{"lida no mesmo instante conta", Mensagem{AutorID: 3, DataCriacao: data(5)}, []int64{1, 2}},
+		{"depois da leitura do 2", Mensagem{AutorID: 1, DataCriacao: data(8)}, []int64{3}},
+		{"ninguém leu ainda", Mensagem{AutorID: 3, DataCriacao: data(30)}, nil},
+		{"autor não entra na lista", Mensagem{AutorID: 2, DataCriacao: data(15)}, []int64{3}},
+	}
+	for _, caso := range casos {
+		if obtido := lidaPor(conversa, caso.mensagem); !reflect.DeepEqual(obtido, caso.esperado) {
+			t.Errorf("%s: lidaPor() = %v, esperado %v", caso.nome, obtido, caso.esperado)
+		}
+	}
+}
+
+func TestContagemNaoLidas(t *testing.T) {
+	conversa := Conversa{Leituras: []Leitura{{UsuarioID: 1}, {UsuarioID: 2}, {UsuarioID: 3}}}
+
+	registrarEnvio(&conversa, Mensagem{AutorID: 1, Conteudo: "primeira", DataCriacao: data(1)})
+	registrarEnvio(&conversa, Mensagem{AutorID: 1, Conteudo: "segunda", DataCriacao: data(2)})
+	registrarEnvio(&conversa, Mensagem{AutorID: 2, Conteudo: "  resposta\n do 2 ", DataCriacao: data(3)})
+
+	esperadas := map[int64]int64{1: 1, 2: 0, 3: 3}
+	for usuarioID, naoLidas := range esperadas {
+		if obtido := conversa.Leitura(usuarioID).NaoLidas; obtido != naoLidas {
+			t.Errorf("usuario %d com %d não lidas, esperado %d", usuarioID, obtido, naoLidas)
+		}
+	}
+	if conversa.UltimaMensagem != "resposta do 2" || !conversa.DataUltimaMensagem.Equal(data(3).Time) {
+		t.Errorf("ultima mensagem %q em %v", conversa.UltimaMensagem, conversa.DataUltimaMensagem)
+	}
+	if !conversa.Leitura(2).Data.Equal(data(3).Time) {
+		t.Errorf("o envio deveria marcar a conversa como lida pelo autor")
+	}
+
+	marcarLeitura(&conversa, 3, data(4))
+	if leitura := conversa.Leitura(3); leitura.NaoLidas != 0 || !leitura.Data.Equal(data(4).Time) {
+		t.Errorf("marcarLeitura() = %+v", leitura)
+	}
+	if conversa.Leitura(1).NaoLidas != 1 {
+		t.Errorf("marcarLeitura() alterou outro participante")
+	}
+
+	outra := Conversa{Leituras: []Leitura{{UsuarioID: 1, NaoLidas: 4}, {UsuarioID: 3, NaoLidas: 2}}}
+	conversas := []Conversa{conversa, outra, {}}
+	casos := map[int64]int64{1: 5, 2: 0, 3: 2, 99: 0}
+	for usuarioID, total := range casos {
+		if obtido := somarNaoLidas(conversas, usuarioID); obtido != total {
+			t.Errorf("somarNaoLidas(%d) = %d, esperado %d", usuarioID, obtido, total)
+		}
+	}
+}
+
+func TestEhUltimaMensagem(t *testing.T) {
+	conversa := &Conversa{DataUltimaMensagem: data(10)}
+
+	casos := []struct {
+		nome     string
+		mensagem Mensagem
+		ultima   bool
+	}{
+		{"mesma data", Mensagem{DataCriacao: data(10)}, true},
+		{"mesmo instante em outro fuso", Mensagem{DataCriacao: utils.JsonSpecialDateTime{Time: data(10).In(time.FixedZone("BRT", -3*3600))}}, true},
+		{"anterior", Mensagem{DataCriacao: data(9)}, false},
+		{"sem data", Mensagem{}, false},
+	}
+	for _, caso := range casos {
+		if obtido := ehUltimaMensagem(conversa, &caso.mensagem); obtido != caso.ultima {
+			t.Errorf("%s: ehUltimaMensagem() = %v, esperado %v", caso.nome, obtido, caso.ultima)
+		}
+	}
+
+	if ehUltimaMensagem(&Conversa{}, &Mensagem{}) {
+		t.Errorf("conversa sem mensagens não tem ultima mensagem")
+	}
+}
+
+func TestVerificarContato(t *testing.T) {
+	casos := []struct {
+		nome             string
+		bloqueado, segue bool
+		permitido        bool
+		consultaSeguidor bool
+	}{
+		{"seguem um ao outro", false, true, true, true},
+		{"não se seguem", false, false, false, true},
+		{"bloqueado", true, true, false, false},
+		{"bloqueado e sem seguir", true, false, false, false},
+	}
+	for _, caso := range casos {
+		consultou := false
+		err := verificarContato(2,
+			func() bool { return caso.bloqueado },
+			func() bool { consultou = true; return caso.segue })
+		if (err == nil) != caso.permitido {
+			t.Errorf("%s: verificarContato() = %v, esperado permitido = %v", caso.nome, err, caso.permitido)
+		}
+		if consultou != caso.consultaSeguidor {
+			t.Errorf("%s: consulta de seguidores feita = %v, esperado %v", caso.nome, consultou, caso.consultaSeguidor)
+		}
+	}
+}
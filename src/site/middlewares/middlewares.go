@@ -3,11 +3,13 @@ package middlewares
 import (
 	"net/http"
 	"site/autenticacao"
+	"site/moderacao"
+	"site/usuario"
 	"site/utils"
 	"site/utils/log"
 )
 
-// Autenticar verifica se o usuario fazendo a requisição está autenticado
+// Autenticar verifica se o usuario fazendo a requisição está autenticado e não está suspenso nem banido
 func Autenticar(proximaFuncao http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		c := r.Context()
@@ -16,6 +18,32 @@ func Autenticar(proximaFuncao http.HandlerFunc) http.HandlerFunc {
 			utils.RespondWithError(w, http.StatusUnauthorized, 0, "Erro ao validar Token")
 			return
 		}
+
+		usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+		if err != nil {
+			log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+			utils.RespondWithError(w, http.StatusUnauthorized, 0, "Erro ao extrair usuarioID do token")
+			return
+		}
+		if sancao := usuario.SancaoAtiva(c, usuarioID); sancao != nil {
+			log.Warningf(c, "Usuario %d com sanção ativa tentou acessar a API", usuarioID)
+			utils.RespondWithError(w, http.StatusForbidden, 0, sancao.Mensagem())
+			return
+		}
 		proximaFuncao(w, r)
 	}
 }
+
+// AutenticarModerador verifica, alem da autenticação, se o usuario é moderador
+func AutenticarModerador(proximaFuncao http.HandlerFunc) http.HandlerFunc {
+	return Autenticar(func(w http.ResponseWriter, r *http.Request) {
+		c := r.Context()
+		usuarioID, _ := autenticacao.ExtrairUsuarioID(r)
+		if !moderacao.EhModerador(c, usuarioID) {
+			log.Warningf(c, "Usuario %d não é moderador", usuarioID)
+			utils.RespondWithError(w, http.StatusForbidden, 0, "Acesso restrito aos moderadores")
+			return
+		}
+		proximaFuncao(w, r)
+	})
+}
@@ -0,0 +1,168 @@
+package moderacao
+
+import (
+	"context"
+	"fmt"
+	"site/config"
+	"site/publicacao"
+	"site/usuario"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AcaoDescartar = "descartar"
+	AcaoOcultar   = "ocultar"
+	AcaoSuspender = "suspender"
+	AcaoBanir     = "banir"
+	AcaoRevogar   = "revogar"
+
+	diasMaximoSuspensao = 365
+)
+
+// Decisao é o que o moderador resolveu fazer com uma denuncia. Dias só vale para suspender.
+type Decisao struct {
+	Acao       string
+	Dias       int64
+	Observacao string
+}
+
+func (decisao *Decisao) validar(denuncia *Denuncia) error {
+	switch decisao.Acao {
+	case AcaoDescartar, AcaoBanir:
+	case AcaoOcultar:
+		if denuncia.TipoAlvo == AlvoPerfil {
+			return fmt.Errorf("Perfis não podem ser ocultados, use suspender ou banir")
+		}
+	case AcaoSuspender:
+		if decisao.Dias < 1 || decisao.Dias > diasMaximoSuspensao {
+			return fmt.Errorf("A suspensão deve ser de 1 a %d dias", diasMaximoSuspensao)
+		}
+	default:
+		return fmt.Errorf("Ação inválida: %v", decisao.Acao)
+	}
+	return nil
+}
+
+// EhModerador diz se o usuario está na lista de moderadores, guardada na config
+// moderacao.moderadores como ids separados por virgula
+func EhModerador(c context.Context, usuarioID int64) bool {
+	moderadores := config.GetDefault(c, config.ModeracaoModeradores, "").Value
+	for _, campo := range strings.Split(moderadores, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(campo), 10, 64)
+		if err == nil && id == usuarioID {
+			return true
+		}
+	}
+	return false
+}
+
+// Moderar aplica a decisão e fecha a denuncia junto com as outras denuncias pendentes do mesmo alvo,
+// para o moderador não precisar decidir o mesmo caso varias vezes. A ação fica na auditoria.
+func Moderar(c context.Context, denunciaID, moderadorID int64, decisao Decisao) (*Denuncia, error) {
+	denuncia := GetDenuncia(c, denunciaID)
+	if denuncia == nil {
+		return nil, fmt.Errorf("Denuncia não encontrada")
+	}
+	if denuncia.Status != StatusPendente {
+		return nil, fmt.Errorf("A denuncia já foi analisada")
+	}
+	if denuncia.AutorAlvoID == moderadorID {
+		return nil, fmt.Errorf("Um moderador não pode decidir denuncias sobre o proprio conteudo")
+	}
+	if err := decisao.validar(denuncia); err != nil {
+		return nil, err
+	}
+
+	if err := aplicar(c, denuncia, moderadorID, decisao); err != nil {
+		return nil, err
+	}
+
+	status := StatusResolvida
+	if decisao.Acao == AcaoDescartar {
+		status = StatusDescartada
+	}
+
+	pendentes, err := FiltrarDenuncias(c, Filtro{Status: StatusPendente, TipoAlvo: denuncia.TipoAlvo, AlvoID: denuncia.AlvoID})
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar denuncias pendentes do alvo %s %d: %v", denuncia.TipoAlvo, denuncia.AlvoID, err)
+		pendentes = []Denuncia{*denuncia}
+	}
+
+	agora := utils.GetSpecialTimeNow()
+	for i := range pendentes {
+		pendentes[i].Status = status
+		pendentes[i].ModeradorID = moderadorID
+		pendentes[i].Acao = decisao.Acao
+		pendentes[i].DataResolucao = agora
+		if err := PutDenuncia(c, &pendentes[i]); err != nil {
+			log.Warningf(c, "Falha ao fechar denuncia %d: %v", pendentes[i].ID, err)
+		}
+		if pendentes[i].ID == denuncia.ID {
+			denuncia = &pendentes[i]
+		}
+	}
+
+	auditar(c, RegistroAuditoria{
+		ModeradorID:      moderadorID,
+		Acao:             decisao.Acao,
+		DenunciaID:       denuncia.ID,
+		TipoAlvo:         denuncia.TipoAlvo,
+		AlvoID:           denuncia.AlvoID,
+		UsuarioAfetadoID: denuncia.AutorAlvoID,
+		Dias:             decisao.Dias,
+		Observacao:       decisao.Observacao,
+	})
+	return denuncia, nil
+}
+
+// aplicar executa a ação sobre o conteudo ou a conta denunciada
+func aplicar(c context.Context, denuncia *Denuncia, moderadorID int64, decisao Decisao) error {
+	switch decisao.Acao {
+	case AcaoOcultar:
+		_, err := publicacao.Ocultar(c, denuncia.AlvoID)
+		return err
+
+	case AcaoSuspender, AcaoBanir:
+		if atual := usuario.GetSancao(c, denuncia.AutorAlvoID); atual != nil && atual.Banido {
+			return fmt.Errorf("O usuario já está banido")
+		}
+
+		sancao := usuario.Sancao{
+			UsuarioID:   denuncia.AutorAlvoID,
+			Banido:      decisao.Acao == AcaoBanir,
+			Motivo:      denuncia.Motivo,
+			ModeradorID: moderadorID,
+			DataCriacao: utils.GetSpecialTimeNow(),
+		}
+		if !sancao.Banido {
+			sancao.SuspensoAte = utils.GetTimeNow().Add(time.Duration(decisao.Dias) * 24 * time.Hour)
+		}
+		return usuario.PutSancao(c, &sancao)
+	}
+	return nil
+}
+
+// RevogarSancao encerra antes da hora a suspensão ou o banimento do usuario
+func RevogarSancao(c context.Context, usuarioID, moderadorID int64, observacao string) error {
+	if usuario.GetSancao(c, usuarioID) == nil {
+		return fmt.Errorf("O usuario não possui suspensão nem banimento")
+	}
+
+	if err := usuario.RemoverSancao(c, usuarioID); err != nil {
+		return fmt.Errorf("Erro ao revogar sanção")
+	}
+
+	auditar(c, RegistroAuditoria{
+		ModeradorID:      moderadorID,
+		Acao:             AcaoRevogar,
+		TipoAlvo:         AlvoPerfil,
+		AlvoID:           usuarioID,
+		UsuarioAfetadoID: usuarioID,
+		Observacao:       observacao,
+	})
+	return nil
+}
@@ -0,0 +1,81 @@
+package moderacao
+
+import (
+	"context"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindAuditoria = "AuditoriaModeracao"
+)
+
+// RegistroAuditoria guarda cada ação de moderação: quem fez, o que fez e sobre quem. Os registros
+// nunca são alterados nem apagados.
+type RegistroAuditoria struct {
+	ID               int64 `datastore:"-"`
+	ModeradorID      int64
+	Acao             string
+	DenunciaID       int64
+	TipoAlvo         string
+	AlvoID           int64
+	UsuarioAfetadoID int64
+	Dias             int64  `datastore:",noindex"`
+	Observacao       string `datastore:",noindex"`
+	DataCriacao      utils.JsonSpecialDateTime
+}
+
+// auditar grava o registro. Uma falha aqui é só logada para não desfazer a ação já aplicada.
+func auditar(c context.Context, registro RegistroAuditoria) {
+	registro.DataCriacao = utils.GetSpecialTimeNow()
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IncompleteKey(KindAuditoria, nil)
+	if _, err = datastoreClient.Put(c, key, &registro); err != nil {
+		log.Errorf(c, "Falha ao gravar auditoria da ação %s do moderador %d: %v", registro.Acao, registro.ModeradorID, err)
+	}
+}
+
+// BuscarAuditoria traz os registros mais recentes primeiro. Com moderadorID ou usuarioID diferentes
+// de zero traz só as ações daquele moderador ou sobre aquele usuario.
+func BuscarAuditoria(c context.Context, moderadorID, usuarioID int64) ([]RegistroAuditoria, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	q := datastore.NewQuery(KindAuditoria)
+	if moderadorID != 0 {
+		q = q.Filter("ModeradorID =", moderadorID)
+	}
+	if usuarioID != 0 {
+		q = q.Filter("UsuarioAfetadoID =", usuarioID)
+	}
+
+	registros := make([]RegistroAuditoria, 0)
+	keys, err := datastoreClient.GetAll(c, q, &registros)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar auditoria de moderação: %v", err)
+		return nil, err
+	}
+	for i := range keys {
+		registros[i].ID = keys[i].ID
+	}
+
+	sort.Slice(registros, func(i, j int) bool {
+		return registros[i].DataCriacao.After(registros[j].DataCriacao.Time)
+	})
+	return registros, nil
+}
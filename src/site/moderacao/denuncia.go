@@ -0,0 +1,247 @@
+package moderacao
+
+import (
+	"context"
+	"fmt"
+	"site/publicacao"
+	"site/usuario"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindDenuncias = "Denuncias"
+
+	AlvoPublicacao = "publicacao"
+	AlvoComentario = "comentario"
+	AlvoPerfil     = "perfil"
+
+	MotivoSpam            = "spam"
+	MotivoAssedio         = "assedio"
+	MotivoDiscursoOdio    = "discurso_odio"
+	MotivoViolencia       = "violencia"
+	MotivoConteudoSexual  = "conteudo_sexual"
+	MotivoInformacaoFalsa = "informacao_falsa"
+	MotivoFalsaIdentidade = "falsa_identidade"
+	MotivoOutro           = "outro"
+
+	StatusPendente   = "pendente"
+	StatusDescartada = "descartada"
+	StatusResolvida  = "resolvida"
+
+	tamanhoMaximoDetalhes = 1000
+)
+
+// Alvos lista o que pode ser denunciado. Comentarios são as citações, publicações com comentario
+// sobre outra publicação.
+var Alvos = []string{AlvoPublicacao, AlvoComentario, AlvoPerfil}
+
+// Motivos lista os codigos de motivo aceitos nas denuncias, com a descrição mostrada ao usuario
+var Motivos = map[string]string{
+	MotivoSpam:            "Spam ou propaganda enganosa",
+	MotivoAssedio:         "Assédio ou bullying",
+	MotivoDiscursoOdio:    "Discurso de ódio",
+	MotivoViolencia:       "Violência ou ameaça",
+	MotivoConteudoSexual:  "Conteúdo sexual",
+	MotivoInformacaoFalsa: "Informação falsa",
+	MotivoFalsaIdentidade: "Perfil falso ou se passando por outra pessoa",
+	MotivoOutro:           "Outro motivo",
+}
+
+// Denuncia feita por um usuario sobre uma publicação, um comentario ou um perfil. AutorAlvoID é o
+// dono do conteudo denunciado, quem sofre a suspensão ou o banimento.
+type Denuncia struct {
+	ID            int64 `datastore:"-"`
+	TipoAlvo      string
+	AlvoID        int64
+	AutorAlvoID   int64
+	DenuncianteID int64
+	Motivo        string
+	Detalhes      string `datastore:",noindex"`
+	Status        string
+	ModeradorID   int64
+	Acao          string
+	DataCriacao   utils.JsonSpecialDateTime
+	DataResolucao utils.JsonSpecialDateTime
+}
+
+// Filtro da fila de moderação. Campos vazios não filtram.
+type Filtro struct {
+	Status      string
+	TipoAlvo    string
+	Motivo      string
+	AlvoID      int64
+	AutorAlvoID int64
+}
+
+// validar confere o tipo de alvo, o motivo e o tamanho dos detalhes
+func (denuncia *Denuncia) validar() error {
+	if !utils.InArray(denuncia.TipoAlvo, Alvos) {
+		return fmt.Errorf("Tipo de alvo inválido: %v", denuncia.TipoAlvo)
+	}
+	if _, ok := Motivos[denuncia.Motivo]; !ok {
+		return fmt.Errorf("Motivo inválido: %v", denuncia.Motivo)
+	}
+	if denuncia.AlvoID == 0 {
+		return fmt.Errorf("Alvo da denuncia não informado")
+	}
+
+	denuncia.Detalhes = strings.TrimSpace(denuncia.Detalhes)
+	if len([]rune(denuncia.Detalhes)) > tamanhoMaximoDetalhes {
+		return fmt.Errorf("Os detalhes devem ter no maximo %d caracteres", tamanhoMaximoDetalhes)
+	}
+	if denuncia.Motivo == MotivoOutro && denuncia.Detalhes == "" {
+		return fmt.Errorf("Descreva o motivo da denuncia")
+	}
+	return nil
+}
+
+// autorDoAlvo confere se o alvo existe e é visivel para quem denuncia e devolve o dono dele
+func autorDoAlvo(c context.Context, denuncia *Denuncia) (int64, error) {
+	if denuncia.TipoAlvo == AlvoPerfil {
+		if usuario.GetUsuario(c, denuncia.AlvoID) == nil {
+			return 0, fmt.Errorf("Usuario não encontrado")
+		}
+		return denuncia.AlvoID, nil
+	}
+
+	public := publicacao.GetPublicacao(c, denuncia.AlvoID)
+	if public == nil || !publicacao.NovoLeitor(c, denuncia.DenuncianteID).PodeVer(public) {
+		return 0, fmt.Errorf("Publicação não encontrada")
+	}
+
+	citacao := public.Repost() && public.Conteudo != ""
+	if denuncia.TipoAlvo == AlvoComentario && !citacao {
+		return 0, fmt.Errorf("A publicação informada não é um comentario")
+	}
+	if denuncia.TipoAlvo == AlvoPublicacao && public.Repost() && !citacao {
+		//Repost sem comentario não tem conteudo proprio, a denuncia vai para a original
+		denuncia.AlvoID = public.OriginalID
+		if public = publicacao.GetPublicacao(c, public.OriginalID); public == nil {
+			return 0, fmt.Errorf("Publicação não encontrada")
+		}
+	}
+	return public.AutorID, nil
+}
+
+// Denunciar registra a denuncia na fila de moderação. Cada usuario só tem uma denuncia pendente
+// por alvo; denunciar de novo atualiza o motivo e os detalhes.
+func Denunciar(c context.Context, denuncia Denuncia) (*Denuncia, error) {
+	if err := denuncia.validar(); err != nil {
+		return nil, err
+	}
+
+	autorID, err := autorDoAlvo(c, &denuncia)
+	if err != nil {
+		return nil, err
+	}
+	if autorID == denuncia.DenuncianteID {
+		return nil, fmt.Errorf("Não é possivel denunciar o proprio conteudo")
+	}
+
+	pendentes, err := FiltrarDenuncias(c, Filtro{Status: StatusPendente, TipoAlvo: denuncia.TipoAlvo, AlvoID: denuncia.AlvoID})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pendentes {
+		if p.DenuncianteID == denuncia.DenuncianteID {
+			denuncia.ID = p.ID
+			denuncia.DataCriacao = p.DataCriacao
+		}
+	}
+
+	denuncia.AutorAlvoID = autorID
+	denuncia.Status = StatusPendente
+	denuncia.ModeradorID = 0
+	denuncia.Acao = ""
+	if denuncia.DataCriacao.IsZero() {
+		denuncia.DataCriacao = utils.GetSpecialTimeNow()
+	}
+
+	if err = PutDenuncia(c, &denuncia); err != nil {
+		return nil, fmt.Errorf("Erro ao registrar denuncia")
+	}
+	return &denuncia, nil
+}
+
+func GetDenuncia(c context.Context, id int64) *Denuncia {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil
+	}
+	defer datastoreClient.Close()
+
+	var denuncia Denuncia
+	if err = datastoreClient.Get(c, datastore.IDKey(KindDenuncias, id, nil), &denuncia); err != nil {
+		log.Warningf(c, "Falha ao buscar denuncia %d: %v", id, err)
+		return nil
+	}
+	denuncia.ID = id
+	return &denuncia
+}
+
+func PutDenuncia(c context.Context, denuncia *Denuncia) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindDenuncias, denuncia.ID, nil)
+	key, err = datastoreClient.Put(c, key, denuncia)
+	if err != nil {
+		log.Warningf(c, "Erro ao gravar denuncia: %v", err)
+		return err
+	}
+	denuncia.ID = key.ID
+	return nil
+}
+
+// FiltrarDenuncias traz a fila de moderação, das denuncias mais antigas para as mais recentes
+func FiltrarDenuncias(c context.Context, filtro Filtro) ([]Denuncia, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	q := datastore.NewQuery(KindDenuncias)
+	if filtro.Status != "" {
+		q = q.Filter("Status =", filtro.Status)
+	}
+	if filtro.TipoAlvo != "" {
+		q = q.Filter("TipoAlvo =", filtro.TipoAlvo)
+	}
+	if filtro.Motivo != "" {
+		q = q.Filter("Motivo =", filtro.Motivo)
+	}
+	if filtro.AlvoID != 0 {
+		q = q.Filter("AlvoID =", filtro.AlvoID)
+	}
+	if filtro.AutorAlvoID != 0 {
+		q = q.Filter("AutorAlvoID =", filtro.AutorAlvoID)
+	}
+
+	denuncias := make([]Denuncia, 0)
+	keys, err := datastoreClient.GetAll(c, q, &denuncias)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar denuncias: %v", err)
+		return nil, err
+	}
+	for i := range keys {
+		denuncias[i].ID = keys[i].ID
+	}
+
+	sort.Slice(denuncias, func(i, j int) bool {
+		return denuncias[i].DataCriacao.Before(denuncias[j].DataCriacao.Time)
+	})
+	return denuncias, nil
+}
@@ -0,0 +1,56 @@
+package moderacao
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidarDenuncia(t *testing.T) {
+	casos := []struct {
+		nome     string
+		denuncia Denuncia
+		valida   bool
+	}{
+		{"publicacao", Denuncia{TipoAlvo: AlvoPublicacao, AlvoID: 1, Motivo: MotivoSpam}, true},
+		{"perfil", Denuncia{TipoAlvo: AlvoPerfil, AlvoID: 1, Motivo: MotivoFalsaIdentidade}, true},
+		{"outro com detalhes", Denuncia{TipoAlvo: AlvoComentario, AlvoID: 1, Motivo: MotivoOutro, Detalhes: "golpe"}, true},
+		{"outro sem detalhes", Denuncia{TipoAlvo: AlvoComentario, AlvoID: 1, Motivo: MotivoOutro, Detalhes: "   "}, false},
+		{"alvo desconhecido", Denuncia{TipoAlvo: "mensagem", AlvoID: 1, Motivo: MotivoSpam}, false},
+		{"motivo desconhecido", Denuncia{TipoAlvo: AlvoPerfil, AlvoID: 1, Motivo: "chato"}, false},
+		{"sem alvo", Denuncia{TipoAlvo: AlvoPerfil, Motivo: MotivoSpam}, false},
+		{"detalhes longos", Denuncia{TipoAlvo: AlvoPerfil, AlvoID: 1, Motivo: MotivoSpam, Detalhes: strings.Repeat("a", tamanhoMaximoDetalhes+1)}, false},
+	}
+
+	for _, caso := range casos {
+		if err := caso.denuncia.validar(); (err == nil) != caso.valida {
+			t.Errorf("%s: validar() = %v, esperado valida = %v", caso.nome, err, caso.valida)
+		}
+	}
+}
+
+func TestValidarDecisao(t *testing.T) {
+	publicacao := &Denuncia{TipoAlvo: AlvoPublicacao}
+	perfil := &Denuncia{TipoAlvo: AlvoPerfil}
+
+	casos := []struct {
+		nome     string
+		decisao  Decisao
+		denuncia *Denuncia
+		valida   bool
+	}{
+		{"descartar", Decisao{Acao: AcaoDescartar}, perfil, true},
+		{"ocultar publicacao", Decisao{Acao: AcaoOcultar}, publicacao, true},
+		{"ocultar perfil", Decisao{Acao: AcaoOcultar}, perfil, false},
+		{"suspender", Decisao{Acao: AcaoSuspender, Dias: 7}, perfil, true},
+		{"suspender sem dias", Decisao{Acao: AcaoSuspender}, perfil, false},
+		{"suspender demais", Decisao{Acao: AcaoSuspender, Dias: diasMaximoSuspensao + 1}, perfil, false},
+		{"banir", Decisao{Acao: AcaoBanir}, publicacao, true},
+		{"revogar não é decisão de denuncia", Decisao{Acao: AcaoRevogar}, perfil, false},
+	}
+
+	for _, caso := range casos {
+		if err := caso.decisao.validar(caso.denuncia); (err == nil) != caso.valida {
+			t.Errorf("%s: validar() = %v, esperado valida = %v", caso.nome, err, caso.valida)
+		}
+	}
+}
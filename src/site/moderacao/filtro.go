@@ -0,0 +1,66 @@
+package moderacao
+
+import (
+	"context"
+	"site/publicacao"
+	"site/utils"
+	"site/utils/log"
+	"strings"
+)
+
+// DenuncianteSistema é o denunciante das denuncias abertas pelo filtro de conteudo
+const DenuncianteSistema int64 = 0
+
+func init() {
+	publicacao.AoSinalizar = sinalizarPublicacao
+}
+
+// motivoViolacoes traduz as regras do filtro para os motivos de denuncia
+func motivoViolacoes(violacoes []publicacao.Violacao) string {
+	for _, v := range violacoes {
+		if v.Regra == publicacao.FiltroRepeticao || v.Regra == publicacao.FiltroRajada || v.Regra == publicacao.FiltroLinks {
+			return MotivoSpam
+		}
+	}
+	return MotivoOutro
+}
+
+// sinalizarPublicacao coloca na fila de moderação a publicação que o filtro de conteudo sinalizou.
+// Se já houver uma denuncia automatica pendente para ela, só os detalhes são atualizados.
+func sinalizarPublicacao(c context.Context, public *publicacao.Publicacao, violacoes []publicacao.Violacao) {
+	tipoAlvo := AlvoPublicacao
+	if public.Repost() {
+		tipoAlvo = AlvoComentario
+	}
+
+	detalhes := make([]string, len(violacoes))
+	for i, v := range violacoes {
+		detalhes[i] = v.Regra + ": " + v.Motivo
+	}
+
+	denuncia := Denuncia{
+		TipoAlvo:      tipoAlvo,
+		AlvoID:        public.ID,
+		AutorAlvoID:   public.AutorID,
+		DenuncianteID: DenuncianteSistema,
+		Motivo:        motivoViolacoes(violacoes),
+		Detalhes:      "Filtro automatico - " + strings.Join(detalhes, "; "),
+		Status:        StatusPendente,
+		DataCriacao:   utils.GetSpecialTimeNow(),
+	}
+
+	pendentes, err := FiltrarDenuncias(c, Filtro{Status: StatusPendente, TipoAlvo: tipoAlvo, AlvoID: public.ID})
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar denuncias da publicação %d sinalizada: %v", public.ID, err)
+	}
+	for _, p := range pendentes {
+		if p.DenuncianteID == DenuncianteSistema {
+			denuncia.ID = p.ID
+			denuncia.DataCriacao = p.DataCriacao
+		}
+	}
+
+	if err = PutDenuncia(c, &denuncia); err != nil {
+		log.Errorf(c, "Falha ao sinalizar publicação %d para moderação: %v", public.ID, err)
+	}
+}
@@ -0,0 +1,109 @@
+package notificacao
+
+import (
+	"fmt"
+	"site/utils"
+)
+
+// MaxOrigensGrupo é quantos usuarios aparecem nominalmente em um grupo
+const MaxOrigensGrupo = 3
+
+// Origem é o usuario que causou a notificação
+type Origem struct {
+	ID   int64
+	Nick string
+}
+
+// Grupo junta notificações do mesmo tipo sobre o mesmo alvo, como "joao e outras 5 pessoas curtiram sua publicação"
+type Grupo struct {
+	IDs          []int64
+	Tipo         string
+	PublicacaoID int64
+	Origens      []Origem
+	Total        int
+	Lida         bool
+	DataCriacao  utils.JsonSpecialDateTime
+	Texto        string
+}
+
+type chaveGrupo struct {
+	tipo         string
+	publicacaoID int64
+	lida         bool
+}
+
+// Agrupar recebe as notificações ordenadas da mais recente para a mais antiga e mantém essa ordem nos grupos.
+// Menções não são agrupadas, cada uma aponta para uma publicação diferente.
+func Agrupar(notificacoes []Notificacao) []Grupo {
+	var grupos []Grupo
+	indices := make(map[chaveGrupo]int)
+	origensVistas := make(map[chaveGrupo]map[int64]bool)
+
+	for _, n := range notificacoes {
+		chave := chaveGrupo{tipo: n.Tipo, publicacaoID: n.PublicacaoID, lida: n.Lida}
+
+		i, existe := indices[chave]
+		if !existe || n.Tipo == TipoMencao {
+			grupos = append(grupos, Grupo{
+				Tipo:         n.Tipo,
+				PublicacaoID: n.PublicacaoID,
+				Lida:         n.Lida,
+				DataCriacao:  n.DataCriacao,
+			})
+			i = len(grupos) - 1
+			indices[chave] = i
+			origensVistas[chave] = make(map[int64]bool)
+		}
+
+		grupo := &grupos[i]
+		grupo.IDs = append(grupo.IDs, n.ID)
+
+		if origensVistas[chave][n.OrigemID] {
+			continue
+		}
+		origensVistas[chave][n.OrigemID] = true
+		grupo.Total++
+		if len(grupo.Origens) < MaxOrigensGrupo {
+			grupo.Origens = append(grupo.Origens, Origem{ID: n.OrigemID, Nick: n.OrigemNick})
+		}
+	}
+
+	for i := range grupos {
+		grupos[i].Texto = textoGrupo(grupos[i])
+	}
+	return grupos
+}
+
+func textoGrupo(grupo Grupo) string {
+	var singular, plural string
+	switch grupo.Tipo {
+	case TipoSeguidor:
+		singular, plural = "começou a seguir você", "começaram a seguir você"
+	case TipoCurtida:
+		singular, plural = "curtiu sua publicação", "curtiram sua publicação"
+	case TipoMencao:
+		singular, plural = "mencionou você em uma publicação", "mencionaram você em uma publicação"
+	case TipoSolicitacao:
+		singular, plural = "pediu para seguir você", "pediram para seguir você"
+	case TipoAprovacao:
+		singular, plural = "aceitou seu pedido para seguir", "aceitaram seu pedido para seguir"
+	case TipoConvite:
+		singular, plural = "convidou você para a equipe de um estabelecimento", "convidaram você para a equipe de um estabelecimento"
+	default:
+		singular, plural = "interagiu com você", "interagiram com você"
+	}
+
+	if len(grupo.Origens) == 0 {
+		return ""
+	}
+	primeiro := grupo.Origens[0].Nick
+
+	switch grupo.Total {
+	case 1:
+		return fmt.Sprintf("%s %s", primeiro, singular)
+	case 2:
+		return fmt.Sprintf("%s e %s %s", primeiro, grupo.Origens[1].Nick, plural)
+	default:
+		return fmt.Sprintf("%s e outras %d pessoas %s", primeiro, grupo.Total-1, plural)
+	}
+}
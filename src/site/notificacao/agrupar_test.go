@@ -0,0 +1,70 @@
+package notificacao
+
+import "testing"
+
+func TestAgrupar(t *testing.T) {
+	notificacoes := []Notificacao{
+		{ID: 1, Tipo: TipoCurtida, PublicacaoID: 10, OrigemID: 2, OrigemNick: "ana"},
+		{ID: 2, Tipo: TipoSeguidor, OrigemID: 3, OrigemNick: "bia"},
+		{ID: 3, Tipo: TipoCurtida, PublicacaoID: 10, OrigemID: 3, OrigemNick: "bia"},
+		{ID: 4, Tipo: TipoCurtida, PublicacaoID: 10, OrigemID: 2, OrigemNick: "ana"},
+		{ID: 5, Tipo: TipoCurtida, PublicacaoID: 10, OrigemID: 4, OrigemNick: "caio"},
+		{ID: 6, Tipo: TipoCurtida, PublicacaoID: 10, OrigemID: 5, OrigemNick: "duda"},
+		{ID: 7, Tipo: TipoCurtida, PublicacaoID: 11, OrigemID: 5, OrigemNick: "duda"},
+		{ID: 8, Tipo: TipoMencao, PublicacaoID: 12, OrigemID: 2, OrigemNick: "ana"},
+		{ID: 9, Tipo: TipoMencao, PublicacaoID: 12, OrigemID: 2, OrigemNick: "ana"},
+		{ID: 10, Tipo: TipoCurtida, PublicacaoID: 10, OrigemID: 6, OrigemNick: "eva", Lida: true},
+	}
+
+	grupos := Agrupar(notificacoes)
+
+	esperados := []struct {
+		ids   int
+		total int
+		texto string
+	}{
+		{5, 4, "ana e outras 3 pessoas curtiram sua publicação"},
+		{1, 1, "bia começou a seguir você"},
+		{1, 1, "duda curtiu sua publicação"},
+		{1, 1, "ana mencionou você em uma publicação"},
+		{1, 1, "ana mencionou você em uma publicação"},
+		{1, 1, "eva curtiu sua publicação"},
+	}
+
+	if len(grupos) != len(esperados) {
+		t.Fatalf("Esperado %d grupos, obtido %d: %#v", len(esperados), len(grupos), grupos)
+	}
+	for i, esperado := range esperados {
+		g := grupos[i]
+		if len(g.IDs) != esperado.ids || g.Total != esperado.total || g.Texto != esperado.texto {
+			t.Errorf("Grupo %d: ids=%d total=%d texto=%q, esperado ids=%d total=%d texto=%q",
+				i, len(g.IDs), g.Total, g.Texto, esperado.ids, esperado.total, esperado.texto)
+		}
+	}
+
+	if len(grupos[0].Origens) != MaxOrigensGrupo {
+		t.Errorf("Grupo deveria listar %d origens, listou %d", MaxOrigensGrupo, len(grupos[0].Origens))
+	}
+}
+
+func TestTextoDuasOrigens(t *testing.T) {
+	grupos := Agrupar([]Notificacao{
+		{ID: 1, Tipo: TipoSeguidor, OrigemID: 2, OrigemNick: "ana"},
+		{ID: 2, Tipo: TipoSeguidor, OrigemID: 3, OrigemNick: "bia"},
+	})
+	if len(grupos) != 1 || grupos[0].Texto != "ana e bia começaram a seguir você" {
+		t.Errorf("Texto inesperado: %#v", grupos)
+	}
+}
+
+func TestPreferencias(t *testing.T) {
+	preferencias := Preferencias{Desativadas: []string{TipoCurtida}}
+
+	if preferencias.Ativa(TipoCurtida) {
+		t.Errorf("Curtidas deveriam estar desativadas")
+	}
+	mapa := preferencias.Mapa()
+	if !mapa[TipoSeguidor] || !mapa[TipoMencao] || mapa[TipoCurtida] {
+		t.Errorf("Mapa de preferencias inesperado: %v", mapa)
+	}
+}
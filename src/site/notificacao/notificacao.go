@@ -0,0 +1,312 @@
+package notificacao
+
+import (
+	"context"
+	"fmt"
+	"site/eventos"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindNotificacao = "Notificacao"
+
+	TipoSeguidor    = "seguidor"
+	TipoCurtida     = "curtida"
+	TipoMencao      = "mencao"
+	TipoSolicitacao = "solicitacao"
+	TipoAprovacao   = "aprovacao"
+	TipoConvite     = "convite"
+
+	LimitePadrao = 50
+	LimiteMaximo = 200
+
+	tamanhoLote = 500
+)
+
+// Tipos lista todos os tipos de notificação, usados também nas preferencias do usuario
+var Tipos = []string{TipoSeguidor, TipoCurtida, TipoMencao, TipoSolicitacao, TipoAprovacao, TipoConvite}
+
+// Notificacao avisa o usuario de uma ação feita por outro usuario
+type Notificacao struct {
+	ID           int64 `datastore:"-"`
+	UsuarioID    int64
+	Tipo         string
+	OrigemID     int64
+	OrigemNick   string
+	PublicacaoID int64
+	Lida         bool
+	DataCriacao  utils.JsonSpecialDateTime
+}
+
+func GetNotificacao(c context.Context, id int64) *Notificacao {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindNotificacao, id, nil)
+
+	var notificacao Notificacao
+	if err = datastoreClient.Get(c, key, &notificacao); err != nil {
+		log.Warningf(c, "Falha ao buscar notificação: %v", err)
+		return nil
+	}
+	notificacao.ID = id
+	return &notificacao
+}
+
+func GetMultNotificacao(c context.Context, keys []*datastore.Key) ([]Notificacao, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return []Notificacao{}, err
+	}
+	defer datastoreClient.Close()
+
+	notificacoes := make([]Notificacao, len(keys))
+	if err := datastoreClient.GetMulti(c, keys, notificacoes); err != nil {
+		if errs, ok := err.(datastore.MultiError); ok {
+			for _, e := range errs {
+				if e == datastore.ErrNoSuchEntity {
+					return []Notificacao{}, nil
+				}
+			}
+		}
+		log.Warningf(c, "Erro ao buscar Multi Notificações: %v", err)
+		return []Notificacao{}, err
+	}
+	for i := range keys {
+		notificacoes[i].ID = keys[i].ID
+	}
+	return notificacoes, nil
+}
+
+func PutNotificacao(c context.Context, notificacao *Notificacao) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindNotificacao, notificacao.ID, nil)
+	key, err = datastoreClient.Put(c, key, notificacao)
+	if err != nil {
+		log.Warningf(c, "Erro ao inserir notificação: %v", err)
+		return err
+	}
+	notificacao.ID = key.ID
+	return nil
+}
+
+func PutMultNotificacao(c context.Context, notificacoes []Notificacao) error {
+	if len(notificacoes) == 0 {
+		return nil
+	}
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	keys := make([]*datastore.Key, 0, len(notificacoes))
+	for _, n := range notificacoes {
+		keys = append(keys, datastore.IDKey(KindNotificacao, n.ID, nil))
+	}
+
+	// O Datastore aceita no maximo 500 entidades por PutMulti
+	for inicio := 0; inicio < len(keys); inicio += tamanhoLote {
+		fim := inicio + tamanhoLote
+		if fim > len(keys) {
+			fim = len(keys)
+		}
+		if _, err = datastoreClient.PutMulti(c, keys[inicio:fim], notificacoes[inicio:fim]); err != nil {
+			log.Warningf(c, "Erro ao inserir Multi Notificações: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// FiltrarNotificacoes busca as notificações do usuario. Com somenteNaoLidas traz apenas as que ainda não foram lidas.
+func FiltrarNotificacoes(c context.Context, usuarioID int64, somenteNaoLidas bool) ([]Notificacao, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	q := datastore.NewQuery(KindNotificacao).Filter("UsuarioID =", usuarioID)
+
+	if somenteNaoLidas {
+		q = q.Filter("Lida =", false)
+	}
+
+	q = q.KeysOnly()
+	keys, err := datastoreClient.GetAll(c, q, nil)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar notificações: %v", err)
+		return nil, err
+	}
+	return GetMultNotificacao(c, keys)
+}
+
+// buscarPagina traz até limite notificações do usuario, das mais recentes para as mais antigas.
+// Com antes preenchido, traz só as criadas antes dessa data.
+func buscarPagina(c context.Context, usuarioID int64, antes time.Time, limite int) ([]Notificacao, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	q := datastore.NewQuery(KindNotificacao).Filter("UsuarioID =", usuarioID)
+	if !antes.IsZero() {
+		q = q.Filter("DataCriacao.Time <", antes)
+	}
+	q = q.Order("-DataCriacao.Time").Limit(limite).KeysOnly()
+	keys, err := datastoreClient.GetAll(c, q, nil)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar notificações: %v", err)
+		return nil, err
+	}
+	return GetMultNotificacao(c, keys)
+}
+
+// Notificar grava uma nova notificação para o usuario. Ações do usuario sobre ele mesmo e
+// tipos desativados nas preferencias do destinatario não geram notificação.
+func Notificar(c context.Context, notificacao Notificacao) error {
+	if notificacao.UsuarioID == 0 || notificacao.UsuarioID == notificacao.OrigemID {
+		return nil
+	}
+
+	preferencias := GetPreferencias(c, notificacao.UsuarioID)
+	if !preferencias.Ativa(notificacao.Tipo) {
+		log.Debugf(c, "Usuario %d desativou notificações do tipo %s", notificacao.UsuarioID, notificacao.Tipo)
+		return nil
+	}
+
+	notificacao.ID = 0
+	notificacao.Lida = false
+	notificacao.DataCriacao = utils.GetSpecialTimeNow()
+
+	if err := PutNotificacao(c, &notificacao); err != nil {
+		return err
+	}
+
+	eventos.Publicar(c, eventos.CanalUsuario(notificacao.UsuarioID), eventos.TipoNotificacao, &notificacao)
+	return nil
+}
+
+// Listar traz uma pagina de até limite notificações do usuario, agrupadas, das mais recentes para as mais antigas.
+// antesID é o cursor devolvido pela pagina anterior, ou zero para a primeira. O cursor da proxima pagina é o id
+// da notificação mais antiga trazida, e vem zero quando não há mais notificações.
+func Listar(c context.Context, usuarioID, antesID int64, limite int) ([]Grupo, int64, error) {
+	if limite <= 0 {
+		limite = LimitePadrao
+	}
+	if limite > LimiteMaximo {
+		limite = LimiteMaximo
+	}
+
+	var antes time.Time
+	if antesID != 0 {
+		anterior := GetNotificacao(c, antesID)
+		if anterior == nil || anterior.UsuarioID != usuarioID {
+			return nil, 0, fmt.Errorf("Notificação não encontrada")
+		}
+		antes = anterior.DataCriacao.Time
+	}
+
+	notificacoes, err := buscarPagina(c, usuarioID, antes, limite)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(notificacoes, func(i, j int) bool {
+		return notificacoes[i].DataCriacao.After(notificacoes[j].DataCriacao.Time)
+	})
+
+	var proximaID int64
+	if len(notificacoes) == limite {
+		proximaID = notificacoes[len(notificacoes)-1].ID
+	}
+	return Agrupar(notificacoes), proximaID, nil
+}
+
+// ContarNaoLidas retorna quantas notificações o usuario ainda não leu
+func ContarNaoLidas(c context.Context, usuarioID int64) (int, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return 0, err
+	}
+	defer datastoreClient.Close()
+
+	q := datastore.NewQuery(KindNotificacao).
+		Filter("UsuarioID =", usuarioID).
+		Filter("Lida =", false)
+
+	total, err := datastoreClient.Count(c, q)
+	if err != nil {
+		log.Warningf(c, "Erro ao contar notificações não lidas: %v", err)
+		return 0, err
+	}
+	return total, nil
+}
+
+// MarcarLidas marca como lidas as notificações informadas, desde que pertençam ao usuario
+func MarcarLidas(c context.Context, usuarioID int64, ids []int64) error {
+	if len(ids) == 0 {
+		return fmt.Errorf("Nenhuma notificação informada")
+	}
+
+	keys := make([]*datastore.Key, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, datastore.IDKey(KindNotificacao, id, nil))
+	}
+
+	notificacoes, err := GetMultNotificacao(c, keys)
+	if err != nil {
+		return err
+	}
+	if len(notificacoes) != len(ids) {
+		return fmt.Errorf("Notificação não encontrada")
+	}
+
+	var alteradas []Notificacao
+	for _, n := range notificacoes {
+		if n.UsuarioID != usuarioID {
+			return fmt.Errorf("Notificação %d não pertence ao usuario", n.ID)
+		}
+		if !n.Lida {
+			n.Lida = true
+			alteradas = append(alteradas, n)
+		}
+	}
+	return PutMultNotificacao(c, alteradas)
+}
+
+// MarcarTodasLidas marca como lidas todas as notificações pendentes do usuario
+func MarcarTodasLidas(c context.Context, usuarioID int64) error {
+	notificacoes, err := FiltrarNotificacoes(c, usuarioID, true)
+	if err != nil {
+		return err
+	}
+
+	for i := range notificacoes {
+		notificacoes[i].Lida = true
+	}
+	return PutMultNotificacao(c, notificacoes)
+}
@@ -0,0 +1,95 @@
+package notificacao
+
+import (
+	"context"
+	"fmt"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindPreferencias = "PreferenciasNotificacao"
+)
+
+// Preferencias guarda os tipos de notificação que o usuario não quer receber. Tudo vem ativo por padrão.
+type Preferencias struct {
+	UsuarioID   int64 `datastore:"-"`
+	Desativadas []string
+}
+
+// GetPreferencias nunca retorna nil: sem registro gravado o usuario recebe todos os tipos
+func GetPreferencias(c context.Context, usuarioID int64) *Preferencias {
+	preferencias := Preferencias{UsuarioID: usuarioID}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return &preferencias
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindPreferencias, usuarioID, nil)
+	if err = datastoreClient.Get(c, key, &preferencias); err != nil && err != datastore.ErrNoSuchEntity {
+		log.Warningf(c, "Falha ao buscar preferencias de notificação do usuario %d: %v", usuarioID, err)
+	}
+	preferencias.UsuarioID = usuarioID
+	return &preferencias
+}
+
+func PutPreferencias(c context.Context, preferencias *Preferencias) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindPreferencias, preferencias.UsuarioID, nil)
+	if _, err = datastoreClient.Put(c, key, preferencias); err != nil {
+		log.Warningf(c, "Erro ao gravar preferencias de notificação: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (preferencias *Preferencias) Ativa(tipo string) bool {
+	return !utils.InArray(tipo, preferencias.Desativadas)
+}
+
+// Mapa devolve cada tipo de notificação com true quando ativo
+func (preferencias *Preferencias) Mapa() map[string]bool {
+	mapa := make(map[string]bool, len(Tipos))
+	for _, tipo := range Tipos {
+		mapa[tipo] = preferencias.Ativa(tipo)
+	}
+	return mapa
+}
+
+// AtualizarPreferencias aplica as alterações enviadas; tipos não informados mantêm o valor atual
+func AtualizarPreferencias(c context.Context, usuarioID int64, alteracoes map[string]bool) (*Preferencias, error) {
+	for tipo := range alteracoes {
+		if !utils.InArray(tipo, Tipos) {
+			return nil, fmt.Errorf("Tipo de notificação desconhecido: %s", tipo)
+		}
+	}
+
+	mapa := GetPreferencias(c, usuarioID).Mapa()
+	for tipo, ativa := range alteracoes {
+		mapa[tipo] = ativa
+	}
+
+	preferencias := Preferencias{UsuarioID: usuarioID}
+	for _, tipo := range Tipos {
+		if !mapa[tipo] {
+			preferencias.Desativadas = append(preferencias.Desativadas, tipo)
+		}
+	}
+
+	if err := PutPreferencias(c, &preferencias); err != nil {
+		return nil, err
+	}
+	return &preferencias, nil
+}
@@ -0,0 +1,112 @@
+package publicacao
+
+import (
+	"context"
+	"fmt"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindAfinidades = "AfinidadeAutor"
+
+	interacaoCurtida          = "curtida"
+	interacaoCompartilhamento = "compartilhamento"
+	interacaoCitacao          = "citacao"
+	interacaoMencao           = "mencao"
+)
+
+// Afinidade conta as interações de um usuario com as publicações de um autor e alimenta o feed por
+// relevância. A chave é "usuario:autor".
+type Afinidade struct {
+	UsuarioID         int64
+	AutorID           int64
+	Curtidas          int64 `datastore:",noindex"`
+	Compartilhamentos int64 `datastore:",noindex"`
+	Citacoes          int64 `datastore:",noindex"`
+	Mencoes           int64 `datastore:",noindex"`
+	DataAtualizacao   utils.JsonSpecialDateTime
+}
+
+// Pontos resume as interações em um numero. Citar e compartilhar pesam mais que curtir.
+func (afinidade *Afinidade) Pontos() float64 {
+	return float64(afinidade.Curtidas) + 2*float64(afinidade.Compartilhamentos+afinidade.Mencoes) + 3*float64(afinidade.Citacoes)
+}
+
+func chaveAfinidade(usuarioID, autorID int64) *datastore.Key {
+	return datastore.NameKey(KindAfinidades, fmt.Sprintf("%d:%d", usuarioID, autorID), nil)
+}
+
+// registrarInteracao soma delta ao contador da interação do usuario com o autor. Interações com as
+// proprias publicações não contam. Falhas só são logadas, a interação em si já aconteceu.
+func registrarInteracao(c context.Context, usuarioID, autorID int64, interacao string, delta int64) {
+	if usuarioID == autorID || usuarioID == 0 || autorID == 0 {
+		return
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return
+	}
+	defer datastoreClient.Close()
+
+	key := chaveAfinidade(usuarioID, autorID)
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		afinidade := Afinidade{UsuarioID: usuarioID, AutorID: autorID}
+		if err := tx.Get(key, &afinidade); err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+
+		var contador *int64
+		switch interacao {
+		case interacaoCurtida:
+			contador = &afinidade.Curtidas
+		case interacaoCompartilhamento:
+			contador = &afinidade.Compartilhamentos
+		case interacaoCitacao:
+			contador = &afinidade.Citacoes
+		case interacaoMencao:
+			contador = &afinidade.Mencoes
+		default:
+			return fmt.Errorf("Interação desconhecida: %v", interacao)
+		}
+		*contador += delta
+		if *contador < 0 {
+			*contador = 0
+		}
+		afinidade.DataAtualizacao = utils.GetSpecialTimeNow()
+
+		_, err := tx.Put(key, &afinidade)
+		return err
+	})
+	if err != nil {
+		log.Warningf(c, "Falha ao registrar %s do usuario %d com o autor %d: %v", interacao, usuarioID, autorID, err)
+	}
+}
+
+// BuscarAfinidades traz os pontos de afinidade do usuario com cada autor com quem já interagiu
+func BuscarAfinidades(c context.Context, usuarioID int64) (map[int64]float64, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	var afinidades []Afinidade
+	q := datastore.NewQuery(KindAfinidades).Filter("UsuarioID =", usuarioID)
+	if _, err = datastoreClient.GetAll(c, q, &afinidades); err != nil {
+		log.Warningf(c, "Erro ao buscar afinidades do usuario %d: %v", usuarioID, err)
+		return nil, err
+	}
+
+	pontos := make(map[int64]float64, len(afinidades))
+	for i := range afinidades {
+		pontos[afinidades[i].AutorID] = afinidades[i].Pontos()
+	}
+	return pontos, nil
+}
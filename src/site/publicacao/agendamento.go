@@ -0,0 +1,255 @@
+package publicacao
+
+import (
+	"context"
+	"fmt"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	StatusRascunho  = "rascunho"
+	StatusAgendada  = "agendada"
+	StatusPublicada = "publicada"
+
+	layoutAgendamento = "2006-01-02 15:04:05"
+)
+
+// IntervaloAgendador define quando o agendador procura publicações vencidas, no formato do utils.ValidaExecucao
+var IntervaloAgendador = utils.Intervalo{Minuto: "*", Hora: "*", DiaSemana: "*", DiaMes: "*"}
+
+var errJaPublicada = fmt.Errorf("A publicação já foi publicada")
+var errNaoVencida = fmt.Errorf("A publicação ainda não chegou no horario agendado")
+
+// Publicada diz se a publicação está visivel para os outros usuarios. Publicações gravadas antes
+// de existir o status não têm o campo preenchido e são consideradas publicadas.
+func (publicacao *Publicacao) Publicada() bool {
+	return publicacao.Status == "" || publicacao.Status == StatusPublicada
+}
+
+// Vencida diz se a publicação agendada já pode ser publicada
+func (publicacao *Publicacao) Vencida(agora time.Time) bool {
+	return publicacao.Status == StatusAgendada && !publicacao.DataAgendamento.After(agora)
+}
+
+// validar confere o texto da publicação. Rascunhos podem ficar incompletos, mas não vazios.
+func (publicacao *Publicacao) validar() error {
+	if publicacao.Repost() {
+		return nil
+	}
+
+	if publicacao.Status == StatusRascunho {
+		if publicacao.Titulo == "" && publicacao.Conteudo == "" {
+			return fmt.Errorf("O rascunho precisa ter titulo ou conteudo")
+		}
+		return nil
+	}
+
+	if publicacao.Titulo == "" {
+		return fmt.Errorf("O titulo não pode estar em branco")
+	}
+
+	if publicacao.Conteudo == "" {
+		return fmt.Errorf("O conteudo não pode estar em branco")
+	}
+	return nil
+}
+
+// HorarioAgendamento interpreta a data informada pelo usuario no horario de Brasilia. O JSON não
+// traz fuso, então a data chega como UTC mas representa o horario local de quem agendou.
+func HorarioAgendamento(data utils.JsonSpecialDateTime) (utils.JsonSpecialDateTime, error) {
+	if data.IsZero() {
+		return data, nil
+	}
+	return utils.ParseJsonSpecialDateTime(layoutAgendamento, data.Format(layoutAgendamento))
+}
+
+// BuscarRascunhos traz os rascunhos e as publicações agendadas do usuario, as agendadas primeiro
+func BuscarRascunhos(c context.Context, usuarioID int64) ([]Publicacao, error) {
+	publics, err := FiltrarPublicacoes(c, Publicacao{AutorID: usuarioID})
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar rascunhos do usuario %d: %v", usuarioID, err)
+		return nil, err
+	}
+
+	rascunhos := make([]Publicacao, 0)
+	for _, p := range publics {
+		if !p.Publicada() {
+			rascunhos = append(rascunhos, p)
+		}
+	}
+
+	sort.Slice(rascunhos, func(i, j int) bool {
+		if rascunhos[i].Status != rascunhos[j].Status {
+			return rascunhos[i].Status == StatusAgendada
+		}
+		if rascunhos[i].Status == StatusAgendada {
+			return rascunhos[i].DataAgendamento.Before(rascunhos[j].DataAgendamento.Time)
+		}
+		return rascunhos[i].DataCriacao.After(rascunhos[j].DataCriacao.Time)
+	})
+	return rascunhos, nil
+}
+
+// Agendar marca um rascunho, ou uma publicação já agendada, para ser publicado na data informada
+func Agendar(c context.Context, publicacaoID int64, data utils.JsonSpecialDateTime) (*Publicacao, error) {
+	if !data.After(utils.GetTimeNow()) {
+		return nil, fmt.Errorf("A data de agendamento precisa estar no futuro")
+	}
+
+	return alterarStatus(c, publicacaoID, func(public *Publicacao) error {
+		public.Status = StatusAgendada
+		public.DataAgendamento = data
+		return public.validar()
+	})
+}
+
+// CancelarAgendamento volta a publicação agendada para rascunho
+func CancelarAgendamento(c context.Context, publicacaoID int64) (*Publicacao, error) {
+	return alterarStatus(c, publicacaoID, func(public *Publicacao) error {
+		if public.Status != StatusAgendada {
+			return fmt.Errorf("A publicação não está agendada")
+		}
+		public.Status = StatusRascunho
+		public.DataAgendamento = utils.JsonSpecialDateTime{}
+		return nil
+	})
+}
+
+// alterarStatus muda um rascunho ou agendamento dentro de uma transação, para não concorrer com o agendador
+func alterarStatus(c context.Context, publicacaoID int64, alterar func(*Publicacao) error) (*Publicacao, error) {
+	return alterarPublicacao(c, publicacaoID, func(public *Publicacao) error {
+		if public.Publicada() {
+			return errJaPublicada
+		}
+		return alterar(public)
+	})
+}
+
+// Publicar publica agora um rascunho ou uma publicação agendada
+func Publicar(c context.Context, publicacaoID int64) (*Publicacao, error) {
+	return publicar(c, publicacaoID, false)
+}
+
+// publicar troca o status para publicada em uma transação. Só quem efetivou a troca segue com a
+// indexação, as notificações e o evento, então a mesma publicação nunca é publicada duas vezes,
+// mesmo com o agendador reiniciando ou rodando em mais de uma instancia.
+func publicar(c context.Context, publicacaoID int64, somenteVencida bool) (*Publicacao, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	var public Publicacao
+	key := datastore.IDKey(KindPublicacoes, publicacaoID, nil)
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		if err := tx.Get(key, &public); err != nil {
+			return err
+		}
+		if public.Publicada() {
+			return errJaPublicada
+		}
+		agora := utils.GetSpecialTimeNow()
+		if somenteVencida && !public.Vencida(agora.Time) {
+			return errNaoVencida
+		}
+
+		public.Status = StatusPublicada
+		if err := public.validar(); err != nil {
+			return err
+		}
+		//A publicação entra no feed no momento em que é publicada
+		public.DataCriacao = agora
+
+		_, err := tx.Put(key, &public)
+		return err
+	})
+	if err == datastore.ErrNoSuchEntity {
+		return nil, fmt.Errorf("Publicação não encontrada")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	public.ID = publicacaoID
+	sincronizarBusca(c, &public)
+	processarMarcacoes(c, &public, nil)
+	publicarEvento(c, &public)
+	return &public, nil
+}
+
+// PublicarAgendadas publica todas as publicações cujo horario agendado já passou e devolve quantas publicou
+func PublicarAgendadas(c context.Context) (int, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return 0, err
+	}
+	defer datastoreClient.Close()
+
+	var agendadas []Publicacao
+	q := datastore.NewQuery(KindPublicacoes).Filter("Status =", StatusAgendada)
+	keys, err := datastoreClient.GetAll(c, q, &agendadas)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar publicações agendadas: %v", err)
+		return 0, err
+	}
+
+	agora := utils.GetTimeNow()
+	publicadas := 0
+	for i := range agendadas {
+		if !agendadas[i].Vencida(agora) {
+			continue
+		}
+
+		_, err := publicar(c, keys[i].ID, true)
+		if err == errJaPublicada || err == errNaoVencida {
+			continue
+		}
+		if err != nil {
+			log.Warningf(c, "Falha ao publicar publicação agendada %d: %v", keys[i].ID, err)
+			continue
+		}
+		publicadas++
+	}
+	return publicadas, nil
+}
+
+// IniciarAgendador verifica a cada minuto, conforme IntervaloAgendador, se há publicações agendadas
+// vencidas. A primeira verificação é feita na hora, para publicar o que venceu enquanto o servidor
+// esteve parado. Roda até o contexto ser cancelado.
+func IniciarAgendador(c context.Context) {
+	executar := func() {
+		total, err := PublicarAgendadas(c)
+		if err != nil {
+			log.Warningf(c, "Falha ao executar agendador de publicações: %v", err)
+			return
+		}
+		if total > 0 {
+			log.Debugf(c, "%d publicações agendadas foram publicadas", total)
+		}
+	}
+
+	executar()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if utils.ValidaExecucao(IntervaloAgendador) {
+				executar()
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package publicacao
+
+import (
+	"site/utils"
+	"testing"
+	"time"
+)
+
+func TestPublicada(t *testing.T) {
+	casos := map[string]bool{
+		"":              true,
+		StatusPublicada: true,
+		StatusRascunho:  false,
+		StatusAgendada:  false,
+	}
+
+	for status, esperado := range casos {
+		p := Publicacao{Status: status}
+		if obtido := p.Publicada(); obtido != esperado {
+			t.Errorf("Publicada() com status %q = %v, esperado %v", status, obtido, esperado)
+		}
+	}
+}
+
+func TestVencida(t *testing.T) {
+	agora := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	casos := []struct {
+		status   string
+		data     time.Time
+		esperado bool
+	}{
+		{StatusAgendada, agora.Add(-time.Minute), true},
+		{StatusAgendada, agora, true},
+		{StatusAgendada, agora.Add(time.Minute), false},
+		{StatusRascunho, agora.Add(-time.Minute), false},
+		{StatusPublicada, agora.Add(-time.Minute), false},
+	}
+
+	for _, caso := range casos {
+		p := Publicacao{Status: caso.status, DataAgendamento: utils.JsonSpecialDateTime{Time: caso.data}}
+		if obtido := p.Vencida(agora); obtido != caso.esperado {
+			t.Errorf("Vencida() com status %q e data %v = %v, esperado %v", caso.status, caso.data, obtido, caso.esperado)
+		}
+	}
+}
+
+func TestValidar(t *testing.T) {
+	casos := []struct {
+		publicacao Publicacao
+		valida     bool
+	}{
+		{Publicacao{Titulo: "t", Conteudo: "c"}, true},
+		{Publicacao{Titulo: "t"}, false},
+		{Publicacao{Conteudo: "c", Status: StatusAgendada}, false},
+		{Publicacao{Titulo: "t", Status: StatusRascunho}, true},
+		{Publicacao{Status: StatusRascunho}, false},
+		{Publicacao{OriginalID: 1}, true},
+	}
+
+	for _, caso := range casos {
+		if err := caso.publicacao.validar(); (err == nil) != caso.valida {
+			t.Errorf("validar(%+v) = %v, esperado valida=%v", caso.publicacao, err, caso.valida)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package publicacao
+
+import (
+	"context"
+	"fmt"
+	"site/armazenamento"
+	"site/utils"
+	"site/utils/imagem"
+	"site/utils/log"
+)
+
+const (
+	MaxAnexos          = 4
+	TamanhoMaximoAnexo = imagem.TamanhoMaximo
+
+	LarguraMaximaAnexo = 2048
+	AlturaMaximaAnexo  = 2048
+	LarguraMiniatura   = 400
+	AlturaMiniatura    = 400
+)
+
+// Anexo representa uma imagem enviada junto com a publicação
+type Anexo struct {
+	URL              string `datastore:",noindex"`
+	MiniaturaURL     string `datastore:",noindex"`
+	Tipo             string `datastore:",noindex"`
+	Largura          int64  `datastore:",noindex"`
+	Altura           int64  `datastore:",noindex"`
+	Arquivo          string `datastore:",noindex" json:"-"`
+	ArquivoMiniatura string `datastore:",noindex" json:"-"`
+}
+
+// ProcessarAnexos valida, recodifica e armazena as imagens enviadas para uma publicação
+func ProcessarAnexos(c context.Context, autorID int64, arquivos [][]byte) ([]Anexo, error) {
+	if len(arquivos) > MaxAnexos {
+		return nil, fmt.Errorf("Uma publicação pode ter no máximo %d anexos", MaxAnexos)
+	}
+
+	arm, err := armazenamento.Novo(c)
+	if err != nil {
+		log.Warningf(c, "Falha ao iniciar armazenamento: %v", err)
+		return nil, err
+	}
+	return processarAnexos(c, arm, autorID, arquivos)
+}
+
+// processarAnexos processa os arquivos em ordem. Se um falhar, os já salvos são apagados.
+func processarAnexos(c context.Context, arm armazenamento.Armazenamento, autorID int64, arquivos [][]byte) ([]Anexo, error) {
+	var anexos []Anexo
+	for i, dados := range arquivos {
+		anexo, err := processarAnexo(c, arm, autorID, i, dados)
+		if err != nil {
+			removerArquivos(c, arm, anexos)
+			return nil, fmt.Errorf("Anexo %d inválido: %v", i+1, err)
+		}
+		anexos = append(anexos, anexo)
+	}
+	return anexos, nil
+}
+
+// processarAnexo gera a imagem final e a miniatura. As duas são recodificadas a partir
+// dos pixels, o que descarta os metadados EXIF do arquivo original (localização, câmera etc).
+func processarAnexo(c context.Context, arm armazenamento.Armazenamento, autorID int64, indice int, dados []byte) (Anexo, error) {
+	if _, err := imagem.Validar(dados, TamanhoMaximoAnexo); err != nil {
+		return Anexo{}, err
+	}
+
+	img, err := imagem.Decodificar(dados)
+	if err != nil {
+		return Anexo{}, err
+	}
+
+	img = imagem.Redimensionar(img, LarguraMaximaAnexo, AlturaMaximaAnexo)
+	conteudo, err := imagem.CodificarJPEG(img)
+	if err != nil {
+		return Anexo{}, err
+	}
+
+	miniatura, err := imagem.CodificarJPEG(imagem.Recortar(img, LarguraMiniatura, AlturaMiniatura))
+	if err != nil {
+		return Anexo{}, err
+	}
+
+	base := fmt.Sprintf("publicacoes/%d/%d-%d", autorID, utils.GetTimeNow().UnixNano(), indice)
+	anexo := Anexo{
+		Tipo:             imagem.TipoJPEG,
+		Largura:          int64(img.Bounds().Dx()),
+		Altura:           int64(img.Bounds().Dy()),
+		Arquivo:          base + ".jpg",
+		ArquivoMiniatura: base + "-miniatura.jpg",
+	}
+
+	if anexo.URL, err = arm.Salvar(c, anexo.Arquivo, conteudo, imagem.TipoJPEG); err != nil {
+		return Anexo{}, err
+	}
+
+	if anexo.MiniaturaURL, err = arm.Salvar(c, anexo.ArquivoMiniatura, miniatura, imagem.TipoJPEG); err != nil {
+		arm.Remover(c, anexo.Arquivo)
+		return Anexo{}, err
+	}
+
+	return anexo, nil
+}
+
+// RemoverAnexos apaga do armazenamento os arquivos de uma publicação
+func RemoverAnexos(c context.Context, anexos []Anexo) error {
+	if len(anexos) == 0 {
+		return nil
+	}
+
+	arm, err := armazenamento.Novo(c)
+	if err != nil {
+		log.Warningf(c, "Falha ao iniciar armazenamento: %v", err)
+		return err
+	}
+	return removerArquivos(c, arm, anexos)
+}
+
+func removerArquivos(c context.Context, arm armazenamento.Armazenamento, anexos []Anexo) error {
+	var erro error
+	for _, anexo := range anexos {
+		for _, nome := range []string{anexo.Arquivo, anexo.ArquivoMiniatura} {
+			if nome == "" {
+				continue
+			}
+			if err := arm.Remover(c, nome); err != nil {
+				log.Warningf(c, "Falha ao remover anexo %s: %v", nome, err)
+				erro = err
+			}
+		}
+	}
+	return erro
+}
@@ -0,0 +1,167 @@
+package publicacao
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"site/utils/imagem"
+	"strings"
+	"testing"
+)
+
+// armazenamentoMemoria guarda os arquivos em um map e pode falhar ao salvar um nome especifico
+type armazenamentoMemoria struct {
+	arquivos map[string][]byte
+	falharEm string
+}
+
+func novoArmazenamentoMemoria() *armazenamentoMemoria {
+	return &armazenamentoMemoria{arquivos: map[string][]byte{}}
+}
+
+func (arm *armazenamentoMemoria) Salvar(c context.Context, nome string, conteudo []byte, tipo string) (string, error) {
+	if arm.falharEm != "" && strings.HasSuffix(nome, arm.falharEm) {
+		return "", fmt.Errorf("falha simulada ao salvar %s", nome)
+	}
+	arm.arquivos[nome] = conteudo
+	return "/arquivos/" + nome, nil
+}
+
+func (arm *armazenamentoMemoria) Remover(c context.Context, nome string) error {
+	delete(arm.arquivos, nome)
+	return nil
+}
+
+func gerarImagem(largura, altura int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, largura, altura))
+	for y := 0; y < altura; y++ {
+		for x := 0; x < largura; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func gerarPNG(t *testing.T, largura, altura int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gerarImagem(largura, altura)); err != nil {
+		t.Fatalf("Falha ao gerar PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func gerarGIF(t *testing.T, largura, altura int) []byte {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, gerarImagem(largura, altura), nil); err != nil {
+		t.Fatalf("Falha ao gerar GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func dimensoesJPEG(t *testing.T, dados []byte) (int, int) {
+	config, err := jpeg.DecodeConfig(bytes.NewReader(dados))
+	if err != nil {
+		t.Fatalf("Arquivo salvo não é JPEG: %v", err)
+	}
+	return config.Width, config.Height
+}
+
+func TestProcessarAnexo(t *testing.T) {
+	pngGrande := gerarPNG(t, 3000, 1500)
+	acimaDoLimite := append(gerarPNG(t, 10, 10), make([]byte, TamanhoMaximoAnexo)...)
+
+	casos := []struct {
+		nome                 string
+		dados                []byte
+		valido               bool
+		largura, altura      int
+		miniLarg, miniAltura int
+	}{
+		{"PNG pequeno", gerarPNG(t, 300, 200), true, 300, 200, 200, 200},
+		{"GIF", gerarGIF(t, 50, 80), true, 50, 80, 50, 50},
+		{"PNG acima da largura maxima", pngGrande, true, 2048, 1024, 400, 400},
+		{"PNG alto", gerarPNG(t, 500, 2500), true, 409, 2048, 400, 400},
+		{"vazio", nil, false, 0, 0, 0, 0},
+		{"acima do tamanho maximo", acimaDoLimite, false, 0, 0, 0, 0},
+		{"texto", []byte("não sou uma imagem, apenas texto"), false, 0, 0, 0, 0},
+		{"PDF", []byte("%PDF-1.4\n%âãÏÓ\n1 0 obj"), false, 0, 0, 0, 0},
+		{"cabeçalho PNG com conteudo corrompido", append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0xff}, 64)...), false, 0, 0, 0, 0},
+	}
+
+	for _, caso := range casos {
+		arm := novoArmazenamentoMemoria()
+		anexo, err := processarAnexo(context.Background(), arm, 7, 0, caso.dados)
+		if (err == nil) != caso.valido {
+			t.Errorf("%s: processarAnexo() = %v, esperado valido = %v", caso.nome, err, caso.valido)
+			continue
+		}
+		if err != nil {
+			if len(arm.arquivos) != 0 {
+				t.Errorf("%s: anexo inválido deixou %d arquivos salvos", caso.nome, len(arm.arquivos))
+			}
+			continue
+		}
+
+		if anexo.Tipo != imagem.TipoJPEG {
+			t.Errorf("%s: tipo %q, esperado %q", caso.nome, anexo.Tipo, imagem.TipoJPEG)
+		}
+		if !strings.HasPrefix(anexo.Arquivo, "publicacoes/7/") || !strings.HasSuffix(anexo.ArquivoMiniatura, "-miniatura.jpg") {
+			t.Errorf("%s: nomes de arquivo inesperados %q e %q", caso.nome, anexo.Arquivo, anexo.ArquivoMiniatura)
+		}
+		if anexo.URL != "/arquivos/"+anexo.Arquivo || anexo.MiniaturaURL != "/arquivos/"+anexo.ArquivoMiniatura {
+			t.Errorf("%s: URLs inesperadas %q e %q", caso.nome, anexo.URL, anexo.MiniaturaURL)
+		}
+
+		if anexo.Largura != int64(caso.largura) || anexo.Altura != int64(caso.altura) {
+			t.Errorf("%s: anexo %dx%d, esperado %dx%d", caso.nome, anexo.Largura, anexo.Altura, caso.largura, caso.altura)
+		}
+		if largura, altura := dimensoesJPEG(t, arm.arquivos[anexo.Arquivo]); largura != caso.largura || altura != caso.altura {
+			t.Errorf("%s: imagem salva %dx%d, esperado %dx%d", caso.nome, largura, altura, caso.largura, caso.altura)
+		}
+		if largura, altura := dimensoesJPEG(t, arm.arquivos[anexo.ArquivoMiniatura]); largura != caso.miniLarg || altura != caso.miniAltura {
+			t.Errorf("%s: miniatura %dx%d, esperado %dx%d", caso.nome, largura, altura, caso.miniLarg, caso.miniAltura)
+		}
+	}
+}
+
+func TestProcessarAnexosLimite(t *testing.T) {
+	arquivos := make([][]byte, MaxAnexos+1)
+	if _, err := ProcessarAnexos(context.Background(), 7, arquivos); err == nil {
+		t.Errorf("ProcessarAnexos() aceitou %d anexos", len(arquivos))
+	}
+}
+
+func TestProcessarAnexosDesfazFalha(t *testing.T) {
+	valido := gerarPNG(t, 20, 20)
+
+	arm := novoArmazenamentoMemoria()
+	if _, err := processarAnexos(context.Background(), arm, 7, [][]byte{valido, valido, []byte("texto")}); err == nil {
+		t.Fatalf("processarAnexos() aceitou anexo inválido")
+	}
+	if len(arm.arquivos) != 0 {
+		t.Errorf("anexos anteriores ao inválido não foram apagados: %v", len(arm.arquivos))
+	}
+
+	arm = novoArmazenamentoMemoria()
+	arm.falharEm = "-1-miniatura.jpg"
+	if _, err := processarAnexos(context.Background(), arm, 7, [][]byte{valido, valido}); err == nil {
+		t.Fatalf("processarAnexos() ignorou falha do armazenamento")
+	}
+	if len(arm.arquivos) != 0 {
+		t.Errorf("falha ao salvar a miniatura deixou %d arquivos salvos", len(arm.arquivos))
+	}
+
+	arm = novoArmazenamentoMemoria()
+	anexos, err := processarAnexos(context.Background(), arm, 7, [][]byte{valido, valido})
+	if err != nil || len(anexos) != 2 || len(arm.arquivos) != 4 {
+		t.Fatalf("processarAnexos() = %d anexos, %v, %d arquivos", len(anexos), err, len(arm.arquivos))
+	}
+	if err = removerArquivos(context.Background(), arm, anexos); err != nil || len(arm.arquivos) != 0 {
+		t.Errorf("removerArquivos() = %v, restaram %d arquivos", err, len(arm.arquivos))
+	}
+}
@@ -0,0 +1,222 @@
+package publicacao
+
+import (
+	"context"
+	"fmt"
+	"site/usuario"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"strings"
+
+	"cloud.google.com/go/datastore"
+)
+
+// Compartilhar cria um repost da publicação original na linha do tempo do usuario. Com comentario
+// vira uma citação. Compartilhar um repost aponta sempre para a publicação original. Só publicações
+// publicas de contas publicas podem ser compartilhadas, para o repost não expor quem não deveria.
+func Compartilhar(c context.Context, usuarioID, originalID int64, comentario string) (*Publicacao, error) {
+	leitor := NovoLeitor(c, usuarioID)
+
+	original := GetPublicacao(c, originalID)
+	if original == nil || !original.Publicada() || !leitor.PodeVer(original) {
+		return nil, fmt.Errorf("Publicação não encontrada")
+	}
+
+	if original.Repost() {
+		originalID = original.OriginalID
+		if original = GetPublicacao(c, originalID); original == nil || !leitor.PodeVer(original) {
+			return nil, fmt.Errorf("Publicação não encontrada")
+		}
+	}
+
+	if original.visibilidade() != VisibilidadePublica || leitor.acesso(original.AutorID).contaPrivada {
+		return nil, fmt.Errorf("Esta publicação não pode ser compartilhada")
+	}
+
+	comentario = strings.TrimSpace(comentario)
+
+	if comentario == "" {
+		existente, err := buscarRepost(c, usuarioID, originalID)
+		if err != nil {
+			return nil, err
+		}
+		if existente != nil {
+			return nil, fmt.Errorf("Você já compartilhou esta publicação")
+		}
+	}
+
+	usuarioBanco := usuario.GetUsuario(c, usuarioID)
+	if usuarioBanco == nil {
+		return nil, fmt.Errorf("Usuario não encontrado")
+	}
+
+	repost := Publicacao{
+		Status:       StatusPublicada,
+		Visibilidade: VisibilidadePublica,
+		Conteudo:     comentario,
+		AutorID:      usuarioBanco.ID,
+		AutorNick:    usuarioBanco.Nick,
+		OriginalID:   originalID,
+		DataCriacao:  utils.GetSpecialTimeNow(),
+	}
+
+	resultado, err := filtrarConteudo(c, &repost, false)
+	if err != nil {
+		return nil, err
+	}
+
+	prepararMarcacoes(c, &repost)
+
+	if err := PutPublicacao(c, &repost); err != nil {
+		return nil, err
+	}
+
+	resultado.sinalizar(c, &repost)
+
+	if !repost.Oculta {
+		processarMarcacoes(c, &repost, nil)
+	}
+
+	if err := alterarCompartilhamentos(c, originalID, 1, comentario != ""); err != nil {
+		log.Warningf(c, "Falha ao contar compartilhamento da publicação %d: %v", originalID, err)
+	}
+
+	if comentario != "" {
+		registrarInteracao(c, usuarioID, original.AutorID, interacaoCitacao, 1)
+	} else {
+		registrarInteracao(c, usuarioID, original.AutorID, interacaoCompartilhamento, 1)
+	}
+
+	repost.Original = original
+
+	publicarEvento(c, &repost)
+	return &repost, nil
+}
+
+// DesfazerCompartilhamento remove o repost simples que o usuario fez da publicação
+func DesfazerCompartilhamento(c context.Context, usuarioID, originalID int64) error {
+	repost, err := buscarRepost(c, usuarioID, originalID)
+	if err != nil {
+		return err
+	}
+	if repost == nil {
+		return fmt.Errorf("Você não compartilhou esta publicação")
+	}
+	return Deletar(c, *repost)
+}
+
+// buscarRepost traz o repost sem comentario feito pelo usuario, ou nil se não houver
+func buscarRepost(c context.Context, usuarioID, originalID int64) (*Publicacao, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	q := datastore.NewQuery(KindPublicacoes).
+		Filter("AutorID =", usuarioID).
+		Filter("OriginalID =", originalID).
+		KeysOnly()
+
+	keys, err := datastoreClient.GetAll(c, q, nil)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar compartilhamentos: %v", err)
+		return nil, err
+	}
+
+	publics, err := GetMultPublicacao(c, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i := range publics {
+		if publics[i].Conteudo == "" {
+			return &publics[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// alterarCompartilhamentos soma delta ao contador da publicação original dentro de uma transação.
+// Citações contam também como comentarios.
+func alterarCompartilhamentos(c context.Context, originalID int64, delta int64, citacao bool) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindPublicacoes, originalID, nil)
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		var original Publicacao
+		if err := tx.Get(key, &original); err != nil {
+			return err
+		}
+		contarCompartilhamento(&original, delta, citacao)
+		_, err := tx.Put(key, &original)
+		return err
+	})
+	if err == datastore.ErrNoSuchEntity {
+		return nil
+	}
+	return err
+}
+
+// contarCompartilhamento aplica o delta nos contadores sem deixar nenhum negativo
+func contarCompartilhamento(original *Publicacao, delta int64, citacao bool) {
+	original.Compartilhamentos += delta
+	if original.Compartilhamentos < 0 {
+		original.Compartilhamentos = 0
+	}
+	if citacao {
+		original.Comentarios += delta
+		if original.Comentarios < 0 {
+			original.Comentarios = 0
+		}
+	}
+}
+
+// carregarOriginais preenche a publicação original de cada repost. Reposts cuja original foi
+// excluida, ou deixou de ser visivel para o leitor, ficam ocultos e são retirados da lista.
+func carregarOriginais(c context.Context, leitor *Leitor, publics []Publicacao) ([]Publicacao, error) {
+	var keys []*datastore.Key
+	for _, p := range publics {
+		if p.Repost() {
+			keys = append(keys, datastore.IDKey(KindPublicacoes, p.OriginalID, nil))
+		}
+	}
+	if len(keys) == 0 {
+		return publics, nil
+	}
+
+	encontradas, err := buscarExistentes(c, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	originais := make(map[int64]*Publicacao, len(encontradas))
+	for i := range encontradas {
+		originais[encontradas[i].ID] = &encontradas[i]
+	}
+	return juntarOriginais(leitor, publics, originais), nil
+}
+
+// juntarOriginais liga cada repost à sua original, deixando de fora os que não têm original
+// ou cuja original o leitor não pode ver
+func juntarOriginais(leitor *Leitor, publics []Publicacao, originais map[int64]*Publicacao) []Publicacao {
+	visiveis := make([]Publicacao, 0, len(publics))
+	for _, p := range publics {
+		if p.Repost() {
+			encontrada, ok := originais[p.OriginalID]
+			if !ok || !leitor.PodeVer(encontrada) {
+				continue
+			}
+			original := *encontrada
+			p.Original = &original
+		}
+		visiveis = append(visiveis, p)
+	}
+	return visiveis
+}
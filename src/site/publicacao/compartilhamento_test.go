@@ -0,0 +1,91 @@
+package publicacao
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContarCompartilhamento(t *testing.T) {
+	casos := []struct {
+		nome                                       string
+		compartilhamentos, comentarios             int64
+		delta                                      int64
+		citacao                                    bool
+		compartilhamentosDepois, comentariosDepois int64
+	}{
+		{"repost", 2, 5, 1, false, 3, 5},
+		{"citação conta como comentario", 2, 5, 1, true, 3, 6},
+		{"desfazer repost", 2, 5, -1, false, 1, 5},
+		{"remover citação", 2, 5, -1, true, 1, 4},
+		{"não fica negativo", 0, 0, -1, true, 0, 0},
+		{"só o comentario zerado", 3, 0, -1, true, 2, 0},
+	}
+	for _, caso := range casos {
+		original := Publicacao{Compartilhamentos: caso.compartilhamentos, Comentarios: caso.comentarios}
+		contarCompartilhamento(&original, caso.delta, caso.citacao)
+		if original.Compartilhamentos != caso.compartilhamentosDepois || original.Comentarios != caso.comentariosDepois {
+			t.Errorf("%s: compartilhamentos = %d, comentarios = %d, esperado %d e %d", caso.nome,
+				original.Compartilhamentos, original.Comentarios, caso.compartilhamentosDepois, caso.comentariosDepois)
+		}
+	}
+}
+
+func TestJuntarOriginais(t *testing.T) {
+	const (
+		leitorID  = 1
+		publicoID = 2
+		privadoID = 3
+		banidoID  = 4
+	)
+	leitor := leitorTeste(leitorID, nil, map[int64]acessoAutor{
+		publicoID: {},
+		privadoID: {contaPrivada: true},
+		banidoID:  {banido: true},
+	})
+
+	originais := map[int64]*Publicacao{
+		100: {ID: 100, AutorID: publicoID, Conteudo: "visivel"},
+		101: {ID: 101, AutorID: privadoID, Conteudo: "conta privada"},
+		102: {ID: 102, AutorID: publicoID, Oculta: true},
+		103: {ID: 103, AutorID: publicoID, Visibilidade: VisibilidadeSeguidores},
+		104: {ID: 104, AutorID: banidoID},
+		105: {ID: 105, AutorID: leitorID, Visibilidade: VisibilidadePrivada},
+	}
+
+	publics := []Publicacao{
+		{ID: 1, AutorID: publicoID, Conteudo: "sem repost"},
+		{ID: 2, AutorID: publicoID, OriginalID: 100},
+		{ID: 3, AutorID: publicoID, OriginalID: 101},
+		{ID: 4, AutorID: publicoID, OriginalID: 102},
+		{ID: 5, AutorID: publicoID, OriginalID: 103},
+		{ID: 6, AutorID: publicoID, OriginalID: 104},
+		{ID: 7, AutorID: publicoID, OriginalID: 999},
+		{ID: 8, AutorID: publicoID, OriginalID: 105},
+		{ID: 9, AutorID: leitorID, OriginalID: 100, Conteudo: "citação"},
+	}
+
+	visiveis := juntarOriginais(leitor, publics, originais)
+
+	var ids []int64
+	for _, p := range visiveis {
+		ids = append(ids, p.ID)
+		if p.Repost() && (p.Original == nil || p.Original.ID != p.OriginalID) {
+			t.Errorf("repost %d sem a original %d preenchida: %#v", p.ID, p.OriginalID, p.Original)
+		}
+	}
+	if esperados := []int64{1, 2, 8, 9}; !reflect.DeepEqual(ids, esperados) {
+		t.Errorf("juntarOriginais() = %v, esperado %v", ids, esperados)
+	}
+
+	//Cada repost recebe sua propria copia da original
+	if visiveis[1].Original == visiveis[3].Original || visiveis[1].Original == originais[100] {
+		t.Errorf("reposts da mesma original compartilham o ponteiro")
+	}
+}
+
+func TestJuntarOriginaisSemReposts(t *testing.T) {
+	publics := []Publicacao{{ID: 1}, {ID: 2}}
+	if visiveis := juntarOriginais(leitorTeste(1, nil, nil), publics, nil); len(visiveis) != 2 {
+		t.Errorf("publicações sem repost deveriam passar direto: %v", visiveis)
+	}
+}
@@ -0,0 +1,356 @@
+package publicacao
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"site/config"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	AcaoFiltroSinalizar = "sinalizar"
+	AcaoFiltroOcultar   = "ocultar"
+	AcaoFiltroRejeitar  = "rejeitar"
+
+	FiltroPalavras  = "palavras"
+	FiltroLinks     = "links"
+	FiltroRepeticao = "repeticao"
+	FiltroRajada    = "rajada"
+
+	// Janela das publicações recentes do autor olhadas pelas regras de spam
+	janelaHistoricoFiltro = 24 * time.Hour
+
+	limiteRajadaPadrao = 5
+	janelaRajadaPadrao = 10 * time.Minute
+)
+
+// gravidadeAcaoFiltro ordena as ações; quando varias regras são violadas vale a mais grave
+var gravidadeAcaoFiltro = map[string]int{
+	"":                  0,
+	AcaoFiltroSinalizar: 1,
+	AcaoFiltroOcultar:   2,
+	AcaoFiltroRejeitar:  3,
+}
+
+// AoSinalizar é chamado depois de gravar uma publicação sinalizada pelo filtro. O pacote de moderação
+// registra aqui a criação da denuncia automatica, sem que este pacote precise conhecê-lo.
+var AoSinalizar func(c context.Context, publicacao *Publicacao, violacoes []Violacao)
+
+// Avaliacao é o que as regras recebem: a publicação e as publicações recentes do autor
+type Avaliacao struct {
+	Publicacao *Publicacao
+	Recentes   []Publicacao
+	Edicao     bool
+	Agora      time.Time
+}
+
+// RegraConteudo confere um aspecto da publicação e diz se ela foi violada e por quê
+type RegraConteudo interface {
+	Verificar(avaliacao *Avaliacao) (motivo string, violada bool)
+}
+
+// RegraConteudoFunc permite usar uma função simples como regra
+type RegraConteudoFunc func(avaliacao *Avaliacao) (string, bool)
+
+func (f RegraConteudoFunc) Verificar(avaliacao *Avaliacao) (string, bool) {
+	return f(avaliacao)
+}
+
+// Filtro é uma regra com o nome e a ação tomada quando ela é violada
+type Filtro struct {
+	Nome  string
+	Acao  string
+	Regra RegraConteudo
+}
+
+// Violacao registra uma regra violada pela publicação
+type Violacao struct {
+	Regra  string
+	Acao   string
+	Motivo string
+}
+
+// ResultadoFiltro traz a ação mais grave entre as regras violadas. Acao vazia é publicação aprovada.
+type ResultadoFiltro struct {
+	Acao      string
+	Violacoes []Violacao
+}
+
+// Motivos junta os motivos das violações em um texto só
+func (resultado *ResultadoFiltro) Motivos() string {
+	motivos := make([]string, len(resultado.Violacoes))
+	for i, v := range resultado.Violacoes {
+		motivos[i] = v.Motivo
+	}
+	return strings.Join(motivos, "; ")
+}
+
+// FabricasFiltro montam cada filtro a partir da config. Novas regras entram nesta lista.
+var FabricasFiltro = []func(c context.Context) Filtro{
+	novoFiltroPalavras,
+	novoFiltroLinks,
+	novoFiltroRepeticao,
+	novoFiltroRajada,
+}
+
+// AplicarFiltros passa a publicação por todos os filtros e devolve a ação mais grave
+func AplicarFiltros(filtros []Filtro, avaliacao *Avaliacao) ResultadoFiltro {
+	var resultado ResultadoFiltro
+	for _, filtro := range filtros {
+		motivo, violada := filtro.Regra.Verificar(avaliacao)
+		if !violada {
+			continue
+		}
+		resultado.Violacoes = append(resultado.Violacoes, Violacao{Regra: filtro.Nome, Acao: filtro.Acao, Motivo: motivo})
+		if gravidadeAcaoFiltro[filtro.Acao] > gravidadeAcaoFiltro[resultado.Acao] {
+			resultado.Acao = filtro.Acao
+		}
+	}
+	return resultado
+}
+
+// filtrarConteudo roda os filtros na criação ou na edição. Rejeitada devolve erro; ocultada fica
+// visivel só para o autor, sem ele saber. A sinalização acontece depois de gravar, em sinalizar.
+func filtrarConteudo(c context.Context, publicacao *Publicacao, edicao bool) (*ResultadoFiltro, error) {
+	if publicacao.Titulo == "" && publicacao.Conteudo == "" {
+		return &ResultadoFiltro{}, nil
+	}
+
+	filtros := make([]Filtro, 0, len(FabricasFiltro))
+	for _, fabrica := range FabricasFiltro {
+		filtros = append(filtros, fabrica(c))
+	}
+
+	avaliacao := Avaliacao{
+		Publicacao: publicacao,
+		Recentes:   publicacoesRecentes(c, publicacao.AutorID),
+		Edicao:     edicao,
+		Agora:      utils.GetTimeNow(),
+	}
+
+	resultado := AplicarFiltros(filtros, &avaliacao)
+	switch resultado.Acao {
+	case AcaoFiltroRejeitar:
+		log.Infof(c, "Publicação do usuario %d recusada pelo filtro: %s", publicacao.AutorID, resultado.Motivos())
+		return nil, fmt.Errorf("Publicação recusada: %s", resultado.Motivos())
+	case AcaoFiltroOcultar:
+		log.Infof(c, "Publicação do usuario %d ocultada pelo filtro: %s", publicacao.AutorID, resultado.Motivos())
+		publicacao.Oculta = true
+	}
+	return &resultado, nil
+}
+
+// sinalizar manda para a moderação a publicação já gravada que violou alguma regra sem ser recusada
+func (resultado *ResultadoFiltro) sinalizar(c context.Context, publicacao *Publicacao) {
+	if resultado == nil || len(resultado.Violacoes) == 0 || AoSinalizar == nil {
+		return
+	}
+	AoSinalizar(c, publicacao, resultado.Violacoes)
+}
+
+// publicacoesRecentes traz o que o autor publicou dentro da janela do historico. A consulta usa o
+// indice composto de AutorID e DataCriacao declarado no index.yaml.
+func publicacoesRecentes(c context.Context, autorID int64) []Publicacao {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil
+	}
+	defer datastoreClient.Close()
+
+	var publics []Publicacao
+	q := datastore.NewQuery(KindPublicacoes).
+		Filter("AutorID =", autorID).
+		Filter("DataCriacao.Time >=", utils.GetTimeNow().Add(-janelaHistoricoFiltro))
+	keys, err := datastoreClient.GetAll(c, q, &publics)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar publicações recentes do usuario %d para o filtro: %v", autorID, err)
+		return nil
+	}
+	for i := range keys {
+		publics[i].ID = keys[i].ID
+	}
+	return somentePublicadas(publics)
+}
+
+// acaoFiltro lê a ação configurada para a regra em filtro.<regra>.acao
+func acaoFiltro(c context.Context, regra, padrao string) string {
+	nome := fmt.Sprintf("filtro.%s.acao", regra)
+	acao := strings.TrimSpace(config.GetDefault(c, nome, padrao).Value)
+	if _, ok := gravidadeAcaoFiltro[acao]; !ok || acao == "" {
+		log.Warningf(c, "Ação %q inválida em %s, usando %s", acao, nome, padrao)
+		return padrao
+	}
+	return acao
+}
+
+// listaConfig lê uma config com itens separados por virgula ou quebra de linha
+func listaConfig(c context.Context, nome string) []string {
+	valor := config.GetDefault(c, nome, "").Value
+	itens := make([]string, 0)
+	for _, item := range strings.FieldsFunc(valor, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			itens = append(itens, item)
+		}
+	}
+	return itens
+}
+
+func novoFiltroPalavras(c context.Context) Filtro {
+	return Filtro{
+		Nome:  FiltroPalavras,
+		Acao:  acaoFiltro(c, FiltroPalavras, AcaoFiltroRejeitar),
+		Regra: NovaRegraPalavras(listaConfig(c, config.FiltroPalavras)),
+	}
+}
+
+func novoFiltroLinks(c context.Context) Filtro {
+	return Filtro{
+		Nome:  FiltroLinks,
+		Acao:  acaoFiltro(c, FiltroLinks, AcaoFiltroRejeitar),
+		Regra: NovaRegraLinks(listaConfig(c, config.FiltroLinks)),
+	}
+}
+
+func novoFiltroRepeticao(c context.Context) Filtro {
+	return Filtro{
+		Nome:  FiltroRepeticao,
+		Acao:  acaoFiltro(c, FiltroRepeticao, AcaoFiltroOcultar),
+		Regra: RegraConteudoFunc(verificarRepeticao),
+	}
+}
+
+func novoFiltroRajada(c context.Context) Filtro {
+	limite, err := strconv.Atoi(config.GetDefault(c, config.FiltroRajadaLimite, "").Value)
+	if err != nil || limite < 1 {
+		limite = limiteRajadaPadrao
+	}
+	janela := janelaRajadaPadrao
+	if minutos, err := strconv.Atoi(config.GetDefault(c, config.FiltroRajadaJanela, "").Value); err == nil && minutos > 0 {
+		janela = time.Duration(minutos) * time.Minute
+	}
+
+	return Filtro{
+		Nome:  FiltroRajada,
+		Acao:  acaoFiltro(c, FiltroRajada, AcaoFiltroSinalizar),
+		Regra: RegraRajada{Limite: limite, Janela: janela},
+	}
+}
+
+// normalizarTexto deixa o texto em minusculas, sem acentos e com as palavras separadas por um espaço
+func normalizarTexto(texto string) string {
+	texto = strings.ToLower(utils.LimparString(texto))
+	palavras := strings.FieldsFunc(texto, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(palavras, " ")
+}
+
+func textoPublicacao(publicacao *Publicacao) string {
+	return publicacao.Titulo + "\n" + publicacao.Conteudo
+}
+
+// RegraPalavras recusa publicações com palavras ou expressões proibidas. A comparação ignora acentos
+// e maiusculas e só casa palavras inteiras, então "cao" não pega "caodromo".
+type RegraPalavras struct {
+	expressoes []string
+}
+
+func NovaRegraPalavras(palavras []string) RegraPalavras {
+	regra := RegraPalavras{}
+	for _, p := range palavras {
+		if p = normalizarTexto(p); p != "" {
+			regra.expressoes = append(regra.expressoes, p)
+		}
+	}
+	return regra
+}
+
+func (regra RegraPalavras) Verificar(avaliacao *Avaliacao) (string, bool) {
+	for _, campo := range []string{avaliacao.Publicacao.Titulo, avaliacao.Publicacao.Conteudo} {
+		texto := " " + normalizarTexto(campo) + " "
+		for _, expressao := range regra.expressoes {
+			if strings.Contains(texto, " "+expressao+" ") {
+				return "contém termo proibido", true
+			}
+		}
+	}
+	return "", false
+}
+
+// RegraLinks recusa publicações com links para dominios bloqueados, incluindo os subdominios
+type RegraLinks struct {
+	dominios map[string]*regexp.Regexp
+}
+
+func NovaRegraLinks(dominios []string) RegraLinks {
+	regra := RegraLinks{dominios: make(map[string]*regexp.Regexp)}
+	for _, d := range dominios {
+		d = strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(d), "http://"), "https://")
+		d = strings.Trim(strings.TrimPrefix(d, "www."), "/")
+		if d == "" {
+			continue
+		}
+		regra.dominios[d] = regexp.MustCompile(`(?i)(^|[^a-z0-9.-])([a-z0-9-]+\.)*` + regexp.QuoteMeta(d) + `([^a-z0-9-]|$)`)
+	}
+	return regra
+}
+
+func (regra RegraLinks) Verificar(avaliacao *Avaliacao) (string, bool) {
+	texto := textoPublicacao(avaliacao.Publicacao)
+	for dominio, expressao := range regra.dominios {
+		if expressao.MatchString(texto) {
+			return fmt.Sprintf("contém link bloqueado (%s)", dominio), true
+		}
+	}
+	return "", false
+}
+
+// verificarRepeticao pega a mesma publicação repetida pelo autor dentro da janela do historico
+func verificarRepeticao(avaliacao *Avaliacao) (string, bool) {
+	texto := normalizarTexto(textoPublicacao(avaliacao.Publicacao))
+	for i := range avaliacao.Recentes {
+		recente := &avaliacao.Recentes[i]
+		if recente.ID == avaliacao.Publicacao.ID || recente.Repost() != avaliacao.Publicacao.Repost() {
+			continue
+		}
+		if normalizarTexto(textoPublicacao(recente)) == texto {
+			return "publicação repetida", true
+		}
+	}
+	return "", false
+}
+
+// RegraRajada pega autores que publicam Limite vezes ou mais dentro da Janela. Só vale
+// para publicações novas; editar não conta como publicar de novo.
+type RegraRajada struct {
+	Limite int
+	Janela time.Duration
+}
+
+func (regra RegraRajada) Verificar(avaliacao *Avaliacao) (string, bool) {
+	if avaliacao.Edicao {
+		return "", false
+	}
+
+	inicio := avaliacao.Agora.Add(-regra.Janela)
+	quantidade := 0
+	for _, recente := range avaliacao.Recentes {
+		if recente.DataCriacao.After(inicio) {
+			quantidade++
+		}
+	}
+	if quantidade >= regra.Limite {
+		return fmt.Sprintf("muitas publicações em sequência (%d em %v)", quantidade+1, regra.Janela), true
+	}
+	return "", false
+}
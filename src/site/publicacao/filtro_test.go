@@ -0,0 +1,122 @@
+package publicacao
+
+import (
+	"site/utils"
+	"testing"
+	"time"
+)
+
+func avaliacaoTexto(titulo, conteudo string) *Avaliacao {
+	return &Avaliacao{
+		Publicacao: &Publicacao{Titulo: titulo, Conteudo: conteudo},
+		Agora:      agoraRanking,
+	}
+}
+
+func TestRegraPalavras(t *testing.T) {
+	regra := NovaRegraPalavras([]string{"Palavrão", "golpe do pix", " "})
+
+	casos := []struct {
+		titulo, conteudo string
+		violada          bool
+	}{
+		{"Olá", "um texto qualquer", false},
+		{"Olá", "isso é um PALAVRAO!", true},
+		{"Cuidado", "caí no Golpe do   Pix ontem", true},
+		{"Palavrãozinho", "palavra dentro de outra não conta", false},
+		{"golpe", "do pix separado em titulo e conteudo", false},
+	}
+	for _, caso := range casos {
+		if _, violada := regra.Verificar(avaliacaoTexto(caso.titulo, caso.conteudo)); violada != caso.violada {
+			t.Errorf("%q / %q: violada = %v, esperado %v", caso.titulo, caso.conteudo, violada, caso.violada)
+		}
+	}
+}
+
+func TestRegraLinks(t *testing.T) {
+	regra := NovaRegraLinks([]string{"https://golpe.com/", "www.spam.net"})
+
+	casos := []struct {
+		conteudo string
+		violada  bool
+	}{
+		{"veja https://golpe.com/promo", true},
+		{"acesse GOLPE.COM.", true},
+		{"sub.golpe.com/x", true},
+		{"http://www.spam.net", true},
+		{"naogolpe.com é outro site", false},
+		{"golpe.community não é bloqueado", false},
+		{"sem links aqui", false},
+	}
+	for _, caso := range casos {
+		if _, violada := regra.Verificar(avaliacaoTexto("Link", caso.conteudo)); violada != caso.violada {
+			t.Errorf("%q: violada = %v, esperado %v", caso.conteudo, violada, caso.violada)
+		}
+	}
+}
+
+func TestRegraRepeticao(t *testing.T) {
+	avaliacao := avaliacaoTexto("Promoção", "Compre já!")
+	avaliacao.Recentes = []Publicacao{{ID: 1, Titulo: "promocao", Conteudo: "compre ja"}}
+
+	if _, violada := verificarRepeticao(avaliacao); !violada {
+		t.Errorf("publicação igual à recente deveria ser repetição")
+	}
+
+	avaliacao.Publicacao.ID = 1
+	if _, violada := verificarRepeticao(avaliacao); violada {
+		t.Errorf("a propria publicação em edição não conta como repetição")
+	}
+}
+
+func TestRegraRajada(t *testing.T) {
+	regra := RegraRajada{Limite: 3, Janela: 10 * time.Minute}
+	recente := func(idade time.Duration) Publicacao {
+		return Publicacao{DataCriacao: utils.JsonSpecialDateTime{Time: agoraRanking.Add(-idade)}}
+	}
+
+	avaliacao := avaliacaoTexto("Oi", "oi")
+	avaliacao.Recentes = []Publicacao{recente(time.Minute), recente(5 * time.Minute), recente(time.Hour)}
+	if _, violada := regra.Verificar(avaliacao); violada {
+		t.Errorf("duas publicações na janela não deveriam ser rajada")
+	}
+
+	avaliacao.Recentes = append(avaliacao.Recentes, recente(9*time.Minute))
+	if _, violada := regra.Verificar(avaliacao); !violada {
+		t.Errorf("três publicações na janela deveriam ser rajada")
+	}
+
+	avaliacao.Edicao = true
+	if _, violada := regra.Verificar(avaliacao); violada {
+		t.Errorf("edição não deveria contar como rajada")
+	}
+}
+
+func TestAplicarFiltros(t *testing.T) {
+	violada := RegraConteudoFunc(func(*Avaliacao) (string, bool) { return "motivo", true })
+	aprovada := RegraConteudoFunc(func(*Avaliacao) (string, bool) { return "", false })
+
+	casos := []struct {
+		nome    string
+		filtros []Filtro
+		acao    string
+	}{
+		{"nenhuma violação", []Filtro{{Nome: "a", Acao: AcaoFiltroRejeitar, Regra: aprovada}}, ""},
+		{"sinalizar", []Filtro{{Nome: "a", Acao: AcaoFiltroSinalizar, Regra: violada}}, AcaoFiltroSinalizar},
+		{"ocultar vence sinalizar", []Filtro{
+			{Nome: "a", Acao: AcaoFiltroOcultar, Regra: violada},
+			{Nome: "b", Acao: AcaoFiltroSinalizar, Regra: violada},
+		}, AcaoFiltroOcultar},
+		{"rejeitar vence todas", []Filtro{
+			{Nome: "a", Acao: AcaoFiltroSinalizar, Regra: violada},
+			{Nome: "b", Acao: AcaoFiltroRejeitar, Regra: violada},
+			{Nome: "c", Acao: AcaoFiltroOcultar, Regra: violada},
+		}, AcaoFiltroRejeitar},
+	}
+	for _, caso := range casos {
+		resultado := AplicarFiltros(caso.filtros, avaliacaoTexto("t", "c"))
+		if resultado.Acao != caso.acao {
+			t.Errorf("%s: Acao = %q, esperado %q", caso.nome, resultado.Acao, caso.acao)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package publicacao
+
+import (
+	"context"
+	"fmt"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindHashtags = "Hashtags"
+
+	PeriodoEmAltaPadrao = 24 * time.Hour
+	PeriodoEmAltaMaximo = 7 * 24 * time.Hour
+	LimiteEmAltaPadrao  = 10
+
+	//Usos mais recentes lidos para montar as tags em alta
+	maximoHashtagsEmAlta = 5000
+)
+
+// Hashtag liga uma tag a uma publicação. A chave é "tag:idpublicacao", então gravar de novo não duplica.
+// Publica diz se a publicação é visivel para todos; só essas contam nas tags em alta.
+type Hashtag struct {
+	Tag          string
+	PublicacaoID int64
+	AutorID      int64
+	Publica      bool
+	DataCriacao  time.Time
+}
+
+// HashtagEmAlta é uma tag com a quantidade de publicações que a usaram no periodo
+type HashtagEmAlta struct {
+	Tag         string
+	Publicacoes int64
+}
+
+func chaveHashtag(tag string, publicacaoID int64) *datastore.Key {
+	return datastore.NameKey(KindHashtags, fmt.Sprintf("%s:%d", tag, publicacaoID), nil)
+}
+
+// sincronizarHashtags grava as tags novas da publicação e remove as que deixaram de ser usadas
+func sincronizarHashtags(c context.Context, publicacao *Publicacao, anteriores []string) error {
+	var novasKeys, removidasKeys []*datastore.Key
+	var novas []Hashtag
+
+	for _, tag := range publicacao.Hashtags {
+		if utils.InArray(tag, anteriores) {
+			continue
+		}
+		novasKeys = append(novasKeys, chaveHashtag(tag, publicacao.ID))
+		novas = append(novas, Hashtag{
+			Tag:          tag,
+			PublicacaoID: publicacao.ID,
+			AutorID:      publicacao.AutorID,
+			Publica:      publicacao.visibilidade() == VisibilidadePublica && !publicacao.Oculta,
+			DataCriacao:  utils.GetTimeNow(),
+		})
+	}
+
+	for _, tag := range anteriores {
+		if !utils.InArray(tag, publicacao.Hashtags) {
+			removidasKeys = append(removidasKeys, chaveHashtag(tag, publicacao.ID))
+		}
+	}
+
+	if len(novasKeys) == 0 && len(removidasKeys) == 0 {
+		return nil
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	if len(novasKeys) > 0 {
+		if _, err = datastoreClient.PutMulti(c, novasKeys, novas); err != nil {
+			log.Warningf(c, "Erro ao inserir hashtags: %v", err)
+			return err
+		}
+	}
+
+	if len(removidasKeys) > 0 {
+		if err = datastoreClient.DeleteMulti(c, removidasKeys); err != nil {
+			log.Warningf(c, "Erro ao remover hashtags: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// removerHashtags apaga todas as tags de uma publicação excluida ou ocultada
+func removerHashtags(c context.Context, publicacao Publicacao) error {
+	anteriores := publicacao.Hashtags
+	publicacao.Hashtags = nil
+	return sincronizarHashtags(c, &publicacao, anteriores)
+}
+
+// BuscarPorHashtag traz as publicações que usam a tag e que o leitor pode ver, da mais recente para a mais antiga
+func BuscarPorHashtag(c context.Context, leitorID int64, tag string) ([]Publicacao, error) {
+	tag = NormalizarHashtag(tag)
+	if tag == "" {
+		return nil, fmt.Errorf("Hashtag inválida")
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	var hashtags []Hashtag
+	q := datastore.NewQuery(KindHashtags).Filter("Tag =", tag)
+	if _, err = datastoreClient.GetAll(c, q, &hashtags); err != nil {
+		log.Warningf(c, "Erro ao buscar hashtag %s: %v", tag, err)
+		return nil, err
+	}
+
+	keys := make([]*datastore.Key, 0, len(hashtags))
+	for _, h := range hashtags {
+		keys = append(keys, datastore.IDKey(KindPublicacoes, h.PublicacaoID, nil))
+	}
+
+	//O indice de hashtags pode apontar para publicações que já foram apagadas
+	publics, err := buscarExistentes(c, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	leitor := NovoLeitor(c, leitorID)
+	publics = leitor.Filtrar(publics)
+
+	sort.Slice(publics, func(i, j int) bool {
+		return publics[i].DataCriacao.After(publics[j].DataCriacao.Time)
+	})
+	return carregarOriginais(c, leitor, publics)
+}
+
+// HashtagsEmAlta conta as tags das publicações publicas no periodo, de até PeriodoEmAltaMaximo, e retorna
+// as mais usadas. Só os maximoHashtagsEmAlta usos mais recentes entram na contagem.
+func HashtagsEmAlta(c context.Context, periodo time.Duration, limite int) ([]HashtagEmAlta, error) {
+	if periodo <= 0 {
+		periodo = PeriodoEmAltaPadrao
+	}
+	if periodo > PeriodoEmAltaMaximo {
+		periodo = PeriodoEmAltaMaximo
+	}
+	if limite <= 0 {
+		limite = LimiteEmAltaPadrao
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	var hashtags []Hashtag
+	q := datastore.NewQuery(KindHashtags).
+		Filter("Publica =", true).
+		Filter("DataCriacao >=", utils.GetTimeNow().Add(-periodo)).
+		Order("-DataCriacao").
+		Limit(maximoHashtagsEmAlta)
+	if _, err = datastoreClient.GetAll(c, q, &hashtags); err != nil {
+		log.Warningf(c, "Erro ao buscar hashtags recentes: %v", err)
+		return nil, err
+	}
+
+	contagem := make(map[string]int64)
+	for _, h := range hashtags {
+		contagem[h.Tag]++
+	}
+
+	emAlta := make([]HashtagEmAlta, 0, len(contagem))
+	for tag, total := range contagem {
+		emAlta = append(emAlta, HashtagEmAlta{Tag: tag, Publicacoes: total})
+	}
+
+	sort.Slice(emAlta, func(i, j int) bool {
+		if emAlta[i].Publicacoes != emAlta[j].Publicacoes {
+			return emAlta[i].Publicacoes > emAlta[j].Publicacoes
+		}
+		return emAlta[i].Tag < emAlta[j].Tag
+	})
+
+	if len(emAlta) > limite {
+		emAlta = emAlta[:limite]
+	}
+	return emAlta, nil
+}
@@ -0,0 +1,132 @@
+package publicacao
+
+import (
+	"context"
+	"regexp"
+	"site/notificacao"
+	"site/usuario"
+	"site/utils"
+	"site/utils/log"
+	"strings"
+	"unicode"
+)
+
+const (
+	TamanhoMaximoHashtag = 50
+	MaxMencoes           = 10
+)
+
+var (
+	// O caractere anterior precisa ser inicio de texto ou separador, evitando casar com urls (site.com/#ancora) e emails (a@b.com)
+	regexHashtag = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#&/])#([\p{L}\p{N}_]+)`)
+	regexMencao  = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@./])@([\p{L}\p{N}_.]+)`)
+)
+
+// Mencao é um usuario citado com @nick no conteudo da publicação
+type Mencao struct {
+	UsuarioID int64
+	Nick      string
+}
+
+// ExtrairHashtags retorna as hashtags do texto, sem o # e em minusculo, na ordem em que aparecem
+func ExtrairHashtags(texto string) []string {
+	var hashtags []string
+	for _, grupo := range regexHashtag.FindAllStringSubmatch(texto, -1) {
+		tag := NormalizarHashtag(grupo[1])
+		if tag == "" || utils.InArray(tag, hashtags) {
+			continue
+		}
+		hashtags = append(hashtags, tag)
+	}
+	return hashtags
+}
+
+// NormalizarHashtag deixa a hashtag no formato em que é gravada. Hashtags só com numeros ou muito longas são descartadas.
+func NormalizarHashtag(tag string) string {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if len([]rune(tag)) > TamanhoMaximoHashtag || strings.IndexFunc(tag, unicode.IsLetter) < 0 {
+		return ""
+	}
+	return tag
+}
+
+// ExtrairMencoes retorna os nicks citados com @ no texto, na ordem em que aparecem
+func ExtrairMencoes(texto string) []string {
+	var nicks []string
+	for _, grupo := range regexMencao.FindAllStringSubmatch(texto, -1) {
+		nick := strings.TrimRight(grupo[1], ".")
+		if nick == "" || utils.InArray(nick, nicks) {
+			continue
+		}
+		nicks = append(nicks, nick)
+	}
+	return nicks
+}
+
+// prepararMarcacoes extrai as hashtags e resolve as menções do conteudo para o id dos usuarios
+func prepararMarcacoes(c context.Context, publicacao *Publicacao) {
+	publicacao.Hashtags = ExtrairHashtags(publicacao.Conteudo)
+	publicacao.Mencoes = nil
+
+	for _, nick := range ExtrairMencoes(publicacao.Conteudo) {
+		if len(publicacao.Mencoes) == MaxMencoes {
+			log.Debugf(c, "Limite de %d menções atingido, demais nicks ignorados", MaxMencoes)
+			break
+		}
+
+		usuarios, err := usuario.FiltrarUsuario(c, usuario.Usuario{Nick: nick})
+		if err != nil {
+			log.Warningf(c, "Falha ao buscar usuario mencionado %s: %v", nick, err)
+			continue
+		}
+		if len(usuarios) == 0 {
+			continue
+		}
+		publicacao.Mencoes = append(publicacao.Mencoes, Mencao{UsuarioID: usuarios[0].ID, Nick: usuarios[0].Nick})
+	}
+}
+
+// processarMarcacoes atualiza as hashtags gravadas e notifica os usuarios mencionados pela primeira vez.
+// anterior é a publicação antes da edição, ou nil na criação.
+func processarMarcacoes(c context.Context, publicacao *Publicacao, anterior *Publicacao) {
+	var hashtagsAnteriores []string
+	var mencoesAnteriores []Mencao
+	if anterior != nil {
+		hashtagsAnteriores = anterior.Hashtags
+		mencoesAnteriores = anterior.Mencoes
+	}
+
+	if err := sincronizarHashtags(c, publicacao, hashtagsAnteriores); err != nil {
+		log.Warningf(c, "Falha ao gravar hashtags da publicação %d: %v", publicacao.ID, err)
+	}
+
+	for _, mencao := range publicacao.Mencoes {
+		if mencionado(mencoesAnteriores, mencao.UsuarioID) {
+			continue
+		}
+		registrarInteracao(c, publicacao.AutorID, mencao.UsuarioID, interacaoMencao, 1)
+		//Quem não pode ver a publicação não é avisado da menção
+		if !NovoLeitor(c, mencao.UsuarioID).PodeVer(publicacao) {
+			continue
+		}
+		err := notificacao.Notificar(c, notificacao.Notificacao{
+			UsuarioID:    mencao.UsuarioID,
+			Tipo:         notificacao.TipoMencao,
+			OrigemID:     publicacao.AutorID,
+			OrigemNick:   publicacao.AutorNick,
+			PublicacaoID: publicacao.ID,
+		})
+		if err != nil {
+			log.Warningf(c, "Falha ao notificar menção ao usuario %d: %v", mencao.UsuarioID, err)
+		}
+	}
+}
+
+func mencionado(mencoes []Mencao, usuarioID int64) bool {
+	for _, m := range mencoes {
+		if m.UsuarioID == usuarioID {
+			return true
+		}
+	}
+	return false
+}
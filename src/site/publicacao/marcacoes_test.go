@@ -0,0 +1,44 @@
+package publicacao
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtrairHashtags(t *testing.T) {
+	casos := []struct {
+		texto    string
+		esperado []string
+	}{
+		{"Bom dia #Brasil e #brasil de novo", []string{"brasil"}},
+		{"#inicio meio #fim.", []string{"inicio", "fim"}},
+		{"Acentos #SãoPaulo #café_da_manhã", []string{"sãopaulo", "café_da_manhã"}},
+		{"Só numeros #2021 não vale, mas #copa2022 vale", []string{"copa2022"}},
+		{"Links site.com/#ancora e &#39; não são tags", nil},
+		{"colado#tag não conta", nil},
+	}
+
+	for _, caso := range casos {
+		if obtido := ExtrairHashtags(caso.texto); !reflect.DeepEqual(obtido, caso.esperado) {
+			t.Errorf("ExtrairHashtags(%q) = %v, esperado %v", caso.texto, obtido, caso.esperado)
+		}
+	}
+}
+
+func TestExtrairMencoes(t *testing.T) {
+	casos := []struct {
+		texto    string
+		esperado []string
+	}{
+		{"Oi @joao e @maria.silva.", []string{"joao", "maria.silva"}},
+		{"@joao @joao repetido", []string{"joao"}},
+		{"email fulano@exemplo.com não é menção", nil},
+		{"(@ana) entre parenteses", []string{"ana"}},
+	}
+
+	for _, caso := range casos {
+		if obtido := ExtrairMencoes(caso.texto); !reflect.DeepEqual(obtido, caso.esperado) {
+			t.Errorf("ExtrairMencoes(%q) = %v, esperado %v", caso.texto, obtido, caso.esperado)
+		}
+	}
+}
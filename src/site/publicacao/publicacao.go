@@ -3,6 +3,10 @@ package publicacao
 import (
 	"context"
 	"fmt"
+	"site/busca"
+	"site/estabelecimento"
+	"site/eventos"
+	"site/notificacao"
 	"site/seguidores"
 	"site/usuario"
 	"site/utils"
@@ -16,19 +20,57 @@ import (
 
 const (
 	KindPublicacoes = "Publicacoes"
+
+	//Uma transação do Datastore grava no maximo 500 entidades
+	tamanhoLoteDatastore = 500
 )
 
+func init() {
+	estabelecimento.AoExcluir(desmarcarEstabelecimento)
+}
+
+// CurtidasAlteradas é enviado em tempo real quando a quantidade de curtidas de uma publicação muda
+type CurtidasAlteradas struct {
+	PublicacaoID int64
+	Curtidas     int64
+}
+
+// Publicacao feita por um usuario. Com OriginalID preenchido é um repost da publicação original,
+// e o Conteudo, quando houver, é o comentario da citação. Rascunhos e publicações agendadas só são
+// vistos pelo autor até o Status passar para publicada. A Visibilidade limita quem vê a publicação
+// depois de publicada, veja Leitor.
 type Publicacao struct {
-	ID          int64 `datastore:"-"`
-	Titulo      string
-	Conteudo    string
-	AutorID     int64
-	AutorNick   string
-	Curtidas    int64
-	DataCriacao utils.JsonSpecialDateTime
+	ID                  int64 `datastore:"-"`
+	Titulo              string
+	Conteudo            string
+	AutorID             int64
+	AutorNick           string
+	Curtidas            int64
+	Anexos              []Anexo
+	Hashtags            []string
+	Mencoes             []Mencao
+	OriginalID          int64
+	Original            *Publicacao `datastore:"-"`
+	Compartilhamentos   int64
+	Comentarios         int64
+	Revisoes            int64
+	Editada             bool
+	Status              string
+	Visibilidade        string
+	Oculta              bool
+	EstabelecimentoID   int64 // Estabelecimento marcado na publicação
+	EstabelecimentoNome string
+	DataAgendamento     utils.JsonSpecialDateTime
+	DataCriacao         utils.JsonSpecialDateTime
+	DataAtualizacao     utils.JsonSpecialDateTime
+}
+
+// Repost diz se a publicação é um compartilhamento de outra
+func (publicacao *Publicacao) Repost() bool {
+	return publicacao.OriginalID != 0
 }
 
-//Cria uma publicação
+// Cria uma publicação
 func PutPublicacao(c context.Context, publicacao *Publicacao) error {
 	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
 	if err != nil {
@@ -44,9 +86,74 @@ func PutPublicacao(c context.Context, publicacao *Publicacao) error {
 		return err
 	}
 	publicacao.ID = key.ID
+
+	sincronizarBusca(c, publicacao)
 	return nil
 }
 
+// alterarPublicacao lê e grava a publicação na mesma transação. Curtidas, compartilhamentos, edições
+// e a moderação mudam campos diferentes da mesma entidade e nenhum pode gravar por cima dos outros.
+func alterarPublicacao(c context.Context, publicacaoID int64, alterar func(*Publicacao) error) (*Publicacao, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	var public Publicacao
+	key := datastore.IDKey(KindPublicacoes, publicacaoID, nil)
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		public = Publicacao{}
+		if err := tx.Get(key, &public); err != nil {
+			return err
+		}
+		if err := alterar(&public); err != nil {
+			return err
+		}
+		_, err := tx.Put(key, &public)
+		return err
+	})
+	if err == datastore.ErrNoSuchEntity {
+		return nil, fmt.Errorf("Publicação não encontrada")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	public.ID = publicacaoID
+	return &public, nil
+}
+
+func sincronizarBusca(c context.Context, publicacao *Publicacao) {
+	if publicacao.Indexavel() {
+		busca.SincronizarPublicacao(c, publicacao.DocumentoBusca())
+	}
+}
+
+// Indexavel diz se a publicação deve aparecer na busca. Rascunhos, agendadas, ocultadas e restritas
+// ficam de fora, assim como reposts sem comentario, que não têm texto proprio para ser encontrado.
+func (publicacao *Publicacao) Indexavel() bool {
+	if !publicacao.Publicada() || publicacao.Oculta || publicacao.visibilidade() != VisibilidadePublica {
+		return false
+	}
+	return !publicacao.Repost() || publicacao.Conteudo != ""
+}
+
+// DocumentoBusca retorna os dados da publicação que são indexados no Elasticsearch
+func (publicacao *Publicacao) DocumentoBusca() busca.DocumentoPublicacao {
+	return busca.DocumentoPublicacao{
+		ID:          publicacao.ID,
+		Titulo:      publicacao.Titulo,
+		Conteudo:    publicacao.Conteudo,
+		AutorID:     publicacao.AutorID,
+		AutorNick:   publicacao.AutorNick,
+		DataCriacao: publicacao.DataCriacao.Time,
+	}
+}
+
+// CriarPublic grava uma nova publicação. Com Status rascunho ou agendada ela fica guardada só para
+// o autor, sem notificar ninguém, até ser publicada.
 func CriarPublic(c context.Context, usuarioID int64, publicacao *Publicacao) error {
 	usuarioBanco := usuario.GetUsuario(c, usuarioID)
 
@@ -57,18 +164,63 @@ func CriarPublic(c context.Context, usuarioID int64, publicacao *Publicacao) err
 		publicacao.DataCriacao = utils.GetSpecialTimeNow()
 	}
 
-	if publicacao.Titulo == "" {
-		return fmt.Errorf("O titulo não pode estar em branco")
-	}
-
-	if publicacao.Conteudo == "" {
-		return fmt.Errorf("O conteudo não pode estar em branco")
+	switch publicacao.Status {
+	case "", StatusPublicada:
+		publicacao.Status = StatusPublicada
+		publicacao.DataAgendamento = utils.JsonSpecialDateTime{}
+	case StatusRascunho:
+		publicacao.DataAgendamento = utils.JsonSpecialDateTime{}
+	case StatusAgendada:
+		if !publicacao.DataAgendamento.After(utils.GetTimeNow()) {
+			return fmt.Errorf("A data de agendamento precisa estar no futuro")
+		}
+	default:
+		return fmt.Errorf("Status de publicação inválido")
 	}
 
 	publicacao.Titulo = strings.TrimSpace(publicacao.Titulo)
 	publicacao.Conteudo = strings.TrimSpace(publicacao.Conteudo)
 
-	return PutPublicacao(c, publicacao)
+	if err := publicacao.validar(); err != nil {
+		return err
+	}
+
+	if err := publicacao.validarVisibilidade(); err != nil {
+		return err
+	}
+
+	if err := marcarEstabelecimento(c, publicacao); err != nil {
+		return err
+	}
+
+	resultado, err := filtrarConteudo(c, publicacao, false)
+	if err != nil {
+		return err
+	}
+
+	prepararMarcacoes(c, publicacao)
+
+	if err := PutPublicacao(c, publicacao); err != nil {
+		return err
+	}
+
+	resultado.sinalizar(c, publicacao)
+
+	if !publicacao.Publicada() || publicacao.Oculta {
+		return nil
+	}
+
+	processarMarcacoes(c, publicacao, nil)
+	publicarEvento(c, publicacao)
+	return nil
+}
+
+// publicarEvento avisa em tempo real quem acompanha o autor. Publicações privadas e ocultas só o autor vê.
+func publicarEvento(c context.Context, publicacao *Publicacao) {
+	if publicacao.visibilidade() == VisibilidadePrivada || publicacao.Oculta {
+		return
+	}
+	eventos.Publicar(c, eventos.CanalAutor(publicacao.AutorID), eventos.TipoPublicacao, publicacao)
 }
 
 func GetPublicacao(c context.Context, id int64) *Publicacao {
@@ -116,6 +268,51 @@ func GetMultPublicacao(c context.Context, keys []*datastore.Key) ([]Publicacao,
 	return publicacao, nil
 }
 
+// buscarExistentes traz as publicações das chaves, pulando as que já foram apagadas
+func buscarExistentes(c context.Context, keys []*datastore.Key) ([]Publicacao, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	publics := make([]Publicacao, len(keys))
+	encontradas := make([]bool, len(keys))
+	for i := range encontradas {
+		encontradas[i] = true
+	}
+
+	if err = datastoreClient.GetMulti(c, keys, publics); err != nil {
+		errs, ok := err.(datastore.MultiError)
+		if !ok {
+			log.Warningf(c, "Erro ao buscar publicações: %v", err)
+			return nil, err
+		}
+		for i, e := range errs {
+			if e == datastore.ErrNoSuchEntity {
+				encontradas[i] = false
+			} else if e != nil {
+				log.Warningf(c, "Erro ao buscar publicação %d: %v", keys[i].ID, e)
+				return nil, e
+			}
+		}
+	}
+
+	existentes := make([]Publicacao, 0, len(keys))
+	for i := range keys {
+		if encontradas[i] {
+			publics[i].ID = keys[i].ID
+			existentes = append(existentes, publics[i])
+		}
+	}
+	return existentes, nil
+}
+
 func FiltrarPublicacoes(c context.Context, publicacao Publicacao) ([]Publicacao, error) {
 	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
 	if err != nil {
@@ -134,6 +331,10 @@ func FiltrarPublicacoes(c context.Context, publicacao Publicacao) ([]Publicacao,
 		q = q.Filter("AutorID =", publicacao.AutorID)
 	}
 
+	if publicacao.EstabelecimentoID != 0 {
+		q = q.Filter("EstabelecimentoID =", publicacao.EstabelecimentoID)
+	}
+
 	if publicacao.ID != 0 {
 		key := datastore.IDKey(KindPublicacoes, publicacao.ID, nil)
 		q = q.Filter("__key__ =", key)
@@ -148,7 +349,15 @@ func FiltrarPublicacoes(c context.Context, publicacao Publicacao) ([]Publicacao,
 	return GetMultPublicacao(c, keys)
 }
 
-func Buscar(c context.Context, usuarioID int64) ([]Publicacao, error) {
+func Buscar(c context.Context, usuarioID int64, modo string) ([]Publicacao, error) {
+	var ranqueador Ranqueador
+	if modo != "" && modo != FeedCronologico {
+		var ok bool
+		if ranqueador, ok = Ranqueadores[modo]; !ok {
+			return nil, fmt.Errorf("Modo de feed inválido: %v", modo)
+		}
+	}
+
 	var publicacao Publicacao
 
 	publicacao.AutorID = usuarioID
@@ -158,6 +367,8 @@ func Buscar(c context.Context, usuarioID int64) ([]Publicacao, error) {
 		log.Warningf(c, "Erro ao filtrar publicações pelo usuarioID: %v", err)
 		return nil, err
 	}
+	leitor := NovoLeitor(c, usuarioID)
+	publics = somentePublicadas(publics)
 
 	seguidos, err := seguidores.BuscarUsuariosSeguidos(c, usuarioID)
 	if err != nil {
@@ -173,7 +384,7 @@ func Buscar(c context.Context, usuarioID int64) ([]Publicacao, error) {
 				log.Warningf(c, "Erro filtrar publicações pelo usuarioID dos seguidos: %v", err)
 				return nil, err
 			}
-			publics = append(publics, publicSeguidos...)
+			publics = append(publics, leitor.Filtrar(somentePublicadas(publicSeguidos))...)
 		}
 	}
 
@@ -182,21 +393,98 @@ func Buscar(c context.Context, usuarioID int64) ([]Publicacao, error) {
 		return publics[i].DataCriacao.After(publics[j].DataCriacao.Time)
 	})
 
+	publics, err = carregarOriginais(c, leitor, publics)
+	if err != nil || ranqueador == nil {
+		return publics, err
+	}
+
+	//Sem as afinidades o feed ranqueado cai para a ordem cronologica
+	afinidades, err := BuscarAfinidades(c, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar afinidades, feed do usuario %d ficará cronologico: %v", usuarioID, err)
+		return publics, nil
+	}
+
+	Ranquear(publics, ranqueador, afinidades, utils.GetTimeNow())
 	return publics, nil
 }
 
-func Atualizar(c context.Context, publicacao Publicacao) error {
+// Atualizar troca o titulo e o conteudo da publicação. O texto anterior fica guardado em uma revisão
+// e a data de criação é mantida; a data da edição vai para DataAtualizacao. Rascunhos e agendadas
+// ainda não foram vistos por ninguém, então são alterados sem gerar revisão.
+func Atualizar(c context.Context, publicacao Publicacao) (*Publicacao, error) {
 	publicBanco := GetPublicacao(c, publicacao.ID)
+	if publicBanco == nil {
+		return nil, fmt.Errorf("Publicação não encontrada")
+	}
 
-	publicBanco.Titulo = publicacao.Titulo
-	publicBanco.Conteudo = publicacao.Conteudo
+	titulo := strings.TrimSpace(publicacao.Titulo)
+	conteudo := strings.TrimSpace(publicacao.Conteudo)
 
-	publicacao.AutorID = publicBanco.AutorID
-	publicacao.AutorNick = publicBanco.AutorNick
+	if titulo == publicBanco.Titulo && conteudo == publicBanco.Conteudo {
+		return publicBanco, nil
+	}
 
-	publicacao.DataCriacao = utils.GetSpecialTimeNow()
+	atualizada := *publicBanco
+	atualizada.Titulo = titulo
+	atualizada.Conteudo = conteudo
+
+	if err := atualizada.validar(); err != nil {
+		return nil, err
+	}
 
-	return PutPublicacao(c, &publicacao)
+	resultado, err := filtrarConteudo(c, &atualizada, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !publicBanco.Publicada() {
+		rascunho, err := atualizarRascunho(c, publicBanco, &atualizada)
+		if err == nil {
+			resultado.sinalizar(c, rascunho)
+		}
+		return rascunho, err
+	}
+
+	atualizada.Revisoes++
+	atualizada.Editada = true
+	atualizada.DataAtualizacao = utils.GetSpecialTimeNow()
+
+	prepararMarcacoes(c, &atualizada)
+
+	if err := salvarEdicao(c, publicBanco, &atualizada); err != nil {
+		return nil, err
+	}
+
+	resultado.sinalizar(c, &atualizada)
+
+	if atualizada.Oculta {
+		busca.DesindexarPublicacao(c, atualizada.ID)
+		if err := removerHashtags(c, *publicBanco); err != nil {
+			log.Warningf(c, "Falha ao remover hashtags da publicação ocultada %d: %v", atualizada.ID, err)
+		}
+		return &atualizada, nil
+	}
+
+	sincronizarBusca(c, &atualizada)
+	processarMarcacoes(c, &atualizada, publicBanco)
+	return &atualizada, nil
+}
+
+// atualizarRascunho grava o novo texto sem passar por cima de um agendamento que acabou de ser publicado
+func atualizarRascunho(c context.Context, anterior, atualizada *Publicacao) (*Publicacao, error) {
+	prepararMarcacoes(c, atualizada)
+
+	titulo, conteudo := atualizada.Titulo, atualizada.Conteudo
+	hashtags, mencoes := atualizada.Hashtags, atualizada.Mencoes
+	oculta := atualizada.Oculta
+
+	return alterarStatus(c, anterior.ID, func(public *Publicacao) error {
+		public.Titulo, public.Conteudo = titulo, conteudo
+		public.Hashtags, public.Mencoes = hashtags, mencoes
+		public.Oculta = public.Oculta || oculta
+		return public.validar()
+	})
 }
 
 func Deletar(c context.Context, publicacao Publicacao) error {
@@ -212,10 +500,49 @@ func Deletar(c context.Context, publicacao Publicacao) error {
 		log.Warningf(c, "Falha ao deletar publicação: %v", err)
 		return err
 	}
+
+	busca.DesindexarPublicacao(c, publicacao.ID)
+
+	if err = removerHashtags(c, publicacao); err != nil {
+		log.Warningf(c, "Falha ao remover hashtags da publicação %d: %v", publicacao.ID, err)
+	}
+
+	if err = removerRevisoes(c, publicacao); err != nil {
+		log.Warningf(c, "Falha ao remover revisões da publicação %d: %v", publicacao.ID, err)
+	}
+
+	if err = RemoverAnexos(c, publicacao.Anexos); err != nil {
+		log.Warningf(c, "Falha ao remover anexos da publicação %d: %v", publicacao.ID, err)
+	}
+
+	if publicacao.Repost() {
+		if err = alterarCompartilhamentos(c, publicacao.OriginalID, -1, publicacao.Conteudo != ""); err != nil {
+			log.Warningf(c, "Falha ao descontar compartilhamento da publicação %d: %v", publicacao.OriginalID, err)
+		}
+	}
 	return nil
 }
 
-func BuscarPorUsuario(c context.Context, usuarioID int64) ([]Publicacao, error) {
+// Ocultar esconde a publicação de todos, menos do autor, e tira ela da busca. Usado pela moderação.
+func Ocultar(c context.Context, publicacaoID int64) (*Publicacao, error) {
+	public, err := alterarPublicacao(c, publicacaoID, func(banco *Publicacao) error {
+		banco.Oculta = true
+		return nil
+	})
+	if err != nil {
+		log.Warningf(c, "Erro ao ocultar publicação %d: %v", publicacaoID, err)
+		return nil, err
+	}
+
+	busca.DesindexarPublicacao(c, publicacaoID)
+	if err = removerHashtags(c, *public); err != nil {
+		log.Warningf(c, "Falha ao remover hashtags da publicação ocultada %d: %v", publicacaoID, err)
+	}
+	return public, nil
+}
+
+// BuscarPorUsuario traz as publicações do usuario que o leitor pode ver
+func BuscarPorUsuario(c context.Context, leitorID, usuarioID int64) ([]Publicacao, error) {
 	var publicacao Publicacao
 	publicacao.AutorID = usuarioID
 
@@ -225,34 +552,188 @@ func BuscarPorUsuario(c context.Context, usuarioID int64) ([]Publicacao, error)
 		return nil, err
 	}
 
-	return publics, nil
+	leitor := NovoLeitor(c, leitorID)
+	return carregarOriginais(c, leitor, leitor.Filtrar(somentePublicadas(publics)))
 }
 
-func Curtir(c context.Context, publicacaoID int64) error {
-	public := GetPublicacao(c, publicacaoID)
+// BuscarPorEstabelecimento traz as publicações que marcaram o estabelecimento e que o leitor pode ver,
+// das mais novas para as mais antigas
+func BuscarPorEstabelecimento(c context.Context, leitorID, estabelecimentoID int64) ([]Publicacao, error) {
+	publics, err := FiltrarPublicacoes(c, Publicacao{EstabelecimentoID: estabelecimentoID})
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar publicações do estabelecimento %d: %v", estabelecimentoID, err)
+		return nil, err
+	}
+
+	leitor := NovoLeitor(c, leitorID)
+	publics = leitor.Filtrar(publics)
+
+	sort.Slice(publics, func(i, j int) bool {
+		return publics[i].DataCriacao.After(publics[j].DataCriacao.Time)
+	})
+	return carregarOriginais(c, leitor, publics)
+}
+
+// marcarEstabelecimento confere se o estabelecimento marcado existe e guarda o nome dele na publicação
+func marcarEstabelecimento(c context.Context, publicacao *Publicacao) error {
+	publicacao.EstabelecimentoNome = ""
+	if publicacao.EstabelecimentoID == 0 {
+		return nil
+	}
+
+	estab := estabelecimento.GetEstabelecimento(c, publicacao.EstabelecimentoID)
+	if estab == nil {
+		return fmt.Errorf("Estabelecimento marcado não encontrado")
+	}
+	publicacao.EstabelecimentoNome = estab.Nome
+	return nil
+}
 
-	public.Curtidas++
+// desmarcarEstabelecimento tira a marcação de um estabelecimento excluido das publicações. Cada lote é
+// gravado em uma transação para não perder curtidas e contadores alterados no meio tempo.
+func desmarcarEstabelecimento(c context.Context, estabelecimentoID int64) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	q := datastore.NewQuery(KindPublicacoes).Filter("EstabelecimentoID =", estabelecimentoID).KeysOnly()
+	keys, err := datastoreClient.GetAll(c, q, nil)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar publicações do estabelecimento %d: %v", estabelecimentoID, err)
+		return err
+	}
+
+	for inicio := 0; inicio < len(keys); inicio += tamanhoLoteDatastore {
+		fim := inicio + tamanhoLoteDatastore
+		if fim > len(keys) {
+			fim = len(keys)
+		}
+		lote := keys[inicio:fim]
+
+		_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+			publics := make([]Publicacao, len(lote))
+			existentes := lote
+			if err := tx.GetMulti(lote, publics); err != nil {
+				errs, ok := err.(datastore.MultiError)
+				if !ok {
+					return err
+				}
+				//Publicações apagadas depois da consulta ficam de fora
+				existentes = make([]*datastore.Key, 0, len(lote))
+				encontradas := make([]Publicacao, 0, len(lote))
+				for i, e := range errs {
+					if e == nil {
+						existentes = append(existentes, lote[i])
+						encontradas = append(encontradas, publics[i])
+					} else if e != datastore.ErrNoSuchEntity {
+						return e
+					}
+				}
+				publics = encontradas
+			}
+
+			for i := range publics {
+				publics[i].EstabelecimentoID = 0
+				publics[i].EstabelecimentoNome = ""
+			}
+			_, err := tx.PutMulti(existentes, publics)
+			return err
+		})
+		if err != nil {
+			log.Warningf(c, "Erro ao desmarcar o estabelecimento %d das publicações: %v", estabelecimentoID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// somentePublicadas retira da lista os rascunhos e as publicações agendadas
+func somentePublicadas(publics []Publicacao) []Publicacao {
+	publicadas := make([]Publicacao, 0, len(publics))
+	for _, p := range publics {
+		if p.Publicada() {
+			publicadas = append(publicadas, p)
+		}
+	}
+	return publicadas
+}
 
-	if err := Atualizar(c, *public); err != nil {
+var errNaoCurtida = fmt.Errorf("Não tem como descurtir uma publicação que não foi curtida")
+
+func Curtir(c context.Context, publicacaoID, usuarioID int64) error {
+	public := GetPublicacao(c, publicacaoID)
+	if public == nil || !public.Publicada() || !NovoLeitor(c, usuarioID).PodeVer(public) {
+		return fmt.Errorf("Publicação não encontrada")
+	}
+
+	public, err := alterarPublicacao(c, publicacaoID, func(banco *Publicacao) error {
+		banco.Curtidas++
+		return nil
+	})
+	if err != nil {
 		log.Warningf(c, "Erro ao atualizar curtida da publicação no banco: %v", err)
 		return err
 	}
+
+	publicarCurtidas(c, public)
+	registrarInteracao(c, usuarioID, public.AutorID, interacaoCurtida, 1)
+	notificarCurtida(c, public, usuarioID)
 	return nil
 }
 
-func Descurtir(c context.Context, publicacaoID int64) error {
+// notificarCurtida avisa o autor da publicação. Falhas não desfazem a curtida.
+func notificarCurtida(c context.Context, public *Publicacao, usuarioID int64) {
+	var nick string
+	if usu := usuario.GetUsuario(c, usuarioID); usu != nil {
+		nick = usu.Nick
+	}
+
+	err := notificacao.Notificar(c, notificacao.Notificacao{
+		UsuarioID:    public.AutorID,
+		Tipo:         notificacao.TipoCurtida,
+		OrigemID:     usuarioID,
+		OrigemNick:   nick,
+		PublicacaoID: public.ID,
+	})
+	if err != nil {
+		log.Warningf(c, "Falha ao notificar curtida na publicação %d: %v", public.ID, err)
+	}
+}
+
+func Descurtir(c context.Context, publicacaoID, usuarioID int64) error {
 	public := GetPublicacao(c, publicacaoID)
+	if public == nil || !public.Publicada() || !NovoLeitor(c, usuarioID).PodeVer(public) {
+		return fmt.Errorf("Publicação não encontrada")
+	}
 
-	if public.Curtidas > 0 {
-		public.Curtidas--
-	} else {
+	public, err := alterarPublicacao(c, publicacaoID, func(banco *Publicacao) error {
+		if banco.Curtidas <= 0 {
+			return errNaoCurtida
+		}
+		banco.Curtidas--
+		return nil
+	})
+	if err == errNaoCurtida {
 		log.Warningf(c, "Não tem como descurtir uma publicação que não foi curtido")
-		return fmt.Errorf("Não tem como descurtir uma publicação que não foi curtida")
+		return err
 	}
-
-	if err := Atualizar(c, *public); err != nil {
+	if err != nil {
 		log.Warningf(c, "Erro ao atualizar descurtida da publicação no banco: %v", err)
 		return err
 	}
+
+	publicarCurtidas(c, public)
+	registrarInteracao(c, usuarioID, public.AutorID, interacaoCurtida, -1)
 	return nil
 }
+
+// publicarCurtidas avisa a nova contagem a quem acompanha o autor, com a mesma regra de publicarEvento
+func publicarCurtidas(c context.Context, public *Publicacao) {
+	if public.visibilidade() == VisibilidadePrivada || public.Oculta {
+		return
+	}
+	eventos.Publicar(c, eventos.CanalAutor(public.AutorID), eventos.TipoCurtidas, CurtidasAlteradas{PublicacaoID: public.ID, Curtidas: public.Curtidas})
+}
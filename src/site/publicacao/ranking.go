@@ -0,0 +1,104 @@
+package publicacao
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	FeedCronologico = "cronologico"
+	FeedRelevancia  = "relevancia"
+)
+
+// Sinais são os dados de uma publicação do feed usados para ranquear, já calculados para o leitor
+type Sinais struct {
+	Idade             time.Duration
+	Curtidas          int64
+	Comentarios       int64
+	Compartilhamentos int64
+	Afinidade         float64 // pontos de interação do leitor com o autor, veja Afinidade.Pontos
+}
+
+// Ranqueador dá uma pontuação para cada publicação; no feed as maiores aparecem primeiro
+type Ranqueador interface {
+	Pontuar(sinais Sinais) float64
+}
+
+// RanqueadorFunc permite usar uma função simples como Ranqueador
+type RanqueadorFunc func(sinais Sinais) float64
+
+func (f RanqueadorFunc) Pontuar(sinais Sinais) float64 {
+	return f(sinais)
+}
+
+// RanqueadorRelevancia multiplica o engajamento e a afinidade por um decaimento exponencial da
+// idade: a cada MeiaVida a pontuação cai pela metade, então uma publicação antiga só passa uma
+// recente se tiver muito mais engajamento.
+type RanqueadorRelevancia struct {
+	MeiaVida         time.Duration
+	PesoCurtidas     float64
+	PesoComentarios  float64
+	PesoCompartilhar float64
+	PesoAfinidade    float64
+}
+
+func (r RanqueadorRelevancia) Pontuar(sinais Sinais) float64 {
+	engajamento := r.PesoCurtidas*math.Log1p(float64(sinais.Curtidas)) +
+		r.PesoComentarios*math.Log1p(float64(sinais.Comentarios)) +
+		r.PesoCompartilhar*math.Log1p(float64(sinais.Compartilhamentos))
+	afinidade := r.PesoAfinidade * math.Log1p(sinais.Afinidade)
+
+	idade := sinais.Idade
+	if idade < 0 {
+		idade = 0
+	}
+	decaimento := math.Exp2(-float64(idade) / float64(r.MeiaVida))
+
+	return (1 + engajamento + afinidade) * decaimento
+}
+
+// Ranqueadores lista os modos de feed ranqueados aceitos por Buscar. Trocar ou incluir um
+// Ranqueador aqui muda o feed sem mexer na busca.
+var Ranqueadores = map[string]Ranqueador{
+	FeedRelevancia: RanqueadorRelevancia{
+		MeiaVida:         12 * time.Hour,
+		PesoCurtidas:     1,
+		PesoComentarios:  1.5,
+		PesoCompartilhar: 1.2,
+		PesoAfinidade:    1,
+	},
+}
+
+// sinais monta os Sinais da publicação. Em reposts o engajamento é o da original, mas a idade
+// e a afinidade são as de quem compartilhou, que é quem o leitor segue.
+func (publicacao *Publicacao) sinais(afinidades map[int64]float64, agora time.Time) Sinais {
+	engajamento := publicacao
+	if publicacao.Original != nil {
+		engajamento = publicacao.Original
+	}
+
+	return Sinais{
+		Idade:             agora.Sub(publicacao.DataCriacao.Time),
+		Curtidas:          engajamento.Curtidas,
+		Comentarios:       engajamento.Comentarios,
+		Compartilhamentos: engajamento.Compartilhamentos,
+		Afinidade:         afinidades[publicacao.AutorID],
+	}
+}
+
+// Ranquear ordena as publicações pela pontuação do ranqueador. Empates ficam com a mais recente primeiro.
+func Ranquear(publics []Publicacao, ranqueador Ranqueador, afinidades map[int64]float64, agora time.Time) {
+	pontuacoes := make(map[int64]float64, len(publics))
+	for i := range publics {
+		pontuacoes[publics[i].ID] = ranqueador.Pontuar(publics[i].sinais(afinidades, agora))
+	}
+
+	sort.SliceStable(publics, func(i, j int) bool {
+		pi, pj := pontuacoes[publics[i].ID], pontuacoes[publics[j].ID]
+		if pi != pj {
+			return pi > pj
+		}
+		return publics[i].DataCriacao.After(publics[j].DataCriacao.Time)
+	})
+}
@@ -0,0 +1,109 @@
+package publicacao
+
+import (
+	"site/utils"
+	"testing"
+	"time"
+)
+
+var agoraRanking = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func publicacaoRanking(id, autorID int64, idade time.Duration, curtidas, comentarios, compartilhamentos int64) Publicacao {
+	return Publicacao{
+		ID:                id,
+		AutorID:           autorID,
+		Curtidas:          curtidas,
+		Comentarios:       comentarios,
+		Compartilhamentos: compartilhamentos,
+		DataCriacao:       utils.JsonSpecialDateTime{Time: agoraRanking.Add(-idade)},
+	}
+}
+
+// fixtureFeed monta um feed com um caso para cada sinal do ranqueador
+func fixtureFeed() []Publicacao {
+	original := publicacaoRanking(100, 9, 48*time.Hour, 80, 10, 30)
+	repost := publicacaoRanking(6, 5, 2*time.Hour, 0, 0, 0)
+	repost.OriginalID = original.ID
+	repost.Original = &original
+
+	return []Publicacao{
+		publicacaoRanking(1, 2, 10*time.Minute, 0, 0, 0),    // recente, sem engajamento
+		publicacaoRanking(2, 2, 3*time.Hour, 40, 5, 8),      // algumas horas, muito engajamento
+		publicacaoRanking(3, 3, 72*time.Hour, 500, 50, 100), // antiga, viral
+		publicacaoRanking(4, 4, 2*time.Hour, 0, 0, 0),       // autor com muita afinidade
+		publicacaoRanking(5, 2, 2*time.Hour, 0, 0, 0),       // mesma idade, sem afinidade
+		repost, // repost de publicação engajada
+	}
+}
+
+func idsFeed(publics []Publicacao) []int64 {
+	ids := make([]int64, len(publics))
+	for i := range publics {
+		ids[i] = publics[i].ID
+	}
+	return ids
+}
+
+func TestRanquearRelevancia(t *testing.T) {
+	publics := fixtureFeed()
+	afinidades := map[int64]float64{4: 30}
+
+	Ranquear(publics, Ranqueadores[FeedRelevancia], afinidades, agoraRanking)
+
+	esperado := []int64{6, 2, 4, 1, 5, 3}
+	obtido := idsFeed(publics)
+	for i := range esperado {
+		if obtido[i] != esperado[i] {
+			t.Fatalf("Ranquear() = %v, esperado %v", obtido, esperado)
+		}
+	}
+}
+
+func TestRelevanciaDecaimento(t *testing.T) {
+	r := Ranqueadores[FeedRelevancia].(RanqueadorRelevancia)
+
+	agora := r.Pontuar(Sinais{Curtidas: 10})
+	meiaVida := r.Pontuar(Sinais{Curtidas: 10, Idade: r.MeiaVida})
+	if diferenca := agora/2 - meiaVida; diferenca > 1e-9 || diferenca < -1e-9 {
+		t.Errorf("depois de uma meia vida a pontuação deveria cair pela metade: %v -> %v", agora, meiaVida)
+	}
+
+	if futuro := r.Pontuar(Sinais{Curtidas: 10, Idade: -time.Hour}); futuro != agora {
+		t.Errorf("idade negativa deveria contar como zero: %v, esperado %v", futuro, agora)
+	}
+}
+
+func TestRelevanciaSinais(t *testing.T) {
+	r := Ranqueadores[FeedRelevancia]
+	base := r.Pontuar(Sinais{Idade: time.Hour})
+
+	casos := map[string]Sinais{
+		"curtidas":          {Idade: time.Hour, Curtidas: 5},
+		"comentarios":       {Idade: time.Hour, Comentarios: 5},
+		"compartilhamentos": {Idade: time.Hour, Compartilhamentos: 5},
+		"afinidade":         {Idade: time.Hour, Afinidade: 5},
+	}
+	for nome, sinais := range casos {
+		if obtido := r.Pontuar(sinais); obtido <= base {
+			t.Errorf("%s deveria aumentar a pontuação: %v <= %v", nome, obtido, base)
+		}
+	}
+}
+
+func TestRanquearRanqueadorPersonalizado(t *testing.T) {
+	publics := fixtureFeed()
+
+	// Um ranqueador que só olha curtidas, para mostrar que a ordenação não depende do padrão
+	porCurtidas := RanqueadorFunc(func(sinais Sinais) float64 {
+		return float64(sinais.Curtidas)
+	})
+	Ranquear(publics, porCurtidas, nil, agoraRanking)
+
+	esperado := []int64{3, 6, 2, 1, 4, 5}
+	obtido := idsFeed(publics)
+	for i := range esperado {
+		if obtido[i] != esperado[i] {
+			t.Fatalf("Ranquear() = %v, esperado %v (empates pela mais recente)", obtido, esperado)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package publicacao
+
+import (
+	"context"
+	"fmt"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindRevisoes = "RevisoesPublicacao"
+)
+
+// Revisao guarda o texto que a publicação tinha antes de uma edição. A chave é "idpublicacao:versao"
+// e a gravação é feita com insert, então uma revisão nunca é sobrescrita.
+type Revisao struct {
+	PublicacaoID int64
+	Versao       int64
+	Titulo       string
+	Conteudo     string `datastore:",noindex"`
+	AutorID      int64
+	DataCriacao  utils.JsonSpecialDateTime
+}
+
+func chaveRevisao(publicacaoID, versao int64) *datastore.Key {
+	return datastore.NameKey(KindRevisoes, fmt.Sprintf("%d:%d", publicacaoID, versao), nil)
+}
+
+// salvarEdicao grava a publicação editada junto com a revisão do texto anterior em uma unica transação.
+// Se outra edição foi gravada depois que a publicação foi lida, nada é gravado.
+func salvarEdicao(c context.Context, anterior, atualizada *Publicacao) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	revisao := novaRevisao(anterior)
+
+	key := datastore.IDKey(KindPublicacoes, anterior.ID, nil)
+	_, err = datastoreClient.RunInTransaction(c, func(tx *datastore.Transaction) error {
+		var banco Publicacao
+		if err := tx.Get(key, &banco); err != nil {
+			return err
+		}
+		if err := mesclarEdicao(&banco, anterior, atualizada); err != nil {
+			return err
+		}
+
+		if _, err := tx.Mutate(datastore.NewInsert(chaveRevisao(revisao.PublicacaoID, revisao.Versao), &revisao)); err != nil {
+			return err
+		}
+
+		_, err := tx.Put(key, atualizada)
+		return err
+	})
+	if err != nil {
+		log.Warningf(c, "Erro ao gravar edição da publicação %d: %v", anterior.ID, err)
+	}
+	return err
+}
+
+// novaRevisao guarda o texto da publicação antes da edição como a proxima versão
+func novaRevisao(anterior *Publicacao) Revisao {
+	revisao := Revisao{
+		PublicacaoID: anterior.ID,
+		Versao:       anterior.Revisoes + 1,
+		Titulo:       anterior.Titulo,
+		Conteudo:     anterior.Conteudo,
+		AutorID:      anterior.AutorID,
+		DataCriacao:  anterior.DataCriacao,
+	}
+	//O texto anterior passou a valer na ultima edição, ou na criação se nunca foi editado
+	if !anterior.DataAtualizacao.IsZero() {
+		revisao.DataCriacao = anterior.DataAtualizacao
+	}
+	return revisao
+}
+
+// mesclarEdicao recusa a edição se outra foi gravada depois da leitura e traz para a atualizada
+// os contadores e a ocultação que estão no banco
+func mesclarEdicao(banco, anterior, atualizada *Publicacao) error {
+	if banco.Revisoes != anterior.Revisoes {
+		return fmt.Errorf("A publicação foi alterada por outra edição, tente novamente")
+	}
+
+	//Contadores podem ter mudado desde a leitura, vale o que está gravado
+	atualizada.Curtidas = banco.Curtidas
+	atualizada.Compartilhamentos = banco.Compartilhamentos
+	atualizada.Comentarios = banco.Comentarios
+	atualizada.Oculta = atualizada.Oculta || banco.Oculta
+	return nil
+}
+
+// BuscarRevisoes traz as versões anteriores da publicação, da mais antiga para a mais recente
+func BuscarRevisoes(c context.Context, publicacaoID int64) ([]Revisao, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	var revisoes []Revisao
+	q := datastore.NewQuery(KindRevisoes).Filter("PublicacaoID =", publicacaoID)
+	if _, err = datastoreClient.GetAll(c, q, &revisoes); err != nil {
+		log.Warningf(c, "Erro ao buscar revisões da publicação %d: %v", publicacaoID, err)
+		return nil, err
+	}
+
+	sort.Slice(revisoes, func(i, j int) bool {
+		return revisoes[i].Versao < revisoes[j].Versao
+	})
+	return revisoes, nil
+}
+
+// removerRevisoes apaga o historico de uma publicação excluida
+func removerRevisoes(c context.Context, publicacao Publicacao) error {
+	if publicacao.Revisoes == 0 {
+		return nil
+	}
+
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	return datastoreClient.DeleteMulti(c, chavesRevisoes(publicacao))
+}
+
+// chavesRevisoes gera as chaves de todas as versões guardadas, da 1 até publicacao.Revisoes
+func chavesRevisoes(publicacao Publicacao) []*datastore.Key {
+	keys := make([]*datastore.Key, 0, publicacao.Revisoes)
+	for versao := int64(1); versao <= publicacao.Revisoes; versao++ {
+		keys = append(keys, chaveRevisao(publicacao.ID, versao))
+	}
+	return keys
+}
@@ -0,0 +1,72 @@
+package publicacao
+
+import (
+	"site/utils"
+	"testing"
+	"time"
+)
+
+func TestNovaRevisao(t *testing.T) {
+	criacao := utils.JsonSpecialDateTime{Time: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)}
+	edicao := utils.JsonSpecialDateTime{Time: time.Date(2021, 6, 2, 10, 0, 0, 0, time.UTC)}
+
+	casos := []struct {
+		nome     string
+		anterior Publicacao
+		versao   int64
+		data     utils.JsonSpecialDateTime
+	}{
+		{"primeira edição", Publicacao{ID: 7, Titulo: "t", Conteudo: "c", AutorID: 3, DataCriacao: criacao}, 1, criacao},
+		{"já editada", Publicacao{ID: 7, Titulo: "t", Conteudo: "c", AutorID: 3, Revisoes: 2, DataCriacao: criacao, DataAtualizacao: edicao}, 3, edicao},
+	}
+	for _, caso := range casos {
+		revisao := novaRevisao(&caso.anterior)
+		if revisao.Versao != caso.versao || !revisao.DataCriacao.Equal(caso.data.Time) {
+			t.Errorf("%s: versão %d em %v, esperado %d em %v", caso.nome, revisao.Versao, revisao.DataCriacao, caso.versao, caso.data)
+		}
+		if revisao.PublicacaoID != 7 || revisao.Titulo != "t" || revisao.Conteudo != "c" || revisao.AutorID != 3 {
+			t.Errorf("%s: revisão não guardou o texto anterior: %+v", caso.nome, revisao)
+		}
+	}
+}
+
+func TestMesclarEdicao(t *testing.T) {
+	anterior := &Publicacao{Revisoes: 1, Curtidas: 2}
+
+	banco := &Publicacao{Revisoes: 1, Curtidas: 5, Compartilhamentos: 3, Comentarios: 4, Oculta: true}
+	atualizada := &Publicacao{Revisoes: 2, Conteudo: "novo", Curtidas: 2}
+	if err := mesclarEdicao(banco, anterior, atualizada); err != nil {
+		t.Fatalf("mesclarEdicao() = %v", err)
+	}
+	if atualizada.Curtidas != 5 || atualizada.Compartilhamentos != 3 || atualizada.Comentarios != 4 || !atualizada.Oculta || atualizada.Conteudo != "novo" {
+		t.Errorf("contadores do banco não foram mantidos: %+v", atualizada)
+	}
+
+	if err := mesclarEdicao(&Publicacao{Revisoes: 2}, anterior, &Publicacao{Revisoes: 2}); err == nil {
+		t.Errorf("edição concorrente deveria ser recusada")
+	}
+}
+
+func TestChavesRevisoes(t *testing.T) {
+	if keys := chavesRevisoes(Publicacao{ID: 7}); len(keys) != 0 {
+		t.Errorf("publicação sem edições gerou %d chaves", len(keys))
+	}
+
+	keys := chavesRevisoes(Publicacao{ID: 7, Revisoes: 3})
+	esperadas := []string{"7:1", "7:2", "7:3"}
+	if len(keys) != len(esperadas) {
+		t.Fatalf("chavesRevisoes() = %d chaves, esperado %d", len(keys), len(esperadas))
+	}
+	for i, key := range keys {
+		if key.Kind != KindRevisoes || key.Name != esperadas[i] || key.ID != 0 {
+			t.Errorf("chave %d = %v, esperado %s", i, key, esperadas[i])
+		}
+	}
+
+	//Cada versão gravada por novaRevisao tem a chave que removerRevisoes apaga
+	anterior := &Publicacao{ID: 7, Revisoes: 2}
+	revisao := novaRevisao(anterior)
+	if chave := chaveRevisao(revisao.PublicacaoID, revisao.Versao); chave.Name != chavesRevisoes(Publicacao{ID: 7, Revisoes: 3})[2].Name {
+		t.Errorf("chave da nova revisão %v fora das chaves removidas", chave)
+	}
+}
@@ -0,0 +1,130 @@
+package publicacao
+
+import (
+	"context"
+	"fmt"
+	"site/bloqueio"
+	"site/seguidores"
+	"site/usuario"
+	"site/utils"
+)
+
+const (
+	VisibilidadePublica    = "publica"
+	VisibilidadeSeguidores = "seguidores"
+	VisibilidadePrivada    = "privada"
+)
+
+// validarVisibilidade preenche a visibilidade padrão e recusa valores desconhecidos
+func (publicacao *Publicacao) validarVisibilidade() error {
+	switch publicacao.Visibilidade {
+	case "":
+		publicacao.Visibilidade = VisibilidadePublica
+	case VisibilidadePublica, VisibilidadeSeguidores, VisibilidadePrivada:
+	default:
+		return fmt.Errorf("Visibilidade inválida: %v", publicacao.Visibilidade)
+	}
+	return nil
+}
+
+// visibilidade trata publicações gravadas antes de existir o campo como publicas
+func (publicacao *Publicacao) visibilidade() string {
+	if publicacao.Visibilidade == "" {
+		return VisibilidadePublica
+	}
+	return publicacao.Visibilidade
+}
+
+// Leitor decide quais publicações um usuario pode ver. As consultas de seguidos, bloqueios e contas
+// privadas ficam guardadas, então o mesmo Leitor deve ser usado para filtrar uma lista inteira.
+type Leitor struct {
+	c       context.Context
+	ID      int64
+	autores map[int64]acessoAutor
+
+	seguidos           []int64
+	seguidosCarregados bool
+}
+
+type acessoAutor struct {
+	bloqueado    bool
+	contaPrivada bool
+	banido       bool
+}
+
+func NovoLeitor(c context.Context, usuarioID int64) *Leitor {
+	return &Leitor{c: c, ID: usuarioID, autores: make(map[int64]acessoAutor)}
+}
+
+func (leitor *Leitor) segue(autorID int64) bool {
+	if !leitor.seguidosCarregados {
+		if seguidor := seguidores.GetSeguidorByIDSeguidor(leitor.c, leitor.ID); seguidor != nil {
+			leitor.seguidos = seguidor.IDUsuario
+		}
+		leitor.seguidosCarregados = true
+	}
+	return utils.InIntArray(autorID, leitor.seguidos)
+}
+
+func (leitor *Leitor) acesso(autorID int64) acessoAutor {
+	if acesso, ok := leitor.autores[autorID]; ok {
+		return acesso
+	}
+
+	acesso := acessoAutor{bloqueado: bloqueio.Bloqueado(leitor.c, autorID, leitor.ID)}
+	if autor := usuario.GetUsuario(leitor.c, autorID); autor != nil {
+		acesso.contaPrivada = autor.Visibilidade == usuario.VisibilidadePrivada
+	}
+	if sancao := usuario.GetSancao(leitor.c, autorID); sancao != nil {
+		acesso.banido = sancao.Banido
+	}
+	leitor.autores[autorID] = acesso
+	return acesso
+}
+
+// PodeVerAutor diz se o leitor pode ver as publicações publicas do autor: não pode haver bloqueio
+// entre os dois, o autor não pode estar banido e, se a conta for privada, o leitor precisa segui-la
+func (leitor *Leitor) PodeVerAutor(autorID int64) bool {
+	if autorID == leitor.ID {
+		return true
+	}
+
+	acesso := leitor.acesso(autorID)
+	if acesso.bloqueado || acesso.banido {
+		return false
+	}
+	return !acesso.contaPrivada || leitor.segue(autorID)
+}
+
+// PodeVer diz se o leitor pode ver a publicação. O autor sempre vê as suas, inclusive rascunhos e
+// as ocultadas pela moderação; os demais só veem publicações publicadas e não ocultadas, de acordo
+// com a visibilidade da publicação e da conta.
+func (leitor *Leitor) PodeVer(publicacao *Publicacao) bool {
+	if publicacao.AutorID == leitor.ID {
+		return true
+	}
+
+	if !publicacao.Publicada() || publicacao.Oculta || !leitor.PodeVerAutor(publicacao.AutorID) {
+		return false
+	}
+
+	switch publicacao.visibilidade() {
+	case VisibilidadePublica:
+		return true
+	case VisibilidadeSeguidores:
+		return leitor.segue(publicacao.AutorID)
+	default:
+		return false
+	}
+}
+
+// Filtrar retira da lista as publicações que o leitor não pode ver
+func (leitor *Leitor) Filtrar(publics []Publicacao) []Publicacao {
+	visiveis := make([]Publicacao, 0, len(publics))
+	for i := range publics {
+		if leitor.PodeVer(&publics[i]) {
+			visiveis = append(visiveis, publics[i])
+		}
+	}
+	return visiveis
+}
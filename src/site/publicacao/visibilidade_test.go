@@ -0,0 +1,91 @@
+package publicacao
+
+import (
+	"context"
+	"testing"
+)
+
+// leitorTeste monta um Leitor com as consultas já carregadas, sem acessar o Datastore
+func leitorTeste(id int64, seguidos []int64, autores map[int64]acessoAutor) *Leitor {
+	leitor := NovoLeitor(context.Background(), id)
+	leitor.seguidos = seguidos
+	leitor.seguidosCarregados = true
+	for autorID, acesso := range autores {
+		leitor.autores[autorID] = acesso
+	}
+	return leitor
+}
+
+func TestPodeVer(t *testing.T) {
+	const (
+		leitorID     = 1
+		publicoID    = 2
+		privadoID    = 3
+		bloqueadorID = 4
+		banidoID     = 5
+	)
+
+	autores := map[int64]acessoAutor{
+		publicoID:    {},
+		privadoID:    {contaPrivada: true},
+		bloqueadorID: {bloqueado: true},
+		banidoID:     {banido: true},
+	}
+
+	casos := []struct {
+		nome       string
+		seguidos   []int64
+		publicacao Publicacao
+		esperado   bool
+	}{
+		{"publica", nil, Publicacao{AutorID: publicoID}, true},
+		{"publica explicita", nil, Publicacao{AutorID: publicoID, Visibilidade: VisibilidadePublica}, true},
+		{"seguidores sem seguir", nil, Publicacao{AutorID: publicoID, Visibilidade: VisibilidadeSeguidores}, false},
+		{"seguidores seguindo", []int64{publicoID}, Publicacao{AutorID: publicoID, Visibilidade: VisibilidadeSeguidores}, true},
+		{"privada seguindo", []int64{publicoID}, Publicacao{AutorID: publicoID, Visibilidade: VisibilidadePrivada}, false},
+		{"conta privada sem seguir", nil, Publicacao{AutorID: privadoID}, false},
+		{"conta privada seguindo", []int64{privadoID}, Publicacao{AutorID: privadoID}, true},
+		{"bloqueio", []int64{bloqueadorID}, Publicacao{AutorID: bloqueadorID}, false},
+		{"autor banido", []int64{banidoID}, Publicacao{AutorID: banidoID}, false},
+		{"ocultada pela moderação", nil, Publicacao{AutorID: publicoID, Oculta: true}, false},
+		{"propria ocultada", nil, Publicacao{AutorID: leitorID, Oculta: true}, true},
+		{"rascunho de outro", nil, Publicacao{AutorID: publicoID, Status: StatusRascunho}, false},
+		{"propria privada", nil, Publicacao{AutorID: leitorID, Visibilidade: VisibilidadePrivada}, true},
+		{"proprio rascunho", nil, Publicacao{AutorID: leitorID, Status: StatusRascunho}, true},
+	}
+
+	for _, caso := range casos {
+		leitor := leitorTeste(leitorID, caso.seguidos, autores)
+		if obtido := leitor.PodeVer(&caso.publicacao); obtido != caso.esperado {
+			t.Errorf("%s: PodeVer() = %v, esperado %v", caso.nome, obtido, caso.esperado)
+		}
+	}
+}
+
+func TestFiltrar(t *testing.T) {
+	leitor := leitorTeste(1, nil, map[int64]acessoAutor{2: {}, 3: {contaPrivada: true}})
+
+	publics := []Publicacao{
+		{ID: 10, AutorID: 2},
+		{ID: 11, AutorID: 3},
+		{ID: 12, AutorID: 2, Visibilidade: VisibilidadeSeguidores},
+		{ID: 13, AutorID: 1, Visibilidade: VisibilidadePrivada},
+	}
+
+	visiveis := leitor.Filtrar(publics)
+	if len(visiveis) != 2 || visiveis[0].ID != 10 || visiveis[1].ID != 13 {
+		t.Errorf("Filtrar() = %+v, esperado as publicações 10 e 13", visiveis)
+	}
+}
+
+func TestValidarVisibilidade(t *testing.T) {
+	p := Publicacao{}
+	if err := p.validarVisibilidade(); err != nil || p.Visibilidade != VisibilidadePublica {
+		t.Errorf("visibilidade vazia deveria virar publica, obtido %q (%v)", p.Visibilidade, err)
+	}
+
+	p.Visibilidade = "amigos"
+	if err := p.validarVisibilidade(); err == nil {
+		t.Errorf("visibilidade desconhecida deveria ser recusada")
+	}
+}
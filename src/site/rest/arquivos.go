@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"site/armazenamento"
+	"site/utils"
+	"site/utils/log"
+	"strings"
+)
+
+//ArquivosHandler serve os arquivos gravados pelo armazenamento local. A configuração é lida uma vez na
+//inicialização e o handler fica no caminho da URL base, ver CaminhoArquivos.
+func ArquivosHandler(local *armazenamento.Local) http.Handler {
+	arquivos := http.StripPrefix(CaminhoArquivos(local.URLBase)+"/", http.FileServer(http.Dir(local.Diretorio)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			log.Warningf(r.Context(), "Método não permitido")
+			utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+			return
+		}
+		arquivos.ServeHTTP(w, r)
+	})
+}
+
+//CaminhoArquivos retorna o caminho da URL base, sem a barra final. A URL base pode ser um caminho ou a
+//URL completa de um proxy: "https://cdn.exemplo.com/arquivos/" vira "/arquivos".
+func CaminhoArquivos(urlBase string) string {
+	caminho := urlBase
+	if u, err := url.Parse(urlBase); err == nil {
+		caminho = u.Path
+	}
+	if caminho = strings.Trim(caminho, "/"); caminho == "" {
+		return ""
+	}
+	return "/" + caminho
+}
@@ -0,0 +1,261 @@
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"site/autenticacao"
+	"site/estabelecimento"
+	"site/publicacao"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+)
+
+func PerfilEstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaPerfilEstabelecimento(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func AvaliacoesEstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaAvaliacoes(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		AvaliaEstabelecimento(w, r)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		RemoveAvaliacao(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func CheckinsEstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaCheckins(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		FazCheckin(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func PublicacoesEstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaPublicacoesEstabelecimento(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Dados publicos do estabelecimento, com a nota media e o total de check-ins
+func BuscaPerfilEstabelecimento(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	estab := estabelecimento.GetEstabelecimento(c, id)
+	if estab == nil {
+		log.Warningf(c, "Estabelecimento não encontrado: %v", id)
+		responderErroEstabelecimento(w, estabelecimento.ErrNaoEncontrado)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, estab.Perfil())
+}
+
+func BuscaAvaliacoes(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	limite, _ := strconv.Atoi(r.FormValue("limite"))
+	avaliacoes, err := estabelecimento.BuscarAvaliacoes(c, id, limite)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar avaliações do estabelecimento %d: %v", id, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Erro ao buscar avaliações")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, avaliacoes)
+}
+
+//Cria ou substitui a avaliação do usuario logado com {"Nota": 1 a 5, "Texto": "..."}
+func AvaliaEstabelecimento(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body da avaliação: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da avaliação")
+		return
+	}
+
+	var avaliacao estabelecimento.Avaliacao
+	if err = json.Unmarshal(body, &avaliacao); err != nil {
+		log.Warningf(c, "Erro ao realizar unmarshal da avaliação: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao realizar Unmarshal")
+		return
+	}
+
+	gravada, err := estabelecimento.Avaliar(c, usuarioID, id, avaliacao)
+	if err != nil {
+		log.Warningf(c, "Falha ao avaliar estabelecimento %d: %v", id, err)
+		responderErroEstabelecimento(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, gravada)
+}
+
+func RemoveAvaliacao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	err = estabelecimento.RemoverAvaliacao(c, usuarioID, id)
+	if err == estabelecimento.ErrAvaliacaoNaoEncontrada {
+		utils.RespondWithError(w, http.StatusNotFound, 0, err.Error())
+		return
+	}
+	if err != nil {
+		log.Warningf(c, "Falha ao remover avaliação do estabelecimento %d: %v", id, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Erro ao remover avaliação")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, "Avaliação removida")
+}
+
+func BuscaCheckins(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	limite, _ := strconv.Atoi(r.FormValue("limite"))
+	checkins, err := estabelecimento.BuscarCheckins(c, id, limite)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar check-ins do estabelecimento %d: %v", id, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Erro ao buscar check-ins")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, checkins)
+}
+
+func FazCheckin(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	checkin, err := estabelecimento.FazerCheckin(c, usuarioID, id)
+	if err == estabelecimento.ErrCheckinRecente {
+		utils.RespondWithError(w, http.StatusTooManyRequests, 0, err.Error())
+		return
+	}
+	if err != nil {
+		log.Warningf(c, "Falha ao fazer check-in no estabelecimento %d: %v", id, err)
+		responderErroEstabelecimento(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, checkin)
+}
+
+//Publicações que marcaram o estabelecimento e que o usuario logado pode ver
+func BuscaPublicacoesEstabelecimento(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	publicacoes, err := publicacao.BuscarPorEstabelecimento(c, usuarioID, id)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar publicações do estabelecimento %d: %v", id, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Erro ao buscar publicações")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, publicacoes)
+}
@@ -0,0 +1,146 @@
+package rest
+
+import (
+	"net/http"
+	"site/autenticacao"
+	"site/bloqueio"
+	"site/seguidores"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func BloquearHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		BloquearUsuario(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func DesbloquearHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		DesbloquearUsuario(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func BloqueadosHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaBloqueados(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Bloqueia um usuario. Os dois deixam de se seguir e não podem mais trocar mensagens.
+func BloquearUsuario(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	bloqueadoID, err := strconv.ParseInt(mux.Vars(r)["idusuario"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Falha ao converter id do usuário: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao converter id do usuário")
+		return
+	}
+
+	if err = bloqueio.Bloquear(c, usuarioID, bloqueadoID); err != nil {
+		log.Warningf(c, "Falha ao bloquear usuario %d: %v", bloqueadoID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	if seguidores.Segue(c, usuarioID, bloqueadoID) {
+		if err = seguidores.PararDeSeguir(c, bloqueadoID, usuarioID); err != nil {
+			log.Warningf(c, "Falha ao deixar de seguir o usuario bloqueado %d: %v", bloqueadoID, err)
+		}
+	}
+	if seguidores.Segue(c, bloqueadoID, usuarioID) {
+		if err = seguidores.PararDeSeguir(c, usuarioID, bloqueadoID); err != nil {
+			log.Warningf(c, "Falha ao remover o usuario bloqueado %d dos seguidores: %v", bloqueadoID, err)
+		}
+	}
+	if seguidores.GetSolicitacao(c, bloqueadoID, usuarioID) != nil {
+		seguidores.RemoverSolicitacao(c, bloqueadoID, usuarioID)
+	}
+	if seguidores.GetSolicitacao(c, usuarioID, bloqueadoID) != nil {
+		seguidores.RemoverSolicitacao(c, usuarioID, bloqueadoID)
+	}
+
+	log.Debugf(c, "Usuario bloqueado com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, "Usuario bloqueado com sucesso")
+}
+
+//Desfaz o bloqueio de um usuario
+func DesbloquearUsuario(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	bloqueadoID, err := strconv.ParseInt(mux.Vars(r)["idusuario"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Falha ao converter id do usuário: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao converter id do usuário")
+		return
+	}
+
+	if err = bloqueio.Desbloquear(c, usuarioID, bloqueadoID); err != nil {
+		log.Warningf(c, "Falha ao desbloquear usuario %d: %v", bloqueadoID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao desbloquear usuario")
+		return
+	}
+
+	log.Debugf(c, "Usuario desbloqueado com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, "Usuario desbloqueado com sucesso")
+}
+
+//Traz os ids dos usuarios bloqueados pelo usuario logado
+func BuscaBloqueados(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	bloqueados, err := bloqueio.BuscarBloqueados(c, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar usuarios bloqueados: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao buscar usuarios bloqueados")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, bloqueados)
+}
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"net/http"
+	"site/autenticacao"
+	"site/busca"
+	"site/publicacao"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+	"strings"
+)
+
+func BuscaUsuariosHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscarUsuariosTexto(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func BuscaPublicacoesHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscarPublicacoesTexto(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Busca usuarios por parte do nome ou do nick
+func BuscarUsuariosTexto(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	termo := strings.TrimSpace(r.FormValue("q"))
+	if termo == "" {
+		log.Warningf(c, "Termo de busca não informado")
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Termo de busca não informado")
+		return
+	}
+	limite, _ := strconv.Atoi(r.FormValue("limite"))
+
+	b, err := busca.Nova(c)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Elasticsearch: %v", err)
+		utils.RespondWithError(w, http.StatusServiceUnavailable, 0, "Busca indisponivel no momento")
+		return
+	}
+
+	usuarios, err := b.BuscarUsuarios(c, termo, limite)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar usuarios por %q: %v", termo, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao buscar usuarios")
+		return
+	}
+
+	log.Debugf(c, "Busca realizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, usuarios)
+}
+
+//Busca publicações pelo texto do titulo e do conteudo
+func BuscarPublicacoesTexto(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	termo := strings.TrimSpace(r.FormValue("q"))
+	if termo == "" {
+		log.Warningf(c, "Termo de busca não informado")
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Termo de busca não informado")
+		return
+	}
+	limite, _ := strconv.Atoi(r.FormValue("limite"))
+
+	b, err := busca.Nova(c)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Elasticsearch: %v", err)
+		utils.RespondWithError(w, http.StatusServiceUnavailable, 0, "Busca indisponivel no momento")
+		return
+	}
+
+	publicacoes, err := b.BuscarPublicacoes(c, termo, limite)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar publicações por %q: %v", termo, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao buscar publicações")
+		return
+	}
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return
+	}
+
+	//Só publicações publicas são indexadas, mas o autor pode ter bloqueado o leitor ou fechado a conta
+	leitor := publicacao.NovoLeitor(c, usuarioID)
+	visiveis := make([]busca.ResultadoPublicacao, 0, len(publicacoes))
+	for _, p := range publicacoes {
+		if leitor.PodeVerAutor(p.AutorID) {
+			visiveis = append(visiveis, p)
+		}
+	}
+	publicacoes = visiveis
+
+	log.Debugf(c, "Busca realizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, publicacoes)
+}
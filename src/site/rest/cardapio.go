@@ -0,0 +1,471 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"site/autenticacao"
+	"site/cardapio"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func CardapioHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaCardapio(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func CardapioCompletoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaCardapioCompleto(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func CategoriasCardapioHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPost {
+		SalvaCategoriaCardapio(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func CategoriaCardapioHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		SalvaCategoriaCardapio(w, r)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		ExcluiCategoriaCardapio(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func ItensCardapioHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPost {
+		SalvaItemCardapio(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func ItemCardapioHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		SalvaItemCardapio(w, r)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		ExcluiItemCardapio(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func DisponibilidadeItemHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		AlteraDisponibilidadeItem(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func CSVCardapioHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		ExportaCSVCardapio(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		ImportaCSVCardapio(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Cardapio publico, só com os itens disponiveis
+func BuscaCardapio(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	menu, err := cardapio.BuscarCardapio(c, id, true)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar cardapio do estabelecimento %d: %v", id, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Erro ao buscar cardapio")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, menu)
+}
+
+//Cardapio com os itens indisponiveis e as categorias vazias, para o proprietario
+func BuscaCardapioCompleto(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	menu, err := cardapio.BuscarCardapioCompleto(c, usuarioID, id)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar cardapio completo do estabelecimento %d: %v", id, err)
+		responderErroCardapio(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, menu)
+}
+
+//Cria a categoria no POST ou altera a categoria da rota no PUT
+func SalvaCategoriaCardapio(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body da categoria: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da categoria")
+		return
+	}
+
+	var categoria cardapio.Categoria
+	if err = json.Unmarshal(body, &categoria); err != nil {
+		log.Warningf(c, "Erro ao realizar unmarshal da categoria: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao realizar Unmarshal")
+		return
+	}
+
+	status := http.StatusCreated
+	categoria.ID = 0
+	if r.Method == http.MethodPut {
+		if categoria.ID, ok = extrairIDCardapio(w, r, "idcategoria"); !ok {
+			return
+		}
+		status = http.StatusOK
+	}
+
+	if err = cardapio.SalvarCategoria(c, usuarioID, id, &categoria); err != nil {
+		log.Warningf(c, "Falha ao salvar categoria do cardapio: %v", err)
+		responderErroCardapio(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, status, categoria)
+}
+
+func ExcluiCategoriaCardapio(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+	categoriaID, ok := extrairIDCardapio(w, r, "idcategoria")
+	if !ok {
+		return
+	}
+
+	if err = cardapio.ExcluirCategoria(c, usuarioID, id, categoriaID); err != nil {
+		log.Warningf(c, "Falha ao excluir categoria %d: %v", categoriaID, err)
+		responderErroCardapio(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, "Categoria excluida")
+}
+
+//Cria o item no POST ou altera o item da rota no PUT. O preço vai em reais em PrecoReais.
+func SalvaItemCardapio(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body do item: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body do item")
+		return
+	}
+
+	var item cardapio.Item
+	if err = json.Unmarshal(body, &item); err != nil {
+		log.Warningf(c, "Erro ao realizar unmarshal do item: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao realizar Unmarshal")
+		return
+	}
+
+	status := http.StatusCreated
+	item.ID = 0
+	if r.Method == http.MethodPut {
+		if item.ID, ok = extrairIDCardapio(w, r, "iditem"); !ok {
+			return
+		}
+		status = http.StatusOK
+	}
+
+	if err = cardapio.SalvarItem(c, usuarioID, id, &item); err != nil {
+		log.Warningf(c, "Falha ao salvar item do cardapio: %v", err)
+		responderErroCardapio(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, status, item)
+}
+
+func ExcluiItemCardapio(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+	itemID, ok := extrairIDCardapio(w, r, "iditem")
+	if !ok {
+		return
+	}
+
+	if err = cardapio.ExcluirItem(c, usuarioID, id, itemID); err != nil {
+		log.Warningf(c, "Falha ao excluir item %d: %v", itemID, err)
+		responderErroCardapio(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, "Item excluido")
+}
+
+//Liga ou desliga o item com {"Disponivel": true|false}
+func AlteraDisponibilidadeItem(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+	itemID, ok := extrairIDCardapio(w, r, "iditem")
+	if !ok {
+		return
+	}
+
+	var dados struct {
+		Disponivel bool
+	}
+	if err = json.NewDecoder(r.Body).Decode(&dados); err != nil {
+		log.Warningf(c, "Erro ao realizar unmarshal da disponibilidade: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao realizar Unmarshal")
+		return
+	}
+
+	item, err := cardapio.AlterarDisponibilidade(c, usuarioID, id, itemID, dados.Disponivel)
+	if err != nil {
+		log.Warningf(c, "Falha ao alterar disponibilidade do item %d: %v", itemID, err)
+		responderErroCardapio(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, item)
+}
+
+//Baixa o cardapio completo em CSV
+func ExportaCSVCardapio(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	//Monta o arquivo antes de escrever a resposta para ainda poder responder com erro
+	var buf bytes.Buffer
+	if err = cardapio.ExportarCSV(c, usuarioID, id, &buf); err != nil {
+		log.Warningf(c, "Falha ao exportar cardapio do estabelecimento %d: %v", id, err)
+		responderErroCardapio(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"cardapio-%d.csv\"", id))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+//Importa o cardapio de um CSV enviado no corpo ou no campo "arquivo" de um formulario multipart
+func ImportaCSVCardapio(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, cardapio.TamanhoMaximoCSV+(1<<10))
+	var arquivo io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err = r.ParseMultipartForm(cardapio.TamanhoMaximoCSV); err != nil {
+			log.Warningf(c, "Erro ao ler formulario do CSV: %v", err)
+			utils.RespondWithError(w, http.StatusBadRequest, 0, "Arquivo muito grande ou formulario inválido")
+			return
+		}
+		enviado, _, err := r.FormFile("arquivo")
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, 0, "Envie o CSV no campo arquivo")
+			return
+		}
+		defer enviado.Close()
+		arquivo = enviado
+	}
+
+	resultado, err := cardapio.ImportarCSV(c, usuarioID, id, arquivo)
+	if err != nil {
+		log.Warningf(c, "Falha ao importar cardapio do estabelecimento %d: %v", id, err)
+		responderErroCardapio(w, err)
+		return
+	}
+
+	log.Debugf(c, "Cardapio do estabelecimento %d importado: %+v", id, resultado)
+	utils.RespondWithJSON(w, http.StatusOK, resultado)
+}
+
+func extrairIDCardapio(w http.ResponseWriter, r *http.Request, parametro string) (int64, bool) {
+	c := r.Context()
+
+	id, err := strconv.ParseInt(mux.Vars(r)[parametro], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Erro ao converter o ID: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao converter ID")
+		return 0, false
+	}
+	return id, true
+}
+
+//Responde 404 para categoria ou item inexistente e 409 para categoria com itens
+func responderErroCardapio(w http.ResponseWriter, err error) {
+	switch err {
+	case cardapio.ErrCategoriaNaoEncontrada, cardapio.ErrItemNaoEncontrado:
+		utils.RespondWithError(w, http.StatusNotFound, 0, err.Error())
+	case cardapio.ErrCategoriaComItens:
+		utils.RespondWithError(w, http.StatusConflict, 0, err.Error())
+	default:
+		responderErroEstabelecimento(w, err)
+	}
+}
@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"net/http"
+	"site/endereco"
+	"site/utils"
+	"site/utils/log"
+)
+
+func BuscaEnderecoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaEndereco(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+// BuscaEndereco procura os CEPs de um logradouro pela UF, municipio, logradouro e bairro
+func BuscaEndereco(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	filtro := endereco.Endereco{
+		UF:         r.FormValue("uf"),
+		Municipio:  r.FormValue("municipio"),
+		Logradouro: r.FormValue("logradouro"),
+		Bairro:     r.FormValue("bairro"),
+	}
+
+	enderecos, err := endereco.BuscarEndereco(c, &filtro)
+	if err == endereco.ErrCEPIndisponivel {
+		log.Warningf(c, "Falha ao buscar endereço: %v", err)
+		utils.RespondWithError(w, http.StatusServiceUnavailable, 0, err.Error())
+		return
+	}
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar endereço: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, enderecos)
+}
@@ -0,0 +1,286 @@
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"site/autenticacao"
+	"site/estabelecimento"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func EquipeEstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaEquipeEstabelecimento(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func MembroEquipeHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodDelete {
+		RemoveMembroEquipe(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func ConvitesEstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPost {
+		ConvidaEquipe(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func ConviteEstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodDelete {
+		RevogaConviteEquipe(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func ConvitesUsuarioHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaConvitesUsuario(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func RespostaConviteHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPost {
+		RespondeConvite(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func BuscaEquipeEstabelecimento(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	equipe, err := estabelecimento.BuscarEquipe(c, usuarioID, id)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar equipe do Estabelecimento %d: %v", id, err)
+		responderErroEstabelecimento(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, equipe)
+}
+
+//Tira um usuario da equipe. O proprio membro pode usar para sair.
+func RemoveMembroEquipe(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	membroID, err := strconv.ParseInt(mux.Vars(r)["idusuario"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Falha ao converter id do usuário: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao converter id do usuário")
+		return
+	}
+
+	if err = estabelecimento.RemoverDaEquipe(c, usuarioID, id, membroID); err != nil {
+		log.Warningf(c, "Falha ao remover usuario %d da equipe do Estabelecimento %d: %v", membroID, id, err)
+		responderErroEstabelecimento(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, "Usuario removido da equipe")
+}
+
+//Convida alguém pelo email, com o papel de gerente ou atendente. A resposta traz o token do convite.
+func ConvidaEquipe(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body do convite: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body do convite")
+		return
+	}
+
+	var pedido struct {
+		Email string
+		Papel string
+	}
+	if err = json.Unmarshal(body, &pedido); err != nil {
+		log.Warningf(c, "Erro ao realizar unmarshal do convite: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao realizar Unmarshal")
+		return
+	}
+
+	convite, err := estabelecimento.Convidar(c, usuarioID, id, pedido.Email, pedido.Papel)
+	if err != nil {
+		log.Warningf(c, "Falha ao convidar para a equipe do Estabelecimento %d: %v", id, err)
+		responderErroEstabelecimento(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, convite)
+}
+
+func RevogaConviteEquipe(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	if err = estabelecimento.RevogarConvite(c, usuarioID, id, mux.Vars(r)["token"]); err != nil {
+		log.Warningf(c, "Falha ao revogar convite do Estabelecimento %d: %v", id, err)
+		responderErroConvite(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, "Convite revogado")
+}
+
+//Convites pendentes feitos para o email do usuario logado
+func BuscaConvitesUsuario(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	convites, err := estabelecimento.ConvitesUsuario(c, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar convites do usuario %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, convites)
+}
+
+//Aceita ou recusa o convite, conforme o final da URL
+func RespondeConvite(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	var aceitar bool
+	switch mux.Vars(r)["resposta"] {
+	case "aceitar":
+		aceitar = true
+	case "recusar":
+		aceitar = false
+	default:
+		log.Warningf(c, "Resposta de convite inválida: %v", mux.Vars(r)["resposta"])
+		utils.RespondWithError(w, http.StatusNotFound, 0, "Resposta de convite inválida")
+		return
+	}
+
+	convite, err := estabelecimento.ResponderConvite(c, usuarioID, mux.Vars(r)["token"], aceitar)
+	if err != nil {
+		log.Warningf(c, "Falha ao responder convite: %v", err)
+		responderErroConvite(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, convite)
+}
+
+//Responde 404 para convite inexistente, 410 para expirado, 409 para já respondido e 403 para convite de outro email
+func responderErroConvite(w http.ResponseWriter, err error) {
+	switch err {
+	case estabelecimento.ErrConviteNaoEncontrado:
+		utils.RespondWithError(w, http.StatusNotFound, 0, err.Error())
+	case estabelecimento.ErrConviteExpirado:
+		utils.RespondWithError(w, http.StatusGone, 0, err.Error())
+	case estabelecimento.ErrConviteRespondido:
+		utils.RespondWithError(w, http.StatusConflict, 0, err.Error())
+	case estabelecimento.ErrConviteOutroEmail:
+		utils.RespondWithError(w, http.StatusForbidden, 0, err.Error())
+	default:
+		responderErroEstabelecimento(w, err)
+	}
+}
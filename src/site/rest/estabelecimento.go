@@ -2,12 +2,16 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"site/autenticacao"
 	"site/estabelecimento"
 	"site/utils"
 	"site/utils/log"
 	"strconv"
+
+	"github.com/gorilla/mux"
 )
 
 func EstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
@@ -29,9 +33,78 @@ func EstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func EstabelecimentoIDHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaEstabelecimentoID(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		AtualizaEstabelecimento(w, r)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		DeletaEstabelecimento(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func LoteEstabelecimentosHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPost {
+		UpsertEstabelecimentos(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func MeusEstabelecimentosHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaMeusEstabelecimentos(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func EstabelecimentosProximosHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaEstabelecimentosProximos(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
 func BuscaEstabelecimento(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
 
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
 	if r.FormValue("ID") != "" {
 		id, err := strconv.ParseInt(r.FormValue("ID"), 10, 64)
 		if err != nil {
@@ -44,7 +117,10 @@ func BuscaEstabelecimento(w http.ResponseWriter, r *http.Request) {
 		if estab == nil {
 			log.Warningf(c, "Estabelecimento não encontrado: %v", id)
 			utils.RespondWithError(w, http.StatusBadRequest, 0, "Estabelecimento não encontrado")
+			return
 		}
+		utils.RespondWithJSON(w, http.StatusOK, []interface{}{dadosVisiveis(estab, usuarioID)})
+		return
 	}
 	filtros := estabelecimento.Estabelecimento{
 		Nome:  r.FormValue("Nome"),
@@ -58,13 +134,33 @@ func BuscaEstabelecimento(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao buscar Estabelecimento")
 		return
 	}
+	visiveis := make([]interface{}, 0, len(estabelecimentos))
+	for i := range estabelecimentos {
+		visiveis = append(visiveis, dadosVisiveis(&estabelecimentos[i], usuarioID))
+	}
+
 	log.Debugf(c, "Busca realizada com sucesso")
-	utils.RespondWithJSON(w, http.StatusOK, estabelecimentos)
+	utils.RespondWithJSON(w, http.StatusOK, visiveis)
+}
+
+//Quem faz parte da equipe vê o cadastro completo; os demais só o perfil publico, sem CNPJ, contatos e equipe
+func dadosVisiveis(estab *estabelecimento.Estabelecimento, usuarioID int64) interface{} {
+	if estab.Papel(usuarioID) != "" {
+		return estab
+	}
+	return estab.Perfil()
 }
 
 func InsereEstabelecimento(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
 
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
 	estabelecimentos := &estabelecimento.Estabelecimento{}
 
 	body, err := ioutil.ReadAll(r.Body)
@@ -81,13 +177,237 @@ func InsereEstabelecimento(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = estabelecimento.InserirEstabelecimento(c, estabelecimentos)
+	err = estabelecimento.InserirEstabelecimento(c, usuarioID, estabelecimentos)
 	if err != nil {
 		log.Warningf(c, "Falha ao inserir Estabelecimento: %v", err)
-		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao inserir Estabelecimento")
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
 		return
 	}
 
 	log.Debugf(c, "Estabelecimento inserido com sucesso")
-	utils.RespondWithJSON(w, http.StatusOK, "Estabelecimento inserido com sucesso")
+	utils.RespondWithJSON(w, http.StatusCreated, estabelecimentos)
+}
+
+//Traz um estabelecimento pelo ID da rota
+func BuscaEstabelecimentoID(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	estab := estabelecimento.GetEstabelecimento(c, id)
+	if estab == nil {
+		log.Warningf(c, "Estabelecimento não encontrado: %v", id)
+		utils.RespondWithError(w, http.StatusNotFound, 0, "Estabelecimento não encontrado")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, dadosVisiveis(estab, usuarioID))
+}
+
+//Atualiza os dados do estabelecimento, permitido ao proprietario e aos gerentes
+func AtualizaEstabelecimento(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body de Estabelecimento: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body de Estabelecimento")
+		return
+	}
+
+	var estab estabelecimento.Estabelecimento
+	if err = json.Unmarshal(body, &estab); err != nil {
+		log.Warningf(c, "Erro ao realizar unmarshal de Estabelecimento: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao realizar Unmarshal")
+		return
+	}
+	estab.ID = id
+
+	if err = estabelecimento.AtualizarEstabelecimento(c, usuarioID, &estab); err != nil {
+		log.Warningf(c, "Falha ao atualizar Estabelecimento %d: %v", id, err)
+		responderErroEstabelecimento(w, err)
+		return
+	}
+
+	log.Debugf(c, "Estabelecimento atualizado com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, estab)
+}
+
+//Exclui o estabelecimento, permitido só ao proprietario
+func DeletaEstabelecimento(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	id, ok := extrairEstabelecimentoID(w, r)
+	if !ok {
+		return
+	}
+
+	if err = estabelecimento.DeletarEstabelecimento(c, usuarioID, id); err != nil {
+		log.Warningf(c, "Falha ao excluir Estabelecimento %d: %v", id, err)
+		responderErroEstabelecimento(w, err)
+		return
+	}
+
+	log.Debugf(c, "Estabelecimento excluido com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, "Estabelecimento excluido com sucesso")
+}
+
+//Cria ou atualiza varios estabelecimentos de uma vez
+func UpsertEstabelecimentos(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body de Estabelecimentos: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body de Estabelecimentos")
+		return
+	}
+
+	var estabelecimentos []estabelecimento.Estabelecimento
+	if err = json.Unmarshal(body, &estabelecimentos); err != nil {
+		log.Warningf(c, "Erro ao realizar unmarshal de Estabelecimentos: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao realizar Unmarshal")
+		return
+	}
+
+	if err = estabelecimento.UpsertEstabelecimentos(c, usuarioID, estabelecimentos); err != nil {
+		log.Warningf(c, "Falha ao gravar lote de Estabelecimentos: %v", err)
+		responderErroEstabelecimento(w, err)
+		return
+	}
+
+	log.Debugf(c, "Lote de %d estabelecimentos gravado", len(estabelecimentos))
+	utils.RespondWithJSON(w, http.StatusOK, estabelecimentos)
+}
+
+//Traz os estabelecimentos de que o usuario logado é proprietario ou gerente
+func BuscaMeusEstabelecimentos(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	estabelecimentos, err := estabelecimento.BuscarEstabelecimentosUsuario(c, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar estabelecimentos do usuario %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Erro ao buscar Estabelecimentos")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, estabelecimentos)
+}
+
+func extrairEstabelecimentoID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	c := r.Context()
+
+	id, err := strconv.ParseInt(mux.Vars(r)["idestabelecimento"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Erro ao converter o ID: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao converter ID")
+		return 0, false
+	}
+	return id, true
+}
+
+//Responde 404 e 403 para os erros de estabelecimento inexistente e de falta de permissão
+func responderErroEstabelecimento(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, estabelecimento.ErrNaoEncontrado):
+		utils.RespondWithError(w, http.StatusNotFound, 0, err.Error())
+	case errors.Is(err, estabelecimento.ErrSemPermissao):
+		utils.RespondWithError(w, http.StatusForbidden, 0, err.Error())
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+	}
+}
+
+//BuscaEstabelecimentosProximos traz os estabelecimentos ao redor de lat e lng, do mais perto para o mais longe.
+//Aceita raio em km, setor (bar, restaurante, lanchonete), aberto=true e limite.
+func BuscaEstabelecimentosProximos(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	var filtro estabelecimento.FiltroProximos
+	var err error
+
+	filtro.Latitude, err = strconv.ParseFloat(r.FormValue("lat"), 64)
+	if err != nil {
+		log.Warningf(c, "Latitude inválida: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Latitude inválida")
+		return
+	}
+
+	filtro.Longitude, err = strconv.ParseFloat(r.FormValue("lng"), 64)
+	if err != nil {
+		log.Warningf(c, "Longitude inválida: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Longitude inválida")
+		return
+	}
+
+	if r.FormValue("raio") != "" {
+		filtro.RaioKm, err = strconv.ParseFloat(r.FormValue("raio"), 64)
+		if err != nil {
+			log.Warningf(c, "Raio inválido: %v", err)
+			utils.RespondWithError(w, http.StatusBadRequest, 0, "Raio inválido")
+			return
+		}
+	}
+
+	filtro.Setor, err = estabelecimento.SetorPorNome(r.FormValue("setor"))
+	if err != nil {
+		log.Warningf(c, "%v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	filtro.SomenteAbertos = r.FormValue("aberto") == "true"
+	filtro.Limite, _ = strconv.Atoi(r.FormValue("limite"))
+
+	proximos, err := estabelecimento.BuscarProximos(c, filtro)
+	if err != nil {
+		log.Warningf(c, "Erro ao buscar estabelecimentos proximos: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, proximos)
 }
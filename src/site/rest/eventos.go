@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"site/autenticacao"
+	"site/eventos"
+	"site/seguidores"
+	"site/utils"
+	"site/utils/log"
+	"time"
+)
+
+// IntervaloHeartbeat mantém a conexão aberta em proxies que derrubam conexões ociosas
+const IntervaloHeartbeat = 25 * time.Second
+
+func EventosHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		TransmitirEventos(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Mantém a conexão aberta enviando via Server-Sent Events as novas publicações dos seguidos,
+//as alterações de curtidas nelas e as notificações do usuario logado. Quando o usuario segue ou
+//deixa de seguir alguem, os canais assinados são refeitos sem fechar a conexão
+func TransmitirEventos(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Warningf(c, "Servidor não suporta streaming da resposta")
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Streaming não suportado")
+		return
+	}
+
+	assinatura, err := assinarEventos(c, usuarioID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Falha ao assinar eventos")
+		return
+	}
+	defer func() {
+		assinatura.Cancelar()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(IntervaloHeartbeat)
+	defer heartbeat.Stop()
+
+	log.Debugf(c, "Usuario %d conectado aos eventos", usuarioID)
+	for {
+		select {
+		case <-c.Done():
+			log.Debugf(c, "Usuario %d desconectado dos eventos", usuarioID)
+			return
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+
+		case evento, aberto := <-assinatura.Eventos():
+			if !aberto {
+				return
+			}
+			escreverEvento(c, w, evento)
+			if evento.Tipo == eventos.TipoSeguidos {
+				assinatura = reassinarEventos(c, w, usuarioID, assinatura)
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+//assinarEventos assina o canal do usuario, o dele como autor e o de cada conta que ele segue
+func assinarEventos(c context.Context, usuarioID int64) (eventos.Assinatura, error) {
+	canais := []string{eventos.CanalUsuario(usuarioID), eventos.CanalAutor(usuarioID)}
+	if seguidor := seguidores.GetSeguidorByIDSeguidor(c, usuarioID); seguidor != nil {
+		for _, seguidoID := range seguidor.IDUsuario {
+			if seguidoID != 0 {
+				canais = append(canais, eventos.CanalAutor(seguidoID))
+			}
+		}
+	}
+
+	assinatura, err := eventos.Padrao().Assinar(c, canais...)
+	if err != nil {
+		log.Warningf(c, "Falha ao assinar eventos do usuario %d: %v", usuarioID, err)
+		return nil, err
+	}
+	log.Debugf(c, "Usuario %d assinando %d canais", usuarioID, len(canais))
+	return assinatura, nil
+}
+
+//reassinarEventos troca a assinatura por uma com os seguidos atuais. A nova é feita antes de cancelar a
+//antiga e o que ficou pendente na antiga ainda é enviado, para nenhum evento se perder na troca
+func reassinarEventos(c context.Context, w http.ResponseWriter, usuarioID int64, atual eventos.Assinatura) eventos.Assinatura {
+	nova, err := assinarEventos(c, usuarioID)
+	if err != nil {
+		return atual
+	}
+
+	atual.Cancelar()
+	for evento := range atual.Eventos() {
+		escreverEvento(c, w, evento)
+	}
+	return nova
+}
+
+func escreverEvento(c context.Context, w http.ResponseWriter, evento eventos.Evento) {
+	dados, err := json.Marshal(evento.Dados)
+	if err != nil {
+		log.Warningf(c, "Falha ao serializar evento %s: %v", evento.Tipo, err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evento.Tipo, dados)
+}
@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"net/http"
+	"site/autenticacao"
+	"site/publicacao"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func HashtagPublicacoesHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscarPublicacoesPorHashtag(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func HashtagsEmAltaHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscarHashtagsEmAlta(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Traz as publicações que usam uma hashtag
+func BuscarPublicacoesPorHashtag(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	tag := mux.Vars(r)["tag"]
+	if publicacao.NormalizarHashtag(tag) == "" {
+		log.Warningf(c, "Hashtag inválida: %s", tag)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Hashtag inválida")
+		return
+	}
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return
+	}
+
+	publics, err := publicacao.BuscarPorHashtag(c, usuarioID, tag)
+	if err != nil {
+		log.Warningf(c, "Falha na busca das publicações da hashtag %s: %v", tag, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha na busca das publicações da hashtag")
+		return
+	}
+
+	log.Debugf(c, "Busca realizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, publics)
+}
+
+//Traz as hashtags mais usadas nas ultimas horas
+func BuscarHashtagsEmAlta(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	limite, _ := strconv.Atoi(r.FormValue("limite"))
+	horas, _ := strconv.Atoi(r.FormValue("horas"))
+
+	hashtags, err := publicacao.HashtagsEmAlta(c, time.Duration(horas)*time.Hour, limite)
+	if err != nil {
+		log.Warningf(c, "Falha na busca das hashtags em alta: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha na busca das hashtags em alta")
+		return
+	}
+
+	log.Debugf(c, "Busca realizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, hashtags)
+}
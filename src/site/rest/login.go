@@ -53,6 +53,11 @@ func AcessarUsuario(w http.ResponseWriter, r *http.Request) {
 			utils.RespondWithError(w, http.StatusBadRequest, 0, "Senha inválida")
 			return
 		}
+		if sancao := usuario.SancaoAtiva(c, usu.ID); sancao != nil {
+			log.Warningf(c, "Login negado para o usuario %d com sanção ativa", usu.ID)
+			utils.RespondWithError(w, http.StatusForbidden, 0, sancao.Mensagem())
+			return
+		}
 		token, err := autenticacao.CriarToken(c, usu.ID)
 		if err != nil {
 			log.Warningf(c, "Falha ao Criar token para o usuario %v", err)
@@ -0,0 +1,378 @@
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"site/autenticacao"
+	"site/mensagem"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func ConversasHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		ListarConversas(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		CriarConversa(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func ConversaHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaConversa(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func MensagensNaoLidasHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		ContarMensagensNaoLidas(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func MensagensConversaHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		ListarMensagens(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		EnviarMensagem(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func ConversaLidaHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		MarcarConversaLida(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func MensagemHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		EditarMensagem(w, r)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		ExcluirMensagem(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Traz as conversas do usuario logado, as mais recentes primeiro
+func ListarConversas(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	conversas, err := mensagem.BuscarConversas(c, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar conversas do usuario %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao buscar conversas")
+		return
+	}
+
+	log.Debugf(c, "Busca realizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, conversas)
+}
+
+//Abre uma conversa com os participantes informados, ex: {"Participantes": [2, 3], "Titulo": "Grupo"}
+func CriarConversa(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	corpoRequisicao, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da requisição")
+		return
+	}
+
+	var dados struct {
+		Participantes []int64
+		Titulo        string
+	}
+	if err = json.Unmarshal(corpoRequisicao, &dados); err != nil {
+		log.Warningf(c, "Falha ao realizar unmarshal da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao realizar unmarshal da requisição")
+		return
+	}
+
+	conversa, err := mensagem.CriarConversa(c, usuarioID, dados.Participantes, dados.Titulo)
+	if err != nil {
+		log.Warningf(c, "Falha ao criar conversa: %v", err)
+		utils.RespondWithError(w, http.StatusForbidden, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Conversa %d aberta com sucesso", conversa.ID)
+	utils.RespondWithJSON(w, http.StatusOK, conversa)
+}
+
+//Traz uma conversa do usuario logado
+func BuscaConversa(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, conversaID, ok := extrairUsuarioEConversa(w, r)
+	if !ok {
+		return
+	}
+
+	conversa, err := mensagem.BuscarConversa(c, conversaID, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar conversa %d: %v", conversaID, err)
+		utils.RespondWithError(w, http.StatusForbidden, 0, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, conversa)
+}
+
+//Retorna a quantidade de mensagens não lidas do usuario logado
+func ContarMensagensNaoLidas(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	total, err := mensagem.ContarNaoLidas(c, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Falha ao contar mensagens do usuario %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao contar mensagens")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]int64{"NaoLidas": total})
+}
+
+//Traz as mensagens de uma conversa do usuario logado, use antes=id da mensagem mais antiga para paginar
+func ListarMensagens(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, conversaID, ok := extrairUsuarioEConversa(w, r)
+	if !ok {
+		return
+	}
+
+	limite, _ := strconv.Atoi(r.FormValue("limite"))
+	antesID, _ := strconv.ParseInt(r.FormValue("antes"), 10, 64)
+
+	mensagens, err := mensagem.ListarMensagens(c, conversaID, usuarioID, antesID, limite)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar mensagens da conversa %d: %v", conversaID, err)
+		utils.RespondWithError(w, http.StatusForbidden, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Busca realizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, mensagens)
+}
+
+//Envia uma mensagem na conversa, ex: {"Conteudo": "Oi"}
+func EnviarMensagem(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, conversaID, ok := extrairUsuarioEConversa(w, r)
+	if !ok {
+		return
+	}
+
+	conteudo, ok := lerConteudoMensagem(w, r)
+	if !ok {
+		return
+	}
+
+	msg, err := mensagem.EnviarMensagem(c, conversaID, usuarioID, conteudo)
+	if err != nil {
+		log.Warningf(c, "Falha ao enviar mensagem na conversa %d: %v", conversaID, err)
+		utils.RespondWithError(w, http.StatusForbidden, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Mensagem enviada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, msg)
+}
+
+//Registra que o usuario logado leu a conversa
+func MarcarConversaLida(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, conversaID, ok := extrairUsuarioEConversa(w, r)
+	if !ok {
+		return
+	}
+
+	if err := mensagem.MarcarLida(c, conversaID, usuarioID); err != nil {
+		log.Warningf(c, "Falha ao marcar conversa %d como lida: %v", conversaID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao marcar conversa como lida")
+		return
+	}
+
+	log.Debugf(c, "Conversa marcada como lida")
+	utils.RespondWithJSON(w, http.StatusOK, "Conversa marcada como lida")
+}
+
+//Edita uma mensagem do usuario logado
+func EditarMensagem(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, mensagemID, ok := extrairUsuarioEMensagem(w, r)
+	if !ok {
+		return
+	}
+
+	conteudo, ok := lerConteudoMensagem(w, r)
+	if !ok {
+		return
+	}
+
+	msg, err := mensagem.EditarMensagem(c, mensagemID, usuarioID, conteudo)
+	if err != nil {
+		log.Warningf(c, "Falha ao editar mensagem %d: %v", mensagemID, err)
+		utils.RespondWithError(w, http.StatusForbidden, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Mensagem editada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, msg)
+}
+
+//Exclui uma mensagem do usuario logado
+func ExcluirMensagem(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, mensagemID, ok := extrairUsuarioEMensagem(w, r)
+	if !ok {
+		return
+	}
+
+	if err := mensagem.ExcluirMensagem(c, mensagemID, usuarioID); err != nil {
+		log.Warningf(c, "Falha ao excluir mensagem %d: %v", mensagemID, err)
+		utils.RespondWithError(w, http.StatusForbidden, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Mensagem excluida com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, "Mensagem excluida com sucesso")
+}
+
+func extrairUsuarioEConversa(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return 0, 0, false
+	}
+
+	conversaID, err := strconv.ParseInt(mux.Vars(r)["idconversa"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Falha ao converter id da conversa: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao converter id da conversa")
+		return 0, 0, false
+	}
+	return usuarioID, conversaID, true
+}
+
+func extrairUsuarioEMensagem(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return 0, 0, false
+	}
+
+	mensagemID, err := strconv.ParseInt(mux.Vars(r)["idmensagem"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Falha ao converter id da mensagem: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao converter id da mensagem")
+		return 0, 0, false
+	}
+	return usuarioID, mensagemID, true
+}
+
+func lerConteudoMensagem(w http.ResponseWriter, r *http.Request) (string, bool) {
+	c := r.Context()
+
+	corpoRequisicao, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da requisição")
+		return "", false
+	}
+
+	var dados struct {
+		Conteudo string
+	}
+	if err = json.Unmarshal(corpoRequisicao, &dados); err != nil {
+		log.Warningf(c, "Falha ao realizar unmarshal da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao realizar unmarshal da requisição")
+		return "", false
+	}
+	return dados.Conteudo, true
+}
@@ -0,0 +1,235 @@
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"site/autenticacao"
+	"site/moderacao"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func DenunciasHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPost {
+		CriarDenuncia(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func MotivosDenunciaHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		utils.RespondWithJSON(w, http.StatusOK, moderacao.Motivos)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func FilaModeracaoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaFilaModeracao(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func ModerarDenunciaHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		ModerarDenuncia(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func SancaoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodDelete {
+		RevogaSancao(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func AuditoriaModeracaoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaAuditoriaModeracao(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Registra a denuncia do usuario logado sobre uma publicação, um comentario ou um perfil
+func CriarDenuncia(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	corpoRequisicao, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body da denuncia %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da denuncia")
+		return
+	}
+
+	var denuncia moderacao.Denuncia
+	if err = json.Unmarshal(corpoRequisicao, &denuncia); err != nil {
+		log.Warningf(c, "Erro ao fazer unmarshal da denuncia: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao fazer unmarshal da denuncia")
+		return
+	}
+	denuncia.DenuncianteID = usuarioID
+
+	registrada, err := moderacao.Denunciar(c, denuncia)
+	if err != nil {
+		log.Warningf(c, "Falha ao registrar denuncia do usuario %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Denuncia registrada")
+	utils.RespondWithJSON(w, http.StatusCreated, registrada)
+}
+
+//Traz a fila de moderação, por padrão só as denuncias pendentes
+func BuscaFilaModeracao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	filtro := moderacao.Filtro{
+		Status:   r.FormValue("status"),
+		TipoAlvo: r.FormValue("tipo"),
+		Motivo:   r.FormValue("motivo"),
+	}
+	if filtro.Status == "" {
+		filtro.Status = moderacao.StatusPendente
+	} else if filtro.Status == "todas" {
+		filtro.Status = ""
+	}
+	filtro.AlvoID, _ = strconv.ParseInt(r.FormValue("alvo"), 10, 64)
+	filtro.AutorAlvoID, _ = strconv.ParseInt(r.FormValue("autor"), 10, 64)
+
+	denuncias, err := moderacao.FiltrarDenuncias(c, filtro)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar fila de moderação: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Falha ao buscar fila de moderação")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, denuncias)
+}
+
+//Aplica a decisão do moderador sobre a denuncia: descartar, ocultar, suspender ou banir
+func ModerarDenuncia(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	moderadorID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	denunciaID, err := strconv.ParseInt(mux.Vars(r)["iddenuncia"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Falha ao converter id da denuncia: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao converter id da denuncia")
+		return
+	}
+
+	corpoRequisicao, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body da decisão %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da decisão")
+		return
+	}
+
+	var decisao moderacao.Decisao
+	if err = json.Unmarshal(corpoRequisicao, &decisao); err != nil {
+		log.Warningf(c, "Erro ao fazer unmarshal da decisão: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao fazer unmarshal da decisão")
+		return
+	}
+
+	denuncia, err := moderacao.Moderar(c, denunciaID, moderadorID, decisao)
+	if err != nil {
+		log.Warningf(c, "Falha ao moderar denuncia %d: %v", denunciaID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Denuncia %d moderada: %s", denunciaID, decisao.Acao)
+	utils.RespondWithJSON(w, http.StatusOK, denuncia)
+}
+
+//Encerra a suspensão ou o banimento do usuario
+func RevogaSancao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	moderadorID, usuarioID, ok := extrairUsuarioESolicitante(w, r)
+	if !ok {
+		return
+	}
+
+	if err := moderacao.RevogarSancao(c, usuarioID, moderadorID, r.FormValue("observacao")); err != nil {
+		log.Warningf(c, "Falha ao revogar sanção do usuario %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Sanção revogada")
+	utils.RespondWithJSON(w, http.StatusOK, "Sanção revogada")
+}
+
+//Traz o historico de ações de moderação, filtrando por moderador ou por usuario afetado
+func BuscaAuditoriaModeracao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	moderadorID, _ := strconv.ParseInt(r.FormValue("moderador"), 10, 64)
+	usuarioID, _ := strconv.ParseInt(r.FormValue("usuario"), 10, 64)
+
+	registros, err := moderacao.BuscarAuditoria(c, moderadorID, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar auditoria de moderação: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Falha ao buscar auditoria de moderação")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, registros)
+}
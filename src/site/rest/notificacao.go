@@ -0,0 +1,244 @@
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"site/autenticacao"
+	"site/notificacao"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+)
+
+//Cabeçalho com o cursor da proxima pagina de notificações
+const cabecalhoProximaPagina = "X-Proxima-Pagina"
+
+func NotificacoesHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		ListarNotificacoes(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func NotificacoesNaoLidasHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		ContarNotificacoesNaoLidas(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func MarcarNotificacoesLidasHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		MarcarNotificacoesLidas(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func MarcarTodasNotificacoesLidasHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		MarcarTodasNotificacoesLidas(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func PreferenciasNotificacaoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscarPreferenciasNotificacao(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		AtualizarPreferenciasNotificacao(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Traz as notificações do usuario logado, agrupadas. Quando há mais paginas, o cabeçalho X-Proxima-Pagina traz o
+//valor para passar em antes na proxima chamada
+func ListarNotificacoes(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	limite, _ := strconv.Atoi(r.FormValue("limite"))
+	antesID, _ := strconv.ParseInt(r.FormValue("antes"), 10, 64)
+
+	grupos, proximaID, err := notificacao.Listar(c, usuarioID, antesID, limite)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar notificações do usuario %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao buscar notificações")
+		return
+	}
+
+	if proximaID != 0 {
+		w.Header().Set(cabecalhoProximaPagina, strconv.FormatInt(proximaID, 10))
+	}
+
+	log.Debugf(c, "Busca realizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, grupos)
+}
+
+//Retorna a quantidade de notificações não lidas do usuario logado
+func ContarNotificacoesNaoLidas(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	total, err := notificacao.ContarNaoLidas(c, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Falha ao contar notificações do usuario %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao contar notificações")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]int{"NaoLidas": total})
+}
+
+//Marca como lidas as notificações enviadas no corpo da requisição
+func MarcarNotificacoesLidas(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	corpoRequisicao, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da requisição")
+		return
+	}
+
+	var dados struct {
+		IDs []int64
+	}
+	if err = json.Unmarshal(corpoRequisicao, &dados); err != nil {
+		log.Warningf(c, "Falha ao realizar unmarshal da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao realizar unmarshal da requisição")
+		return
+	}
+
+	if err = notificacao.MarcarLidas(c, usuarioID, dados.IDs); err != nil {
+		log.Warningf(c, "Falha ao marcar notificações como lidas: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Notificações marcadas como lidas")
+	utils.RespondWithJSON(w, http.StatusOK, "Notificações marcadas como lidas")
+}
+
+//Marca como lidas todas as notificações do usuario logado
+func MarcarTodasNotificacoesLidas(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	if err = notificacao.MarcarTodasLidas(c, usuarioID); err != nil {
+		log.Warningf(c, "Falha ao marcar todas as notificações como lidas: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao marcar notificações como lidas")
+		return
+	}
+
+	log.Debugf(c, "Notificações marcadas como lidas")
+	utils.RespondWithJSON(w, http.StatusOK, "Notificações marcadas como lidas")
+}
+
+//Traz quais tipos de notificação o usuario logado recebe
+func BuscarPreferenciasNotificacao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, notificacao.GetPreferencias(c, usuarioID).Mapa())
+}
+
+//Ativa ou desativa tipos de notificação, ex: {"curtida": false}
+func AtualizarPreferenciasNotificacao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	corpoRequisicao, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da requisição")
+		return
+	}
+
+	var alteracoes map[string]bool
+	if err = json.Unmarshal(corpoRequisicao, &alteracoes); err != nil {
+		log.Warningf(c, "Falha ao realizar unmarshal da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao realizar unmarshal da requisição")
+		return
+	}
+
+	preferencias, err := notificacao.AtualizarPreferencias(c, usuarioID, alteracoes)
+	if err != nil {
+		log.Warningf(c, "Falha ao atualizar preferencias de notificação: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Preferencias atualizadas com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, preferencias.Mapa())
+}
@@ -2,6 +2,7 @@ package rest
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"site/autenticacao"
@@ -9,6 +10,8 @@ import (
 	"site/utils"
 	"site/utils/log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -105,6 +108,81 @@ func DescurtirPublicHandler(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+func CompartilharPublicHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPost {
+		CompartilharPublicacao(w, r)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		DesfazerCompartilhamento(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func RevisoesPublicHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscarRevisoesPublicacao(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func RascunhosHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscarRascunhos(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func PublicarHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPost {
+		PublicarRascunho(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func AgendarHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		AgendarPublicacao(w, r)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		CancelarAgendamento(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
 func PublicacoesUsuarioHandler(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
 
@@ -128,21 +206,51 @@ func CriarPublicacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	corpoRequisicao, err := ioutil.ReadAll(r.Body)
+	var public publicacao.Publicacao
+	var arquivos [][]byte
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		public, arquivos, err = lerPublicacaoMultipart(w, r)
+		if err != nil {
+			log.Warningf(c, "Falha ao ler formulário da publicação %v", err)
+			utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+			return
+		}
+	} else {
+		corpoRequisicao, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			log.Warningf(c, "Erro ao receber body da requisição %v", err)
+			utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da requisição")
+			return
+		}
+
+		if err = json.Unmarshal(corpoRequisicao, &public); err != nil {
+			log.Warningf(c, "Falha ao realizar unmarshal da requisição %v", err)
+			utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao realizar unmarshal da requisição")
+			return
+		}
+	}
+
+	public.DataAgendamento, err = publicacao.HorarioAgendamento(public.DataAgendamento)
 	if err != nil {
-		log.Warningf(c, "Erro ao receber body da requisição %v", err)
-		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da requisição")
+		log.Warningf(c, "Data de agendamento inválida %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Data de agendamento inválida")
 		return
 	}
 
-	var public publicacao.Publicacao
-	if err = json.Unmarshal(corpoRequisicao, &public); err != nil {
-		log.Warningf(c, "Falha ao realizar unmarshal da requisição %v", err)
-		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao realizar unmarshal da requisição")
-		return
+	// Anexos só podem vir de arquivos enviados, nunca de URLs informadas no corpo
+	public.Anexos = nil
+	if len(arquivos) > 0 {
+		public.Anexos, err = publicacao.ProcessarAnexos(c, usuarioID, arquivos)
+		if err != nil {
+			log.Warningf(c, "Falha ao processar anexos da publicação %v", err)
+			utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+			return
+		}
 	}
 
 	if novaPublic := publicacao.CriarPublic(c, usuarioID, &public); novaPublic != nil {
+		publicacao.RemoverAnexos(c, public.Anexos)
 		log.Warningf(c, "Erro na criação da publicação %v", err)
 		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro na criação da publicação")
 		return
@@ -153,6 +261,62 @@ func CriarPublicacao(w http.ResponseWriter, r *http.Request) {
 
 }
 
+//Lê os campos e as imagens de uma publicação enviada como multipart/form-data
+func lerPublicacaoMultipart(w http.ResponseWriter, r *http.Request) (publicacao.Publicacao, [][]byte, error) {
+	var public publicacao.Publicacao
+
+	r.Body = http.MaxBytesReader(w, r.Body, publicacao.MaxAnexos*publicacao.TamanhoMaximoAnexo+(1<<20))
+	if err := r.ParseMultipartForm(publicacao.TamanhoMaximoAnexo); err != nil {
+		return public, nil, fmt.Errorf("Formulário inválido ou acima do tamanho permitido")
+	}
+
+	public.Titulo = r.FormValue("titulo")
+	public.Conteudo = r.FormValue("conteudo")
+	public.Status = r.FormValue("status")
+	public.Visibilidade = r.FormValue("visibilidade")
+
+	if estabelecimentoID := r.FormValue("estabelecimento"); estabelecimentoID != "" {
+		id, err := strconv.ParseInt(estabelecimentoID, 10, 64)
+		if err != nil {
+			return public, nil, fmt.Errorf("Estabelecimento inválido")
+		}
+		public.EstabelecimentoID = id
+	}
+
+	if dataAgendamento := r.FormValue("dataAgendamento"); dataAgendamento != "" {
+		data, err := time.Parse("2006-01-02 15:04:05", dataAgendamento)
+		if err != nil {
+			return public, nil, fmt.Errorf("Data de agendamento inválida")
+		}
+		public.DataAgendamento = utils.JsonSpecialDateTime{Time: data}
+	}
+
+	cabecalhos := r.MultipartForm.File["anexos"]
+	if len(cabecalhos) > publicacao.MaxAnexos {
+		return public, nil, fmt.Errorf("Uma publicação pode ter no máximo %d anexos", publicacao.MaxAnexos)
+	}
+
+	var arquivos [][]byte
+	for _, cabecalho := range cabecalhos {
+		if cabecalho.Size > publicacao.TamanhoMaximoAnexo {
+			return public, nil, fmt.Errorf("O anexo %s excede o tamanho máximo permitido", cabecalho.Filename)
+		}
+
+		arquivo, err := cabecalho.Open()
+		if err != nil {
+			return public, nil, fmt.Errorf("Falha ao abrir anexo %s", cabecalho.Filename)
+		}
+		dados, err := ioutil.ReadAll(arquivo)
+		arquivo.Close()
+		if err != nil {
+			return public, nil, fmt.Errorf("Falha ao ler anexo %s", cabecalho.Filename)
+		}
+		arquivos = append(arquivos, dados)
+	}
+
+	return public, arquivos, nil
+}
+
 //Traz as publicações que apareceriam no feed do usuario
 func BuscarPublicacoes(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
@@ -164,7 +328,7 @@ func BuscarPublicacoes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	publicacoes, err := publicacao.Buscar(c, usuarioID)
+	publicacoes, err := publicacao.Buscar(c, usuarioID, r.FormValue("modo"))
 	if err != nil {
 		log.Warningf(c, "Falha na busca das publicações: %v", err)
 		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha na busca das publicações")
@@ -187,7 +351,18 @@ func BuscarPublicacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return
+	}
+
 	public := publicacao.GetPublicacao(c, id)
+	if public == nil || !publicacao.NovoLeitor(c, usuarioID).PodeVer(public) {
+		utils.RespondWithError(w, http.StatusNotFound, 0, "Publicação não encontrada")
+		return
+	}
 
 	log.Debugf(c, "Busca realizada com sucesso")
 	utils.RespondWithJSON(w, http.StatusOK, public)
@@ -238,14 +413,15 @@ func AtualizarPublicacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = publicacao.Atualizar(c, public); err != nil {
+	atualizada, err := publicacao.Atualizar(c, public)
+	if err != nil {
 		log.Warningf(c, "Falha na edição da publicação: %v", err)
-		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha na edição da publicação")
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha na edição da publicação: "+err.Error())
 		return
 	}
 
 	log.Debugf(c, "Publicação atualizada com sucesso")
-	utils.RespondWithJSON(w, http.StatusOK, "Publicação atualizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, atualizada)
 	return
 }
 
@@ -298,10 +474,18 @@ func BuscarPublicacoesPorUsuario(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	publics, err := publicacao.BuscarPorUsuario(c, usuarioID)
+	leitorID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return
+	}
+
+	publics, err := publicacao.BuscarPorUsuario(c, leitorID, usuarioID)
 	if err != nil {
 		log.Warningf(c, "Falha na busca das publicações do usuario %v, erro: %v", usuarioID, err)
 		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha na busca das publicações do usuario")
+		return
 	}
 
 	log.Debugf(c, "Busca realizada com sucesso")
@@ -321,7 +505,14 @@ func CurtirPublicacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := publicacao.Curtir(c, publicacaoID); err != nil {
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return
+	}
+
+	if err := publicacao.Curtir(c, publicacaoID, usuarioID); err != nil {
 		log.Warningf(c, "Erro ao curtir publicação: %v", err)
 		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao curtir publicação")
 		return
@@ -343,7 +534,14 @@ func DescurtiPublicacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := publicacao.Descurtir(c, publicacaoID); err != nil {
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return
+	}
+
+	if err := publicacao.Descurtir(c, publicacaoID, usuarioID); err != nil {
 		log.Warningf(c, "Erro ao descurtir a publicação: %v", err)
 		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao descurtir a publicação")
 		return
@@ -353,3 +551,254 @@ func DescurtiPublicacao(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithJSON(w, http.StatusOK, "Publicação descurtida")
 	return
 }
+
+//Compartilha a publicação na linha do tempo do usuario. Com {"Comentario": "..."} vira uma citação.
+func CompartilharPublicacao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	params := mux.Vars(r)
+	publicacaoID, err := strconv.ParseInt(params["idpublic"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Erro ao converter id da publicação: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao converter id da publicação")
+		return
+	}
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return
+	}
+
+	corpoRequisicao, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Falha ao receber body da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao receber body da requisição")
+		return
+	}
+
+	var dados struct {
+		Comentario string
+	}
+	if len(corpoRequisicao) > 0 {
+		if err = json.Unmarshal(corpoRequisicao, &dados); err != nil {
+			log.Warningf(c, "Falha ao realizar unmarshal do corpo da requisição: %v", err)
+			utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao realizar unmarshal do corpo da requisição")
+			return
+		}
+	}
+
+	repost, err := publicacao.Compartilhar(c, usuarioID, publicacaoID, dados.Comentario)
+	if err != nil {
+		log.Warningf(c, "Erro ao compartilhar publicação %d: %v", publicacaoID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Publicação compartilhada")
+	utils.RespondWithJSON(w, http.StatusOK, repost)
+}
+
+//Remove o compartilhamento simples que o usuario fez da publicação
+func DesfazerCompartilhamento(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	params := mux.Vars(r)
+	publicacaoID, err := strconv.ParseInt(params["idpublic"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Erro ao converter id da publicação: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao converter id da publicação")
+		return
+	}
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return
+	}
+
+	if err = publicacao.DesfazerCompartilhamento(c, usuarioID, publicacaoID); err != nil {
+		log.Warningf(c, "Erro ao desfazer compartilhamento da publicação %d: %v", publicacaoID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Compartilhamento desfeito")
+	utils.RespondWithJSON(w, http.StatusOK, "Compartilhamento desfeito")
+}
+
+//Traz as versões anteriores de uma publicação editada
+func BuscarRevisoesPublicacao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	params := mux.Vars(r)
+	publicacaoID, err := strconv.ParseInt(params["idpublic"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Erro ao converter id da publicação: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao converter id da publicação")
+		return
+	}
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return
+	}
+
+	public := publicacao.GetPublicacao(c, publicacaoID)
+	if public == nil || !publicacao.NovoLeitor(c, usuarioID).PodeVer(public) {
+		utils.RespondWithError(w, http.StatusNotFound, 0, "Publicação não encontrada")
+		return
+	}
+
+	revisoes, err := publicacao.BuscarRevisoes(c, publicacaoID)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar revisões da publicação %d: %v", publicacaoID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao buscar revisões da publicação")
+		return
+	}
+
+	log.Debugf(c, "Busca realizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, revisoes)
+}
+
+//Traz os rascunhos e as publicações agendadas do usuario logado
+func BuscarRascunhos(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return
+	}
+
+	rascunhos, err := publicacao.BuscarRascunhos(c, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar rascunhos do usuario %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao buscar rascunhos")
+		return
+	}
+
+	log.Debugf(c, "Busca realizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, rascunhos)
+}
+
+//Publica agora um rascunho ou uma publicação agendada
+func PublicarRascunho(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	publicacaoID, ok := extrairRascunhoDoAutor(w, r)
+	if !ok {
+		return
+	}
+
+	public, err := publicacao.Publicar(c, publicacaoID)
+	if err != nil {
+		log.Warningf(c, "Erro ao publicar publicação %d: %v", publicacaoID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Publicação publicada")
+	utils.RespondWithJSON(w, http.StatusOK, public)
+}
+
+//Agenda a publicação para a data informada em {"DataAgendamento": "2006-01-02 15:04:05"}, no horario de Brasilia
+func AgendarPublicacao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	publicacaoID, ok := extrairRascunhoDoAutor(w, r)
+	if !ok {
+		return
+	}
+
+	corpoRequisicao, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warningf(c, "Falha ao receber body da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao receber body da requisição")
+		return
+	}
+
+	var dados struct {
+		DataAgendamento utils.JsonSpecialDateTime
+	}
+	if err = json.Unmarshal(corpoRequisicao, &dados); err != nil {
+		log.Warningf(c, "Falha ao realizar unmarshal do corpo da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Data de agendamento inválida")
+		return
+	}
+
+	data, err := publicacao.HorarioAgendamento(dados.DataAgendamento)
+	if err != nil {
+		log.Warningf(c, "Data de agendamento inválida: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Data de agendamento inválida")
+		return
+	}
+
+	public, err := publicacao.Agendar(c, publicacaoID, data)
+	if err != nil {
+		log.Warningf(c, "Erro ao agendar publicação %d: %v", publicacaoID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Publicação agendada")
+	utils.RespondWithJSON(w, http.StatusOK, public)
+}
+
+//Cancela o agendamento, a publicação volta a ser um rascunho
+func CancelarAgendamento(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	publicacaoID, ok := extrairRascunhoDoAutor(w, r)
+	if !ok {
+		return
+	}
+
+	public, err := publicacao.CancelarAgendamento(c, publicacaoID)
+	if err != nil {
+		log.Warningf(c, "Erro ao cancelar agendamento da publicação %d: %v", publicacaoID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Agendamento cancelado")
+	utils.RespondWithJSON(w, http.StatusOK, public)
+}
+
+//Lê o id da publicação da rota e confere se o usuario logado é o autor
+func extrairRascunhoDoAutor(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	c := r.Context()
+
+	params := mux.Vars(r)
+	publicacaoID, err := strconv.ParseInt(params["idpublic"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Erro ao converter id da publicação: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao converter id da publicação")
+		return 0, false
+	}
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair id do usuario da requisição: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair id do usuario da requisição")
+		return 0, false
+	}
+
+	public := publicacao.GetPublicacao(c, publicacaoID)
+	if public == nil || !publicacao.NovoLeitor(c, usuarioID).PodeVer(public) {
+		utils.RespondWithError(w, http.StatusNotFound, 0, "Publicação não encontrada")
+		return 0, false
+	}
+
+	if public.AutorID != usuarioID {
+		log.Warningf(c, "Usuario %d tentou alterar a publicação %d de outro autor", usuarioID, publicacaoID)
+		utils.RespondWithError(w, http.StatusForbidden, 0, "Não é possivel alterar uma publicação que não seja sua")
+		return 0, false
+	}
+	return publicacaoID, true
+}
@@ -0,0 +1,180 @@
+package rest
+
+import (
+	"net/http"
+	"site/autenticacao"
+	"site/seguidores"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func SolicitacoesHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaSolicitacoes(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func AprovarSolicitacaoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		AprovarSolicitacao(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func RecusarSolicitacaoHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodDelete {
+		RecusarSolicitacao(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func SolicitacaoEnviadaHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaSolicitacaoEnviada(w, r)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		CancelarSolicitacao(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Traz os pedidos pendentes para seguir o usuario logado
+func BuscaSolicitacoes(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	solicitacoes, err := seguidores.BuscarSolicitacoes(c, usuarioID)
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar solicitações: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao buscar solicitações")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, solicitacoes)
+}
+
+//Aceita o pedido do usuario para seguir o usuario logado
+func AprovarSolicitacao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, seguidorID, ok := extrairUsuarioESolicitante(w, r)
+	if !ok {
+		return
+	}
+
+	if err := seguidores.AprovarSolicitacao(c, usuarioID, seguidorID); err != nil {
+		log.Warningf(c, "Falha ao aprovar solicitação de %d: %v", seguidorID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Solicitação aprovada")
+	utils.RespondWithJSON(w, http.StatusOK, "Solicitação aprovada")
+}
+
+//Recusa o pedido do usuario para seguir o usuario logado
+func RecusarSolicitacao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, seguidorID, ok := extrairUsuarioESolicitante(w, r)
+	if !ok {
+		return
+	}
+
+	if err := seguidores.RemoverSolicitacao(c, seguidorID, usuarioID); err != nil {
+		log.Warningf(c, "Falha ao recusar solicitação de %d: %v", seguidorID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao recusar solicitação")
+		return
+	}
+
+	log.Debugf(c, "Solicitação recusada")
+	utils.RespondWithJSON(w, http.StatusOK, "Solicitação recusada")
+}
+
+//Diz se o usuario logado tem um pedido pendente para seguir o usuario
+func BuscaSolicitacaoEnviada(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	seguidorID, usuarioID, ok := extrairUsuarioESolicitante(w, r)
+	if !ok {
+		return
+	}
+
+	pendente := seguidores.GetSolicitacao(c, seguidorID, usuarioID) != nil
+	utils.RespondWithJSON(w, http.StatusOK, map[string]bool{"Pendente": pendente})
+}
+
+//Desiste do pedido pendente para seguir o usuario
+func CancelarSolicitacao(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	seguidorID, usuarioID, ok := extrairUsuarioESolicitante(w, r)
+	if !ok {
+		return
+	}
+
+	if err := seguidores.RemoverSolicitacao(c, seguidorID, usuarioID); err != nil {
+		log.Warningf(c, "Falha ao cancelar solicitação para %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao cancelar solicitação")
+		return
+	}
+
+	log.Debugf(c, "Solicitação cancelada")
+	utils.RespondWithJSON(w, http.StatusOK, "Solicitação cancelada")
+}
+
+//Lê o usuario logado do token e o outro usuario da rota
+func extrairUsuarioESolicitante(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return 0, 0, false
+	}
+
+	outroID, err := strconv.ParseInt(mux.Vars(r)["idusuario"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Falha ao converter id do usuário: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao converter id do usuário")
+		return 0, 0, false
+	}
+	return usuarioID, outroID, true
+}
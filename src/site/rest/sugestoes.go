@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"net/http"
+	"site/autenticacao"
+	"site/seguidores"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+)
+
+func SugestoesHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaSugestoes(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//Traz contas que o usuario logado talvez queira seguir
+func BuscaSugestoes(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair usuarioID do token %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair usuarioID do token")
+		return
+	}
+
+	limite, _ := strconv.Atoi(r.FormValue("limite"))
+
+	sugestoes, err := seguidores.BuscarSugestoes(c, usuarioID, limite)
+	if err != nil {
+		log.Warningf(c, "Falha na busca das sugestões do usuario %d: %v", usuarioID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, 0, "Falha na busca das sugestões")
+		return
+	}
+
+	log.Debugf(c, "Busca realizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, sugestoes)
+}
@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"site/tributos"
+	"site/utils"
+	"site/utils/log"
+	"strconv"
+	"strings"
+)
+
+//Maior valor aceito no calculo do ICMS, em reais. Bem abaixo do que cabe em centavos num int64.
+const valorMaximoICMS = 1e12
+
+func ICMSHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		CalculaICMS(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func TabelaICMSHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodGet {
+		BuscaTabelaICMS(w, r)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+//CalculaICMS calcula o imposto da venda de origem para destino, que aceitam a sigla da UF ou o codigo IBGE
+//do municipio. ie é a Inscrição Estadual do destinatario (vazia ou ISENTO para não contribuinte) e valor
+//vem em reais.
+func CalculaICMS(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	operacao := tributos.OperacaoICMS{
+		UFOrigem:  r.FormValue("origem"),
+		UFDestino: r.FormValue("destino"),
+		IE:        r.FormValue("ie"),
+	}
+
+	if valor := strings.Replace(strings.TrimSpace(r.FormValue("valor")), ",", ".", 1); valor != "" {
+		reais, err := strconv.ParseFloat(valor, 64)
+		if err == nil && (reais < 0 || reais > valorMaximoICMS || math.IsNaN(reais) || math.IsInf(reais, 0)) {
+			err = fmt.Errorf("valor %q fora do intervalo", valor)
+		}
+		if err != nil {
+			log.Warningf(c, "Valor inválido: %v", err)
+			utils.RespondWithError(w, http.StatusBadRequest, 0, "Valor inválido")
+			return
+		}
+		operacao.Valor = utils.FloatToCurrency(reais)
+	}
+
+	calculo, err := tributos.Calcular(c, operacao)
+	if err != nil {
+		log.Warningf(c, "Falha ao calcular ICMS: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, calculo)
+}
+
+//BuscaTabelaICMS traz a tabela de aliquotas da versão informada, ou a vigente
+func BuscaTabelaICMS(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	var tabela *tributos.Tabela
+	var err error
+	if versao := r.FormValue("versao"); versao != "" {
+		tabela, err = tributos.TabelaVersao(versao)
+	} else {
+		tabela, err = tributos.TabelaVigente(c)
+	}
+	if err != nil {
+		log.Warningf(c, "Falha ao buscar tabela de ICMS: %v", err)
+		utils.RespondWithError(w, http.StatusNotFound, 0, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, tabela)
+}
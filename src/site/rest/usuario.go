@@ -1,7 +1,9 @@
 package rest
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"site/autenticacao"
@@ -9,6 +11,7 @@ import (
 	"site/seguranca"
 	"site/usuario"
 	"site/utils"
+	"site/utils/imagem"
 	"site/utils/log"
 	"strconv"
 
@@ -132,6 +135,32 @@ func BuscaSeguidoresHandler(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+func AvatarUsuarioHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		AtualizaImagemUsuario(w, r, usuario.ImagemAvatar)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
+func BannerUsuarioHandler(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+
+	if r.Method == http.MethodPut {
+		AtualizaImagemUsuario(w, r, usuario.ImagemBanner)
+		return
+	}
+
+	log.Warningf(c, "Método não permitido")
+	utils.RespondWithError(w, http.StatusMethodNotAllowed, 0, "Método não permitido")
+	return
+}
+
 func BuscaUsuario(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
 	var (
@@ -154,14 +183,28 @@ func BuscaUsuario(w http.ResponseWriter, r *http.Request) {
 		Email: r.FormValue("Email"),
 	}
 
-	usuario, err := usuario.FiltrarUsuario(c, filtro)
+	usuarios, err := usuario.FiltrarUsuario(c, filtro)
 	if err != nil {
 		log.Warningf(c, "Erro ao buscar Usuario: %v", err)
 		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao buscar Usuario")
 		return
 	}
+
+	usuarioID, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair token do usuario da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair token do usuario da requisição")
+		return
+	}
+
+	for i := range usuarios {
+		if usuarios[i].ID != usuarioID && usuarios[i].Visibilidade == usuario.VisibilidadePrivada {
+			usuarios[i].OcultarPerfil()
+		}
+	}
+
 	log.Debugf(c, "Busca realizada com sucesso")
-	utils.RespondWithJSON(w, http.StatusOK, usuario)
+	utils.RespondWithJSON(w, http.StatusOK, usuarios)
 }
 
 func InsereUsuario(w http.ResponseWriter, r *http.Request) {
@@ -228,14 +271,27 @@ func AtualizaUsuario(w http.ResponseWriter, r *http.Request) {
 
 	corpoRequisicao, err := ioutil.ReadAll(r.Body)
 
-	var usuNovo usuario.Usuario
-	if err = json.Unmarshal(corpoRequisicao, &usuNovo); err != nil {
+	//Os dados de perfil vão em AlteracaoPerfil, que diferencia campo ausente de campo apagado
+	var dados struct {
+		Nome  string
+		Nick  string
+		Email string
+	}
+	if err = json.Unmarshal(corpoRequisicao, &dados); err != nil {
 		log.Warningf(c, "Falha ao realizar unmarshal da requisição para alterar senha %v", err)
 		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao realizar unmarshal da requisição para alterar senha")
 		return
 	}
+	usuNovo := usuario.Usuario{Nome: dados.Nome, Nick: dados.Nick, Email: dados.Email}
+
+	var perfil usuario.AlteracaoPerfil
+	if err = json.Unmarshal(corpoRequisicao, &perfil); err != nil {
+		log.Warningf(c, "Falha ao realizar unmarshal do perfil %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao realizar unmarshal do perfil")
+		return
+	}
 
-	err = usuario.AtualizarUsuario(c, usu, usuNovo)
+	err = usuario.AtualizarUsuario(c, usu, usuNovo, perfil)
 	if err != nil {
 		log.Warningf(c, "Erro ao atualizar usuario %v", err)
 		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao atualizar usuario")
@@ -281,7 +337,7 @@ func DeletaUsuario(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithJSON(w, http.StatusOK, "Usuario deletado")
 }
 
-//Permite que um usuario siga outro
+//Permite que um usuario siga outro. Contas privadas recebem um pedido que precisa ser aprovado
 func SeguirUsuario(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
 	params := mux.Vars(r)
@@ -305,12 +361,19 @@ func SeguirUsuario(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = seguidores.Seguir(c, seg.ID, seguidorID); err != nil {
+	pendente, err := seguidores.SolicitarSeguir(c, seg.ID, seguidorID)
+	if err != nil {
 		log.Warningf(c, "Erro seguir usuario %v", err)
 		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao seguir usuario")
 		return
 	}
 
+	if pendente {
+		log.Debugf(c, "Solicitação para seguir enviada")
+		utils.RespondWithJSON(w, http.StatusAccepted, "Solicitação para seguir enviada")
+		return
+	}
+
 	log.Debugf(c, "Usuario seguido com sucesso")
 	utils.RespondWithJSON(w, http.StatusOK, "Usuario seguido com sucesso")
 	return
@@ -405,6 +468,78 @@ func BuscaSeguidores(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+// Recebe uma imagem em base64 e atualiza o avatar ou o banner do usuario
+func AtualizaImagemUsuario(w http.ResponseWriter, r *http.Request, tipoImagem string) {
+	c := r.Context()
+
+	params := mux.Vars(r)
+	idUsu, err := strconv.ParseInt(params["idusuario"], 10, 64)
+	if err != nil {
+		log.Warningf(c, "Falha ao converter id do usuário: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao converter id do usuário")
+		return
+	}
+
+	usuarioIDNoToken, err := autenticacao.ExtrairUsuarioID(r)
+	if err != nil {
+		log.Warningf(c, "Erro ao extrair token do usuario da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao extrair token do usuario da requisição")
+		return
+	}
+
+	if idUsu != usuarioIDNoToken {
+		log.Warningf(c, "Usuario não tem autorizaçao para fazer essa ação")
+		utils.RespondWithError(w, http.StatusForbidden, 0, "Usuario não tem autorização para fazer essa ação")
+		return
+	}
+
+	usu := usuario.GetUsuario(c, idUsu)
+	if usu == nil {
+		log.Warningf(c, "Usuario não encontrado: %v", idUsu)
+		utils.RespondWithError(w, http.StatusNotFound, usuario.ErrNaoEncontrado, usuario.GetErro(usuario.ErrNaoEncontrado))
+		return
+	}
+
+	corpoRequisicao, err := ioutil.ReadAll(io.LimitReader(r.Body, imagem.TamanhoMaximo*2))
+	if err != nil {
+		log.Warningf(c, "Erro ao receber body da requisição %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Erro ao receber body da requisição")
+		return
+	}
+
+	var envio struct {
+		Imagem string
+	}
+	if err = json.Unmarshal(corpoRequisicao, &envio); err != nil {
+		log.Warningf(c, "Falha ao realizar unmarshal da imagem %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, 0, "Falha ao realizar unmarshal da imagem")
+		return
+	}
+
+	conteudoBase64, _, err := utils.GetDadosBase64(envio.Imagem)
+	if err != nil {
+		log.Warningf(c, "Imagem em base64 inválida %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, usuario.ErrImagemInvalida, usuario.GetErro(usuario.ErrImagemInvalida))
+		return
+	}
+
+	dados, err := base64.StdEncoding.DecodeString(conteudoBase64)
+	if err != nil {
+		log.Warningf(c, "Falha ao decodificar imagem %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, usuario.ErrImagemInvalida, usuario.GetErro(usuario.ErrImagemInvalida))
+		return
+	}
+
+	if err = usuario.AtualizarImagem(c, usu, tipoImagem, dados); err != nil {
+		log.Warningf(c, "Erro ao atualizar imagem do usuario %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, usuario.ErrImagemInvalida, err.Error())
+		return
+	}
+
+	log.Debugf(c, "Imagem do usuario atualizada com sucesso")
+	utils.RespondWithJSON(w, http.StatusOK, usu)
+}
+
 // Permite alterar a senha do usuario
 func AtualizaSenha(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
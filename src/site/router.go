@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"site/armazenamento"
 	"site/middlewares"
+	"site/publicacao"
 	"site/rest"
+	"site/seguidores"
 
 	"github.com/gorilla/mux"
 )
@@ -19,31 +23,117 @@ func main() {
 
 	//Estabelecimento
 	r.HandleFunc("/estabelecimento", middlewares.Autenticar(rest.EstabelecimentoHandler))
+	r.HandleFunc("/estabelecimento/{idestabelecimento}", middlewares.Autenticar(rest.EstabelecimentoIDHandler))                                           //Busca, atualiza ou exclui um estabelecimento
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/perfil", middlewares.Autenticar(rest.PerfilEstabelecimentoHandler))                                //Pagina publica do estabelecimento
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/avaliacoes", middlewares.Autenticar(rest.AvaliacoesEstabelecimentoHandler))                        //Lista avaliações ou grava e remove a do usuario
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/checkins", middlewares.Autenticar(rest.CheckinsEstabelecimentoHandler))                            //Lista ou faz check-in
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/publicacoes", middlewares.Autenticar(rest.PublicacoesEstabelecimentoHandler))                      //Publicações que marcaram o estabelecimento
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/cardapio", rest.CardapioHandler)                                                                   //Cardapio publico, só itens disponiveis
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/cardapio/completo", middlewares.Autenticar(rest.CardapioCompletoHandler))                          //Cardapio com itens indisponiveis, para a equipe
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/cardapio/categorias", middlewares.Autenticar(rest.CategoriasCardapioHandler))                      //Cria uma categoria
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/cardapio/categorias/{idcategoria}", middlewares.Autenticar(rest.CategoriaCardapioHandler))         //Altera ou exclui uma categoria
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/cardapio/itens", middlewares.Autenticar(rest.ItensCardapioHandler))                                //Cria um item
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/cardapio/itens/{iditem}", middlewares.Autenticar(rest.ItemCardapioHandler))                        //Altera ou exclui um item
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/cardapio/itens/{iditem}/disponibilidade", middlewares.Autenticar(rest.DisponibilidadeItemHandler)) //Liga ou desliga um item
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/cardapio/csv", middlewares.Autenticar(rest.CSVCardapioHandler))                                    //Exporta ou importa o cardapio em CSV
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/equipe", middlewares.Autenticar(rest.EquipeEstabelecimentoHandler))                                //Lista a equipe e os convites pendentes
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/equipe/{idusuario}", middlewares.Autenticar(rest.MembroEquipeHandler))                             //Remove um membro da equipe
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/convites", middlewares.Autenticar(rest.ConvitesEstabelecimentoHandler))                            //Convida alguém para a equipe pelo email
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/convites/{token}", middlewares.Autenticar(rest.ConviteEstabelecimentoHandler))                     //Revoga um convite pendente
+	r.HandleFunc("/estabelecimentos/lote", middlewares.Autenticar(rest.LoteEstabelecimentosHandler))                                                      //Cria ou atualiza varios estabelecimentos
+	r.HandleFunc("/estabelecimentos/meus", middlewares.Autenticar(rest.MeusEstabelecimentosHandler))                                                      //Estabelecimentos do usuario logado
+	r.HandleFunc("/estabelecimentos/proximos", middlewares.Autenticar(rest.EstabelecimentosProximosHandler))                                              //Estabelecimentos num raio, por distancia
+	r.HandleFunc("/endereco/busca", middlewares.Autenticar(rest.BuscaEnderecoHandler))                                                                    //Busca CEPs por UF, municipio e logradouro
+
+	//Convites para equipes de estabelecimentos
+	r.HandleFunc("/convites", middlewares.Autenticar(rest.ConvitesUsuarioHandler))                    //Convites pendentes para o email do usuario
+	r.HandleFunc("/convites/{token}/{resposta}", middlewares.Autenticar(rest.RespostaConviteHandler)) //Aceita ou recusa um convite
 
 	//Usuario
-	r.HandleFunc("/usuario/registrar", rest.RegistraUsuarioHandler)                                          //Registra um usuario
-	r.HandleFunc("/usuario/login", rest.LoginHandler)                                                        //Efetua login do usuario
-	r.HandleFunc("/usuario/buscar", middlewares.Autenticar(rest.BuscaUsuarioHandler))                        //Busca um usuario
-	r.HandleFunc("/usuario/atualizar/{idusuario}", middlewares.Autenticar(rest.AtualizaUsuarioHandler))      //Atualiza dados do usuario
-	r.HandleFunc("/usuario/{id}/atualizarSenha", middlewares.Autenticar(rest.AtualizaSenhaHandler))          //Atualiza senha do usuario
-	r.HandleFunc("/usuario/deletar/{idusuario}", middlewares.Autenticar(rest.DeletaUsuarioHandler))          //Exclui um usuario
-	r.HandleFunc("/usuario/seguir/{idusuario}", middlewares.Autenticar(rest.SeguirHandler))                  //Segue um usuario
-	r.HandleFunc("/usuario/unfollow/{idusuario}", middlewares.Autenticar(rest.UnFollowHandler))              //Para de seguir um usuario
-	r.HandleFunc("/usuario/seguidos/{idusuario}", middlewares.Autenticar(rest.BuscaUsuariosSeguidosHandler)) //Busca todos os usuarios que determinado usuario segue
-	r.HandleFunc("/usuario/seguidores/{idusuario}", middlewares.Autenticar(rest.BuscaSeguidoresHandler))     //Busca todos os usuarios que seguem determinado usuario
+	r.HandleFunc("/usuario/registrar", rest.RegistraUsuarioHandler)                                                   //Registra um usuario
+	r.HandleFunc("/usuario/login", rest.LoginHandler)                                                                 //Efetua login do usuario
+	r.HandleFunc("/usuario/buscar", middlewares.Autenticar(rest.BuscaUsuarioHandler))                                 //Busca um usuario
+	r.HandleFunc("/usuario/atualizar/{idusuario}", middlewares.Autenticar(rest.AtualizaUsuarioHandler))               //Atualiza dados do usuario
+	r.HandleFunc("/usuario/{id}/atualizarSenha", middlewares.Autenticar(rest.AtualizaSenhaHandler))                   //Atualiza senha do usuario
+	r.HandleFunc("/usuario/deletar/{idusuario}", middlewares.Autenticar(rest.DeletaUsuarioHandler))                   //Exclui um usuario
+	r.HandleFunc("/usuario/seguir/{idusuario}", middlewares.Autenticar(rest.SeguirHandler))                           //Segue um usuario
+	r.HandleFunc("/usuario/unfollow/{idusuario}", middlewares.Autenticar(rest.UnFollowHandler))                       //Para de seguir um usuario
+	r.HandleFunc("/usuario/seguidos/{idusuario}", middlewares.Autenticar(rest.BuscaUsuariosSeguidosHandler))          //Busca todos os usuarios que determinado usuario segue
+	r.HandleFunc("/usuario/seguidores/{idusuario}", middlewares.Autenticar(rest.BuscaSeguidoresHandler))              //Busca todos os usuarios que seguem determinado usuario
+	r.HandleFunc("/usuario/{idusuario}/avatar", middlewares.Autenticar(rest.AvatarUsuarioHandler))                    //Atualiza o avatar do usuario
+	r.HandleFunc("/usuario/{idusuario}/banner", middlewares.Autenticar(rest.BannerUsuarioHandler))                    //Atualiza o banner do usuario
+	r.HandleFunc("/usuario/bloquear/{idusuario}", middlewares.Autenticar(rest.BloquearHandler))                       //Bloqueia um usuario
+	r.HandleFunc("/usuario/desbloquear/{idusuario}", middlewares.Autenticar(rest.DesbloquearHandler))                 //Desfaz o bloqueio de um usuario
+	r.HandleFunc("/usuario/bloqueados", middlewares.Autenticar(rest.BloqueadosHandler))                               //Usuarios bloqueados pelo usuario logado
+	r.HandleFunc("/usuario/solicitacoes", middlewares.Autenticar(rest.SolicitacoesHandler))                           //Pedidos pendentes para seguir o usuario logado
+	r.HandleFunc("/usuario/sugestoes", middlewares.Autenticar(rest.SugestoesHandler))                                 //Contas sugeridas para o usuario logado seguir
+	r.HandleFunc("/usuario/solicitacoes/{idusuario}/aprovar", middlewares.Autenticar(rest.AprovarSolicitacaoHandler)) //Aceita o pedido para seguir
+	r.HandleFunc("/usuario/solicitacoes/{idusuario}/recusar", middlewares.Autenticar(rest.RecusarSolicitacaoHandler)) //Recusa o pedido para seguir
+	r.HandleFunc("/usuario/solicitacao/{idusuario}", middlewares.Autenticar(rest.SolicitacaoEnviadaHandler))          //Consulta ou cancela o pedido enviado ao usuario
 
 	//Publicação
 	r.HandleFunc("/publicacao", middlewares.Autenticar(rest.PublicacaoHandler))
 	r.HandleFunc("/publicacao/{id}", middlewares.Autenticar(rest.BuscaPublicHandler))
 	r.HandleFunc("/publicacoes", middlewares.Autenticar(rest.PublicacoesHandler))
+	r.HandleFunc("/publicacoes/rascunhos", middlewares.Autenticar(rest.RascunhosHandler))
 	r.HandleFunc("/publicacoes/{idpublic}", middlewares.Autenticar(rest.AtualizaPublicHandler))
 	r.HandleFunc("/publicacoes/{idpublic}/deletar", middlewares.Autenticar(rest.DeletaPublicHandler))
 	r.HandleFunc("/publicacoes/{idpublic}/curtir", middlewares.Autenticar(rest.CurtirPublicHandler))
 	r.HandleFunc("/publicacoes/{idpublic}/descurtir", middlewares.Autenticar(rest.DescurtirPublicHandler))
+	r.HandleFunc("/publicacoes/{idpublic}/compartilhar", middlewares.Autenticar(rest.CompartilharPublicHandler))
+	r.HandleFunc("/publicacoes/{idpublic}/revisoes", middlewares.Autenticar(rest.RevisoesPublicHandler))
+	r.HandleFunc("/publicacoes/{idpublic}/publicar", middlewares.Autenticar(rest.PublicarHandler))
+	r.HandleFunc("/publicacoes/{idpublic}/agendar", middlewares.Autenticar(rest.AgendarHandler))
 	r.HandleFunc("/usuario/{usuarioId}/publicacoes", middlewares.Autenticar(rest.PublicacoesUsuarioHandler))
 
+	//Hashtags
+	r.HandleFunc("/hashtags/em-alta", middlewares.Autenticar(rest.HashtagsEmAltaHandler))               //Hashtags mais usadas no periodo
+	r.HandleFunc("/hashtags/{tag}/publicacoes", middlewares.Autenticar(rest.HashtagPublicacoesHandler)) //Publicações que usam a hashtag
+
+	//Notificações
+	r.HandleFunc("/notificacoes", middlewares.Autenticar(rest.NotificacoesHandler))                             //Lista as notificações agrupadas
+	r.HandleFunc("/notificacoes/nao-lidas", middlewares.Autenticar(rest.NotificacoesNaoLidasHandler))           //Quantidade de notificações não lidas
+	r.HandleFunc("/notificacoes/lidas", middlewares.Autenticar(rest.MarcarNotificacoesLidasHandler))            //Marca notificações como lidas
+	r.HandleFunc("/notificacoes/lidas/todas", middlewares.Autenticar(rest.MarcarTodasNotificacoesLidasHandler)) //Marca todas as notificações como lidas
+	r.HandleFunc("/notificacoes/preferencias", middlewares.Autenticar(rest.PreferenciasNotificacaoHandler))     //Tipos de notificação que o usuario recebe
+
+	//Mensagens diretas
+	r.HandleFunc("/conversas", middlewares.Autenticar(rest.ConversasHandler))                                //Lista ou abre conversas
+	r.HandleFunc("/conversas/nao-lidas", middlewares.Autenticar(rest.MensagensNaoLidasHandler))              //Quantidade de mensagens não lidas
+	r.HandleFunc("/conversas/{idconversa}", middlewares.Autenticar(rest.ConversaHandler))                    //Busca uma conversa
+	r.HandleFunc("/conversas/{idconversa}/mensagens", middlewares.Autenticar(rest.MensagensConversaHandler)) //Lista ou envia mensagens da conversa
+	r.HandleFunc("/conversas/{idconversa}/lida", middlewares.Autenticar(rest.ConversaLidaHandler))           //Marca a conversa como lida
+	r.HandleFunc("/mensagens/{idmensagem}", middlewares.Autenticar(rest.MensagemHandler))                    //Edita ou exclui uma mensagem
+
+	//Eventos em tempo real
+	r.HandleFunc("/eventos", middlewares.Autenticar(rest.EventosHandler)) //Stream de eventos (Server-Sent Events)
+
+	//Busca
+	r.HandleFunc("/busca/usuarios", middlewares.Autenticar(rest.BuscaUsuariosHandler))       //Busca usuarios por parte do nome ou nick
+	r.HandleFunc("/busca/publicacoes", middlewares.Autenticar(rest.BuscaPublicacoesHandler)) //Busca publicações pelo texto
+
+	//Moderação
+	r.HandleFunc("/denuncias", middlewares.Autenticar(rest.DenunciasHandler))                                       //Denuncia uma publicação, um comentario ou um perfil
+	r.HandleFunc("/denuncias/motivos", middlewares.Autenticar(rest.MotivosDenunciaHandler))                         //Motivos aceitos nas denuncias
+	r.HandleFunc("/moderacao/denuncias", middlewares.AutenticarModerador(rest.FilaModeracaoHandler))                //Fila de moderação com filtros
+	r.HandleFunc("/moderacao/denuncias/{iddenuncia}", middlewares.AutenticarModerador(rest.ModerarDenunciaHandler)) //Descarta, oculta, suspende ou bane
+	r.HandleFunc("/moderacao/sancoes/{idusuario}", middlewares.AutenticarModerador(rest.SancaoHandler))             //Revoga a suspensão ou o banimento
+	r.HandleFunc("/moderacao/auditoria", middlewares.AutenticarModerador(rest.AuditoriaModeracaoHandler))           //Historico das ações de moderação
+
+	//Tributos
+	r.HandleFunc("/tributos/icms", middlewares.Autenticar(rest.ICMSHandler))              //Calcula o ICMS de uma venda entre UFs
+	r.HandleFunc("/tributos/icms/tabela", middlewares.Autenticar(rest.TabelaICMSHandler)) //Tabela de aliquotas de ICMS por UF de origem e destino
+
+	//Arquivos enviados pelos usuarios
+	local := armazenamento.NovoLocal(context.Background())
+	router.PathPrefix(rest.CaminhoArquivos(local.URLBase) + "/").Handler(rest.ArquivosHandler(local))
+
 	http.Handle("/", router)
 
+	//Publica as publicações agendadas conforme vão vencendo
+	go publicacao.IniciarAgendador(context.Background())
+	go seguidores.IniciarJobSugestoes(context.Background())
+
 	var port = os.Getenv("PORT")
 	if port == "" {
 		port = "5000"
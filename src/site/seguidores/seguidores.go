@@ -3,6 +3,9 @@ package seguidores
 import (
 	"context"
 	"fmt"
+	"site/bloqueio"
+	"site/eventos"
+	"site/notificacao"
 	"site/usuario"
 	"site/utils"
 	"site/utils/consts"
@@ -136,7 +139,23 @@ func InserirSeguidor(c context.Context, seguidor *Seguidor) error {
 	return PutSeguidor(c, seguidor)
 }
 
+// Seguir faz seguidorID seguir usuarioID sem passar por aprovação e avisa o usuario seguido.
+// Para respeitar contas privadas use SolicitarSeguir.
 func Seguir(c context.Context, usuarioID, seguidorID int64) error {
+	if err := seguir(c, usuarioID, seguidorID); err != nil {
+		return err
+	}
+
+	notificarSeguidor(c, usuarioID, seguidorID)
+	return nil
+}
+
+func seguir(c context.Context, usuarioID, seguidorID int64) error {
+	if bloqueio.Bloqueado(c, usuarioID, seguidorID) {
+		log.Warningf(c, "Usuario %d e %d possuem bloqueio entre si", usuarioID, seguidorID)
+		return fmt.Errorf("Não é possivel seguir este usuario")
+	}
+
 	var seguidor Seguidor
 	seguidorBanco := GetSeguidorByIDSeguidor(c, seguidorID)
 
@@ -159,9 +178,49 @@ func Seguir(c context.Context, usuarioID, seguidorID int64) error {
 		log.Warningf(c, "Erro na inserção do seguidor no banco: %v", err)
 		return fmt.Errorf("Erro na inserção do seguidor no banco")
 	}
+
+	publicarSeguidos(c, &seguidor)
 	return nil
 }
 
+// publicarSeguidos avisa as conexões em tempo real do seguidor que a lista de seguidos mudou,
+// para passarem a receber, ou deixarem de receber, as publicações dessas contas
+func publicarSeguidos(c context.Context, seguidor *Seguidor) {
+	eventos.Publicar(c, eventos.CanalUsuario(seguidor.IDSeguidor), eventos.TipoSeguidos, seguidor.IDUsuario)
+}
+
+// notificarSeguidor avisa o usuario que ganhou um novo seguidor. Falhas não desfazem o seguir.
+func notificarSeguidor(c context.Context, usuarioID, seguidorID int64) {
+	var nick string
+	if usu := usuario.GetUsuario(c, seguidorID); usu != nil {
+		nick = usu.Nick
+	}
+
+	err := notificacao.Notificar(c, notificacao.Notificacao{
+		UsuarioID:  usuarioID,
+		Tipo:       notificacao.TipoSeguidor,
+		OrigemID:   seguidorID,
+		OrigemNick: nick,
+	})
+	if err != nil {
+		log.Warningf(c, "Falha ao notificar novo seguidor do usuario %d: %v", usuarioID, err)
+	}
+}
+
+// Segue diz se seguidorID segue usuarioID
+func Segue(c context.Context, seguidorID, usuarioID int64) bool {
+	seguidorBanco := GetSeguidorByIDSeguidor(c, seguidorID)
+	if seguidorBanco == nil {
+		return false
+	}
+	return utils.InIntArray(usuarioID, seguidorBanco.IDUsuario)
+}
+
+// SeguemUmAoOutro diz se os dois usuarios se seguem mutuamente
+func SeguemUmAoOutro(c context.Context, usuarioA, usuarioB int64) bool {
+	return Segue(c, usuarioA, usuarioB) && Segue(c, usuarioB, usuarioA)
+}
+
 func PararDeSeguir(c context.Context, usuarioID, seguidorID int64) error {
 	seguidorBanco := GetSeguidorByIDSeguidor(c, seguidorID)
 
@@ -183,6 +242,8 @@ func PararDeSeguir(c context.Context, usuarioID, seguidorID int64) error {
 		log.Warningf(c, "Erro na inserção do seguidor atualizado: %v", err)
 		return fmt.Errorf("Erro na inserção do seguidor atualizado")
 	}
+
+	publicarSeguidos(c, seguidorBanco)
 	return nil
 }
 
@@ -0,0 +1,188 @@
+package seguidores
+
+import (
+	"context"
+	"fmt"
+	"site/bloqueio"
+	"site/notificacao"
+	"site/usuario"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindSolicitacoes = "SolicitacoesSeguir"
+)
+
+// Solicitacao é um pedido para seguir uma conta privada, aguardando aprovação do dono da conta.
+// A chave é "idseguidor:idusuario", então pedir de novo não duplica.
+type Solicitacao struct {
+	SeguidorID   int64
+	SeguidorNick string
+	UsuarioID    int64
+	DataCriacao  utils.JsonSpecialDateTime
+}
+
+func chaveSolicitacao(seguidorID, usuarioID int64) *datastore.Key {
+	return datastore.NameKey(KindSolicitacoes, fmt.Sprintf("%d:%d", seguidorID, usuarioID), nil)
+}
+
+// GetSolicitacao traz o pedido pendente de seguidorID para seguir usuarioID, ou nil se não houver
+func GetSolicitacao(c context.Context, seguidorID, usuarioID int64) *Solicitacao {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil
+	}
+	defer datastoreClient.Close()
+
+	var solicitacao Solicitacao
+	if err = datastoreClient.Get(c, chaveSolicitacao(seguidorID, usuarioID), &solicitacao); err != nil {
+		if err != datastore.ErrNoSuchEntity {
+			log.Warningf(c, "Falha ao buscar solicitação: %v", err)
+		}
+		return nil
+	}
+	return &solicitacao
+}
+
+func PutSolicitacao(c context.Context, solicitacao *Solicitacao) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := chaveSolicitacao(solicitacao.SeguidorID, solicitacao.UsuarioID)
+	if _, err = datastoreClient.Put(c, key, solicitacao); err != nil {
+		log.Warningf(c, "Erro ao gravar solicitação: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RemoverSolicitacao apaga o pedido pendente, se existir
+func RemoverSolicitacao(c context.Context, seguidorID, usuarioID int64) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	if err = datastoreClient.Delete(c, chaveSolicitacao(seguidorID, usuarioID)); err != nil {
+		log.Warningf(c, "Erro ao remover solicitação: %v", err)
+		return err
+	}
+	return nil
+}
+
+// SolicitarSeguir segue direto contas publicas. Em contas privadas grava um pedido que o dono
+// precisa aprovar e devolve pendente = true.
+func SolicitarSeguir(c context.Context, usuarioID, seguidorID int64) (pendente bool, err error) {
+	usu := usuario.GetUsuario(c, usuarioID)
+	if usu == nil {
+		return false, fmt.Errorf("Usuario não encontrado")
+	}
+
+	if usu.Visibilidade != usuario.VisibilidadePrivada {
+		return false, Seguir(c, usuarioID, seguidorID)
+	}
+
+	if bloqueio.Bloqueado(c, usuarioID, seguidorID) {
+		log.Warningf(c, "Usuario %d e %d possuem bloqueio entre si", usuarioID, seguidorID)
+		return false, fmt.Errorf("Não é possivel seguir este usuario")
+	}
+
+	if Segue(c, seguidorID, usuarioID) {
+		return false, fmt.Errorf("Usuario já está sendo seguido")
+	}
+
+	if GetSolicitacao(c, seguidorID, usuarioID) != nil {
+		return true, nil
+	}
+
+	var nick string
+	if seguidor := usuario.GetUsuario(c, seguidorID); seguidor != nil {
+		nick = seguidor.Nick
+	}
+
+	solicitacao := Solicitacao{
+		SeguidorID:   seguidorID,
+		SeguidorNick: nick,
+		UsuarioID:    usuarioID,
+		DataCriacao:  utils.GetSpecialTimeNow(),
+	}
+	if err = PutSolicitacao(c, &solicitacao); err != nil {
+		return false, fmt.Errorf("Erro ao registrar solicitação")
+	}
+
+	err = notificacao.Notificar(c, notificacao.Notificacao{
+		UsuarioID:  usuarioID,
+		Tipo:       notificacao.TipoSolicitacao,
+		OrigemID:   seguidorID,
+		OrigemNick: nick,
+	})
+	if err != nil {
+		log.Warningf(c, "Falha ao notificar solicitação para o usuario %d: %v", usuarioID, err)
+	}
+	return true, nil
+}
+
+// AprovarSolicitacao faz seguidorID passar a seguir usuarioID e avisa quem pediu
+func AprovarSolicitacao(c context.Context, usuarioID, seguidorID int64) error {
+	solicitacao := GetSolicitacao(c, seguidorID, usuarioID)
+	if solicitacao == nil {
+		return fmt.Errorf("Solicitação não encontrada")
+	}
+
+	if err := seguir(c, usuarioID, seguidorID); err != nil {
+		return err
+	}
+
+	if err := RemoverSolicitacao(c, seguidorID, usuarioID); err != nil {
+		log.Warningf(c, "Falha ao remover solicitação aprovada de %d para %d: %v", seguidorID, usuarioID, err)
+	}
+
+	var nick string
+	if usu := usuario.GetUsuario(c, usuarioID); usu != nil {
+		nick = usu.Nick
+	}
+	err := notificacao.Notificar(c, notificacao.Notificacao{
+		UsuarioID:  seguidorID,
+		Tipo:       notificacao.TipoAprovacao,
+		OrigemID:   usuarioID,
+		OrigemNick: nick,
+	})
+	if err != nil {
+		log.Warningf(c, "Falha ao notificar aprovação para o usuario %d: %v", seguidorID, err)
+	}
+	return nil
+}
+
+// BuscarSolicitacoes traz os pedidos pendentes para seguir o usuario, do mais antigo para o mais recente
+func BuscarSolicitacoes(c context.Context, usuarioID int64) ([]Solicitacao, error) {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil, err
+	}
+	defer datastoreClient.Close()
+
+	solicitacoes := make([]Solicitacao, 0)
+	q := datastore.NewQuery(KindSolicitacoes).Filter("UsuarioID =", usuarioID)
+	if _, err = datastoreClient.GetAll(c, q, &solicitacoes); err != nil {
+		log.Warningf(c, "Erro ao buscar solicitações do usuario %d: %v", usuarioID, err)
+		return nil, err
+	}
+
+	sort.Slice(solicitacoes, func(i, j int) bool {
+		return solicitacoes[i].DataCriacao.Before(solicitacoes[j].DataCriacao.Time)
+	})
+	return solicitacoes, nil
+}
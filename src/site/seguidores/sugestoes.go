@@ -0,0 +1,353 @@
+package seguidores
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"site/bloqueio"
+	"site/usuario"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindSugestoes = "SugestoesSeguir"
+
+	// LimiteSugestoes é quantas sugestões ficam gravadas para cada usuario
+	LimiteSugestoes = 20
+
+	// LimiteSugestoesPadrao é quantas sugestões BuscarSugestoes devolve quando o limite não é informado
+	LimiteSugestoesPadrao = 5
+
+	pesoSeguidosEmComum   = 3
+	pesoSeguidoresEmComum = 2
+	pesoSegueVoce         = 2
+)
+
+// IntervaloSugestoes define quando o job recalcula as sugestões de todos os usuarios, no formato do utils.ValidaExecucao
+var IntervaloSugestoes = utils.Intervalo{Minuto: "0", Hora: "4", DiaSemana: "*", DiaMes: "*"}
+
+// Sugestao é uma conta que o usuario talvez queira seguir, com os sinais usados para ordenar
+type Sugestao struct {
+	UsuarioID         int64
+	Nick              string
+	Nome              string
+	AvatarURL         string `datastore:",noindex"`
+	Motivo            string `datastore:",noindex"`
+	SeguidosEmComum   int64  `datastore:",noindex"` // quantos seguidos do usuario seguem a conta
+	SeguidoresEmComum int64  `datastore:",noindex"` // quantos seguidores do usuario também seguem a conta
+	SegueVoce         bool   `datastore:",noindex"`
+	Seguidores        int64  `datastore:",noindex"`
+	Pontuacao         float64
+}
+
+// Sugestoes guarda as sugestões pré-calculadas de um usuario, com a chave igual ao id do usuario
+type Sugestoes struct {
+	UsuarioID   int64 `datastore:"-"`
+	Sugestoes   []Sugestao
+	DataCriacao utils.JsonSpecialDateTime
+}
+
+// Grafo guarda quem cada usuario segue e, no sentido inverso, quem segue cada usuario
+type Grafo struct {
+	seguindo   map[int64][]int64
+	seguidores map[int64][]int64
+}
+
+// NovoGrafo monta o grafo a partir dos registros de Seguidores. O id 0 que PararDeSeguir
+// grava quando a lista fica vazia é descartado.
+func NovoGrafo(registros []Seguidor) *Grafo {
+	grafo := &Grafo{seguindo: make(map[int64][]int64), seguidores: make(map[int64][]int64)}
+	for _, registro := range registros {
+		for _, usuarioID := range registro.IDUsuario {
+			if usuarioID == 0 || usuarioID == registro.IDSeguidor {
+				continue
+			}
+			grafo.seguindo[registro.IDSeguidor] = append(grafo.seguindo[registro.IDSeguidor], usuarioID)
+			grafo.seguidores[usuarioID] = append(grafo.seguidores[usuarioID], registro.IDSeguidor)
+		}
+	}
+	return grafo
+}
+
+// CarregarGrafo lê todos os registros de Seguidores
+func CarregarGrafo(c context.Context) (*Grafo, error) {
+	registros, err := FiltrarSeguidores(c, Seguidor{})
+	if err != nil {
+		log.Warningf(c, "Erro ao carregar grafo de seguidores: %v", err)
+		return nil, err
+	}
+	return NovoGrafo(registros), nil
+}
+
+// Sugerir ordena as contas que o usuario ainda não segue. Pesam os amigos de amigos (contas seguidas
+// por quem o usuario segue), os seguidores em comum e quem já segue o usuario; a popularidade entra
+// com peso logaritmico e desempata, então contas populares completam a lista de quem segue pouca gente.
+func (grafo *Grafo) Sugerir(usuarioID int64, excluidos map[int64]bool) []Sugestao {
+	seguidos := make(map[int64]bool, len(grafo.seguindo[usuarioID]))
+	for _, id := range grafo.seguindo[usuarioID] {
+		seguidos[id] = true
+	}
+
+	ignorar := func(id int64) bool {
+		return id == usuarioID || seguidos[id] || excluidos[id]
+	}
+
+	candidatos := make(map[int64]*Sugestao)
+	candidato := func(id int64) *Sugestao {
+		sugestao, ok := candidatos[id]
+		if !ok {
+			sugestao = &Sugestao{UsuarioID: id, Seguidores: int64(len(grafo.seguidores[id]))}
+			candidatos[id] = sugestao
+		}
+		return sugestao
+	}
+
+	for seguidoID := range seguidos {
+		for _, id := range grafo.seguindo[seguidoID] {
+			if !ignorar(id) {
+				candidato(id).SeguidosEmComum++
+			}
+		}
+	}
+
+	for _, seguidorID := range grafo.seguidores[usuarioID] {
+		if !ignorar(seguidorID) {
+			candidato(seguidorID).SegueVoce = true
+		}
+		for _, id := range grafo.seguindo[seguidorID] {
+			if !ignorar(id) {
+				candidato(id).SeguidoresEmComum++
+			}
+		}
+	}
+
+	for id := range grafo.seguidores {
+		if !ignorar(id) {
+			candidato(id)
+		}
+	}
+
+	sugestoes := make([]Sugestao, 0, len(candidatos))
+	for _, sugestao := range candidatos {
+		sugestao.Pontuacao = sugestao.pontuar()
+		sugestao.Motivo = sugestao.motivo()
+		sugestoes = append(sugestoes, *sugestao)
+	}
+
+	sort.Slice(sugestoes, func(i, j int) bool {
+		if sugestoes[i].Pontuacao != sugestoes[j].Pontuacao {
+			return sugestoes[i].Pontuacao > sugestoes[j].Pontuacao
+		}
+		if sugestoes[i].Seguidores != sugestoes[j].Seguidores {
+			return sugestoes[i].Seguidores > sugestoes[j].Seguidores
+		}
+		return sugestoes[i].UsuarioID < sugestoes[j].UsuarioID
+	})
+	return sugestoes
+}
+
+func (sugestao *Sugestao) pontuar() float64 {
+	pontuacao := float64(pesoSeguidosEmComum*sugestao.SeguidosEmComum + pesoSeguidoresEmComum*sugestao.SeguidoresEmComum)
+	if sugestao.SegueVoce {
+		pontuacao += pesoSegueVoce
+	}
+	return pontuacao + math.Log2(1+float64(sugestao.Seguidores))
+}
+
+func (sugestao *Sugestao) motivo() string {
+	switch {
+	case sugestao.SegueVoce:
+		return "Segue você"
+	case sugestao.SeguidosEmComum == 1:
+		return "Seguido por 1 pessoa que você segue"
+	case sugestao.SeguidosEmComum > 1:
+		return fmt.Sprintf("Seguido por %d pessoas que você segue", sugestao.SeguidosEmComum)
+	case sugestao.SeguidoresEmComum == 1:
+		return "1 seguidor em comum"
+	case sugestao.SeguidoresEmComum > 1:
+		return fmt.Sprintf("%d seguidores em comum", sugestao.SeguidoresEmComum)
+	default:
+		return "Popular na rede"
+	}
+}
+
+func GetSugestoes(c context.Context, usuarioID int64) *Sugestoes {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil
+	}
+	defer datastoreClient.Close()
+
+	var sugestoes Sugestoes
+	key := datastore.IDKey(KindSugestoes, usuarioID, nil)
+	if err = datastoreClient.Get(c, key, &sugestoes); err != nil {
+		if err != datastore.ErrNoSuchEntity {
+			log.Warningf(c, "Falha ao buscar sugestões do usuario %d: %v", usuarioID, err)
+		}
+		return nil
+	}
+	sugestoes.UsuarioID = usuarioID
+	return &sugestoes
+}
+
+func PutSugestoes(c context.Context, sugestoes *Sugestoes) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindSugestoes, sugestoes.UsuarioID, nil)
+	if _, err = datastoreClient.Put(c, key, sugestoes); err != nil {
+		log.Warningf(c, "Erro ao gravar sugestões do usuario %d: %v", sugestoes.UsuarioID, err)
+		return err
+	}
+	return nil
+}
+
+// excluidosPorBloqueio junta quem o usuario bloqueou e quem bloqueou o usuario
+func excluidosPorBloqueio(c context.Context, usuarioID int64) (map[int64]bool, error) {
+	bloqueados, err := bloqueio.BuscarBloqueados(c, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	bloqueadores, err := bloqueio.BuscarBloqueadores(c, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+
+	excluidos := make(map[int64]bool, len(bloqueados)+len(bloqueadores))
+	for _, id := range append(bloqueados, bloqueadores...) {
+		excluidos[id] = true
+	}
+	return excluidos, nil
+}
+
+// gerarSugestoes calcula e grava as sugestões do usuario. buscarUsuario completa nick, nome e avatar
+// e devolve nil para contas que não existem mais, que ficam de fora.
+func gerarSugestoes(c context.Context, grafo *Grafo, usuarioID int64, buscarUsuario func(int64) *usuario.Usuario) (*Sugestoes, error) {
+	excluidos, err := excluidosPorBloqueio(c, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+
+	sugestoes := Sugestoes{UsuarioID: usuarioID, Sugestoes: make([]Sugestao, 0, LimiteSugestoes), DataCriacao: utils.GetSpecialTimeNow()}
+	for _, sugestao := range grafo.Sugerir(usuarioID, excluidos) {
+		if len(sugestoes.Sugestoes) == LimiteSugestoes {
+			break
+		}
+
+		usu := buscarUsuario(sugestao.UsuarioID)
+		if usu == nil {
+			continue
+		}
+		sugestao.Nick = usu.Nick
+		sugestao.Nome = usu.Nome
+		sugestao.AvatarURL = usu.AvatarURL
+		sugestoes.Sugestoes = append(sugestoes.Sugestoes, sugestao)
+	}
+
+	if err = PutSugestoes(c, &sugestoes); err != nil {
+		return nil, err
+	}
+	return &sugestoes, nil
+}
+
+// BuscarSugestoes traz até limite sugestões para o usuario a partir das gravadas pelo job. Carregar o
+// grafo inteiro não cabe numa requisição, então quem ainda não foi processado recebe uma lista vazia.
+// Como o grafo pode ter mudado desde o calculo, contas já seguidas ou com bloqueio são retiradas na leitura.
+func BuscarSugestoes(c context.Context, usuarioID int64, limite int) ([]Sugestao, error) {
+	if limite <= 0 {
+		limite = LimiteSugestoesPadrao
+	}
+	if limite > LimiteSugestoes {
+		limite = LimiteSugestoes
+	}
+
+	sugestoes := GetSugestoes(c, usuarioID)
+	if sugestoes == nil {
+		return []Sugestao{}, nil
+	}
+
+	var seguidos []int64
+	if seguidor := GetSeguidorByIDSeguidor(c, usuarioID); seguidor != nil {
+		seguidos = seguidor.IDUsuario
+	}
+
+	resultado := make([]Sugestao, 0, limite)
+	for _, sugestao := range sugestoes.Sugestoes {
+		if len(resultado) == limite {
+			break
+		}
+		if utils.InIntArray(sugestao.UsuarioID, seguidos) || bloqueio.Bloqueado(c, usuarioID, sugestao.UsuarioID) {
+			continue
+		}
+		resultado = append(resultado, sugestao)
+	}
+	return resultado, nil
+}
+
+// AtualizarSugestoes recalcula as sugestões de todos os usuarios lendo o grafo e os usuarios uma vez
+// só. Devolve quantos usuarios foram atualizados.
+func AtualizarSugestoes(c context.Context) (int, error) {
+	grafo, err := CarregarGrafo(c)
+	if err != nil {
+		return 0, err
+	}
+
+	usuarios, err := usuario.FiltrarUsuario(c, usuario.Usuario{})
+	if err != nil {
+		log.Warningf(c, "Erro ao carregar usuarios para as sugestões: %v", err)
+		return 0, err
+	}
+
+	porID := make(map[int64]*usuario.Usuario, len(usuarios))
+	for i := range usuarios {
+		porID[usuarios[i].ID] = &usuarios[i]
+	}
+	buscarUsuario := func(id int64) *usuario.Usuario {
+		return porID[id]
+	}
+
+	atualizados := 0
+	for _, usu := range usuarios {
+		if _, err := gerarSugestoes(c, grafo, usu.ID, buscarUsuario); err != nil {
+			log.Warningf(c, "Falha ao gerar sugestões do usuario %d: %v", usu.ID, err)
+			continue
+		}
+		atualizados++
+	}
+	return atualizados, nil
+}
+
+// IniciarJobSugestoes recalcula as sugestões de todos os usuarios quando IntervaloSugestoes permitir.
+// BuscarSugestoes só lê o que este job grava. Roda até o contexto ser cancelado.
+func IniciarJobSugestoes(c context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if !utils.ValidaExecucao(IntervaloSugestoes) {
+				continue
+			}
+			total, err := AtualizarSugestoes(c)
+			if err != nil {
+				log.Warningf(c, "Falha ao executar job de sugestões: %v", err)
+				continue
+			}
+			log.Debugf(c, "Sugestões atualizadas para %d usuarios", total)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package seguidores
+
+import (
+	"testing"
+)
+
+// Grafo de exemplo: 1 segue 2 e 3; 2 e 3 seguem 4; 3 segue 5; 6 segue 1 e 5; 7 e 8 seguem 9
+func grafoTeste() *Grafo {
+	return NovoGrafo([]Seguidor{
+		{IDSeguidor: 1, IDUsuario: []int64{2, 3}},
+		{IDSeguidor: 2, IDUsuario: []int64{4}},
+		{IDSeguidor: 3, IDUsuario: []int64{4, 5}},
+		{IDSeguidor: 6, IDUsuario: []int64{1, 5}},
+		{IDSeguidor: 7, IDUsuario: []int64{9}},
+		{IDSeguidor: 8, IDUsuario: []int64{9, 0}},
+	})
+}
+
+func ids(sugestoes []Sugestao) []int64 {
+	resultado := make([]int64, 0, len(sugestoes))
+	for _, s := range sugestoes {
+		resultado = append(resultado, s.UsuarioID)
+	}
+	return resultado
+}
+
+func TestSugerir(t *testing.T) {
+	sugestoes := grafoTeste().Sugerir(1, nil)
+
+	esperado := []int64{4, 5, 6, 9}
+	obtido := ids(sugestoes)
+	if len(obtido) != len(esperado) {
+		t.Fatalf("Sugerir() = %v, esperado %v", obtido, esperado)
+	}
+	for i := range esperado {
+		if obtido[i] != esperado[i] {
+			t.Fatalf("Sugerir() = %v, esperado %v", obtido, esperado)
+		}
+	}
+
+	porID := make(map[int64]Sugestao)
+	for _, s := range sugestoes {
+		porID[s.UsuarioID] = s
+	}
+	if s := porID[4]; s.SeguidosEmComum != 2 || s.Motivo != "Seguido por 2 pessoas que você segue" {
+		t.Errorf("sugestão 4 = %+v, esperado 2 seguidos em comum", s)
+	}
+	if s := porID[5]; s.SeguidosEmComum != 1 || s.SeguidoresEmComum != 1 {
+		t.Errorf("sugestão 5 = %+v, esperado 1 seguido e 1 seguidor em comum", s)
+	}
+	if s := porID[6]; !s.SegueVoce || s.Motivo != "Segue você" {
+		t.Errorf("sugestão 6 = %+v, esperado que siga o usuario", s)
+	}
+	if s := porID[9]; s.Seguidores != 2 || s.Motivo != "Popular na rede" {
+		t.Errorf("sugestão 9 = %+v, esperado conta popular sem relação", s)
+	}
+}
+
+func TestSugerirExcluidos(t *testing.T) {
+	obtido := ids(grafoTeste().Sugerir(1, map[int64]bool{5: true, 9: true}))
+	for _, id := range obtido {
+		if id == 1 || id == 2 || id == 3 || id == 5 || id == 9 || id == 0 {
+			t.Errorf("Sugerir() = %v, não deveria trazer o proprio usuario, os já seguidos nem os excluidos", obtido)
+		}
+	}
+	if len(obtido) != 2 {
+		t.Errorf("Sugerir() = %v, esperado as contas 4 e 6", obtido)
+	}
+}
+
+func TestSugerirSemSeguidos(t *testing.T) {
+	obtido := ids(grafoTeste().Sugerir(10, nil))
+	if len(obtido) != 6 || obtido[0] != 4 || obtido[1] != 5 || obtido[2] != 9 {
+		t.Errorf("Sugerir() = %v, esperado as contas mais seguidas primeiro, desempatando pelo id", obtido)
+	}
+}
@@ -0,0 +1,124 @@
+package tributos
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"site/utils"
+	"strings"
+)
+
+// codigosIBGE liga o codigo IBGE da UF, os dois primeiros digitos do codigo do municipio, à sigla
+var codigosIBGE = map[string]string{
+	"11": "RO", "12": "AC", "13": "AM", "14": "RR", "15": "PA", "16": "AP", "17": "TO",
+	"21": "MA", "22": "PI", "23": "CE", "24": "RN", "25": "PB", "26": "PE", "27": "AL", "28": "SE", "29": "BA",
+	"31": "MG", "32": "ES", "33": "RJ", "35": "SP",
+	"41": "PR", "42": "SC", "43": "RS",
+	"50": "MS", "51": "MT", "52": "GO", "53": "DF",
+}
+
+// OperacaoICMS descreve a venda a ser tributada. IE é a Inscrição Estadual do destinatario: vazia ou
+// "ISENTO" indica consumidor final não contribuinte.
+type OperacaoICMS struct {
+	UFOrigem  string
+	UFDestino string
+	IE        string
+	Valor     int64 // Em centavos
+}
+
+// CalculoICMS é o resultado do calculo. DIFAL é o diferencial de aliquota devido à UF de destino nas
+// vendas interestaduais para não contribuintes, que o remetente recolhe (EC 87/2015).
+type CalculoICMS struct {
+	UFOrigem               string
+	UFDestino              string
+	Interestadual          bool
+	Contribuinte           bool
+	Aliquota               float64
+	AliquotaInternaDestino float64
+	AliquotaDIFAL          float64
+	BaseCalculo            int64
+	ValorICMS              int64
+	ValorDIFAL             int64
+	VersaoTabela           string
+}
+
+// UFPorCodigo aceita a sigla da UF, o codigo IBGE da UF ou o codigo IBGE do municipio
+func UFPorCodigo(codigo string) (string, error) {
+	codigo = strings.ToUpper(strings.TrimSpace(codigo))
+	if utils.InArray(codigo, UFs) {
+		return codigo, nil
+	}
+	if numeros := utils.OnlyNumbers(codigo); len(numeros) == len(codigo) && len(codigo) >= 2 {
+		if uf, ok := codigosIBGE[codigo[:2]]; ok {
+			return uf, nil
+		}
+	}
+	return "", fmt.Errorf("UF ou municipio inválido: %q", codigo)
+}
+
+// CalcularICMS aplica a tabela na operação. Dentro da UF vale a aliquota interna. Entre UFs vale a
+// interestadual e, se o destinatario não é contribuinte, o remetente recolhe também a diferença até a
+// aliquota interna do destino. A IE de contribuinte precisa ser válida na UF de destino.
+func CalcularICMS(tabela *Tabela, operacao OperacaoICMS) (*CalculoICMS, error) {
+	origem, err := UFPorCodigo(operacao.UFOrigem)
+	if err != nil {
+		return nil, err
+	}
+	destino, err := UFPorCodigo(operacao.UFDestino)
+	if err != nil {
+		return nil, err
+	}
+	if operacao.Valor < 0 {
+		return nil, fmt.Errorf("Valor da operação não pode ser negativo")
+	}
+
+	contribuinte := false
+	if ie := strings.TrimSpace(operacao.IE); ie != "" {
+		normalizada, ok := utils.NormalizeIE(ie, destino)
+		if !ok {
+			return nil, fmt.Errorf("Inscrição Estadual inválida para a UF %s", destino)
+		}
+		contribuinte = normalizada != utils.IEIsento
+	}
+
+	aliquota, err := tabela.Aliquota(origem, destino)
+	if err != nil {
+		return nil, err
+	}
+	interna, err := tabela.Interna(destino)
+	if err != nil {
+		return nil, err
+	}
+
+	calculo := &CalculoICMS{
+		UFOrigem:               origem,
+		UFDestino:              destino,
+		Interestadual:          origem != destino,
+		Contribuinte:           contribuinte,
+		Aliquota:               aliquota,
+		AliquotaInternaDestino: interna,
+		BaseCalculo:            operacao.Valor,
+		VersaoTabela:           tabela.Versao,
+	}
+	calculo.ValorICMS = aplicarAliquota(operacao.Valor, aliquota)
+
+	if calculo.Interestadual && !contribuinte && interna > aliquota {
+		calculo.AliquotaDIFAL = interna - aliquota
+		calculo.ValorDIFAL = aplicarAliquota(operacao.Valor, calculo.AliquotaDIFAL)
+	}
+	return calculo, nil
+}
+
+// Calcular calcula o ICMS com a tabela vigente
+func Calcular(c context.Context, operacao OperacaoICMS) (*CalculoICMS, error) {
+	tabela, err := TabelaVigente(c)
+	if err != nil {
+		return nil, err
+	}
+	return CalcularICMS(tabela, operacao)
+}
+
+// aplicarAliquota calcula o percentual do valor em centavos, arredondando para o centavo mais proximo
+func aplicarAliquota(valor int64, aliquota float64) int64 {
+	return int64(math.Round(float64(valor) * aliquota / 100))
+}
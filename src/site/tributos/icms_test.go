@@ -0,0 +1,145 @@
+package tributos
+
+import (
+	"strings"
+	"testing"
+)
+
+var internasEsperadas = map[string]float64{
+	"AC": 19, "AL": 19, "AP": 18, "AM": 20, "BA": 20.5, "CE": 20, "DF": 20, "ES": 17, "GO": 19,
+	"MA": 22, "MT": 17, "MS": 17, "MG": 18, "PA": 19, "PB": 20, "PR": 19.5, "PE": 20.5, "PI": 21,
+	"RJ": 20, "RN": 18, "RS": 17, "RO": 19.5, "RR": 20, "SC": 17, "SP": 18, "SE": 19, "TO": 20,
+}
+
+// Sul e Sudeste sem o ES, que pagam 7% nas vendas para as demais UFs
+var sulSudesteExcetoES = map[string]bool{"MG": true, "PR": true, "RJ": true, "RS": true, "SC": true, "SP": true}
+
+func TestTabelaTodosOsPares(t *testing.T) {
+	tabela, err := TabelaVersao("2024")
+	if err != nil {
+		t.Fatalf("TabelaVersao() = %v", err)
+	}
+
+	if len(internasEsperadas) != len(UFs) {
+		t.Fatalf("esperadas %d UFs no teste, há %d", len(UFs), len(internasEsperadas))
+	}
+
+	for _, origem := range UFs {
+		for _, destino := range UFs {
+			esperada := 12.0
+			switch {
+			case origem == destino:
+				esperada = internasEsperadas[origem]
+			case sulSudesteExcetoES[origem] && !sulSudesteExcetoES[destino]:
+				esperada = 7
+			}
+
+			aliquota, err := tabela.Aliquota(origem, destino)
+			if err != nil || aliquota != esperada {
+				t.Errorf("Aliquota(%s, %s) = %v, %v, esperado %v", origem, destino, aliquota, err, esperada)
+			}
+		}
+	}
+}
+
+func TestTabelaVigente(t *testing.T) {
+	versoes, err := Versoes()
+	if err != nil || len(versoes) == 0 {
+		t.Fatalf("Versoes() = %v, %v", versoes, err)
+	}
+	tabela, err := TabelaVersao("")
+	if err != nil || tabela.Versao != versoes[len(versoes)-1] {
+		t.Errorf("versão vazia deveria trazer a mais nova: %v, %v", tabela, err)
+	}
+	if _, err = TabelaVersao("1999"); err == nil {
+		t.Errorf("versão inexistente deveria dar erro")
+	}
+}
+
+func TestLerTabelaInvalida(t *testing.T) {
+	valida, err := arquivosTabelas.ReadFile("tabelas/icms-2024.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	casos := map[string]string{
+		"sem uma linha":       strings.Replace(string(valida), "\nTO,", "\n#TO,", 1),
+		"UF repetida":         strings.Replace(string(valida), "\nTO,", "\nSE,", 1),
+		"aliquota não numero": strings.Replace(string(valida), "SP,7,", "SP,sete,", 1),
+		"cabeçalho sem UF":    strings.Replace(string(valida), ",TO\n", ",XX\n", 1),
+		"colunas faltando":    strings.Replace(string(valida), ",TO\n", "\n", 1),
+	}
+	for nome, conteudo := range casos {
+		if _, err := lerTabela("teste", []byte(conteudo)); err == nil {
+			t.Errorf("%s: lerTabela() deveria dar erro", nome)
+		}
+	}
+}
+
+func TestUFPorCodigo(t *testing.T) {
+	casos := map[string]string{
+		"SP":      "SP",
+		" ba ":    "BA",
+		"35":      "SP",
+		"3550308": "SP",
+		"5300108": "DF",
+		"1200401": "AC",
+		"99":      "",
+		"XX":      "",
+		"":        "",
+		"35abc":   "",
+	}
+	for codigo, esperada := range casos {
+		uf, err := UFPorCodigo(codigo)
+		if uf != esperada || (err == nil) != (esperada != "") {
+			t.Errorf("UFPorCodigo(%q) = %q, %v, esperado %q", codigo, uf, err, esperada)
+		}
+	}
+}
+
+func TestCalcularICMS(t *testing.T) {
+	tabela, err := TabelaVersao("2024")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	casos := []struct {
+		nome         string
+		operacao     OperacaoICMS
+		valido       bool
+		contribuinte bool
+		aliquota     float64
+		icms         int64
+		difal        int64
+	}{
+		{"interna", OperacaoICMS{UFOrigem: "SP", UFDestino: "SP", IE: "110.042.490.114", Valor: 10000}, true, true, 18, 1800, 0},
+		{"interna não contribuinte", OperacaoICMS{UFOrigem: "SP", UFDestino: "SP", Valor: 10000}, true, false, 18, 1800, 0},
+		{"interestadual para contribuinte", OperacaoICMS{UFOrigem: "SP", UFDestino: "BA", IE: "12345663", Valor: 10000}, true, true, 7, 700, 0},
+		{"interestadual para isento", OperacaoICMS{UFOrigem: "SP", UFDestino: "BA", IE: "isento", Valor: 10000}, true, false, 7, 700, 1350},
+		{"interestadual sem IE", OperacaoICMS{UFOrigem: "BA", UFDestino: "SP", Valor: 10000}, true, false, 12, 1200, 600},
+		{"por codigo de municipio", OperacaoICMS{UFOrigem: "5208707", UFDestino: "3304557", Valor: 999}, true, false, 12, 120, 80},
+		{"destino com interna menor", OperacaoICMS{UFOrigem: "MA", UFDestino: "ES", Valor: 10000}, true, false, 12, 1200, 500},
+		{"IE inválida no destino", OperacaoICMS{UFOrigem: "SP", UFDestino: "BA", IE: "110.042.490.114", Valor: 10000}, false, false, 0, 0, 0},
+		{"UF inexistente", OperacaoICMS{UFOrigem: "SP", UFDestino: "XX", Valor: 10000}, false, false, 0, 0, 0},
+		{"valor negativo", OperacaoICMS{UFOrigem: "SP", UFDestino: "RJ", Valor: -1}, false, false, 0, 0, 0},
+	}
+
+	for _, caso := range casos {
+		calculo, err := CalcularICMS(tabela, caso.operacao)
+		if (err == nil) != caso.valido {
+			t.Errorf("%s: CalcularICMS() = %v, esperado valido = %v", caso.nome, err, caso.valido)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if calculo.Contribuinte != caso.contribuinte || calculo.Aliquota != caso.aliquota ||
+			calculo.ValorICMS != caso.icms || calculo.ValorDIFAL != caso.difal {
+			t.Errorf("%s: CalcularICMS() = %+v, esperado contribuinte=%v aliquota=%v icms=%d difal=%d",
+				caso.nome, calculo, caso.contribuinte, caso.aliquota, caso.icms, caso.difal)
+		}
+		if calculo.VersaoTabela != "2024" {
+			t.Errorf("%s: versão da tabela %q", caso.nome, calculo.VersaoTabela)
+		}
+	}
+}
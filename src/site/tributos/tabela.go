@@ -0,0 +1,188 @@
+package tributos
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/csv"
+	"fmt"
+	"path"
+	"site/config"
+	"site/utils/log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// UFs são as 27 unidades da federação, na ordem das tabelas
+var UFs = []string{
+	"AC", "AL", "AP", "AM", "BA", "CE", "DF", "ES", "GO", "MA", "MT", "MS", "MG", "PA",
+	"PB", "PR", "PE", "PI", "RJ", "RN", "RS", "RO", "RR", "SC", "SP", "SE", "TO",
+}
+
+// Cada arquivo tabelas/icms-<versao>.csv é uma versão da tabela de aliquotas. Uma versão nova entra como
+// arquivo novo, sem alterar as anteriores, para os calculos antigos poderem ser refeitos.
+//
+//go:embed tabelas/icms-*.csv
+var arquivosTabelas embed.FS
+
+var (
+	carregarTabelas sync.Once
+	tabelas         map[string]*Tabela
+	erroTabelas     error
+)
+
+// Tabela traz a aliquota de ICMS de cada par de UFs, em percentual
+type Tabela struct {
+	Versao    string
+	Aliquotas map[string]map[string]float64 // Origem -> destino -> aliquota. A diagonal é a aliquota interna.
+}
+
+// Aliquota retorna a aliquota da operação da origem para o destino
+func (tabela *Tabela) Aliquota(origem, destino string) (float64, error) {
+	origem, destino = strings.ToUpper(strings.TrimSpace(origem)), strings.ToUpper(strings.TrimSpace(destino))
+	aliquota, ok := tabela.Aliquotas[origem][destino]
+	if !ok {
+		return 0, fmt.Errorf("UF inválida: %s para %s", origem, destino)
+	}
+	return aliquota, nil
+}
+
+// Interna retorna a aliquota das operações dentro da UF
+func (tabela *Tabela) Interna(uf string) (float64, error) {
+	return tabela.Aliquota(uf, uf)
+}
+
+// Versoes lista as versões das tabelas disponiveis, da mais antiga para a mais nova
+func Versoes() ([]string, error) {
+	if err := carregar(); err != nil {
+		return nil, err
+	}
+	versoes := make([]string, 0, len(tabelas))
+	for versao := range tabelas {
+		versoes = append(versoes, versao)
+	}
+	sort.Strings(versoes)
+	return versoes, nil
+}
+
+// TabelaVersao retorna a tabela da versão; vazio retorna a mais nova
+func TabelaVersao(versao string) (*Tabela, error) {
+	versoes, err := Versoes()
+	if err != nil {
+		return nil, err
+	}
+	if versao == "" {
+		versao = versoes[len(versoes)-1]
+	}
+	tabela, ok := tabelas[versao]
+	if !ok {
+		return nil, fmt.Errorf("Versão da tabela de ICMS desconhecida: %s", versao)
+	}
+	return tabela, nil
+}
+
+// TabelaVigente retorna a versão fixada em tributos.versaoicms ou, sem configuração, a mais nova
+func TabelaVigente(c context.Context) (*Tabela, error) {
+	versao := config.GetDefault(c, config.TributosVersaoICMS, "").Value
+	tabela, err := TabelaVersao(versao)
+	if err != nil {
+		log.Warningf(c, "Falha ao carregar tabela de ICMS %q: %v", versao, err)
+	}
+	return tabela, err
+}
+
+func carregar() error {
+	carregarTabelas.Do(func() {
+		arquivos, err := arquivosTabelas.ReadDir("tabelas")
+		if err != nil {
+			erroTabelas = err
+			return
+		}
+
+		tabelas = make(map[string]*Tabela, len(arquivos))
+		for _, arquivo := range arquivos {
+			conteudo, err := arquivosTabelas.ReadFile(path.Join("tabelas", arquivo.Name()))
+			if err != nil {
+				erroTabelas = err
+				return
+			}
+			versao := strings.TrimSuffix(strings.TrimPrefix(arquivo.Name(), "icms-"), ".csv")
+			tabela, err := lerTabela(versao, conteudo)
+			if err != nil {
+				erroTabelas = fmt.Errorf("Tabela de ICMS %s: %v", arquivo.Name(), err)
+				return
+			}
+			tabelas[versao] = tabela
+		}
+		if len(tabelas) == 0 {
+			erroTabelas = fmt.Errorf("Nenhuma tabela de ICMS encontrada")
+		}
+	})
+	return erroTabelas
+}
+
+// lerTabela interpreta o CSV com a UF de origem na primeira coluna e uma coluna por UF de destino. A
+// tabela precisa ter todas as 27 UFs nas linhas e nas colunas.
+func lerTabela(versao string, conteudo []byte) (*Tabela, error) {
+	leitor := csv.NewReader(bytes.NewReader(conteudo))
+	leitor.Comment = '#'
+	leitor.TrimLeadingSpace = true
+
+	registros, err := leitor.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(registros) != len(UFs)+1 {
+		return nil, fmt.Errorf("esperadas %d linhas de UFs, encontradas %d", len(UFs), len(registros)-1)
+	}
+
+	destinos := registros[0][1:]
+	if err = conferirUFs(destinos); err != nil {
+		return nil, fmt.Errorf("cabeçalho: %v", err)
+	}
+
+	tabela := &Tabela{Versao: versao, Aliquotas: make(map[string]map[string]float64, len(UFs))}
+	origens := make([]string, 0, len(UFs))
+	for _, registro := range registros[1:] {
+		origem := strings.ToUpper(strings.TrimSpace(registro[0]))
+		origens = append(origens, origem)
+
+		linha := make(map[string]float64, len(destinos))
+		for i, destino := range destinos {
+			aliquota, err := strconv.ParseFloat(strings.TrimSpace(registro[i+1]), 64)
+			if err != nil || aliquota < 0 || aliquota > 100 {
+				return nil, fmt.Errorf("aliquota inválida de %s para %s: %q", origem, destino, registro[i+1])
+			}
+			linha[strings.ToUpper(strings.TrimSpace(destino))] = aliquota
+		}
+		tabela.Aliquotas[origem] = linha
+	}
+	if err = conferirUFs(origens); err != nil {
+		return nil, fmt.Errorf("linhas: %v", err)
+	}
+
+	return tabela, nil
+}
+
+// conferirUFs garante que a lista tem cada UF exatamente uma vez
+func conferirUFs(lista []string) error {
+	vistas := make(map[string]bool, len(lista))
+	for _, uf := range lista {
+		uf = strings.ToUpper(strings.TrimSpace(uf))
+		if vistas[uf] {
+			return fmt.Errorf("UF repetida: %s", uf)
+		}
+		vistas[uf] = true
+	}
+	for _, uf := range UFs {
+		if !vistas[uf] {
+			return fmt.Errorf("UF ausente: %s", uf)
+		}
+	}
+	if len(vistas) != len(UFs) {
+		return fmt.Errorf("UFs desconhecidas na tabela")
+	}
+	return nil
+}
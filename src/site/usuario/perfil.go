@@ -0,0 +1,154 @@
+package usuario
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"site/armazenamento"
+	"site/utils"
+	"site/utils/imagem"
+	"site/utils/log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	VisibilidadePublica = "publica"
+	VisibilidadePrivada = "privada"
+
+	TamanhoMaximoBio         = 160
+	TamanhoMaximoLocalizacao = 60
+
+	ImagemAvatar = "avatar"
+	ImagemBanner = "banner"
+
+	LarguraAvatar = 400
+	AlturaAvatar  = 400
+	LarguraBanner = 1500
+	AlturaBanner  = 500
+)
+
+// AlteracaoPerfil traz os campos de perfil enviados na edição. Campo ausente (nil) fica como está e
+// campo enviado vazio é apagado.
+type AlteracaoPerfil struct {
+	Bio            *string
+	Localizacao    *string
+	Website        *string
+	DataNascimento *string // No formato 2006-01-02
+	Visibilidade   *string
+}
+
+// atualizarPerfil aplica os campos de perfil informados e valida o resultado
+func (usuario *Usuario) atualizarPerfil(alteracao AlteracaoPerfil) error {
+	if alteracao.Bio != nil {
+		usuario.Bio = strings.TrimSpace(*alteracao.Bio)
+	}
+	if alteracao.Localizacao != nil {
+		usuario.Localizacao = strings.TrimSpace(*alteracao.Localizacao)
+	}
+	if alteracao.Website != nil {
+		usuario.Website = strings.TrimSpace(*alteracao.Website)
+	}
+	if alteracao.DataNascimento != nil {
+		usuario.DataNascimento = utils.JsonSpecialDate{}
+		if data := strings.TrimSpace(*alteracao.DataNascimento); data != "" {
+			nascimento, err := time.Parse("2006-01-02", data)
+			if err != nil {
+				return fmt.Errorf("Data de nascimento inválida: %v", data)
+			}
+			usuario.DataNascimento.Time = nascimento
+		}
+	}
+	if alteracao.Visibilidade != nil {
+		usuario.Visibilidade = strings.TrimSpace(*alteracao.Visibilidade)
+	}
+
+	return usuario.validarPerfil()
+}
+
+// validarPerfil valida os campos opcionais do perfil
+func (usuario *Usuario) validarPerfil() error {
+	if utf8.RuneCountInString(usuario.Bio) > TamanhoMaximoBio {
+		return fmt.Errorf("A bio deve ter no máximo %d caracteres", TamanhoMaximoBio)
+	}
+
+	if utf8.RuneCountInString(usuario.Localizacao) > TamanhoMaximoLocalizacao {
+		return fmt.Errorf("A localização deve ter no máximo %d caracteres", TamanhoMaximoLocalizacao)
+	}
+
+	if usuario.Website != "" {
+		site, err := url.Parse(usuario.Website)
+		if err != nil || (site.Scheme != "http" && site.Scheme != "https") || site.Host == "" {
+			return fmt.Errorf("Website inválido: %v", usuario.Website)
+		}
+	}
+
+	if !usuario.DataNascimento.IsZero() && usuario.DataNascimento.After(utils.GetTimeNow()) {
+		return fmt.Errorf("Data de nascimento não pode estar no futuro")
+	}
+
+	if usuario.Visibilidade == "" {
+		usuario.Visibilidade = VisibilidadePublica
+	}
+	if usuario.Visibilidade != VisibilidadePublica && usuario.Visibilidade != VisibilidadePrivada {
+		return fmt.Errorf("Visibilidade inválida: %v", usuario.Visibilidade)
+	}
+
+	return nil
+}
+
+// OcultarPerfil remove os dados de perfil que só devem ser vistos pelo proprio usuario
+func (usuario *Usuario) OcultarPerfil() {
+	usuario.Bio = ""
+	usuario.Localizacao = ""
+	usuario.Website = ""
+	usuario.DataNascimento = utils.JsonSpecialDate{}
+}
+
+// AtualizarImagem valida, redimensiona e armazena o avatar ou o banner do usuario
+func AtualizarImagem(c context.Context, usuario *Usuario, tipoImagem string, dados []byte) error {
+	img, err := imagem.Decodificar(dados)
+	if err != nil {
+		log.Warningf(c, "Imagem de %s inválida: %v", tipoImagem, err)
+		return err
+	}
+
+	switch tipoImagem {
+	case ImagemAvatar:
+		img = imagem.Recortar(img, LarguraAvatar, AlturaAvatar)
+	case ImagemBanner:
+		img = imagem.Recortar(img, LarguraBanner, AlturaBanner)
+	default:
+		return fmt.Errorf("Tipo de imagem desconhecido: %s", tipoImagem)
+	}
+
+	conteudo, err := imagem.CodificarJPEG(img)
+	if err != nil {
+		log.Warningf(c, "Falha ao gerar imagem de %s: %v", tipoImagem, err)
+		return err
+	}
+
+	arm, err := armazenamento.Novo(c)
+	if err != nil {
+		log.Warningf(c, "Falha ao iniciar armazenamento: %v", err)
+		return err
+	}
+
+	nome := fmt.Sprintf("usuarios/%d/%s.jpg", usuario.ID, tipoImagem)
+	urlImagem, err := arm.Salvar(c, nome, conteudo, imagem.TipoJPEG)
+	if err != nil {
+		log.Warningf(c, "Falha ao armazenar imagem de %s: %v", tipoImagem, err)
+		return err
+	}
+
+	// O nome do arquivo não muda, então a versão evita que o navegador use a imagem antiga em cache
+	urlImagem = fmt.Sprintf("%s?v=%d", urlImagem, utils.GetTimeNow().Unix())
+	if tipoImagem == ImagemAvatar {
+		usuario.AvatarURL = urlImagem
+	} else {
+		usuario.BannerURL = urlImagem
+	}
+
+	return PutUsuario(c, usuario)
+}
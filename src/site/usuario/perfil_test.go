@@ -0,0 +1,63 @@
+package usuario
+
+import (
+	"testing"
+	"time"
+)
+
+func texto(s string) *string {
+	return &s
+}
+
+func TestAtualizarPerfil(t *testing.T) {
+	original := func() Usuario {
+		u := Usuario{
+			Bio:          "Cervejeiro",
+			Localizacao:  "Goiânia",
+			Website:      "https://bardoze.com.br",
+			Visibilidade: VisibilidadePrivada,
+		}
+		u.DataNascimento.Time = time.Date(1990, 5, 1, 0, 0, 0, 0, time.UTC)
+		return u
+	}
+
+	casos := []struct {
+		nome      string
+		alteracao AlteracaoPerfil
+		valido    bool
+		conferir  func(u Usuario) bool
+	}{
+		{"nada enviado mantem tudo", AlteracaoPerfil{}, true, func(u Usuario) bool {
+			o := original()
+			return u.Bio == o.Bio && u.Localizacao == o.Localizacao && u.Website == o.Website &&
+				u.Visibilidade == o.Visibilidade && u.DataNascimento.Equal(o.DataNascimento.Time)
+		}},
+		{"campos vazios apagam", AlteracaoPerfil{Bio: texto(""), Localizacao: texto(""), Website: texto(""), DataNascimento: texto("")}, true, func(u Usuario) bool {
+			return u.Bio == "" && u.Localizacao == "" && u.Website == "" && u.DataNascimento.IsZero()
+		}},
+		{"altera bio", AlteracaoPerfil{Bio: texto("  Sommelier  ")}, true, func(u Usuario) bool {
+			return u.Bio == "Sommelier" && u.Localizacao == "Goiânia"
+		}},
+		{"visibilidade vazia volta para publica", AlteracaoPerfil{Visibilidade: texto("")}, true, func(u Usuario) bool {
+			return u.Visibilidade == VisibilidadePublica
+		}},
+		{"data de nascimento", AlteracaoPerfil{DataNascimento: texto("2001-02-03")}, true, func(u Usuario) bool {
+			return u.DataNascimento.Format("2006-01-02") == "2001-02-03"
+		}},
+		{"data inválida", AlteracaoPerfil{DataNascimento: texto("03/02/2001")}, false, nil},
+		{"website inválido", AlteracaoPerfil{Website: texto("bardoze")}, false, nil},
+		{"visibilidade inválida", AlteracaoPerfil{Visibilidade: texto("amigos")}, false, nil},
+	}
+
+	for _, caso := range casos {
+		u := original()
+		err := u.atualizarPerfil(caso.alteracao)
+		if (err == nil) != caso.valido {
+			t.Errorf("%s: atualizarPerfil() = %v, esperado valido = %v", caso.nome, err, caso.valido)
+			continue
+		}
+		if caso.conferir != nil && !caso.conferir(u) {
+			t.Errorf("%s: perfil inesperado: %+v", caso.nome, u)
+		}
+	}
+}
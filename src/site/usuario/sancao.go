@@ -0,0 +1,102 @@
+package usuario
+
+import (
+	"context"
+	"fmt"
+	"site/utils"
+	"site/utils/consts"
+	"site/utils/log"
+	"time"
+
+	"cloud.google.com/go/datastore"
+)
+
+const (
+	KindSancoes = "SancoesUsuario"
+)
+
+// Sancao é a suspensão ou o banimento aplicado por um moderador. A chave é o id do usuario, então
+// cada usuario tem no maximo uma sanção e uma nova substitui a anterior.
+type Sancao struct {
+	UsuarioID   int64 `datastore:"-"`
+	SuspensoAte time.Time
+	Banido      bool
+	Motivo      string `datastore:",noindex"`
+	ModeradorID int64
+	DataCriacao utils.JsonSpecialDateTime
+}
+
+// Ativa diz se a sanção ainda impede o usuario de usar a API
+func (sancao *Sancao) Ativa(agora time.Time) bool {
+	return sancao.Banido || sancao.SuspensoAte.After(agora)
+}
+
+// Mensagem explica a sanção para o usuario
+func (sancao *Sancao) Mensagem() string {
+	if sancao.Banido {
+		return "Conta banida por violar as regras da comunidade"
+	}
+	return fmt.Sprintf("Conta suspensa até %s", sancao.SuspensoAte.In(utils.GetTimeNow().Location()).Format("02/01/2006 15:04"))
+}
+
+// GetSancao traz a sanção do usuario, ou nil se ele nunca foi sancionado
+func GetSancao(c context.Context, usuarioID int64) *Sancao {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return nil
+	}
+	defer datastoreClient.Close()
+
+	var sancao Sancao
+	key := datastore.IDKey(KindSancoes, usuarioID, nil)
+	if err = datastoreClient.Get(c, key, &sancao); err != nil {
+		if err != datastore.ErrNoSuchEntity {
+			log.Warningf(c, "Falha ao buscar sanção do usuario %d: %v", usuarioID, err)
+		}
+		return nil
+	}
+	sancao.UsuarioID = usuarioID
+	return &sancao
+}
+
+func PutSancao(c context.Context, sancao *Sancao) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	key := datastore.IDKey(KindSancoes, sancao.UsuarioID, nil)
+	if _, err = datastoreClient.Put(c, key, sancao); err != nil {
+		log.Warningf(c, "Erro ao gravar sanção do usuario %d: %v", sancao.UsuarioID, err)
+		return err
+	}
+	return nil
+}
+
+// RemoverSancao encerra a suspensão ou o banimento do usuario
+func RemoverSancao(c context.Context, usuarioID int64) error {
+	datastoreClient, err := datastore.NewClient(c, consts.IDProjeto)
+	if err != nil {
+		log.Warningf(c, "Falha ao conectar-se com o Datastore: %v", err)
+		return err
+	}
+	defer datastoreClient.Close()
+
+	if err = datastoreClient.Delete(c, datastore.IDKey(KindSancoes, usuarioID, nil)); err != nil {
+		log.Warningf(c, "Erro ao remover sanção do usuario %d: %v", usuarioID, err)
+		return err
+	}
+	return nil
+}
+
+// SancaoAtiva traz a sanção do usuario se ela ainda estiver valendo, ou nil
+func SancaoAtiva(c context.Context, usuarioID int64) *Sancao {
+	sancao := GetSancao(c, usuarioID)
+	if sancao == nil || !sancao.Ativa(utils.GetTimeNow()) {
+		return nil
+	}
+	return sancao
+}
@@ -3,6 +3,7 @@ package usuario
 import (
 	"context"
 	"fmt"
+	"site/busca"
 	"site/utils"
 	"site/utils/consts"
 	"site/utils/log"
@@ -35,16 +36,25 @@ const (
 	ErrAssinarChave      = 417
 	ErrChaveInvalida     = 418
 	ErrCNPJRegistrado    = 419
+	ErrPerfilInvalido    = 420
+	ErrImagemInvalida    = 421
 	ErrDesconhecido      = 999
 )
 
 type Usuario struct {
-	ID          int64 `datastore:"-"`
-	Nome        string
-	Nick        string
-	Email       string
-	Senha       string
-	DataCriacao time.Time
+	ID             int64 `datastore:"-"`
+	Nome           string
+	Nick           string
+	Email          string
+	Senha          string
+	Bio            string `datastore:",noindex"`
+	Localizacao    string
+	Website        string `datastore:",noindex"`
+	DataNascimento utils.JsonSpecialDate
+	Visibilidade   string
+	AvatarURL      string `datastore:",noindex"`
+	BannerURL      string `datastore:",noindex"`
+	DataCriacao    time.Time
 }
 
 func GetUsuario(c context.Context, id int64) *Usuario {
@@ -126,6 +136,8 @@ func PutUsuario(c context.Context, usuario *Usuario) error {
 		return err
 	}
 	usuario.ID = key.ID
+
+	busca.SincronizarUsuario(c, usuario.DocumentoBusca())
 	return nil
 }
 
@@ -242,10 +254,15 @@ func InserirUsuario(c context.Context, usuario *Usuario) error {
 		usuario.DataCriacao = utils.GetTimeNow()
 	}
 
+	if err := usuario.validarPerfil(); err != nil {
+		return fmt.Errorf("Dados de perfil inválidos %v", err)
+	}
+
 	return PutUsuario(c, usuario)
 }
 
-func AtualizarUsuario(c context.Context, usuario *Usuario, usuNovo Usuario) error {
+// AtualizarUsuario altera nome, nick e email quando vêm preenchidos e aplica a alteração de perfil
+func AtualizarUsuario(c context.Context, usuario *Usuario, usuNovo Usuario, perfil AlteracaoPerfil) error {
 
 	if err := usuario.Preparar("edicao"); err != nil {
 		log.Warningf(c, "Erro ao preparar usuario para edição %v", err)
@@ -262,6 +279,11 @@ func AtualizarUsuario(c context.Context, usuario *Usuario, usuNovo Usuario) erro
 		usuario.Email = usuNovo.Email
 	}
 
+	if err := usuario.atualizarPerfil(perfil); err != nil {
+		log.Warningf(c, "Dados de perfil inválidos %v", err)
+		return err
+	}
+
 	return PutUsuario(c, usuario)
 }
 
@@ -279,9 +301,19 @@ func DeletarUsuario(c context.Context, usuario Usuario) error {
 		return err
 	}
 
+	busca.DesindexarUsuario(c, usuario.ID)
 	return nil
 }
 
+// DocumentoBusca retorna os dados do usuario que são indexados no Elasticsearch
+func (usuario *Usuario) DocumentoBusca() busca.DocumentoUsuario {
+	return busca.DocumentoUsuario{
+		ID:   usuario.ID,
+		Nome: usuario.Nome,
+		Nick: usuario.Nick,
+	}
+}
+
 func GetErro(code int) string {
 	switch code {
 	case ErrUsuarioInvalido:
@@ -320,6 +352,10 @@ func GetErro(code int) string {
 		return "Erro ao assinar chave de autenticação de acesso"
 	case ErrChaveInvalida:
 		return "Erro ao decodificar chave informada"
+	case ErrPerfilInvalido:
+		return "Dados de perfil inválidos"
+	case ErrImagemInvalida:
+		return "Imagem inválida"
 	default:
 		return "Desconhecido"
 	}
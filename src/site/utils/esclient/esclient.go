@@ -2,14 +2,25 @@ package esclient
 
 import (
 	"context"
+	"errors"
 	"site/config"
+	"strings"
 
+	"cloud.google.com/go/datastore"
 	"github.com/olivere/elastic/v7"
 )
 
+// ErrNotConfigured indica que não há elasticsearch.endpoint configurado
+var ErrNotConfigured = errors.New("Elasticsearch não configurado")
+
+// NewClient cria o cliente sem o healthcheck, que faria requisições ao cluster já na criação e
+// periodicamente depois dela. Um nó fora do ar aparece como erro na propria requisição.
 func NewClient(c context.Context) (*elastic.Client, error) {
 
 	esEndpoint, err := config.GetConfig(c, config.ElasticSearchEndpoint)
+	if err == datastore.ErrNoSuchEntity || (err == nil && strings.TrimSpace(esEndpoint.Value) == "") {
+		return nil, ErrNotConfigured
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -24,5 +35,5 @@ func NewClient(c context.Context) (*elastic.Client, error) {
 		return nil, err
 	}
 
-	return elastic.NewClient(elastic.SetSniff(false), elastic.SetURL(esEndpoint.Value), elastic.SetBasicAuth(esUsername.Value, esPassword.Value))
+	return elastic.NewClient(elastic.SetSniff(false), elastic.SetHealthcheck(false), elastic.SetURL(esEndpoint.Value), elastic.SetBasicAuth(esUsername.Value, esPassword.Value))
 }
@@ -0,0 +1,201 @@
+package imagem
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	TipoJPEG = "image/jpeg"
+	TipoPNG  = "image/png"
+	TipoGIF  = "image/gif"
+
+	TamanhoMaximo = 5 << 20  // 5MB
+	PixelsMaximo  = 40000000 // Cerca de 160MB depois de decodificada em RGBA
+	QualidadeJPEG = 85
+)
+
+var tiposPermitidos = []string{TipoJPEG, TipoPNG, TipoGIF}
+
+// DetectarTipo identifica o tipo da imagem pelo conteudo, ignorando o que foi informado pelo cliente
+func DetectarTipo(dados []byte) string {
+	return http.DetectContentType(dados)
+}
+
+// Validar verifica o tamanho e o tipo real do arquivo enviado
+func Validar(dados []byte, tamanhoMaximo int) (string, error) {
+	if len(dados) == 0 {
+		return "", fmt.Errorf("Imagem não informada")
+	}
+
+	if len(dados) > tamanhoMaximo {
+		return "", fmt.Errorf("Imagem excede o tamanho máximo de %d bytes", tamanhoMaximo)
+	}
+
+	tipo := DetectarTipo(dados)
+	for _, v := range tiposPermitidos {
+		if v == tipo {
+			return tipo, nil
+		}
+	}
+	return "", fmt.Errorf("Tipo de imagem não suportado: %s", tipo)
+}
+
+// Decodificar valida e decodifica a imagem recebida
+func Decodificar(dados []byte) (image.Image, error) {
+	tipo, err := Validar(dados, TamanhoMaximo)
+	if err != nil {
+		return nil, err
+	}
+
+	//Um arquivo pequeno pode declarar dimensões enormes, então elas são conferidas antes de decodificar
+	var config image.Config
+	switch tipo {
+	case TipoJPEG:
+		config, err = jpeg.DecodeConfig(bytes.NewReader(dados))
+	case TipoPNG:
+		config, err = png.DecodeConfig(bytes.NewReader(dados))
+	case TipoGIF:
+		config, err = gif.DecodeConfig(bytes.NewReader(dados))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Falha ao decodificar imagem: %v", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > PixelsMaximo {
+		return nil, fmt.Errorf("Imagem de %dx%d excede o máximo de %d pixels", config.Width, config.Height, PixelsMaximo)
+	}
+
+	var img image.Image
+	switch tipo {
+	case TipoJPEG:
+		img, err = jpeg.Decode(bytes.NewReader(dados))
+	case TipoPNG:
+		img, err = png.Decode(bytes.NewReader(dados))
+	case TipoGIF:
+		img, err = gif.Decode(bytes.NewReader(dados))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Falha ao decodificar imagem: %v", err)
+	}
+	return img, nil
+}
+
+// Redimensionar ajusta a imagem para caber em largura x altura mantendo a proporção.
+// Imagens menores que o limite não são ampliadas.
+func Redimensionar(img image.Image, largura, altura int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= largura && b.Dy() <= altura {
+		return img
+	}
+
+	escala := float64(largura) / float64(b.Dx())
+	if e := float64(altura) / float64(b.Dy()); e < escala {
+		escala = e
+	}
+
+	novaLargura := int(float64(b.Dx()) * escala)
+	novaAltura := int(float64(b.Dy()) * escala)
+	if novaLargura < 1 {
+		novaLargura = 1
+	}
+	if novaAltura < 1 {
+		novaAltura = 1
+	}
+	return escalar(img, b, novaLargura, novaAltura)
+}
+
+// Recortar recorta a imagem no centro na proporção de largura x altura e escala para esse tamanho
+func Recortar(img image.Image, largura, altura int) image.Image {
+	b := img.Bounds()
+	proporcao := float64(largura) / float64(altura)
+
+	recorte := b
+	if float64(b.Dx())/float64(b.Dy()) > proporcao {
+		w := int(float64(b.Dy()) * proporcao)
+		x := b.Min.X + (b.Dx()-w)/2
+		recorte = image.Rect(x, b.Min.Y, x+w, b.Max.Y)
+	} else {
+		h := int(float64(b.Dx()) / proporcao)
+		y := b.Min.Y + (b.Dy()-h)/2
+		recorte = image.Rect(b.Min.X, y, b.Max.X, y+h)
+	}
+
+	if recorte.Dx() < largura || recorte.Dy() < altura {
+		largura, altura = recorte.Dx(), recorte.Dy()
+	}
+	return escalar(img, recorte, largura, altura)
+}
+
+// CodificarJPEG gera um novo JPEG a partir dos pixels da imagem.
+// Como o arquivo é recriado, metadados como EXIF não são preservados.
+func CodificarJPEG(img image.Image) ([]byte, error) {
+	// JPEG não tem transparência, então o fundo é preenchido de branco
+	fundo := image.NewRGBA(img.Bounds())
+	draw.Draw(fundo, fundo.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(fundo, fundo.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, fundo, &jpeg.Options{Quality: QualidadeJPEG}); err != nil {
+		return nil, fmt.Errorf("Falha ao codificar imagem: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// escalar amostra a região origem da imagem em uma nova imagem largura x altura usando interpolação bilinear
+func escalar(img image.Image, origem image.Rectangle, largura, altura int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, largura, altura))
+	fx := float64(origem.Dx()) / float64(largura)
+	fy := float64(origem.Dy()) / float64(altura)
+
+	for y := 0; y < altura; y++ {
+		sy := (float64(y)+0.5)*fy - 0.5
+		y0 := int(sy)
+		if sy < 0 {
+			sy, y0 = 0, 0
+		}
+		y1 := y0 + 1
+		if y1 >= origem.Dy() {
+			y1 = origem.Dy() - 1
+		}
+		dy := sy - float64(y0)
+
+		for x := 0; x < largura; x++ {
+			sx := (float64(x)+0.5)*fx - 0.5
+			x0 := int(sx)
+			if sx < 0 {
+				sx, x0 = 0, 0
+			}
+			x1 := x0 + 1
+			if x1 >= origem.Dx() {
+				x1 = origem.Dx() - 1
+			}
+			dx := sx - float64(x0)
+
+			c00 := color.RGBA64Model.Convert(img.At(origem.Min.X+x0, origem.Min.Y+y0)).(color.RGBA64)
+			c10 := color.RGBA64Model.Convert(img.At(origem.Min.X+x1, origem.Min.Y+y0)).(color.RGBA64)
+			c01 := color.RGBA64Model.Convert(img.At(origem.Min.X+x0, origem.Min.Y+y1)).(color.RGBA64)
+			c11 := color.RGBA64Model.Convert(img.At(origem.Min.X+x1, origem.Min.Y+y1)).(color.RGBA64)
+
+			dst.Set(x, y, color.RGBA64{
+				R: interpolar(c00.R, c10.R, c01.R, c11.R, dx, dy),
+				G: interpolar(c00.G, c10.G, c01.G, c11.G, dx, dy),
+				B: interpolar(c00.B, c10.B, c01.B, c11.B, dx, dy),
+				A: interpolar(c00.A, c10.A, c01.A, c11.A, dx, dy),
+			})
+		}
+	}
+	return dst
+}
+
+func interpolar(c00, c10, c01, c11 uint16, dx, dy float64) uint16 {
+	topo := float64(c00)*(1-dx) + float64(c10)*dx
+	base := float64(c01)*(1-dx) + float64(c11)*dx
+	return uint16(topo*(1-dy) + base*dy)
+}
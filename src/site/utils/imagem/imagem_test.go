@@ -0,0 +1,91 @@
+package imagem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func gerarPNG(t *testing.T, largura, altura int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, largura, altura))
+	for y := 0; y < altura; y++ {
+		for x := 0; x < largura; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Falha ao gerar PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidar(t *testing.T) {
+	dados := gerarPNG(t, 10, 10)
+
+	tipo, err := Validar(dados, TamanhoMaximo)
+	if err != nil {
+		t.Errorf("PNG válido foi rejeitado: %v", err)
+	}
+	if tipo != TipoPNG {
+		t.Errorf("Tipo detectado inválido: %v", tipo)
+	}
+
+	if _, err := Validar(dados, len(dados)-1); err == nil {
+		t.Errorf("Imagem acima do tamanho máximo foi aceita")
+	}
+
+	if _, err := Validar([]byte("<html><body>nada</body></html>"), TamanhoMaximo); err == nil {
+		t.Errorf("Conteudo que não é imagem foi aceito")
+	}
+}
+
+func TestDecodificarDimensoesEnormes(t *testing.T) {
+	//Troca as dimensões declaradas no IHDR e recalcula o CRC, o arquivo continua com poucos bytes
+	dados := gerarPNG(t, 1, 1)
+	binary.BigEndian.PutUint32(dados[16:], 50000)
+	binary.BigEndian.PutUint32(dados[20:], 50000)
+	binary.BigEndian.PutUint32(dados[29:], crc32.ChecksumIEEE(dados[12:29]))
+
+	if _, err := Decodificar(dados); err == nil || !strings.Contains(err.Error(), "pixels") {
+		t.Errorf("PNG declarando 50000x50000 deveria ser recusado pelo limite de pixels: %v", err)
+	}
+	if _, err := Decodificar(gerarPNG(t, 1, 1)); err != nil {
+		t.Errorf("PNG de 1x1 foi rejeitado: %v", err)
+	}
+}
+
+func TestRecortarERedimensionar(t *testing.T) {
+	img, err := Decodificar(gerarPNG(t, 300, 100))
+	if err != nil {
+		t.Fatalf("Falha ao decodificar PNG: %v", err)
+	}
+
+	recorte := Recortar(img, 50, 50)
+	if recorte.Bounds().Dx() != 50 || recorte.Bounds().Dy() != 50 {
+		t.Errorf("Recorte com tamanho inválido: %v", recorte.Bounds())
+	}
+
+	reduzida := Redimensionar(img, 150, 150)
+	if reduzida.Bounds().Dx() != 150 || reduzida.Bounds().Dy() != 50 {
+		t.Errorf("Redimensionamento não manteve a proporção: %v", reduzida.Bounds())
+	}
+
+	if Redimensionar(img, 1000, 1000) != img {
+		t.Errorf("Imagem menor que o limite não deveria ser alterada")
+	}
+
+	jpg, err := CodificarJPEG(recorte)
+	if err != nil {
+		t.Fatalf("Falha ao codificar JPEG: %v", err)
+	}
+	if DetectarTipo(jpg) != TipoJPEG {
+		t.Errorf("Imagem codificada não é JPEG")
+	}
+}
@@ -0,0 +1,308 @@
+package utils
+
+import "strings"
+
+// IEIsento é aceito no lugar da Inscrição Estadual por quem não é contribuinte do ICMS
+const IEIsento = "ISENTO"
+
+// Calculo dos digitos verificadores de cada UF, conforme as especificações publicadas pelo SINTEGRA.
+// Recebem só os digitos da inscrição.
+var validadoresIE = map[string]func(d []int) bool{
+	"AC": func(d []int) bool {
+		return len(d) == 13 && prefixoIE(d, "01") &&
+			d[11] == dvModulo11(somaPesos(d, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2)) &&
+			d[12] == dvModulo11(somaPesos(d, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2))
+	},
+	"AL": func(d []int) bool {
+		if len(d) != 9 || !prefixoIE(d, "24") {
+			return false
+		}
+		dv := somaPesos(d, pesosDecrescentes(9, 8)...) * 10 % 11
+		if dv == 10 {
+			dv = 0
+		}
+		return d[8] == dv
+	},
+	"AP": func(d []int) bool {
+		if len(d) != 9 || !prefixoIE(d, "03") {
+			return false
+		}
+		numero := numeroIE(d[:8])
+		p, dvOnze := 0, 0
+		switch {
+		case numero <= 3017000:
+			p, dvOnze = 5, 0
+		case numero <= 3019022:
+			p, dvOnze = 9, 1
+		}
+		dv := 11 - (p+somaPesos(d, pesosDecrescentes(9, 8)...))%11
+		if dv == 10 {
+			dv = 0
+		} else if dv == 11 {
+			dv = dvOnze
+		}
+		return d[8] == dv
+	},
+	"AM": func(d []int) bool {
+		if len(d) != 9 {
+			return false
+		}
+		soma := somaPesos(d, pesosDecrescentes(9, 8)...)
+		dv := dvModulo11(soma)
+		if soma < 11 {
+			dv = 11 - soma
+		}
+		return d[8] == dv
+	},
+	"BA": validarIEBA,
+	"CE": validarIENoveDigitos(""),
+	"DF": func(d []int) bool {
+		return len(d) == 13 && (prefixoIE(d, "07") || prefixoIE(d, "08")) &&
+			d[11] == dvModulo11(somaPesos(d, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2)) &&
+			d[12] == dvModulo11(somaPesos(d, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2))
+	},
+	"ES": validarIENoveDigitos(""),
+	"GO": validarIEGO,
+	"MA": validarIENoveDigitos("12"),
+	"MT": func(d []int) bool {
+		if len(d) > 11 {
+			return false
+		}
+		//Inscrições antigas têm menos digitos e são completadas com zeros à esquerda
+		d = append(make([]int, 11-len(d)), d...)
+		return d[10] == dvModulo11(somaPesos(d, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2))
+	},
+	"MS": func(d []int) bool {
+		return (prefixoIE(d, "28") || prefixoIE(d, "50")) && validarIENoveDigitos("")(d)
+	},
+	"MG": validarIEMG,
+	"PA": validarIENoveDigitos("15"),
+	"PB": validarIENoveDigitos(""),
+	"PR": func(d []int) bool {
+		return len(d) == 10 &&
+			d[8] == dvModulo11(somaPesos(d, 3, 2, 7, 6, 5, 4, 3, 2)) &&
+			d[9] == dvModulo11(somaPesos(d, 4, 3, 2, 7, 6, 5, 4, 3, 2))
+	},
+	"PE": func(d []int) bool {
+		switch len(d) {
+		case 9:
+			return d[7] == dvModulo11(somaPesos(d, pesosDecrescentes(8, 7)...)) &&
+				d[8] == dvModulo11(somaPesos(d, pesosDecrescentes(9, 8)...))
+		case 14:
+			//Formato antigo, anterior ao eFisco
+			dv := 11 - somaPesos(d, 5, 4, 3, 2, 1, 9, 8, 7, 6, 5, 4, 3, 2)%11
+			if dv > 9 {
+				dv -= 10
+			}
+			return d[13] == dv
+		}
+		return false
+	},
+	"PI": validarIENoveDigitos(""),
+	"RJ": func(d []int) bool {
+		return len(d) == 8 && d[7] == dvModulo11(somaPesos(d, 2, 7, 6, 5, 4, 3, 2))
+	},
+	"RN": func(d []int) bool {
+		if (len(d) != 9 && len(d) != 10) || !prefixoIE(d, "20") {
+			return false
+		}
+		dv := somaPesos(d, pesosDecrescentes(len(d), len(d)-1)...) * 10 % 11
+		if dv == 10 {
+			dv = 0
+		}
+		return d[len(d)-1] == dv
+	},
+	"RS": func(d []int) bool {
+		return len(d) == 10 && d[9] == dvModulo11(somaPesos(d, 2, 9, 8, 7, 6, 5, 4, 3, 2))
+	},
+	"RO": func(d []int) bool {
+		var soma int
+		switch len(d) {
+		case 14:
+			soma = somaPesos(d, 6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2)
+		case 9:
+			//Formato antigo: os 3 primeiros digitos são o municipio e ficam fora do calculo
+			soma = somaPesos(d[3:], 6, 5, 4, 3, 2)
+		default:
+			return false
+		}
+		dv := 11 - soma%11
+		if dv >= 10 {
+			dv -= 10
+		}
+		return d[len(d)-1] == dv
+	},
+	"RR": func(d []int) bool {
+		return len(d) == 9 && prefixoIE(d, "24") && d[8] == somaPesos(d, 1, 2, 3, 4, 5, 6, 7, 8)%9
+	},
+	"SC": validarIENoveDigitos(""),
+	"SP": func(d []int) bool {
+		return len(d) == 12 &&
+			d[8] == somaPesos(d, 1, 3, 4, 5, 6, 7, 8, 10)%11%10 &&
+			d[11] == somaPesos(d, 3, 2, 10, 9, 8, 7, 6, 5, 4, 3, 2)%11%10
+	},
+	"SE": validarIENoveDigitos(""),
+	"TO": func(d []int) bool {
+		if len(d) == 11 {
+			//Formato antigo: os digitos 3 e 4 indicam o tipo de empresa e ficam fora do calculo
+			tipo := numeroIE(d[2:4])
+			if tipo != 1 && tipo != 2 && tipo != 3 && tipo != 99 {
+				return false
+			}
+			base := append(append([]int{}, d[:2]...), d[4:]...)
+			return d[10] == dvModulo11(somaPesos(base, pesosDecrescentes(9, 8)...))
+		}
+		return validarIENoveDigitos("")(d)
+	},
+}
+
+// NormalizeIE deixa só os digitos da Inscrição Estadual e confere os digitos verificadores pela regra
+// da UF. "ISENTO" é aceito em qualquer UF.
+func NormalizeIE(ie, uf string) (string, bool) {
+	if strings.EqualFold(strings.TrimSpace(ie), IEIsento) {
+		return IEIsento, true
+	}
+
+	ie = OnlyNumbers(ie)
+	validador, ok := validadoresIE[strings.ToUpper(strings.TrimSpace(uf))]
+	if !ok || ie == "" {
+		return ie, false
+	}
+
+	d := make([]int, len(ie))
+	for i := range ie {
+		d[i] = int(ie[i] - '0')
+	}
+	return ie, validador(d)
+}
+
+func IsValidIE(ie, uf string) bool {
+	_, ok := NormalizeIE(ie, uf)
+	return ok
+}
+
+// validarIENoveDigitos é a regra mais comum: 8 digitos com pesos de 9 a 2 e modulo 11
+func validarIENoveDigitos(prefixo string) func(d []int) bool {
+	return func(d []int) bool {
+		return len(d) == 9 && prefixoIE(d, prefixo) && d[8] == dvModulo11(somaPesos(d, pesosDecrescentes(9, 8)...))
+	}
+}
+
+// validarIEBA calcula primeiro o segundo digito verificador. O modulo depende do primeiro digito
+// (segundo, nas inscrições de 9 digitos).
+func validarIEBA(d []int) bool {
+	n := len(d)
+	if n != 8 && n != 9 {
+		return false
+	}
+
+	controle := d[0]
+	if n == 9 {
+		controle = d[1]
+	}
+	modulo10 := controle <= 5 || controle == 8
+
+	dv := func(soma int) int {
+		if modulo10 {
+			return (10 - soma%10) % 10
+		}
+		return dvModulo11(soma)
+	}
+
+	base := d[:n-2]
+	dv2 := dv(somaPesos(base, pesosDecrescentes(n-1, n-2)...))
+	dv1 := dv(somaPesos(append(append([]int{}, base...), dv2), pesosDecrescentes(n, n-1)...))
+	return d[n-2] == dv1 && d[n-1] == dv2
+}
+
+func validarIEGO(d []int) bool {
+	if len(d) != 9 {
+		return false
+	}
+	prefixo := numeroIE(d[:2])
+	if prefixo != 10 && prefixo != 11 && prefixo != 15 && (prefixo < 20 || prefixo > 29) {
+		return false
+	}
+
+	numero := numeroIE(d[:8])
+	if numero == 11094402 {
+		return d[8] == 0 || d[8] == 1
+	}
+
+	resto := somaPesos(d, pesosDecrescentes(9, 8)...) % 11
+	dv := 11 - resto
+	switch {
+	case resto == 0:
+		dv = 0
+	case resto == 1 && numero >= 10103105 && numero <= 10119997:
+		dv = 1
+	case resto == 1:
+		dv = 0
+	}
+	return d[8] == dv
+}
+
+// validarIEMG usa modulo 10 somando os algarismos dos produtos, com um zero inserido depois do
+// codigo do municipio, e modulo 11 no segundo digito
+func validarIEMG(d []int) bool {
+	if len(d) != 13 {
+		return false
+	}
+
+	base := append(append(append([]int{}, d[:3]...), 0), d[3:11]...)
+	var soma int
+	for i, digito := range base {
+		produto := digito * (1 + i%2)
+		soma += produto/10 + produto%10
+	}
+	dv1 := (10 - soma%10) % 10
+
+	return d[11] == dv1 && d[12] == dvModulo11(somaPesos(d, 3, 2, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2))
+}
+
+// somaPesos multiplica os primeiros digitos pelos pesos, um peso por digito
+func somaPesos(d []int, pesos ...int) int {
+	var soma int
+	for i, peso := range pesos {
+		soma += d[i] * peso
+	}
+	return soma
+}
+
+// pesosDecrescentes devolve quantidade pesos começando em inicio e descendo de 1 em 1
+func pesosDecrescentes(inicio, quantidade int) []int {
+	pesos := make([]int, quantidade)
+	for i := range pesos {
+		pesos[i] = inicio - i
+	}
+	return pesos
+}
+
+// dvModulo11 é 11 menos o resto da divisão por 11, com 10 e 11 virando zero
+func dvModulo11(soma int) int {
+	resto := soma % 11
+	if resto < 2 {
+		return 0
+	}
+	return 11 - resto
+}
+
+func prefixoIE(d []int, prefixo string) bool {
+	if len(d) < len(prefixo) {
+		return false
+	}
+	for i := range prefixo {
+		if d[i] != int(prefixo[i]-'0') {
+			return false
+		}
+	}
+	return true
+}
+
+func numeroIE(d []int) int {
+	var numero int
+	for _, digito := range d {
+		numero = numero*10 + digito
+	}
+	return numero
+}
@@ -0,0 +1,89 @@
+package utils
+
+import "testing"
+
+// Exemplos de inscrições validas das especificações de cada UF no SINTEGRA
+var iesValidas = []struct {
+	uf string
+	ie string
+}{
+	{"AC", "01.004.823/001-12"},
+	{"AL", "240000048"},
+	{"AP", "030123459"},
+	{"AM", "999999990"},
+	{"BA", "123456-63"},
+	{"BA", "1000003-06"},
+	{"CE", "06000001-5"},
+	{"DF", "07300001001-09"},
+	{"ES", "999999990"},
+	{"GO", "10.987.654-7"},
+	{"MA", "120000385"},
+	{"MT", "0013000001-9"},
+	{"MS", "283115947"},
+	{"MG", "062.307.904/0081"},
+	{"PA", "15-999999-5"},
+	{"PB", "06000001-5"},
+	{"PR", "123.45678-50"},
+	{"PE", "0321418-40"},
+	{"PE", "18.1.001.0000004-9"},
+	{"PI", "012345679"},
+	{"RJ", "99.999.99-3"},
+	{"RN", "20.040.040-1"},
+	{"RN", "20.0.040.040-0"},
+	{"RS", "224/3658792"},
+	{"RO", "101.62521-3"},
+	{"RO", "0000000062521-3"},
+	{"RR", "24006628-1"},
+	{"SC", "251.040.852"},
+	{"SP", "110.042.490.114"},
+	{"SE", "27123456-3"},
+	{"TO", "29010227836"},
+}
+
+func TestIsValidIE(t *testing.T) {
+	for _, caso := range iesValidas {
+		if !IsValidIE(caso.ie, caso.uf) {
+			t.Errorf("IsValidIE(%q, %q) = false, esperado true", caso.ie, caso.uf)
+		}
+	}
+}
+
+// Trocar o ultimo digito de uma inscrição valida tem que invalidar a inscrição em todas as UFs
+func TestIsValidIEDigitoErrado(t *testing.T) {
+	for _, caso := range iesValidas {
+		ie := OnlyNumbers(caso.ie)
+		ultimo := ie[len(ie)-1]
+		for digito := byte('0'); digito <= '9'; digito++ {
+			if digito == ultimo {
+				continue
+			}
+			alterada := ie[:len(ie)-1] + string(digito)
+			if IsValidIE(alterada, caso.uf) {
+				t.Errorf("IsValidIE(%q, %q) = true, esperado false", alterada, caso.uf)
+			}
+		}
+	}
+}
+
+func TestNormalizeIE(t *testing.T) {
+	casos := []struct {
+		ie     string
+		uf     string
+		saida  string
+		valido bool
+	}{
+		{"110.042.490.114", "sp", "110042490114", true},
+		{" isento ", "SP", IEIsento, true},
+		{"110.042.490.114", "RJ", "110042490114", false},
+		{"110042490114", "XX", "110042490114", false},
+		{"", "SP", "", false},
+		{"999999990", "MA", "999999990", false},
+	}
+
+	for _, caso := range casos {
+		saida, valido := NormalizeIE(caso.ie, caso.uf)
+		if saida != caso.saida || valido != caso.valido {
+			t.Errorf("NormalizeIE(%q, %q) = %q, %v, esperado %q, %v", caso.ie, caso.uf, saida, valido, caso.saida, caso.valido)
+		}
+	}
+}
@@ -201,7 +201,8 @@ func NormalizeCPFCNPJ(cpfcnpj string) (string, bool) {
 		return cpfcnpj, true
 	}
 
-	if len(cpfcnpj) > 11 {
+	//CPF completado com zeros à esquerda até o tamanho de um CNPJ
+	if len(cpfcnpj) == 14 && cpfcnpj[:3] == "000" {
 		cpfcnpj = cpfcnpj[3:]
 	}
 	if IsValidCPF(cpfcnpj) {
@@ -212,6 +213,12 @@ func NormalizeCPFCNPJ(cpfcnpj string) (string, bool) {
 
 }
 
+// NormalizeCNPJ deixa só os digitos e aceita apenas CNPJ, sem a conversão para CPF de NormalizeCPFCNPJ
+func NormalizeCNPJ(cnpj string) (string, bool) {
+	cnpj = OnlyNumbers(cnpj)
+	return cnpj, IsValidCNPJ(cnpj)
+}
+
 func OnlyNumbers(s string) string {
 	return allNumRe.ReplaceAllString(s, "")
 }
@@ -338,98 +345,6 @@ func Modulo11(numero string) (int64, error) {
 
 }
 
-func GetAliquota(codMunIni int64, codMunFim int64, ie string) (int64, error) {
-
-	var aliquota int64
-	sulSudesteExcetoES := []string{"31", "33", "35", "41", "42", "43"}
-	norteNordesteCentroOesteES := []string{"11", "12", "13", "14", "15", "16", "17", "21", "22", "23", "24", "25", "26", "27", "28", "29", "50", "51", "52", "53", "32"}
-
-	if codMunIni < 99 || codMunFim < 99 {
-		return aliquota, fmt.Errorf("Codigo de municipio não informado: ini %d, fim: %d", codMunIni, codMunFim)
-	}
-
-	strCodMunIni := fmt.Sprint(codMunIni)[:2]
-	strCodMunFim := fmt.Sprint(codMunFim)[:2]
-
-	switch strCodMunIni {
-	case "35":
-		if strCodMunFim == "35" {
-			aliquota = 12
-		} else if InArray(strCodMunIni, sulSudesteExcetoES) {
-			aliquota = 12
-		} else if InArray(strCodMunFim, norteNordesteCentroOesteES) {
-			aliquota = 7
-		}
-	case "52":
-		if ie == "ISENTO" && strCodMunFim == "52" {
-			aliquota = 17
-		} else if ie != "ISENTO" && strCodMunFim == "52" {
-			aliquota = 0
-		} else if InArray(strCodMunFim, sulSudesteExcetoES) {
-			aliquota = 12
-		} else if InArray(strCodMunFim, norteNordesteCentroOesteES) {
-			aliquota = 12
-		}
-	case "53":
-		if strCodMunFim == "53" {
-			aliquota = 0
-		} else if InArray(strCodMunFim, sulSudesteExcetoES) {
-			aliquota = 12
-		} else if InArray(strCodMunFim, norteNordesteCentroOesteES) {
-			aliquota = 12
-		}
-	case "32":
-		if strCodMunFim == "32" {
-			aliquota = 12
-		} else if InArray(strCodMunFim, sulSudesteExcetoES) {
-			aliquota = 12
-		} else if InArray(strCodMunFim, norteNordesteCentroOesteES) {
-			aliquota = 12
-		}
-	case "31":
-		if strCodMunFim == "31" {
-			aliquota = 0
-		} else if InArray(strCodMunFim, sulSudesteExcetoES) {
-			aliquota = 12
-		} else if InArray(strCodMunFim, norteNordesteCentroOesteES) {
-			aliquota = 7
-		}
-	case "33":
-		if strCodMunFim == "33" {
-			aliquota = 12
-		} else if InArray(strCodMunFim, sulSudesteExcetoES) {
-			aliquota = 12
-		} else if InArray(strCodMunFim, norteNordesteCentroOesteES) {
-			aliquota = 7
-		}
-	case "41":
-		if strCodMunFim == "41" {
-			aliquota = 0
-		} else if InArray(strCodMunFim, sulSudesteExcetoES) {
-			aliquota = 12
-		} else if InArray(strCodMunFim, norteNordesteCentroOesteES) {
-			aliquota = 7
-		}
-	case "42":
-		if InArray(strCodMunFim, sulSudesteExcetoES) {
-			aliquota = 12
-		} else if InArray(strCodMunFim, norteNordesteCentroOesteES) {
-			aliquota = 7
-		}
-	case "43":
-		if strCodMunFim == "43" {
-			aliquota = 0
-		} else {
-			aliquota = 12
-		}
-	default:
-		aliquota = 0
-	}
-
-	return aliquota, nil
-
-}
-
 func RandomNumber(number int) int {
 	rand.Seed(time.Now().UnixNano())
 	return rand.Intn(number)
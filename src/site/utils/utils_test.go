@@ -207,3 +207,47 @@ func TestSetValorCampo(t *testing.T) {
 
 	return
 }
+
+func TestNormalizeCNPJ(t *testing.T) {
+	casos := []struct {
+		entrada string
+		saida   string
+		valido  bool
+	}{
+		{"11.222.333/0001-81", "11222333000181", true},
+		{"11222333000181", "11222333000181", true},
+		{"11.222.333/0001-80", "11222333000180", false},
+		{"529.982.247-25", "52998224725", false},
+		{"00052998224725", "00052998224725", false},
+		{"", "", false},
+	}
+
+	for _, caso := range casos {
+		saida, valido := NormalizeCNPJ(caso.entrada)
+		if saida != caso.saida || valido != caso.valido {
+			t.Errorf("NormalizeCNPJ(%q) = %q, %v, esperado %q, %v", caso.entrada, saida, valido, caso.saida, caso.valido)
+		}
+	}
+}
+
+func TestNormalizeCPFCNPJ(t *testing.T) {
+	casos := []struct {
+		entrada string
+		saida   string
+		valido  bool
+	}{
+		{"11.222.333/0001-81", "11222333000181", true},
+		{"529.982.247-25", "52998224725", true},
+		//CPF completado com zeros até 14 digitos
+		{"00052998224725", "52998224725", true},
+		//Antes os 3 primeiros digitos de qualquer numero de 14 digitos eram descartados
+		{"12352998224725", "12352998224725", false},
+	}
+
+	for _, caso := range casos {
+		saida, valido := NormalizeCPFCNPJ(caso.entrada)
+		if saida != caso.saida || valido != caso.valido {
+			t.Errorf("NormalizeCPFCNPJ(%q) = %q, %v, esperado %q, %v", caso.entrada, saida, valido, caso.saida, caso.valido)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+$(document).on('click', '.denunciar-publicacao, .denunciar-perfil', denunciar);
+
+function denunciar(evento) {
+    const alvo = $(evento.currentTarget);
+    const tipoAlvo = alvo.data('tipo-alvo');
+    const alvoId = alvo.data('alvo-id');
+
+    $.ajax({
+        url: "/web/denuncias/motivos",
+        method: "GET"
+    }).done(function(motivos) {
+        Swal.fire({
+            title: "Denunciar",
+            input: "select",
+            inputOptions: motivos,
+            inputPlaceholder: "Selecione o motivo",
+            showCancelButton: true,
+            cancelButtonText: "Cancelar",
+            inputValidator: function(valor) {
+                if (!valor) return "Selecione o motivo da denuncia";
+            }
+        }).then(function(motivo) {
+            if (!motivo.value) return;
+
+            Swal.fire({
+                title: "Detalhes",
+                input: "textarea",
+                inputPlaceholder: motivo.value === "outro" ? "Descreva o motivo" : "Detalhes (opcional)",
+                showCancelButton: true,
+                cancelButtonText: "Cancelar"
+            }).then(function(detalhes) {
+                if (detalhes.isDismissed) return;
+
+                $.ajax({
+                    url: "/web/denuncias",
+                    method: "POST",
+                    contentType: "application/json",
+                    data: JSON.stringify({
+                        TipoAlvo: tipoAlvo,
+                        AlvoID: alvoId,
+                        Motivo: motivo.value,
+                        Detalhes: detalhes.value || ""
+                    })
+                }).done(function() {
+                    Swal.fire("Obrigado!", "Sua denuncia foi enviada para a moderação.", "success");
+                }).fail(function(erro) {
+                    const mensagem = erro.responseJSON && erro.responseJSON.err ? erro.responseJSON.err : "Erro ao enviar a denuncia!";
+                    Swal.fire("Ops...", mensagem, "error");
+                });
+            });
+        });
+    }).fail(function() {
+        Swal.fire("Ops...", "Erro ao carregar os motivos de denuncia!", "error");
+    });
+}
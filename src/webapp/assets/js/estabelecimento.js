@@ -0,0 +1,64 @@
+$('#fazer-checkin').on('click', fazerCheckin);
+$('#avaliar-estabelecimento').on('submit', avaliarEstabelecimento);
+$('#remover-avaliacao').on('click', removerAvaliacao);
+
+function estabelecimentoId() {
+    return $('#estabelecimento').data('estabelecimento-id');
+}
+
+function mensagemErro(erro, padrao) {
+    return erro.responseJSON && erro.responseJSON.err ? erro.responseJSON.err : padrao;
+}
+
+function fazerCheckin() {
+    $.ajax({
+        url: `/web/estabelecimento/${estabelecimentoId()}/checkin`,
+        method: "POST"
+    }).done(function() {
+        Swal.fire("Pronto!", "Check-in feito!", "success")
+            .then(function() {
+                window.location.reload();
+            });
+    }).fail(function(erro) {
+        Swal.fire("Ops...", mensagemErro(erro, "Erro ao fazer check-in!"), "error");
+    });
+}
+
+function avaliarEstabelecimento(evento) {
+    evento.preventDefault();
+
+    $.ajax({
+        url: `/web/estabelecimento/${estabelecimentoId()}/avaliacao`,
+        method: "PUT",
+        contentType: "application/json",
+        data: JSON.stringify({
+            Nota: parseInt($('#nota').val()),
+            Texto: $('#texto-avaliacao').val()
+        })
+    }).done(function() {
+        window.location.reload();
+    }).fail(function(erro) {
+        Swal.fire("Ops...", mensagemErro(erro, "Erro ao salvar a avaliação!"), "error");
+    });
+}
+
+function removerAvaliacao() {
+    Swal.fire({
+        title: "Atenção!",
+        text: "Tem certeza que deseja remover sua avaliação?",
+        showCancelButton: true,
+        cancelButtonText: "Cancelar",
+        icon: "warning"
+    }).then(function(confirmacao) {
+        if (!confirmacao.value) return;
+
+        $.ajax({
+            url: `/web/estabelecimento/${estabelecimentoId()}/avaliacao`,
+            method: "DELETE"
+        }).done(function() {
+            window.location.reload();
+        }).fail(function(erro) {
+            Swal.fire("Ops...", mensagemErro(erro, "Erro ao remover a avaliação!"), "error");
+        });
+    });
+}
@@ -0,0 +1,39 @@
+$(document).ready(function() {
+    // Só abre o stream nas páginas de usuario logado, que têm o cabeçalho com as notificações
+    if (!$('#notificacoes-nao-lidas').length || !window.EventSource) {
+        return;
+    }
+
+    const fonte = new EventSource('/web/eventos');
+    fonte.addEventListener('notificacao', atualizarContadorNotificacoes);
+    fonte.addEventListener('mensagem', receberMensagem);
+    fonte.addEventListener('curtidas', atualizarCurtidas);
+    fonte.addEventListener('publicacao', avisarNovaPublicacao);
+});
+
+let novasPublicacoes = 0;
+
+function atualizarCurtidas(evento) {
+    const dados = JSON.parse(evento.data);
+    $(`[data-publicacao-id="${dados.PublicacaoID}"] .fa-heart`).next('span').text(dados.Curtidas);
+}
+
+function avisarNovaPublicacao(evento) {
+    const aviso = $('#novas-publicacoes');
+    if (!aviso.length) {
+        return;
+    }
+
+    const dados = JSON.parse(evento.data);
+    if ($(`[data-publicacao-id="${dados.ID}"]`).length) {
+        return;
+    }
+
+    novasPublicacoes++;
+    aviso.text(novasPublicacoes === 1 ? '1 nova publicação, clique para ver' : `${novasPublicacoes} novas publicações, clique para ver`);
+    aviso.removeClass('d-none');
+}
+
+$('#novas-publicacoes').on('click', function() {
+    window.location = "/web/home" + window.location.search;
+});
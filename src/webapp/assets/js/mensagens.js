@@ -0,0 +1,137 @@
+$(document).ready(function() {
+    if ($('#mensagens-nao-lidas').length) {
+        atualizarContadorMensagens();
+    }
+    if ($('#mensagens').length) {
+        marcarConversaLida();
+    }
+});
+
+$('#nova-mensagem').on('submit', enviarMensagem);
+$(document).on('click', '.editar-mensagem', editarMensagem);
+$(document).on('click', '.excluir-mensagem', excluirMensagem);
+$('#enviar-mensagem').on('click', abrirConversa);
+
+function atualizarContadorMensagens() {
+    $.ajax({
+        url: "/web/mensagens/nao-lidas",
+        method: "GET"
+    }).done(function(resposta) {
+        const contador = $('#mensagens-nao-lidas');
+        if (resposta && resposta.NaoLidas > 0) {
+            contador.text(resposta.NaoLidas > 99 ? '99+' : resposta.NaoLidas);
+            contador.removeClass('d-none');
+        } else {
+            contador.addClass('d-none');
+        }
+    });
+}
+
+function conversaAtual() {
+    return $('#mensagens').data('conversa-id');
+}
+
+function marcarConversaLida() {
+    $.ajax({
+        url: `/web/mensagens/${conversaAtual()}/lida`,
+        method: "PUT"
+    }).done(atualizarContadorMensagens);
+}
+
+// Chamado pelo stream de eventos quando chega uma mensagem nova
+function receberMensagem(evento) {
+    const dados = JSON.parse(evento.data);
+    if ($('#mensagens').length && dados.ConversaID == conversaAtual()) {
+        window.location = `/web/mensagens/${dados.ConversaID}`;
+        return;
+    }
+    atualizarContadorMensagens();
+}
+
+function enviarMensagem(evento) {
+    evento.preventDefault();
+    const botao = $(this).find('button');
+    botao.prop('disabled', true);
+
+    $.ajax({
+        url: `/web/mensagens/${conversaAtual()}`,
+        method: "POST",
+        contentType: "application/json",
+        data: JSON.stringify({ Conteudo: $('#conteudo-mensagem').val() })
+    }).done(function() {
+        window.location = `/web/mensagens/${conversaAtual()}`;
+    }).fail(function(erro) {
+        const mensagem = erro.responseJSON && erro.responseJSON.err ? erro.responseJSON.err : "Erro ao enviar a mensagem!";
+        Swal.fire("Ops...", mensagem, "error");
+        botao.prop('disabled', false);
+    });
+}
+
+function editarMensagem(evento) {
+    const elemento = $(evento.target).closest('.mensagem');
+    const conteudoAtual = elemento.find('.conteudo-mensagem').text();
+
+    Swal.fire({
+        title: "Editar mensagem",
+        input: "text",
+        inputValue: conteudoAtual,
+        showCancelButton: true,
+        cancelButtonText: "Cancelar"
+    }).then(function(resultado) {
+        if (!resultado.value) {
+            return;
+        }
+        $.ajax({
+            url: `/web/mensagem/${elemento.data('mensagem-id')}`,
+            method: "PUT",
+            contentType: "application/json",
+            data: JSON.stringify({ Conteudo: resultado.value })
+        }).done(function() {
+            window.location = `/web/mensagens/${conversaAtual()}`;
+        }).fail(function() {
+            Swal.fire("Ops...", "Erro ao editar a mensagem!", "error");
+        });
+    });
+}
+
+function excluirMensagem(evento) {
+    const elemento = $(evento.target).closest('.mensagem');
+
+    Swal.fire({
+        title: "Atenção",
+        text: "Tem certeza que deseja excluir essa mensagem?",
+        showCancelButton: true,
+        cancelButtonText: "Cancelar",
+        icon: "warning"
+    }).then(function(confirmacao) {
+        if (!confirmacao.value) {
+            return;
+        }
+        $.ajax({
+            url: `/web/mensagem/${elemento.data('mensagem-id')}`,
+            method: "DELETE"
+        }).done(function() {
+            window.location = `/web/mensagens/${conversaAtual()}`;
+        }).fail(function() {
+            Swal.fire("Ops...", "Erro ao excluir a mensagem!", "error");
+        });
+    });
+}
+
+function abrirConversa() {
+    const usuarioId = $(this).data('usuario-id');
+    $(this).prop('disabled', true);
+
+    $.ajax({
+        url: "/web/mensagens",
+        method: "POST",
+        contentType: "application/json",
+        data: JSON.stringify({ Participantes: [usuarioId] })
+    }).done(function(conversa) {
+        window.location = `/web/mensagens/${conversa.ID}`;
+    }).fail(function(erro) {
+        const mensagem = erro.responseJSON && erro.responseJSON.err ? erro.responseJSON.err : "Erro ao abrir a conversa!";
+        Swal.fire("Ops...", mensagem, "error");
+        $('#enviar-mensagem').prop('disabled', false);
+    });
+}
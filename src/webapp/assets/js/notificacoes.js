@@ -0,0 +1,72 @@
+$(document).ready(function() {
+    if ($('#notificacoes-nao-lidas').length) {
+        atualizarContadorNotificacoes();
+    }
+});
+
+$('#marcar-todas-lidas').on('click', marcarTodasLidas);
+$(document).on('click', '.notificacao.list-group-item-primary', marcarLida);
+$('#preferencias-notificacao').on('submit', salvarPreferencias);
+
+function atualizarContadorNotificacoes() {
+    $.ajax({
+        url: "/web/notificacoes/nao-lidas",
+        method: "GET"
+    }).done(function(resposta) {
+        const contador = $('#notificacoes-nao-lidas');
+        if (resposta && resposta.NaoLidas > 0) {
+            contador.text(resposta.NaoLidas > 99 ? '99+' : resposta.NaoLidas);
+            contador.removeClass('d-none');
+        } else {
+            contador.addClass('d-none');
+        }
+    });
+}
+
+function marcarLida(evento) {
+    const elemento = $(evento.currentTarget);
+    const ids = String(elemento.data('ids')).split(',').map(Number);
+
+    $.ajax({
+        url: "/web/notificacoes/lidas",
+        method: "PUT",
+        contentType: "application/json",
+        data: JSON.stringify({ IDs: ids })
+    }).done(function() {
+        elemento.removeClass('list-group-item-primary');
+        atualizarContadorNotificacoes();
+    });
+}
+
+function marcarTodasLidas() {
+    $.ajax({
+        url: "/web/notificacoes/lidas/todas",
+        method: "PUT"
+    }).done(function() {
+        $('.notificacao').removeClass('list-group-item-primary');
+        atualizarContadorNotificacoes();
+    }).fail(function() {
+        Swal.fire('Ops...', 'Erro ao marcar as notificações como lidas!', 'error');
+    });
+}
+
+function salvarPreferencias(evento) {
+    evento.preventDefault();
+
+    $.ajax({
+        url: "/web/notificacoes/preferencias",
+        method: "PUT",
+        contentType: "application/json",
+        data: JSON.stringify({
+            seguidor: $('#pref-seguidor').is(':checked'),
+            curtida: $('#pref-curtida').is(':checked'),
+            mencao: $('#pref-mencao').is(':checked'),
+            solicitacao: $('#pref-solicitacao').is(':checked'),
+            aprovacao: $('#pref-aprovacao').is(':checked')
+        })
+    }).done(function() {
+        Swal.fire('Sucesso!', 'Preferências salvas!', 'success');
+    }).fail(function() {
+        Swal.fire('Ops...', 'Erro ao salvar as preferências!', 'error');
+    });
+}
@@ -1,25 +1,112 @@
 $('#nova-publicacao').on('submit', criarPublicacao);
+$('#salvar-rascunho').on('click', salvarRascunho);
+$('.publicar-rascunho').on('click', publicarRascunho);
+$('.agendar-rascunho').on('click', agendarRascunho);
+$('.cancelar-agendamento').on('click', cancelarAgendamento);
 
 $(document).on('click', '.curtir-publicacao', curtirPublicacao);
 $(document).on('click', '.descurtir-publicacao', descurtirPublicacao);
 
 $('#atualizar-publicacao').on('click', atualizarPublicacao);
 $('.deletar-publicacao').on('click', deletarPublicacao);
+$(document).on('click', '.compartilhar-publicacao', compartilharPublicacao);
+$(document).on('click', '.citar-publicacao', citarPublicacao);
+
+// O campo datetime-local usa "2006-01-02T15:04", a API espera "2006-01-02 15:04:05"
+function formatarAgendamento(valor) {
+    return valor ? valor.replace('T', ' ') + ':00' : '';
+}
+
+function salvarRascunho(evento) {
+    enviarPublicacao(evento, 'rascunho');
+}
 
 function criarPublicacao(evento) {
+    enviarPublicacao(evento, $('#data-agendamento').val() ? 'agendada' : 'publicada');
+}
+
+function enviarPublicacao(evento, status) {
     evento.preventDefault();
 
+    const dados = new FormData();
+    dados.append('titulo', $('#titulo').val());
+    dados.append('conteudo', $('#conteudo').val());
+    dados.append('status', status);
+    dados.append('visibilidade', $('#visibilidade').val());
+    if ($('#estabelecimento-id').length) {
+        dados.append('estabelecimento', $('#estabelecimento-id').val());
+    }
+    if (status == 'agendada') {
+        dados.append('dataAgendamento', formatarAgendamento($('#data-agendamento').val()));
+    }
+
+    const anexos = $('#anexos')[0] ? $('#anexos')[0].files : [];
+    for (let i = 0; i < anexos.length; i++) {
+        dados.append('anexos', anexos[i]);
+    }
+
     $.ajax({
         url: "/web/publicacoes",
         method: "POST",
-        data: {
-            titulo: $('#titulo').val(),
-            conteudo: $('#conteudo').val(),
+        data: dados,
+        processData: false,
+        contentType: false
+    }).done(function() {
+        if (status == 'publicada' && $('#estabelecimento-id').length) {
+            window.location.reload();
+            return;
         }
+        window.location = status == 'publicada' ? "/web/home" : "/web/rascunhos";
+    }).fail(function() {
+        alert("Erro ao criar a publicação!");
+    });
+}
+
+function publicarRascunho(evento) {
+    const publicacaoId = $(evento.target).closest('div').data('publicacao-id');
+
+    $.ajax({
+        url: `/web/publicacoes/${publicacaoId}/publicar`,
+        method: "POST"
     }).done(function() {
         window.location = "/web/home";
     }).fail(function() {
-        alert("Erro ao criar a publicação!");
+        Swal.fire('Ops...', 'Erro ao publicar! Confira se o titulo e o conteudo estão preenchidos.', 'error');
+    });
+}
+
+function agendarRascunho(evento) {
+    const publicacao = $(evento.target).closest('div');
+    const publicacaoId = publicacao.data('publicacao-id');
+    const dataAgendamento = formatarAgendamento(publicacao.find('.data-agendamento').val());
+
+    if (!dataAgendamento) {
+        Swal.fire('Ops...', 'Informe a data e a hora do agendamento!', 'error');
+        return;
+    }
+
+    $.ajax({
+        url: `/web/publicacoes/${publicacaoId}/agendar`,
+        method: "PUT",
+        contentType: "application/json",
+        data: JSON.stringify({ DataAgendamento: dataAgendamento })
+    }).done(function() {
+        window.location = "/web/rascunhos";
+    }).fail(function() {
+        Swal.fire('Ops...', 'Erro ao agendar! A data precisa estar no futuro.', 'error');
+    });
+}
+
+function cancelarAgendamento(evento) {
+    const publicacaoId = $(evento.target).closest('div').data('publicacao-id');
+
+    $.ajax({
+        url: `/web/publicacoes/${publicacaoId}/agendar`,
+        method: "DELETE"
+    }).done(function() {
+        window.location = "/web/rascunhos";
+    }).fail(function() {
+        Swal.fire('Ops...', 'Erro ao cancelar o agendamento!', 'error');
     });
 }
 
@@ -153,4 +240,68 @@ function deletarPublicacao(evento) {
         });
     })
 
-}
\ No newline at end of file
+}
+
+function compartilharPublicacao(evento) {
+    const publicacaoId = $(evento.target).data('original-id');
+
+    $.ajax({
+        url: `/web/publicacoes/${publicacaoId}/compartilhar`,
+        method: "POST",
+        contentType: "application/json",
+        data: JSON.stringify({})
+    }).done(function() {
+        Swal.fire('Sucesso!', 'Publicação compartilhada!', 'success')
+            .then(function() {
+                window.location = "/web/home";
+            });
+    }).fail(function() {
+        desfazerCompartilhamento(publicacaoId);
+    });
+}
+
+function desfazerCompartilhamento(publicacaoId) {
+    Swal.fire({
+        title: "Compartilhamento",
+        text: "Não foi possível compartilhar. Se você já compartilhou essa publicação, deseja desfazer o compartilhamento?",
+        showCancelButton: true,
+        cancelButtonText: "Cancelar",
+        icon: "question"
+    }).then(function(confirmacao) {
+        if (!confirmacao.value) return;
+
+        $.ajax({
+            url: `/web/publicacoes/${publicacaoId}/compartilhar`,
+            method: "DELETE"
+        }).done(function() {
+            window.location = "/web/home";
+        }).fail(function() {
+            Swal.fire('Ops...', 'Erro ao desfazer o compartilhamento!', 'error');
+        });
+    });
+}
+
+function citarPublicacao(evento) {
+    const publicacaoId = $(evento.target).data('original-id');
+
+    Swal.fire({
+        title: "Citar publicação",
+        input: "textarea",
+        inputPlaceholder: "Escreva seu comentário",
+        showCancelButton: true,
+        cancelButtonText: "Cancelar"
+    }).then(function(resultado) {
+        if (!resultado.value) return;
+
+        $.ajax({
+            url: `/web/publicacoes/${publicacaoId}/compartilhar`,
+            method: "POST",
+            contentType: "application/json",
+            data: JSON.stringify({ Comentario: resultado.value })
+        }).done(function() {
+            window.location = "/web/home";
+        }).fail(function() {
+            Swal.fire('Ops...', 'Erro ao citar a publicação!', 'error');
+        });
+    });
+}
@@ -1,8 +1,16 @@
 $('#parar-de-seguir').on('click', paraDeSeguir);
 $('#seguir').on('click', seguir);
+$('#cancelar-solicitacao').on('click', cancelarSolicitacao);
+$('.seguir-sugestao').on('click', seguirSugestao);
+$('.aprovar-solicitacao').on('click', function() { responderSolicitacao(this, 'aprovar', 'PUT'); });
+$('.recusar-solicitacao').on('click', function() { responderSolicitacao(this, 'recusar', 'DELETE'); });
+$('#bloquear').on('click', bloquear);
+$('#desbloquear').on('click', desbloquear);
 $('#editar-usuario').on('submit', editar);
 $('#atualizar-senha').on('submit', atualizarSenha);
 $('#deletar-usuario').on('click', deletarUsuario);
+$('#avatar').on('change', function() { enviarImagem(this, 'avatar'); });
+$('#banner').on('change', function() { enviarImagem(this, 'banner'); });
 
 function paraDeSeguir(){
     const usuarioId = $(this).data('usuario-id');
@@ -26,7 +34,14 @@ function seguir(){
     $.ajax({
         url: `/web/usuario/${usuarioId}/seguir`,
         method: "POST"
-    }).done(function(){
+    }).done(function(_, __, xhr){
+        if (xhr.status == 202) {
+            Swal.fire("Solicitação enviada", "A conta é privada. Você passará a seguir quando o pedido for aprovado.", "info")
+                .then(function(){
+                    window.location = `/web/usuario/${usuarioId}`;
+                });
+            return;
+        }
         window.location = `/web/usuario/${usuarioId}`;
     }).fail(function(){
         Swal.fire("Ops...","Erro ao seguir o usuario!","error");
@@ -34,6 +49,95 @@ function seguir(){
     });
 }
 
+function seguirSugestao(){
+    const sugestao = $(this).closest('div[data-usuario-id]');
+    const usuarioId = sugestao.data('usuario-id');
+    const botao = $(this);
+    botao.prop('disabled', true);
+
+    $.ajax({
+        url: `/web/usuario/${usuarioId}/seguir`,
+        method: "POST"
+    }).done(function(_, __, xhr){
+        botao.text(xhr.status == 202 ? "Solicitação enviada" : "Seguindo");
+    }).fail(function(){
+        Swal.fire("Ops...","Erro ao seguir o usuario!","error");
+        botao.prop('disabled', false);
+    });
+}
+
+function cancelarSolicitacao(){
+    const usuarioId = $(this).data('usuario-id');
+    $(this).prop('disabled',true);
+
+    $.ajax({
+        url: `/web/usuario/${usuarioId}/solicitacao`,
+        method: "DELETE"
+    }).done(function(){
+        window.location = `/web/usuario/${usuarioId}`;
+    }).fail(function(){
+        Swal.fire("Ops...","Erro ao cancelar a solicitação!","error");
+        $('#cancelar-solicitacao').prop('disabled', false);
+    });
+}
+
+function responderSolicitacao(botao, acao, metodo){
+    const solicitacao = $(botao).closest('div');
+    const usuarioId = solicitacao.data('usuario-id');
+    solicitacao.find('button').prop('disabled', true);
+
+    $.ajax({
+        url: `/web/solicitacoes/${usuarioId}/${acao}`,
+        method: metodo
+    }).done(function(){
+        solicitacao.fadeOut("slow", function(){
+            $(this).remove();
+        });
+    }).fail(function(){
+        Swal.fire("Ops...","Erro ao responder a solicitação!","error");
+        solicitacao.find('button').prop('disabled', false);
+    });
+}
+
+function bloquear(){
+    const usuarioId = $(this).data('usuario-id');
+
+    Swal.fire({
+        title: "Atenção",
+        text: "Ao bloquear, vocês deixam de se seguir e não podem mais trocar mensagens. Deseja continuar?",
+        showCancelButton: true,
+        cancelButtonText: "Cancelar",
+        icon: "warning"
+    }).then(function(confirmacao){
+        if (!confirmacao.value) {
+            return;
+        }
+        $.ajax({
+            url: `/web/usuario/${usuarioId}/bloquear`,
+            method: "POST"
+        }).done(function(){
+            window.location = `/web/usuario/${usuarioId}`;
+        }).fail(function(){
+            Swal.fire("Ops...","Erro ao bloquear o usuario!","error");
+        });
+    });
+}
+
+function desbloquear(){
+    const usuarioId = $(this).data('usuario-id');
+    $(this).prop('disabled',true);
+
+    $.ajax({
+        url: `/web/usuario/${usuarioId}/desbloquear`,
+        method: "POST"
+    }).done(function(){
+        window.location = `/web/usuario/${usuarioId}`;
+    }).fail(function(){
+        Swal.fire("Ops...","Erro ao desbloquear o usuario!","error");
+        $('#desbloquear').prop('disabled', false);
+    });
+}
+
 function editar(evento){
     evento.preventDefault();
 
@@ -44,6 +148,11 @@ function editar(evento){
             nome: $('#nome').val(),
             email: $('#email').val(),
             nick: $('#nick').val(),
+            bio: $('#bio').val(),
+            localizacao: $('#localizacao').val(),
+            website: $('#website').val(),
+            datanascimento: $('#datanascimento').val(),
+            visibilidade: $('#visibilidade').val(),
         }
     }).done(function() {
         Swal.fire("Sucesso!", "Usuario atualizado com sucesso!", "success")
@@ -55,6 +164,27 @@ function editar(evento){
     });
 }
 
+function enviarImagem(campo, tipo){
+    const arquivo = campo.files[0];
+    if (!arquivo) return;
+
+    const leitor = new FileReader();
+    leitor.onload = function() {
+        $.ajax({
+            url: `/web/perfil/${tipo}`,
+            method: "POST",
+            data: {
+                imagem: leitor.result
+            }
+        }).done(function(){
+            window.location = "/web/perfil";
+        }).fail(function(){
+            Swal.fire("Ops...", "Erro ao enviar a imagem!", "error");
+        });
+    };
+    leitor.readAsDataURL(arquivo);
+}
+
 function atualizarSenha(evento){
     evento.preventDefault();
 
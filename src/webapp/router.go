@@ -39,7 +39,12 @@ func main() {
 	r.HandleFunc("/usuario/{idusuario}", middlewares.Logger(middlewares.Autenticar(rest.CarregarPerfilUsuarioHandler)))
 	r.HandleFunc("/usuario/{idusuario}/parar-de-seguir", middlewares.Logger(middlewares.Autenticar(rest.PararDeSeguirHandler)))
 	r.HandleFunc("/usuario/{idusuario}/seguir", middlewares.Logger(middlewares.Autenticar(rest.SeguirHandler)))
+	r.HandleFunc("/usuario/{idusuario}/solicitacao", middlewares.Logger(middlewares.Autenticar(rest.CancelarSolicitacaoHandler)))
+	r.HandleFunc("/usuario/{idusuario}/bloquear", middlewares.Logger(middlewares.Autenticar(rest.BloquearHandler)))
+	r.HandleFunc("/usuario/{idusuario}/desbloquear", middlewares.Logger(middlewares.Autenticar(rest.DesbloquearHandler)))
 	r.HandleFunc("/perfil", middlewares.Logger(middlewares.Autenticar(rest.CarregarPerfilUsuarioLogadoHandler)))
+	r.HandleFunc("/perfil/avatar", middlewares.Logger(middlewares.Autenticar(rest.AvatarHandler)))
+	r.HandleFunc("/perfil/banner", middlewares.Logger(middlewares.Autenticar(rest.BannerHandler)))
 	r.HandleFunc("/editar-usuario", middlewares.Logger(middlewares.Autenticar(rest.PagEdicaoHandler)))
 	r.HandleFunc("/atualizar-senha", middlewares.Logger(middlewares.Autenticar(rest.PagAttSenhaHandler)))
 	r.HandleFunc("/deletar-usuario", middlewares.Logger(middlewares.Autenticar(rest.DeletarUsuario)))
@@ -48,13 +53,50 @@ func main() {
 	r.HandleFunc("/home", middlewares.Logger(middlewares.Autenticar(rest.HomeHandler)))
 
 	//Publicacoes
+	r.HandleFunc("/solicitacoes", middlewares.Logger(middlewares.Autenticar(rest.PaginaSolicitacoesHandler)))
+	r.HandleFunc("/solicitacoes/{idusuario}/aprovar", middlewares.Logger(middlewares.Autenticar(rest.AprovarSolicitacaoHandler)))
+	r.HandleFunc("/solicitacoes/{idusuario}/recusar", middlewares.Logger(middlewares.Autenticar(rest.RecusarSolicitacaoHandler)))
+	r.HandleFunc("/rascunhos", middlewares.Logger(middlewares.Autenticar(rest.PaginaRascunhosHandler)))
 	r.HandleFunc("/publicacoes", middlewares.Logger(middlewares.Autenticar(rest.PublicacaoHandler)))
 	r.HandleFunc("/publicacoes/{publicacaoId}/curtir", middlewares.Logger(middlewares.Autenticar(rest.CurtirPublicHandler)))
 	r.HandleFunc("/publicacoes/{publicacaoId}/descurtir", middlewares.Logger(middlewares.Autenticar(rest.DescurtirPublicHandler)))
+	r.HandleFunc("/publicacoes/{publicacaoId}/compartilhar", middlewares.Logger(middlewares.Autenticar(rest.CompartilharPublicHandler)))
+	r.HandleFunc("/publicacoes/{publicacaoId}/publicar", middlewares.Logger(middlewares.Autenticar(rest.PublicarRascunhoHandler)))
+	r.HandleFunc("/publicacoes/{publicacaoId}/agendar", middlewares.Logger(middlewares.Autenticar(rest.AgendarPublicHandler)))
 	r.HandleFunc("/publicacoes/{publicacaoId}/editar", middlewares.Logger(middlewares.Autenticar(rest.PaginaEditPublicHandler)))
+	r.HandleFunc("/publicacoes/{publicacaoId}/revisoes", middlewares.Logger(middlewares.Autenticar(rest.PaginaRevisoesPublicHandler)))
 	r.HandleFunc("/publicacoes/{publicacaoId}", middlewares.Logger(middlewares.Autenticar(rest.AtualizaPublicHandler)))
 	r.HandleFunc("/publicacoes/{publicacaoId}/deletar", middlewares.Logger(middlewares.Autenticar(rest.ExcluiPublicHandler)))
 
+	//Denuncias
+	r.HandleFunc("/denuncias", middlewares.Logger(middlewares.Autenticar(rest.DenunciasHandler)))
+	r.HandleFunc("/denuncias/motivos", middlewares.Logger(middlewares.Autenticar(rest.MotivosDenunciaHandler)))
+
+	//Notificações
+	r.HandleFunc("/notificacoes", middlewares.Logger(middlewares.Autenticar(rest.NotificacoesHandler)))
+	r.HandleFunc("/notificacoes/nao-lidas", middlewares.Logger(middlewares.Autenticar(rest.NotificacoesNaoLidasHandler)))
+	r.HandleFunc("/notificacoes/lidas", middlewares.Logger(middlewares.Autenticar(rest.MarcarNotificacoesLidasHandler)))
+	r.HandleFunc("/notificacoes/lidas/todas", middlewares.Logger(middlewares.Autenticar(rest.MarcarTodasNotificacoesLidasHandler)))
+	r.HandleFunc("/notificacoes/preferencias", middlewares.Logger(middlewares.Autenticar(rest.PreferenciasNotificacaoHandler)))
+
+	//Mensagens diretas
+	r.HandleFunc("/mensagens", middlewares.Logger(middlewares.Autenticar(rest.MensagensHandler)))
+	r.HandleFunc("/mensagens/nao-lidas", middlewares.Logger(middlewares.Autenticar(rest.MensagensNaoLidasHandler)))
+	r.HandleFunc("/mensagens/{idconversa}", middlewares.Logger(middlewares.Autenticar(rest.ConversaHandler)))
+	r.HandleFunc("/mensagens/{idconversa}/lida", middlewares.Logger(middlewares.Autenticar(rest.ConversaLidaHandler)))
+	r.HandleFunc("/mensagem/{idmensagem}", middlewares.Logger(middlewares.Autenticar(rest.MensagemHandler)))
+
+	//Eventos em tempo real
+	r.HandleFunc("/eventos", middlewares.Logger(middlewares.Autenticar(rest.EventosHandler)))
+
+	//Hashtags
+	r.HandleFunc("/hashtag/{tag}", middlewares.Logger(middlewares.Autenticar(rest.CarregarPagHashtagHandler)))
+
+	//Estabelecimentos
+	r.HandleFunc("/estabelecimento/{idestabelecimento}", middlewares.Logger(middlewares.Autenticar(rest.CarregarPagEstabelecimentoHandler)))
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/avaliacao", middlewares.Logger(middlewares.Autenticar(rest.AvaliacaoEstabelecimentoHandler)))
+	r.HandleFunc("/estabelecimento/{idestabelecimento}/checkin", middlewares.Logger(middlewares.Autenticar(rest.CheckinEstabelecimentoHandler)))
+
 	http.Handle("/", router)
 
 	fmt.Printf("Escutando na porta %d\n", config.Porta)
@@ -1,16 +1,130 @@
 package modelos
 
 import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
 	"webapp/src/utils"
 )
 
-//Representa uma publicação feita por um usuario
+// Mesmas regras usadas pela API para reconhecer hashtags e menções no conteudo
+var regexMarcacao = regexp.MustCompile(`(^|[^\p{L}\p{N}_#&/@.])([#@])([\p{L}\p{N}_.]+)`)
+
+//Representa uma publicação feita por um usuario. Com Original preenchido é um compartilhamento.
 type Publicacao struct {
-	ID          int64
-	Titulo      string
-	Conteudo    string
-	AutorID     int64
-	AutorNick   string
-	Curtidas    int64
-	DataCriacao utils.JsonSpecialDateTime
+	ID                  int64
+	Titulo              string
+	Conteudo            string
+	AutorID             int64
+	AutorNick           string
+	Curtidas            int64
+	Anexos              []Anexo
+	Hashtags            []string
+	Mencoes             []Mencao
+	OriginalID          int64
+	Original            *Publicacao
+	Compartilhamentos   int64
+	Comentarios         int64
+	Revisoes            int64
+	Editada             bool
+	Status              string
+	Visibilidade        string
+	EstabelecimentoID   int64
+	EstabelecimentoNome string
+	DataAgendamento     utils.JsonSpecialDateTime
+	DataCriacao         utils.JsonSpecialDateTime
+	DataAtualizacao     utils.JsonSpecialDateTime
+}
+
+//Representa o texto que uma publicação tinha antes de uma edição
+type Revisao struct {
+	PublicacaoID int64
+	Versao       int64
+	Titulo       string
+	Conteudo     string
+	DataCriacao  utils.JsonSpecialDateTime
+}
+
+//Representa uma imagem anexada a uma publicação
+type Anexo struct {
+	URL          string
+	MiniaturaURL string
+	Largura      int64
+	Altura       int64
+}
+
+//Representa um usuario citado com @nick na publicação
+type Mencao struct {
+	UsuarioID int64
+	Nick      string
+}
+
+//Diz se a publicação está agendada para ser publicada depois
+func (publicacao Publicacao) Agendada() bool {
+	return publicacao.Status == "agendada"
+}
+
+//Diz se a publicação está restrita aos seguidores ou somente ao autor
+func (publicacao Publicacao) Restrita() bool {
+	return publicacao.Visibilidade != "" && publicacao.Visibilidade != "publica"
+}
+
+//Retorna o conteudo escapado com as hashtags e menções reconhecidas pela API transformadas em links
+func (publicacao Publicacao) ConteudoFormatado() template.HTML {
+	var html strings.Builder
+	ultimo := 0
+
+	for _, indices := range regexMarcacao.FindAllStringSubmatchIndex(publicacao.Conteudo, -1) {
+		inicio, fim := indices[4], indices[7]
+		simbolo := publicacao.Conteudo[indices[4]:indices[5]]
+		termo := publicacao.Conteudo[indices[6]:indices[7]]
+
+		var link string
+		if simbolo == "#" {
+			// Hashtags não aceitam ponto, então a tag termina no primeiro que aparecer
+			if ponto := strings.IndexByte(termo, '.'); ponto >= 0 {
+				termo = termo[:ponto]
+			}
+			if tag := strings.ToLower(termo); termo != "" && publicacao.temHashtag(tag) {
+				fim = indices[6] + len(termo)
+				link = fmt.Sprintf(`<a href="/web/hashtag/%s">%s</a>`, url.PathEscape(tag), template.HTMLEscapeString(publicacao.Conteudo[inicio:fim]))
+			}
+		} else {
+			nick := strings.TrimRight(termo, ".")
+			if usuarioID := publicacao.usuarioMencionado(nick); usuarioID != 0 {
+				fim = indices[6] + len(nick)
+				link = fmt.Sprintf(`<a href="/web/usuario/%d">%s</a>`, usuarioID, template.HTMLEscapeString(publicacao.Conteudo[inicio:fim]))
+			}
+		}
+
+		if link == "" {
+			continue
+		}
+		html.WriteString(template.HTMLEscapeString(publicacao.Conteudo[ultimo:inicio]))
+		html.WriteString(link)
+		ultimo = fim
+	}
+	html.WriteString(template.HTMLEscapeString(publicacao.Conteudo[ultimo:]))
+
+	return template.HTML(html.String())
+}
+
+func (publicacao Publicacao) temHashtag(tag string) bool {
+	for _, h := range publicacao.Hashtags {
+		if h == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (publicacao Publicacao) usuarioMencionado(nick string) int64 {
+	for _, m := range publicacao.Mencoes {
+		if m.Nick == nick {
+			return m.UsuarioID
+		}
+	}
+	return 0
 }
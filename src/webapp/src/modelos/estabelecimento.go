@@ -0,0 +1,157 @@
+package modelos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"webapp/src/config"
+	"webapp/src/requisicoes"
+)
+
+//Representa o endereço de um estabelecimento
+type EnderecoEstabelecimento struct {
+	CEP         string
+	Numero      string
+	Logradouro  string
+	Bairro      string
+	Municipio   string
+	UF          string
+	Complemento string
+}
+
+//Representa a pagina publica de um estabelecimento
+type Estabelecimento struct {
+	ID              int64
+	Nome            string
+	Setor           int64
+	Telefone        string
+	Endereco        EnderecoEstabelecimento
+	AbertoAgora     bool
+	NotaMedia       float64
+	TotalAvaliacoes int64
+	Checkins        int64
+	Avaliacoes      []Avaliacao
+	UltimosCheckins []Checkin
+	Publicacoes     []Publicacao
+}
+
+//Nome do setor para exibição
+func (estabelecimento Estabelecimento) NomeSetor() string {
+	switch estabelecimento.Setor {
+	case 1:
+		return "Bar"
+	case 2:
+		return "Restaurante"
+	case 3:
+		return "Lanchonete"
+	}
+	return "Outros"
+}
+
+//Representa a nota e o comentario de um usuario sobre um estabelecimento
+type Avaliacao struct {
+	EstabelecimentoID int64
+	UsuarioID         int64
+	UsuarioNick       string
+	Nota              int64
+	Texto             string
+	DataAtualizacao   time.Time
+}
+
+//Estrelas preenchidas e vazias para exibir a nota
+func (avaliacao Avaliacao) Estrelas() []bool {
+	estrelas := make([]bool, 5)
+	for i := range estrelas {
+		estrelas[i] = int64(i) < avaliacao.Nota
+	}
+	return estrelas
+}
+
+//Representa a visita de um usuario a um estabelecimento
+type Checkin struct {
+	UsuarioID   int64
+	UsuarioNick string
+	Data        time.Time
+}
+
+// Faz 4 requisições na API para montar a pagina do estabelecimento
+func BuscarEstabelecimentoCompleto(estabelecimentoID int64, r *http.Request) (Estabelecimento, error) {
+	canalPerfil := make(chan *Estabelecimento)
+	canalAvaliacoes := make(chan []Avaliacao)
+	canalCheckins := make(chan []Checkin)
+	canalPublicacoes := make(chan []Publicacao)
+
+	go func() {
+		var perfil Estabelecimento
+		if buscarEstabelecimento(r, fmt.Sprintf("%s/estabelecimento/%d/perfil", config.ApiUrl, estabelecimentoID), &perfil) != nil {
+			canalPerfil <- nil
+			return
+		}
+		canalPerfil <- &perfil
+	}()
+	go func() {
+		avaliacoes := make([]Avaliacao, 0)
+		if buscarEstabelecimento(r, fmt.Sprintf("%s/estabelecimento/%d/avaliacoes", config.ApiUrl, estabelecimentoID), &avaliacoes) != nil {
+			avaliacoes = nil
+		}
+		canalAvaliacoes <- avaliacoes
+	}()
+	go func() {
+		checkins := make([]Checkin, 0)
+		if buscarEstabelecimento(r, fmt.Sprintf("%s/estabelecimento/%d/checkins", config.ApiUrl, estabelecimentoID), &checkins) != nil {
+			checkins = nil
+		}
+		canalCheckins <- checkins
+	}()
+	go func() {
+		publicacoes := make([]Publicacao, 0)
+		if buscarEstabelecimento(r, fmt.Sprintf("%s/estabelecimento/%d/publicacoes", config.ApiUrl, estabelecimentoID), &publicacoes) != nil {
+			publicacoes = nil
+		}
+		canalPublicacoes <- publicacoes
+	}()
+
+	var (
+		estabelecimento *Estabelecimento
+		avaliacoes      []Avaliacao
+		checkins        []Checkin
+		publicacoes     []Publicacao
+	)
+
+	for i := 0; i < 4; i++ {
+		select {
+		case estabelecimento = <-canalPerfil:
+		case avaliacoes = <-canalAvaliacoes:
+		case checkins = <-canalCheckins:
+		case publicacoes = <-canalPublicacoes:
+		}
+	}
+
+	if estabelecimento == nil {
+		return Estabelecimento{}, errors.New("Erro ao buscar o estabelecimento")
+	}
+	if avaliacoes == nil || checkins == nil || publicacoes == nil {
+		return Estabelecimento{}, errors.New("Erro ao buscar avaliações, check-ins e publicações do estabelecimento")
+	}
+
+	estabelecimento.Avaliacoes = avaliacoes
+	estabelecimento.UltimosCheckins = checkins
+	estabelecimento.Publicacoes = publicacoes
+	return *estabelecimento, nil
+}
+
+//Chama a API e decodifica a resposta em destino
+func buscarEstabelecimento(r *http.Request, url string, destino interface{}) error {
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("API respondeu com status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(destino)
+}
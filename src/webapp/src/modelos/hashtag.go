@@ -0,0 +1,35 @@
+package modelos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"webapp/src/config"
+	"webapp/src/requisicoes"
+)
+
+//Representa uma hashtag e quantas publicações a usaram recentemente
+type HashtagEmAlta struct {
+	Tag         string
+	Publicacoes int64
+}
+
+//Chama API para buscar as hashtags mais usadas nas ultimas horas
+func BuscarHashtagsEmAlta(r *http.Request) ([]HashtagEmAlta, error) {
+	url := fmt.Sprintf("%s/hashtags/em-alta", config.ApiUrl)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API respondeu com status %d", resp.StatusCode)
+	}
+
+	var hashtags []HashtagEmAlta
+	if err = json.NewDecoder(resp.Body).Decode(&hashtags); err != nil {
+		return nil, err
+	}
+	return hashtags, nil
+}
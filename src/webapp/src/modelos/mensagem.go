@@ -0,0 +1,132 @@
+package modelos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"webapp/src/config"
+	"webapp/src/requisicoes"
+	"webapp/src/utils"
+)
+
+//Representa até quando um participante leu a conversa
+type Leitura struct {
+	UsuarioID int64
+	Data      utils.JsonSpecialDateTime
+	NaoLidas  int64
+}
+
+//Representa uma conversa privada entre usuarios
+type Conversa struct {
+	ID                 int64
+	Participantes      []int64
+	Nicks              []string
+	Chave              string
+	Titulo             string
+	CriadorID          int64
+	Leituras           []Leitura
+	UltimaMensagem     string
+	DataUltimaMensagem utils.JsonSpecialDateTime
+	DataCriacao        utils.JsonSpecialDateTime
+}
+
+//Representa uma mensagem enviada em uma conversa
+type Mensagem struct {
+	ID          int64
+	ConversaID  int64
+	AutorID     int64
+	AutorNick   string
+	Conteudo    string
+	Editada     bool
+	Excluida    bool
+	LidaPor     []int64
+	DataCriacao utils.JsonSpecialDateTime
+	DataEdicao  utils.JsonSpecialDateTime
+}
+
+//Retorna o titulo da conversa ou, sem titulo, os nicks dos outros participantes
+func (conversa Conversa) Nome(usuarioID int64) string {
+	if conversa.Titulo != "" {
+		return conversa.Titulo
+	}
+
+	var nicks []string
+	for i, id := range conversa.Participantes {
+		if id != usuarioID && i < len(conversa.Nicks) {
+			nicks = append(nicks, conversa.Nicks[i])
+		}
+	}
+	return strings.Join(nicks, ", ")
+}
+
+//Retorna quantas mensagens o usuario ainda não leu na conversa
+func (conversa Conversa) NaoLidas(usuarioID int64) int64 {
+	for _, l := range conversa.Leituras {
+		if l.UsuarioID == usuarioID {
+			return l.NaoLidas
+		}
+	}
+	return 0
+}
+
+//Indica se a mensagem já foi lida por algum outro participante
+func (mensagem Mensagem) Lida() bool {
+	return len(mensagem.LidaPor) > 0
+}
+
+//Chama API para buscar as conversas do usuario logado
+func BuscarConversas(r *http.Request) ([]Conversa, error) {
+	url := fmt.Sprintf("%s/conversas", config.ApiUrl)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API respondeu com status %d", resp.StatusCode)
+	}
+
+	var conversas []Conversa
+	if err = json.NewDecoder(resp.Body).Decode(&conversas); err != nil {
+		return nil, err
+	}
+	return conversas, nil
+}
+
+//Chama API para buscar uma conversa e suas mensagens
+func BuscarConversa(conversaID int64, r *http.Request) (Conversa, []Mensagem, error) {
+	url := fmt.Sprintf("%s/conversas/%d", config.ApiUrl, conversaID)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		return Conversa{}, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return Conversa{}, nil, fmt.Errorf("API respondeu com status %d", resp.StatusCode)
+	}
+
+	var conversa Conversa
+	if err = json.NewDecoder(resp.Body).Decode(&conversa); err != nil {
+		return Conversa{}, nil, err
+	}
+
+	url = fmt.Sprintf("%s/conversas/%d/mensagens", config.ApiUrl, conversaID)
+	respMensagens, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		return Conversa{}, nil, err
+	}
+	defer respMensagens.Body.Close()
+
+	if respMensagens.StatusCode >= 400 {
+		return Conversa{}, nil, fmt.Errorf("API respondeu com status %d", respMensagens.StatusCode)
+	}
+
+	var mensagens []Mensagem
+	if err = json.NewDecoder(respMensagens.Body).Decode(&mensagens); err != nil {
+		return Conversa{}, nil, err
+	}
+	return conversa, mensagens, nil
+}
@@ -0,0 +1,23 @@
+package modelos
+
+import (
+	"webapp/src/utils"
+)
+
+//Representa o usuario que causou uma notificação
+type OrigemNotificacao struct {
+	ID   int64
+	Nick string
+}
+
+//Representa um grupo de notificações, como "joao e outras 5 pessoas curtiram sua publicação"
+type GrupoNotificacao struct {
+	IDs          []int64
+	Tipo         string
+	PublicacaoID int64
+	Origens      []OrigemNotificacao
+	Total        int
+	Lida         bool
+	DataCriacao  utils.JsonSpecialDateTime
+	Texto        string
+}
@@ -8,18 +8,26 @@ import (
 	"time"
 	"webapp/src/config"
 	"webapp/src/requisicoes"
+	"webapp/src/utils"
 )
 
 //Representa uma pessoa utilizando a rede social
 type Usuario struct {
-	ID          int64
-	Nome        string
-	Email       string
-	Nick        string
-	CriadoEm    time.Time
-	Seguidores  []Usuario
-	Seguindo    []Usuario
-	Publicacoes []Publicacao
+	ID             int64
+	Nome           string
+	Email          string
+	Nick           string
+	Bio            string
+	Localizacao    string
+	Website        string
+	DataNascimento utils.JsonSpecialDate
+	Visibilidade   string
+	AvatarURL      string
+	BannerURL      string
+	CriadoEm       time.Time
+	Seguidores     []Usuario
+	Seguindo       []Usuario
+	Publicacoes    []Publicacao
 }
 
 // Faz 4 requisições na API para maontar o perfil do usuario
@@ -97,6 +105,13 @@ func BuscarDadosUsuario(canal chan<- Usuario, usuarioID int64, r *http.Request)
 		usu.Nome = v.Nome
 		usu.Email = v.Email
 		usu.Nick = v.Nick
+		usu.Bio = v.Bio
+		usu.Localizacao = v.Localizacao
+		usu.Website = v.Website
+		usu.DataNascimento = v.DataNascimento
+		usu.Visibilidade = v.Visibilidade
+		usu.AvatarURL = v.AvatarURL
+		usu.BannerURL = v.BannerURL
 		usu.CriadoEm = v.CriadoEm
 	}
 
@@ -174,3 +189,100 @@ func BuscarPublicacoes(canal chan<- []Publicacao, usuarioID int64, r *http.Reque
 
 	canal <- publicacoes
 }
+
+//Chama API para buscar os ids dos usuarios bloqueados pelo usuario logado
+func BuscarBloqueados(r *http.Request) ([]int64, error) {
+	url := fmt.Sprintf("%s/usuario/bloqueados", config.ApiUrl)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API respondeu com status %d", resp.StatusCode)
+	}
+
+	var bloqueados []int64
+	if err = json.NewDecoder(resp.Body).Decode(&bloqueados); err != nil {
+		return nil, err
+	}
+	return bloqueados, nil
+}
+
+//Representa um pedido pendente para seguir uma conta privada
+type Solicitacao struct {
+	SeguidorID   int64
+	SeguidorNick string
+	UsuarioID    int64
+	DataCriacao  utils.JsonSpecialDateTime
+}
+
+//Chama API para buscar os pedidos pendentes para seguir o usuario logado
+func BuscarSolicitacoes(r *http.Request) ([]Solicitacao, error) {
+	url := fmt.Sprintf("%s/usuario/solicitacoes", config.ApiUrl)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API respondeu com status %d", resp.StatusCode)
+	}
+
+	var solicitacoes []Solicitacao
+	if err = json.NewDecoder(resp.Body).Decode(&solicitacoes); err != nil {
+		return nil, err
+	}
+	return solicitacoes, nil
+}
+
+//Chama API para saber se o usuario logado tem um pedido pendente para seguir o usuario
+func SolicitacaoPendente(usuarioID int64, r *http.Request) (bool, error) {
+	url := fmt.Sprintf("%s/usuario/solicitacao/%d", config.ApiUrl, usuarioID)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return false, fmt.Errorf("API respondeu com status %d", resp.StatusCode)
+	}
+
+	var solicitacao struct{ Pendente bool }
+	if err = json.NewDecoder(resp.Body).Decode(&solicitacao); err != nil {
+		return false, err
+	}
+	return solicitacao.Pendente, nil
+}
+
+//Representa uma conta sugerida para o usuario logado seguir
+type Sugestao struct {
+	UsuarioID int64
+	Nick      string
+	Nome      string
+	AvatarURL string
+	Motivo    string
+}
+
+//Chama API para buscar contas que o usuario logado talvez queira seguir
+func BuscarSugestoes(r *http.Request) ([]Sugestao, error) {
+	url := fmt.Sprintf("%s/usuario/sugestoes", config.ApiUrl)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API respondeu com status %d", resp.StatusCode)
+	}
+
+	var sugestoes []Sugestao
+	if err = json.NewDecoder(resp.Body).Decode(&sugestoes); err != nil {
+		return nil, err
+	}
+	return sugestoes, nil
+}
@@ -24,3 +24,43 @@ func FazerRequisicaoComAutenticacao(r *http.Request, metodo, url string, dados i
 
 	return response, nil
 }
+
+//Repassa o corpo para a API mantendo o Content-Type original, usado no envio de arquivos
+func FazerRequisicaoComAutenticacaoETipo(r *http.Request, metodo, url string, dados io.Reader, tipo string) (*http.Response, error) {
+	request, err := http.NewRequest(metodo, url, dados)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie, _ := cookies.Ler(r)
+	request.Header.Add("Authorization", "Bearer "+cookie["token"])
+	request.Header.Set("Content-Type", tipo)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+//Abre uma conexão de streaming com a API que é encerrada junto com a requisição do navegador
+func AbrirFluxoComAutenticacao(r *http.Request, url string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie, _ := cookies.Ler(r)
+	request.Header.Add("Authorization", "Bearer "+cookie["token"])
+	request.Header.Set("Accept", "text/event-stream")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"webapp/src/config"
+	"webapp/src/requisicoes"
+	"webapp/src/utils"
+)
+
+func DenunciasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		repassarDenuncia(w, r, http.MethodPost, "", r.Body)
+		return
+	}
+}
+
+func MotivosDenunciaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		repassarDenuncia(w, r, http.MethodGet, "/motivos", nil)
+		return
+	}
+}
+
+//Chama a API de denuncias e devolve a resposta para o navegador
+func repassarDenuncia(w http.ResponseWriter, r *http.Request, metodo, caminho string, corpo io.Reader) {
+	url := fmt.Sprintf("%s/denuncias%s", config.ApiUrl, caminho)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, metodo, url, corpo)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	var dados interface{}
+	if err = json.NewDecoder(resp.Body).Decode(&dados); err != nil {
+		utils.JSON(w, http.StatusUnprocessableEntity, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	utils.JSON(w, resp.StatusCode, dados)
+}
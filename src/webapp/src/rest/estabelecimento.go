@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"webapp/src/config"
+	"webapp/src/requisicoes"
+	"webapp/src/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func AvaliacaoEstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		repassarEstabelecimento(w, r, http.MethodPut, "/avaliacoes", r.Body)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		repassarEstabelecimento(w, r, http.MethodDelete, "/avaliacoes", nil)
+		return
+	}
+}
+
+func CheckinEstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		repassarEstabelecimento(w, r, http.MethodPost, "/checkins", nil)
+		return
+	}
+}
+
+//Chama a API do estabelecimento da rota e devolve a resposta para o navegador
+func repassarEstabelecimento(w http.ResponseWriter, r *http.Request, metodo, caminho string, corpo io.Reader) {
+	parametros := mux.Vars(r)
+	estabelecimentoID, err := strconv.ParseInt(parametros["idestabelecimento"], 10, 64)
+	if err != nil {
+		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	url := fmt.Sprintf("%s/estabelecimento/%d%s", config.ApiUrl, estabelecimentoID, caminho)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, metodo, url, corpo)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	var dados interface{}
+	if err = json.NewDecoder(resp.Body).Decode(&dados); err != nil {
+		utils.JSON(w, http.StatusUnprocessableEntity, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	utils.JSON(w, resp.StatusCode, dados)
+}
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"webapp/src/config"
+	"webapp/src/requisicoes"
+	"webapp/src/utils"
+)
+
+func EventosHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		TransmitirEventos(w, r)
+		return
+	}
+}
+
+//Repassa para o navegador o stream de eventos da API, autenticando com o token do cookie
+func TransmitirEventos(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: "Streaming não suportado"})
+		return
+	}
+
+	url := fmt.Sprintf("%s/eventos", config.ApiUrl)
+	resp, err := requisicoes.AbrirFluxoComAutenticacao(r, url)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	buffer := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buffer)
+		if n > 0 {
+			if _, errEscrita := w.Write(buffer[:n]); errEscrita != nil {
+				return
+			}
+			flusher.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
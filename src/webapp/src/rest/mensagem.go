@@ -0,0 +1,137 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"webapp/src/config"
+	"webapp/src/cookies"
+	"webapp/src/modelos"
+	"webapp/src/requisicoes"
+	"webapp/src/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func MensagensHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		CarregarPaginaMensagens(w, r)
+		return
+	}
+	if r.Method == http.MethodPost {
+		repassarMensagens(w, r, http.MethodPost, "/conversas", r.Body)
+		return
+	}
+}
+
+func MensagensNaoLidasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		repassarMensagens(w, r, http.MethodGet, "/conversas/nao-lidas", nil)
+		return
+	}
+}
+
+func ConversaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		CarregarPaginaConversa(w, r)
+		return
+	}
+	if r.Method == http.MethodPost {
+		caminho := fmt.Sprintf("/conversas/%s/mensagens", mux.Vars(r)["idconversa"])
+		repassarMensagens(w, r, http.MethodPost, caminho, r.Body)
+		return
+	}
+}
+
+func ConversaLidaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		caminho := fmt.Sprintf("/conversas/%s/lida", mux.Vars(r)["idconversa"])
+		repassarMensagens(w, r, http.MethodPut, caminho, nil)
+		return
+	}
+}
+
+func MensagemHandler(w http.ResponseWriter, r *http.Request) {
+	caminho := fmt.Sprintf("/mensagens/%s", mux.Vars(r)["idmensagem"])
+	if r.Method == http.MethodPut {
+		repassarMensagens(w, r, http.MethodPut, caminho, r.Body)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		repassarMensagens(w, r, http.MethodDelete, caminho, nil)
+		return
+	}
+}
+
+//Renderiza a caixa de entrada com as conversas do usuario
+func CarregarPaginaMensagens(w http.ResponseWriter, r *http.Request) {
+	cookie, _ := cookies.Ler(r)
+	usuarioLogadoID, _ := strconv.ParseInt(cookie["id"], 10, 64)
+
+	conversas, err := modelos.BuscarConversas(r)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	utils.ExecutarTemplate(w, "mensagens.html", struct {
+		Conversas       []modelos.Conversa
+		UsuarioLogadoID int64
+	}{
+		Conversas:       conversas,
+		UsuarioLogadoID: usuarioLogadoID,
+	})
+}
+
+//Renderiza uma conversa com as ultimas mensagens
+func CarregarPaginaConversa(w http.ResponseWriter, r *http.Request) {
+	conversaID, err := strconv.ParseInt(mux.Vars(r)["idconversa"], 10, 64)
+	if err != nil {
+		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	cookie, _ := cookies.Ler(r)
+	usuarioLogadoID, _ := strconv.ParseInt(cookie["id"], 10, 64)
+
+	conversa, mensagens, err := modelos.BuscarConversa(conversaID, r)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	utils.ExecutarTemplate(w, "conversa.html", struct {
+		Conversa        modelos.Conversa
+		Mensagens       []modelos.Mensagem
+		UsuarioLogadoID int64
+	}{
+		Conversa:        conversa,
+		Mensagens:       mensagens,
+		UsuarioLogadoID: usuarioLogadoID,
+	})
+}
+
+//Repassa a requisição para as rotas de mensagens da API e devolve a resposta em JSON
+func repassarMensagens(w http.ResponseWriter, r *http.Request, metodo, caminho string, corpo io.Reader) {
+	url := fmt.Sprintf("%s%s", config.ApiUrl, caminho)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, metodo, url, corpo)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	var dados interface{}
+	if err = json.NewDecoder(resp.Body).Decode(&dados); err != nil {
+		utils.JSON(w, http.StatusUnprocessableEntity, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	utils.JSON(w, resp.StatusCode, dados)
+}
@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"webapp/src/config"
+	"webapp/src/modelos"
+	"webapp/src/requisicoes"
+	"webapp/src/utils"
+)
+
+func NotificacoesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		CarregarPaginaNotificacoes(w, r)
+		return
+	}
+}
+
+func NotificacoesNaoLidasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		repassarNotificacoes(w, r, http.MethodGet, "nao-lidas", nil)
+		return
+	}
+}
+
+func MarcarNotificacoesLidasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		repassarNotificacoes(w, r, http.MethodPut, "lidas", r.Body)
+		return
+	}
+}
+
+func MarcarTodasNotificacoesLidasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		repassarNotificacoes(w, r, http.MethodPut, "lidas/todas", nil)
+		return
+	}
+}
+
+func PreferenciasNotificacaoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		repassarNotificacoes(w, r, http.MethodPut, "preferencias", r.Body)
+		return
+	}
+}
+
+//Renderiza a pagina com as notificações e as preferencias do usuario
+func CarregarPaginaNotificacoes(w http.ResponseWriter, r *http.Request) {
+	url := fmt.Sprintf("%s/notificacoes", config.ApiUrl)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	var grupos []modelos.GrupoNotificacao
+	if err = json.NewDecoder(resp.Body).Decode(&grupos); err != nil {
+		utils.JSON(w, http.StatusUnprocessableEntity, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	url = fmt.Sprintf("%s/notificacoes/preferencias", config.ApiUrl)
+	respPreferencias, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer respPreferencias.Body.Close()
+
+	if respPreferencias.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, respPreferencias)
+		return
+	}
+
+	var preferencias map[string]bool
+	if err = json.NewDecoder(respPreferencias.Body).Decode(&preferencias); err != nil {
+		utils.JSON(w, http.StatusUnprocessableEntity, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	utils.ExecutarTemplate(w, "notificacoes.html", struct {
+		Grupos       []modelos.GrupoNotificacao
+		Preferencias map[string]bool
+	}{
+		Grupos:       grupos,
+		Preferencias: preferencias,
+	})
+}
+
+//Repassa a requisição para a rota de notificações da API e devolve a resposta em JSON
+func repassarNotificacoes(w http.ResponseWriter, r *http.Request, metodo, caminho string, corpo io.Reader) {
+	url := fmt.Sprintf("%s/notificacoes/%s", config.ApiUrl, caminho)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, metodo, url, corpo)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	var dados interface{}
+	if err = json.NewDecoder(resp.Body).Decode(&dados); err != nil {
+		utils.JSON(w, http.StatusUnprocessableEntity, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	utils.JSON(w, resp.StatusCode, dados)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"webapp/src/config"
 	"webapp/src/cookies"
@@ -51,6 +52,27 @@ func PaginaEditPublicHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func PaginaRascunhosHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		CarregarPaginaRascunhos(w, r)
+		return
+	}
+}
+
+func PaginaSolicitacoesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		CarregarPaginaSolicitacoes(w, r)
+		return
+	}
+}
+
+func PaginaRevisoesPublicHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		CarregarPagRevisoesPublic(w, r)
+		return
+	}
+}
+
 func CarregarPagUsuarioHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
 		CarregarPaginaUsuarios(w, r)
@@ -58,6 +80,20 @@ func CarregarPagUsuarioHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func CarregarPagHashtagHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		CarregarPaginaHashtag(w, r)
+		return
+	}
+}
+
+func CarregarPagEstabelecimentoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		CarregarPaginaEstabelecimento(w, r)
+		return
+	}
+}
+
 func CarregarPerfilUsuarioHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
 		CarregarPerfilUsuario(w, r)
@@ -113,7 +149,12 @@ func CarregarTelaCadastroUsuario(w http.ResponseWriter, r *http.Request) {
 
 //Renderiza a pagina princial com as publicações
 func CarregarHome(w http.ResponseWriter, r *http.Request) {
-	url := fmt.Sprintf("%s/publicacoes", config.ApiUrl)
+	modo := r.URL.Query().Get("modo")
+	if modo != "relevancia" {
+		modo = "cronologico"
+	}
+
+	url := fmt.Sprintf("%s/publicacoes?modo=%s", config.ApiUrl, modo)
 	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
 	if err != nil {
 		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
@@ -135,15 +176,139 @@ func CarregarHome(w http.ResponseWriter, r *http.Request) {
 	cookie, _ := cookies.Ler(r)
 	usuarioID, _ := strconv.ParseInt(cookie["id"], 10, 64)
 
+	// As hashtags em alta e as sugestões são só um complemento, a home abre mesmo se a busca falhar
+	hashtags, _ := modelos.BuscarHashtagsEmAlta(r)
+	sugestoes, _ := modelos.BuscarSugestoes(r)
+
 	utils.ExecutarTemplate(w, "home.html", struct {
 		Publicacoes []modelos.Publicacao
+		EmAlta      []modelos.HashtagEmAlta
+		Sugestoes   []modelos.Sugestao
 		UsuarioID   int64
+		Modo        string
 	}{
 		Publicacoes: publicacoes,
+		EmAlta:      hashtags,
+		Sugestoes:   sugestoes,
 		UsuarioID:   usuarioID,
+		Modo:        modo,
+	})
+}
+
+//Renderiza a pagina com as publicações de uma hashtag
+func CarregarPaginaHashtag(w http.ResponseWriter, r *http.Request) {
+	tag := mux.Vars(r)["tag"]
+	url := fmt.Sprintf("%s/hashtags/%s/publicacoes", config.ApiUrl, url.PathEscape(tag))
+
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	var publicacoes []modelos.Publicacao
+	if err = json.NewDecoder(resp.Body).Decode(&publicacoes); err != nil {
+		utils.JSON(w, http.StatusUnprocessableEntity, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	cookie, _ := cookies.Ler(r)
+	usuarioID, _ := strconv.ParseInt(cookie["id"], 10, 64)
+
+	hashtags, _ := modelos.BuscarHashtagsEmAlta(r)
+
+	utils.ExecutarTemplate(w, "hashtag.html", struct {
+		Tag         string
+		Publicacoes []modelos.Publicacao
+		EmAlta      []modelos.HashtagEmAlta
+		UsuarioID   int64
+	}{
+		Tag:         tag,
+		Publicacoes: publicacoes,
+		EmAlta:      hashtags,
+		UsuarioID:   usuarioID,
+	})
+}
+
+//Renderiza a pagina publica de um estabelecimento com as avaliações e as publicações que o marcaram
+func CarregarPaginaEstabelecimento(w http.ResponseWriter, r *http.Request) {
+	parametros := mux.Vars(r)
+	estabelecimentoID, err := strconv.ParseInt(parametros["idestabelecimento"], 10, 64)
+	if err != nil {
+		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	estabelecimento, err := modelos.BuscarEstabelecimentoCompleto(estabelecimentoID, r)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	cookie, _ := cookies.Ler(r)
+	usuarioID, _ := strconv.ParseInt(cookie["id"], 10, 64)
+
+	var minhaAvaliacao *modelos.Avaliacao
+	for i := range estabelecimento.Avaliacoes {
+		if estabelecimento.Avaliacoes[i].UsuarioID == usuarioID {
+			minhaAvaliacao = &estabelecimento.Avaliacoes[i]
+		}
+	}
+
+	utils.ExecutarTemplate(w, "estabelecimento.html", struct {
+		Estabelecimento modelos.Estabelecimento
+		MinhaAvaliacao  *modelos.Avaliacao
+		Notas           []int64
+		UsuarioID       int64
+	}{
+		Estabelecimento: estabelecimento,
+		MinhaAvaliacao:  minhaAvaliacao,
+		Notas:           []int64{1, 2, 3, 4, 5},
+		UsuarioID:       usuarioID,
 	})
 }
 
+//Renderiza a pagina com os rascunhos e as publicações agendadas do usuario logado
+func CarregarPaginaRascunhos(w http.ResponseWriter, r *http.Request) {
+	url := fmt.Sprintf("%s/publicacoes/rascunhos", config.ApiUrl)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	var rascunhos []modelos.Publicacao
+	if err = json.NewDecoder(resp.Body).Decode(&rascunhos); err != nil {
+		utils.JSON(w, http.StatusUnprocessableEntity, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	utils.ExecutarTemplate(w, "rascunhos.html", rascunhos)
+}
+
+//Renderiza a pagina com os pedidos pendentes para seguir o usuario logado
+func CarregarPaginaSolicitacoes(w http.ResponseWriter, r *http.Request) {
+	solicitacoes, err := modelos.BuscarSolicitacoes(r)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	utils.ExecutarTemplate(w, "solicitacoes.html", solicitacoes)
+}
+
 //Renderiza a pagina para edição de uma publicação
 func CarregarPagEditPublic(w http.ResponseWriter, r *http.Request) {
 	parametros := mux.Vars(r)
@@ -175,10 +340,66 @@ func CarregarPagEditPublic(w http.ResponseWriter, r *http.Request) {
 	utils.ExecutarTemplate(w, "atualizar-publicacao.html", public)
 }
 
+//Renderiza o historico de edições de uma publicação
+func CarregarPagRevisoesPublic(w http.ResponseWriter, r *http.Request) {
+	parametros := mux.Vars(r)
+	publicID, err := strconv.ParseInt(parametros["publicacaoId"], 10, 64)
+	if err != nil {
+		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	url := fmt.Sprintf("%s/publicacao/%d", config.ApiUrl, publicID)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	var public modelos.Publicacao
+	if err = json.NewDecoder(resp.Body).Decode(&public); err != nil {
+		utils.JSON(w, http.StatusUnprocessableEntity, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	url = fmt.Sprintf("%s/publicacoes/%d/revisoes", config.ApiUrl, publicID)
+	respRevisoes, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer respRevisoes.Body.Close()
+
+	if respRevisoes.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, respRevisoes)
+		return
+	}
+
+	var revisoes []modelos.Revisao
+	if err = json.NewDecoder(respRevisoes.Body).Decode(&revisoes); err != nil {
+		utils.JSON(w, http.StatusUnprocessableEntity, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	utils.ExecutarTemplate(w, "revisoes-publicacao.html", struct {
+		Publicacao modelos.Publicacao
+		Revisoes   []modelos.Revisao
+	}{
+		Publicacao: public,
+		Revisoes:   revisoes,
+	})
+}
+
 //Renderiza a pagina de usuarios que atendem o filtro passado
 func CarregarPaginaUsuarios(w http.ResponseWriter, r *http.Request) {
 	nomeOuNick := r.URL.Query().Get("usuario")
-	url := fmt.Sprintf("%s/usuario/buscar?Nick=%s", config.ApiUrl, nomeOuNick)
+	url := fmt.Sprintf("%s/busca/usuarios?q=%s", config.ApiUrl, url.QueryEscape(nomeOuNick))
 
 	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodGet, url, nil)
 	if err != nil {
@@ -224,12 +445,35 @@ func CarregarPerfilUsuario(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	bloqueados, err := modelos.BuscarBloqueados(r)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	var bloqueado bool
+	for _, id := range bloqueados {
+		if id == usuarioID {
+			bloqueado = true
+		}
+	}
+
+	pendente, err := modelos.SolicitacaoPendente(usuarioID, r)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
 	utils.ExecutarTemplate(w, "usuario.html", struct {
-		Usuario         modelos.Usuario
-		UsuarioLogadoID int64
+		Usuario             modelos.Usuario
+		UsuarioLogadoID     int64
+		Bloqueado           bool
+		SolicitacaoPendente bool
 	}{
-		Usuario:         usuario,
-		UsuarioLogadoID: usuarioLogadoID,
+		Usuario:             usuario,
+		UsuarioLogadoID:     usuarioLogadoID,
+		Bloqueado:           bloqueado,
+		SolicitacaoPendente: pendente,
 	})
 }
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"webapp/src/config"
 	"webapp/src/requisicoes"
 	"webapp/src/utils"
@@ -34,6 +35,27 @@ func DescurtirPublicHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func CompartilharPublicHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost || r.Method == http.MethodDelete {
+		CompartilharPublicacao(w, r)
+		return
+	}
+}
+
+func PublicarRascunhoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		RepassarRascunho(w, r, "publicar")
+		return
+	}
+}
+
+func AgendarPublicHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut || r.Method == http.MethodDelete {
+		RepassarRascunho(w, r, "agendar")
+		return
+	}
+}
+
 func AtualizaPublicHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPut {
 		AtualizarPublicacao(w, r)
@@ -50,12 +72,25 @@ func ExcluiPublicHandler(w http.ResponseWriter, r *http.Request) {
 
 //Chama a API para cadastrar a publicação no banco de dados
 func CriarPublicacao(w http.ResponseWriter, r *http.Request) {
+	tipo := r.Header.Get("Content-Type")
+	if strings.HasPrefix(tipo, "multipart/form-data") {
+		CriarPublicacaoComAnexos(w, r, tipo)
+		return
+	}
+
 	r.ParseForm()
 
-	publicacao, err := json.Marshal(map[string]string{
-		"titulo":   r.FormValue("titulo"),
-		"conteudo": r.FormValue("conteudo"),
-	})
+	dados := map[string]string{
+		"titulo":       r.FormValue("titulo"),
+		"conteudo":     r.FormValue("conteudo"),
+		"status":       r.FormValue("status"),
+		"visibilidade": r.FormValue("visibilidade"),
+	}
+	if dataAgendamento := r.FormValue("dataAgendamento"); dataAgendamento != "" {
+		dados["dataAgendamento"] = dataAgendamento
+	}
+
+	publicacao, err := json.Marshal(dados)
 
 	if err != nil {
 		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
@@ -78,6 +113,24 @@ func CriarPublicacao(w http.ResponseWriter, r *http.Request) {
 	utils.JSON(w, response.StatusCode, nil)
 }
 
+//Repassa para a API o formulário com as imagens anexadas à publicação
+func CriarPublicacaoComAnexos(w http.ResponseWriter, r *http.Request, tipo string) {
+	url := fmt.Sprintf("%s/publicacao", config.ApiUrl)
+	response, err := requisicoes.FazerRequisicaoComAutenticacaoETipo(r, http.MethodPost, url, r.Body, tipo)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, response)
+		return
+	}
+
+	utils.JSON(w, response.StatusCode, nil)
+}
+
 //TODO: Desenvolver metodo de salvar dados do usuario que curte a publicação na API
 
 //Chama a API para curtir uma publicação
@@ -190,3 +243,53 @@ func ExcluirPublicacao(w http.ResponseWriter, r *http.Request) {
 
 	utils.JSON(w, resp.StatusCode, nil)
 }
+
+//Chama a API para compartilhar uma publicação ou, com DELETE, desfazer o compartilhamento
+func CompartilharPublicacao(w http.ResponseWriter, r *http.Request) {
+	parametros := mux.Vars(r)
+	publicacaoID, err := strconv.ParseInt(parametros["publicacaoId"], 10, 64)
+	if err != nil {
+		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	url := fmt.Sprintf("%s/publicacoes/%d/compartilhar", config.ApiUrl, publicacaoID)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, r.Method, url, r.Body)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	utils.JSON(w, resp.StatusCode, nil)
+}
+
+//Chama a API para publicar, agendar ou cancelar o agendamento de um rascunho
+func RepassarRascunho(w http.ResponseWriter, r *http.Request, acao string) {
+	parametros := mux.Vars(r)
+	publicacaoID, err := strconv.ParseInt(parametros["publicacaoId"], 10, 64)
+	if err != nil {
+		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	url := fmt.Sprintf("%s/publicacoes/%d/%s", config.ApiUrl, publicacaoID, acao)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, r.Method, url, r.Body)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	utils.JSON(w, resp.StatusCode, nil)
+}
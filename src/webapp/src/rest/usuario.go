@@ -37,6 +37,55 @@ func SeguirHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func BloquearHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		AlterarBloqueio(w, r, "bloquear")
+		return
+	}
+}
+
+func DesbloquearHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		AlterarBloqueio(w, r, "desbloquear")
+		return
+	}
+}
+
+func CancelarSolicitacaoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		AlterarSolicitacao(w, r, http.MethodDelete, "/usuario/solicitacao/%d")
+		return
+	}
+}
+
+func AprovarSolicitacaoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		AlterarSolicitacao(w, r, http.MethodPut, "/usuario/solicitacoes/%d/aprovar")
+		return
+	}
+}
+
+func RecusarSolicitacaoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		AlterarSolicitacao(w, r, http.MethodDelete, "/usuario/solicitacoes/%d/recusar")
+		return
+	}
+}
+
+func AvatarHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		AtualizarImagem(w, r, "avatar")
+		return
+	}
+}
+
+func BannerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		AtualizarImagem(w, r, "banner")
+		return
+	}
+}
+
 func DeletaUsuarioHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodDelete {
 		DeletarUsuario(w, r)
@@ -131,14 +180,71 @@ func Seguir(w http.ResponseWriter, r *http.Request) {
 	utils.JSON(w, resp.StatusCode, nil)
 }
 
+//Chama a API para bloquear ou desbloquear um usuario
+func AlterarBloqueio(w http.ResponseWriter, r *http.Request, acao string) {
+	parametros := mux.Vars(r)
+	usuarioID, err := strconv.ParseInt(parametros["idusuario"], 10, 64)
+	if err != nil {
+		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	url := fmt.Sprintf("%s/usuario/%s/%d", config.ApiUrl, acao, usuarioID)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodPut, url, nil)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	utils.JSON(w, resp.StatusCode, nil)
+}
+
+//Chama a API para cancelar, aprovar ou recusar um pedido para seguir. O caminho recebe o id do usuario da url.
+func AlterarSolicitacao(w http.ResponseWriter, r *http.Request, metodo, caminho string) {
+	parametros := mux.Vars(r)
+	usuarioID, err := strconv.ParseInt(parametros["idusuario"], 10, 64)
+	if err != nil {
+		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	url := config.ApiUrl + fmt.Sprintf(caminho, usuarioID)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, metodo, url, nil)
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	utils.JSON(w, resp.StatusCode, nil)
+}
+
 //Chama a API para editar o usuario
 func EditarUsuario(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
-	usuario, err := json.Marshal(map[string]string{
-		"nome":  r.FormValue("nome"),
-		"email": r.FormValue("email"),
-		"nick":  r.FormValue("nick"),
-	})
+	dados := map[string]string{
+		"nome":           r.FormValue("nome"),
+		"email":          r.FormValue("email"),
+		"nick":           r.FormValue("nick"),
+		"bio":            r.FormValue("bio"),
+		"localizacao":    r.FormValue("localizacao"),
+		"website":        r.FormValue("website"),
+		"visibilidade":   r.FormValue("visibilidade"),
+		"datanascimento": r.FormValue("datanascimento"),
+	}
+
+	usuario, err := json.Marshal(dados)
 
 	if err != nil {
 		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
@@ -164,6 +270,37 @@ func EditarUsuario(w http.ResponseWriter, r *http.Request) {
 	utils.JSON(w, resp.StatusCode, nil)
 }
 
+//Chama a API para atualizar o avatar ou o banner do usuario
+func AtualizarImagem(w http.ResponseWriter, r *http.Request, tipo string) {
+	r.ParseForm()
+	imagem, err := json.Marshal(map[string]string{
+		"imagem": r.FormValue("imagem"),
+	})
+
+	if err != nil {
+		utils.JSON(w, http.StatusBadRequest, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+
+	cookie, _ := cookies.Ler(r)
+	usuarioID, _ := strconv.ParseInt(cookie["id"], 10, 64)
+
+	url := fmt.Sprintf("%s/usuario/%d/%s", config.ApiUrl, usuarioID, tipo)
+	resp, err := requisicoes.FazerRequisicaoComAutenticacao(r, http.MethodPut, url, bytes.NewBuffer(imagem))
+	if err != nil {
+		utils.JSON(w, http.StatusInternalServerError, utils.ErroAPI{Erro: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		utils.TratarStatusCodeErro(w, resp)
+		return
+	}
+
+	utils.JSON(w, resp.StatusCode, nil)
+}
+
 //Chama a API para atualizar a senha do usuario
 func AtualizarSenha(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
@@ -6,6 +6,32 @@ import (
 	"time"
 )
 
+type JsonSpecialDate struct {
+	time.Time
+}
+
+func (sd *JsonSpecialDate) UnmarshalJSON(input []byte) error {
+	strInput := string(input)
+	strInput = strings.Trim(strInput, `"`)
+	if strInput == "0000-00-00" {
+		sd.Time = time.Time{}
+		return nil
+	}
+	newTime, err := time.Parse("2006-01-02", strInput)
+	if err != nil {
+		return err
+	}
+	sd.Time = newTime
+	return nil
+}
+
+func (sd *JsonSpecialDate) MarshalJSON() ([]byte, error) {
+	if sd.Time.IsZero() {
+		return []byte("\"0001-01-01\""), nil
+	}
+	return []byte(fmt.Sprintf("\"%s\"", sd.Time.Format("2006-01-02"))), nil
+}
+
 type JsonSpecialDateTime struct {
 	time.Time
 }